@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/kdimtricp/vshazam/internal/database"
+	"github.com/kdimtricp/vshazam/internal/imdb"
+)
+
+// imdb-import loads the IMDb datasets from https://datasets.imdbws.com/
+// (title.basics, title.akas, title.principals and name.basics, gzipped or
+// not) into the database for offline title matching. Run migrations first.
+func main() {
+	var (
+		dbType     = flag.String("db", "postgres", "Database type (postgres or sqlite)")
+		host       = flag.String("host", "localhost", "Database host")
+		port       = flag.Int("port", 5432, "Database port")
+		user       = flag.String("user", "vshazam", "Database user")
+		password   = flag.String("password", "vshazam_dev", "Database password")
+		dbName     = flag.String("name", "vshazam", "Database name")
+		sqlitePath = flag.String("sqlite", "./vshazam.db", "SQLite database path")
+		dir        = flag.String("dir", ".", "Directory containing the dataset files")
+		types      = flag.String("types", strings.Join(imdb.DefaultTitleTypes, ","), "Comma-separated title types to import")
+	)
+	flag.Parse()
+
+	config := database.Config{
+		Type:       *dbType,
+		Host:       *host,
+		Port:       *port,
+		User:       *user,
+		Password:   *password,
+		Name:       *dbName,
+		SQLitePath: *sqlitePath,
+	}
+
+	// Override with environment variables if set
+	if env := os.Getenv("DB_TYPE"); env != "" {
+		config.Type = env
+	}
+	if env := os.Getenv("DB_HOST"); env != "" {
+		config.Host = env
+	}
+	if env := os.Getenv("DB_USER"); env != "" {
+		config.User = env
+	}
+	if env := os.Getenv("DB_PASSWORD"); env != "" {
+		config.Password = env
+	}
+	if env := os.Getenv("DB_NAME"); env != "" {
+		config.Name = env
+	}
+	if env := os.Getenv("DB_PATH"); env != "" {
+		config.SQLitePath = env
+	}
+
+	db, err := database.NewDB(config)
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	defer db.Close()
+
+	importer := imdb.NewImporter(database.NewIMDbRepo(db))
+	importer.UseTitleTypes(strings.Split(*types, ",")...)
+
+	fmt.Printf("Importing IMDb datasets from %s...\n", *dir)
+	stats, err := importer.ImportDir(context.Background(), *dir)
+	if err != nil {
+		log.Fatal("Failed to import IMDb datasets:", err)
+	}
+	fmt.Printf("Imported %d titles, %d alternative titles, %d principals and %d names.\n",
+		stats.Titles, stats.Akas, stats.Principals, stats.Names)
+}
@@ -1,15 +1,29 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/kdimtricp/vshazam/internal/ai"
 	"github.com/kdimtricp/vshazam/internal/api"
+	"github.com/kdimtricp/vshazam/internal/auth"
+	"github.com/kdimtricp/vshazam/internal/catalog"
 	"github.com/kdimtricp/vshazam/internal/database"
+	"github.com/kdimtricp/vshazam/internal/faces"
+	"github.com/kdimtricp/vshazam/internal/identify"
+	"github.com/kdimtricp/vshazam/internal/imdb"
+	"github.com/kdimtricp/vshazam/internal/mdb"
+	"github.com/kdimtricp/vshazam/internal/metering"
+	"github.com/kdimtricp/vshazam/internal/ratelimit"
+	"github.com/kdimtricp/vshazam/internal/sharing"
 	"github.com/kdimtricp/vshazam/internal/storage"
+	"github.com/kdimtricp/vshazam/internal/subtitles"
+	"github.com/kdimtricp/vshazam/internal/trash"
 )
 
 func main() {
@@ -101,14 +115,48 @@ func main() {
 
 	videoRepo := database.NewVideoRepository(db)
 	frameRepo := database.NewFrameAnalysisRepo(db)
+	identificationRepo := database.NewIdentificationRepo(db)
+	usageRepo := database.NewUsageRepo(db)
+	faceRepo := database.NewFaceRepo(db)
+	transcriptRepo := database.NewTranscriptRepo(db)
+	subtitleRepo := database.NewSubtitleRepo(db)
+	filmRepo := database.NewFilmRepo(db)
+	imdbRepo := database.NewIMDbRepo(db)
+	userRepo := database.NewUserRepo(db)
+
+	authService := auth.NewService(userRepo)
+	authService.UseAPIKeys(database.NewAPIKeyRepo(db))
+
+	sharingService := sharing.NewService(database.NewShareRepo(db), os.Getenv("SHARE_SECRET"))
+	if ttlStr := os.Getenv("SESSION_TTL"); ttlStr != "" {
+		if ttl, err := time.ParseDuration(ttlStr); err == nil && ttl > 0 {
+			authService.UseSessionTTL(ttl)
+		}
+	}
 
 	aiConfig := &ai.Config{
 		OpenAIAPIKey:               os.Getenv("OPENAI_API_KEY"),
+		OpenAIBaseURL:              os.Getenv("OPENAI_BASE_URL"),
+		OpenAIModel:                os.Getenv("OPENAI_MODEL"),
+		OpenAIAPIVersion:           os.Getenv("OPENAI_API_VERSION"),
+		OpenAIImageDetail:          os.Getenv("OPENAI_IMAGE_DETAIL"),
 		GoogleVisionKey:            os.Getenv("GOOGLE_VISION_API_KEY"),
 		GoogleVisionServiceAccount: os.Getenv("GOOGLE_VISION_SERVICE_ACCOUNT"),
 		GoogleSearchAPIKey:         os.Getenv("GOOGLE_SEARCH_API_KEY"),
 		GoogleCSEID:                os.Getenv("GOOGLE_CSE_ID"),
 		TMDbAPIKey:                 os.Getenv("TMDB_API_KEY"),
+		TMDbLanguage:               os.Getenv("TMDB_LANGUAGE"),
+		TMDbRegion:                 os.Getenv("TMDB_REGION"),
+		OMDbAPIKey:                 os.Getenv("OMDB_API_KEY"),
+		IMDbDataset:                os.Getenv("IMDB_DATASET") == "true",
+		SpeechProvider:             os.Getenv("SPEECH_PROVIDER"),
+		SpeechBaseURL:              os.Getenv("SPEECH_BASE_URL"),
+		SpeechAPIKey:               os.Getenv("SPEECH_API_KEY"),
+		SpeechModel:                os.Getenv("SPEECH_MODEL"),
+		SpeechLanguage:             os.Getenv("SPEECH_LANGUAGE"),
+	}
+	if aiConfig.SpeechAPIKey == "" && aiConfig.SpeechProvider == ai.SpeechOpenAI {
+		aiConfig.SpeechAPIKey = aiConfig.OpenAIAPIKey
 	}
 
 	maxFramesStr := os.Getenv("MAX_FRAMES_PER_VIDEO")
@@ -131,14 +179,108 @@ func main() {
 		aiConfig.FrameSize = 512
 	}
 
+	thresholdStr := os.Getenv("CONFIDENCE_THRESHOLD")
+	if thresholdStr != "" {
+		if threshold, err := strconv.ParseFloat(thresholdStr, 64); err == nil {
+			aiConfig.ConfidenceThreshold = threshold
+		}
+	}
+	if aiConfig.ConfidenceThreshold == 0 {
+		aiConfig.ConfidenceThreshold = 0.90
+	}
+
+	marginStr := os.Getenv("CONFIDENCE_MARGIN")
+	if marginStr != "" {
+		if margin, err := strconv.ParseFloat(marginStr, 64); err == nil {
+			aiConfig.ConfidenceMargin = margin
+		}
+	}
+	if aiConfig.ConfidenceMargin == 0 {
+		aiConfig.ConfidenceMargin = 0.20
+	}
+
+	maxAnalyzeStr := os.Getenv("MAX_FRAMES_ANALYZE")
+	if maxAnalyzeStr != "" {
+		if maxAnalyze, err := strconv.Atoi(maxAnalyzeStr); err == nil {
+			aiConfig.MaxFramesAnalyze = maxAnalyze
+		}
+	}
+	if aiConfig.MaxFramesAnalyze == 0 {
+		aiConfig.MaxFramesAnalyze = 10
+	}
+
+	speechMaxStr := os.Getenv("SPEECH_MAX_SECONDS")
+	if speechMaxStr != "" {
+		if speechMax, err := strconv.ParseFloat(speechMaxStr, 64); err == nil {
+			aiConfig.SpeechMaxSeconds = speechMax
+		}
+	}
+
+	aiConfig.OpenAIPlainCaption, _ = strconv.ParseBool(os.Getenv("OPENAI_PLAIN_CAPTION"))
+	aiConfig.OpenAIMultiFrame, _ = strconv.ParseBool(os.Getenv("OPENAI_MULTI_FRAME"))
+
+	if features := os.Getenv("GOOGLE_VISION_FEATURES"); features != "" {
+		aiConfig.GoogleVisionFeatures = strings.Split(features, ",")
+	}
+
+	maxTokensStr := os.Getenv("OPENAI_MAX_TOKENS")
+	if maxTokensStr != "" {
+		if maxTokens, err := strconv.Atoi(maxTokensStr); err == nil {
+			aiConfig.OpenAIMaxTokens = maxTokens
+		}
+	}
+
+	temperatureStr := os.Getenv("OPENAI_TEMPERATURE")
+	if temperatureStr != "" {
+		if temperature, err := strconv.ParseFloat(temperatureStr, 64); err == nil {
+			aiConfig.OpenAITemperature = &temperature
+		}
+	}
+
+	promptPath := os.Getenv("OPENAI_PROMPT_TEMPLATE")
+	if promptPath != "" {
+		prompt, err := os.ReadFile(promptPath)
+		if err != nil {
+			log.Fatal("Failed to read prompt template:", err)
+		}
+		aiConfig.OpenAIPromptTemplate = string(prompt)
+	}
+
+	budget := metering.DefaultBudget()
+
+	dailyBudgetStr := os.Getenv("BUDGET_DAILY_USD")
+	if dailyBudgetStr != "" {
+		if daily, err := strconv.ParseFloat(dailyBudgetStr, 64); err == nil {
+			budget.Daily = daily
+		}
+	}
+
+	monthlyBudgetStr := os.Getenv("BUDGET_MONTHLY_USD")
+	if monthlyBudgetStr != "" {
+		if monthly, err := strconv.ParseFloat(monthlyBudgetStr, 64); err == nil {
+			budget.Monthly = monthly
+		}
+	}
+
+	degradeAtStr := os.Getenv("BUDGET_DEGRADE_AT")
+	if degradeAtStr != "" {
+		if degradeAt, err := strconv.ParseFloat(degradeAtStr, 64); err == nil {
+			budget.DegradeAt = degradeAt
+		}
+	}
+
+	meter := metering.NewTracker(usageRepo, metering.DefaultPricing(), budget)
+
 	var visionService ai.VisionService
 	var frameExtractor *ai.FrameExtractor
 
-	if aiConfig.OpenAIAPIKey != "" || aiConfig.GoogleVisionKey != "" || aiConfig.GoogleVisionServiceAccount != "" {
-		visionService, err = ai.NewVisionService(aiConfig)
+	if aiConfig.OpenAIAPIKey != "" || aiConfig.OpenAIBaseURL != "" || aiConfig.GoogleVisionKey != "" || aiConfig.GoogleVisionServiceAccount != "" {
+		service, err := ai.NewVisionService(aiConfig)
 		if err != nil {
 			log.Printf("Warning: Failed to initialize vision service: %v", err)
 		} else {
+			service.UseMeter(meter)
+			visionService = service
 			frameExtractor, err = ai.NewFrameExtractor()
 			if err != nil {
 				log.Printf("Warning: Failed to initialize frame extractor: %v", err)
@@ -148,15 +290,161 @@ func main() {
 		log.Printf("AI services not configured. Set at least one: OPENAI_API_KEY, GOOGLE_VISION_API_KEY, or GOOGLE_VISION_SERVICE_ACCOUNT")
 	}
 
+	var tmdbClient *mdb.TMDbClient
+	if aiConfig.TMDbAPIKey != "" {
+		tmdbClient = mdb.NewTMDbClient(aiConfig.TMDbAPIKey)
+		tmdbClient.UseMeter(meter)
+		tmdbClient.UseLocale(aiConfig.TMDbLanguage, aiConfig.TMDbRegion)
+	}
+
+	filmCatalog := catalog.New(filmRepo, nil)
+	if tmdbClient != nil {
+		filmCatalog = catalog.New(filmRepo, tmdbClient)
+	}
+	if maxAgeStr := os.Getenv("FILM_CATALOG_MAX_AGE"); maxAgeStr != "" {
+		if maxAge, err := time.ParseDuration(maxAgeStr); err == nil && maxAge > 0 {
+			filmCatalog.UseMaxAge(maxAge)
+		}
+	}
+	if tmdbClient != nil {
+		interval := 24 * time.Hour
+		if intervalStr := os.Getenv("FILM_CATALOG_REFRESH_INTERVAL"); intervalStr != "" {
+			if parsed, err := time.ParseDuration(intervalStr); err == nil {
+				interval = parsed
+			}
+		}
+		if interval > 0 {
+			go filmCatalog.Run(context.Background(), interval)
+		}
+	}
+
+	var identifier *identify.Identifier
+	var faceService *faces.Service
+	if visionService != nil && frameExtractor != nil {
+		var searchClient identify.GoogleSearchClientInterface
+		if aiConfig.GoogleSearchAPIKey != "" && aiConfig.GoogleCSEID != "" {
+			googleSearch := ai.NewGoogleSearchClient(aiConfig.GoogleSearchAPIKey, aiConfig.GoogleCSEID)
+			googleSearch.UseMeter(meter)
+			searchClient = googleSearch
+		}
+
+		// The catalog answers from films identified before and falls back to
+		// TMDb when it is configured.
+		scorer := identify.NewScorer(filmCatalog, searchClient, identify.DefaultWeights())
+		scorer.UseReferences(identificationRepo)
+		// The offline dataset is free, so it is asked before OMDb's daily
+		// quota is spent.
+		if aiConfig.IMDbDataset {
+			dataset := imdb.NewDataset(imdbRepo)
+			scorer.UseProviders(dataset)
+			scorer.UseAliases(dataset)
+		}
+		if aiConfig.OMDbAPIKey != "" {
+			omdbClient := mdb.NewOMDbClient(aiConfig.OMDbAPIKey)
+			omdbClient.UseMeter(meter)
+			scorer.UseProviders(omdbClient)
+		}
+		identifier = identify.NewIdentifier(visionService, frameExtractor, frameRepo, scorer, aiConfig)
+
+		// Thumbnails compared pixel by pixel only match within a video; a
+		// face recognition model is needed to match people across the library.
+		embeddingURL := os.Getenv("FACE_EMBEDDING_URL")
+		var embedder faces.Embedder = faces.NewPixelEmbedder()
+		if embeddingURL != "" {
+			embedder = faces.NewHTTPEmbedder(embeddingURL)
+		}
+		faceService = faces.NewService(localStorage, faceRepo, embedder)
+		if embeddingURL != "" {
+			faceService.UseLibraryClustering()
+		}
+		if thresholdStr := os.Getenv("FACE_CLUSTER_THRESHOLD"); thresholdStr != "" {
+			if threshold, err := strconv.ParseFloat(thresholdStr, 64); err == nil {
+				faceService.UseThreshold(threshold)
+			}
+		}
+		identifier.UseFaces(faceService)
+		identifier.UseTranscripts(transcriptRepo)
+		identifier.UseCatalog(filmCatalog)
+
+		if aiConfig.SpeechProvider != "" {
+			speechClient, err := ai.NewSpeechClient(aiConfig.SpeechAPIKey, ai.SpeechOptions{
+				Provider: aiConfig.SpeechProvider,
+				BaseURL:  aiConfig.SpeechBaseURL,
+				Model:    aiConfig.SpeechModel,
+				Language: aiConfig.SpeechLanguage,
+			})
+			if err != nil {
+				log.Printf("Warning: Failed to initialize speech transcription: %v", err)
+			} else {
+				speechClient.UseMeter(meter)
+				identifier.UseSpeech(frameExtractor, speechClient)
+				log.Printf("Speech transcription enabled (provider: %s)", aiConfig.SpeechProvider)
+			}
+		}
+	}
+
+	subtitleService := subtitles.NewService(localStorage, subtitleRepo, transcriptRepo)
+	if subtitleExtractor, err := subtitles.NewExtractor(); err != nil {
+		log.Printf("Warning: Embedded subtitles will not be imported: %v", err)
+	} else {
+		subtitleService.UseExtractor(subtitleExtractor)
+	}
+
+	trashService := trash.New(videoRepo, localStorage)
+	if faceService != nil {
+		trashService.UseFaces(faceService)
+	}
+	if daysStr := os.Getenv("TRASH_RETENTION_DAYS"); daysStr != "" {
+		days, err := strconv.Atoi(daysStr)
+		if err != nil || days < 0 {
+			log.Fatal("Invalid TRASH_RETENTION_DAYS:", daysStr)
+		}
+		trashService.UseRetention(time.Duration(days) * 24 * time.Hour)
+	}
+	go trashService.Run(context.Background(), time.Hour)
+
 	app := &api.App{
-		Storage:        localStorage,
-		DB:             db,
-		VideoRepo:      videoRepo,
-		FrameRepo:      frameRepo,
-		MaxUploadSize:  maxSize,
-		VisionService:  visionService,
-		FrameExtractor: frameExtractor,
-		AIConfig:       aiConfig,
+		Storage:            localStorage,
+		DB:                 db,
+		VideoRepo:          videoRepo,
+		FrameRepo:          frameRepo,
+		IdentificationRepo: identificationRepo,
+		UsageRepo:          usageRepo,
+		FaceRepo:           faceRepo,
+		TranscriptRepo:     transcriptRepo,
+		SubtitleRepo:       subtitleRepo,
+		FilmRepo:           filmRepo,
+		Subtitles:          subtitleService,
+		MaxUploadSize:      maxSize,
+		VisionService:      visionService,
+		FrameExtractor:     frameExtractor,
+		AIConfig:           aiConfig,
+		Identifier:         identifier,
+		TMDbClient:         tmdbClient,
+		Meter:              meter,
+		Auth:               authService,
+		Sharing:            sharingService,
+		Trash:              trashService,
+	}
+
+	app.RateLimits = api.RateLimits{
+		Default:  rateLimiter("RATE_LIMIT", "600/m"),
+		Upload:   rateLimiter("RATE_LIMIT_UPLOAD", "20/h"),
+		Identify: rateLimiter("RATE_LIMIT_IDENTIFY", "30/h"),
+	}
+	if quotaStr := os.Getenv("USER_STORAGE_QUOTA"); quotaStr != "" {
+		quota, err := strconv.ParseInt(quotaStr, 10, 64)
+		if err != nil {
+			log.Fatal("Invalid USER_STORAGE_QUOTA:", err)
+		}
+		app.StorageQuota = quota
+	}
+	if quotaStr := os.Getenv("IDENTIFY_DAILY_QUOTA"); quotaStr != "" {
+		quota, err := strconv.Atoi(quotaStr)
+		if err != nil {
+			log.Fatal("Invalid IDENTIFY_DAILY_QUOTA:", err)
+		}
+		app.DailyIdentifyQuota = quota
 	}
 
 	router := api.NewRouter(app)
@@ -175,3 +463,17 @@ func main() {
 		log.Fatal(err)
 	}
 }
+
+// rateLimiter builds the limiter configured in the environment variable
+// name, e.g. "20/h", falling back to fallback.
+func rateLimiter(name, fallback string) *ratelimit.Limiter {
+	value := os.Getenv(name)
+	if value == "" {
+		value = fallback
+	}
+	policy, err := ratelimit.ParsePolicy(value)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", name, err)
+	}
+	return ratelimit.NewLimiter(policy)
+}
@@ -84,6 +84,53 @@ func (fe *FrameExtractor) ExtractFrames(videoPath string, count int, size int) (
 	return frames, nil
 }
 
+// VideoDuration returns the length of the video in seconds.
+func (fe *FrameExtractor) VideoDuration(videoPath string) (float64, error) {
+	if _, err := os.Stat(videoPath); err != nil {
+		return 0, fmt.Errorf("video file not accessible: %w", err)
+	}
+	return fe.getVideoDuration(videoPath)
+}
+
+// ExtractFrameAt extracts a single JPEG frame at the given timestamp in seconds.
+func (fe *FrameExtractor) ExtractFrameAt(videoPath string, timestamp float64, size int) ([]byte, error) {
+	return fe.extractSingleFrame(videoPath, timestamp, size)
+}
+
+// ExtractAudio returns up to maxSeconds of the video's audio as 16 kHz mono
+// WAV, the format speech models are trained on. maxSeconds <= 0 uses a
+// 10 minute limit.
+func (fe *FrameExtractor) ExtractAudio(videoPath string, maxSeconds float64) ([]byte, error) {
+	if maxSeconds <= 0 {
+		maxSeconds = defaultSpeechMaxSeconds
+	}
+
+	args := []string{
+		"-i", videoPath,
+		"-t", fmt.Sprintf("%.2f", maxSeconds),
+		"-vn",
+		"-ac", "1",
+		"-ar", "16000",
+		"-f", "wav",
+		"pipe:1",
+	}
+	cmd := exec.Command(fe.ffmpegPath, args...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		log.Printf("FFmpeg stderr output: %s", stderr.String())
+		return nil, fmt.Errorf("failed to extract audio: %w", err)
+	}
+	if stdout.Len() == 0 {
+		return nil, fmt.Errorf("video has no audio track")
+	}
+
+	return stdout.Bytes(), nil
+}
+
 func (fe *FrameExtractor) getVideoDuration(videoPath string) (float64, error) {
 	// Try ffprobe first for more reliable duration detection
 	ffprobePath, err := exec.LookPath("ffprobe")
@@ -6,41 +6,93 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
-	"time"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/kdimtricp/vshazam/internal/metering"
+	"github.com/kdimtricp/vshazam/internal/outbound"
 )
 
 type GoogleSearchClient struct {
 	apiKey         string
 	searchEngineID string
-	httpClient     *http.Client
+	baseURL        string
+	httpClient     *outbound.Client
+	meter          metering.Meter
 }
 
+// SearchResult is one web page. FilmTitle and Year are set when the page is
+// about a single film, and IMDbID when it names the film's IMDb entry.
 type SearchResult struct {
-	Title   string
-	Link    string
-	Snippet string
+	Title     string
+	Link      string
+	Snippet   string
+	FilmTitle string
+	Year      int
+	IMDbID    string
+}
+
+type googleSearchItem struct {
+	Title   string `json:"title"`
+	Link    string `json:"link"`
+	Snippet string `json:"snippet"`
+	// Pagemap carries structured data Google extracted from the page, such
+	// as schema.org Movie entries and Open Graph meta tags.
+	Pagemap struct {
+		Movie []struct {
+			Name          string `json:"name"`
+			DatePublished string `json:"datepublished"`
+			DateCreated   string `json:"datecreated"`
+		} `json:"movie"`
+		Metatags []map[string]string `json:"metatags"`
+	} `json:"pagemap"`
 }
 
 type googleSearchResponse struct {
-	Items []struct {
-		Title   string `json:"title"`
-		Link    string `json:"link"`
-		Snippet string `json:"snippet"`
-	} `json:"items"`
+	Items []googleSearchItem `json:"items"`
 }
 
 func NewGoogleSearchClient(apiKey, searchEngineID string) *GoogleSearchClient {
 	return &GoogleSearchClient{
 		apiKey:         apiKey,
 		searchEngineID: searchEngineID,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		baseURL:        "https://www.googleapis.com/customsearch/v1",
+		httpClient:     outbound.New("google_search", googleSearchPolicy()),
+		meter:          metering.Nop{},
 	}
 }
 
+// UseBaseURL points the client at another Custom Search endpoint.
+func (c *GoogleSearchClient) UseBaseURL(baseURL string) {
+	c.baseURL = baseURL
+}
+
+// UseTransport sends requests through rt instead of the default transport.
+func (c *GoogleSearchClient) UseTransport(rt http.RoundTripper) {
+	c.httpClient.UseTransport(rt)
+}
+
+// UseMeter records every query with m and checks its budget before each call.
+func (c *GoogleSearchClient) UseMeter(m metering.Meter) {
+	c.meter = m
+}
+
+// googleSearchPolicy stays well under the Custom Search limit of 100 queries
+// per minute.
+func googleSearchPolicy() outbound.Policy {
+	policy := outbound.DefaultPolicy()
+	policy.RequestsPerSecond = 1
+	policy.Burst = 5
+	return policy
+}
+
 func (c *GoogleSearchClient) SearchFilms(ctx context.Context, query string) ([]SearchResult, error) {
-	apiURL := "https://www.googleapis.com/customsearch/v1"
+	if err := c.meter.Allow(ctx, metering.ProviderGoogleSearch); err != nil {
+		return nil, err
+	}
 
 	params := url.Values{}
 	params.Set("key", c.apiKey)
@@ -48,7 +100,7 @@ func (c *GoogleSearchClient) SearchFilms(ctx context.Context, query string) ([]S
 	params.Set("q", query)
 	params.Set("num", "10")
 
-	fullURL := fmt.Sprintf("%s?%s", apiURL, params.Encode())
+	fullURL := fmt.Sprintf("%s?%s", c.baseURL, params.Encode())
 
 	req, err := http.NewRequestWithContext(ctx, "GET", fullURL, nil)
 	if err != nil {
@@ -61,9 +113,12 @@ func (c *GoogleSearchClient) SearchFilms(ctx context.Context, query string) ([]S
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("search API returned status %d", resp.StatusCode)
-	}
+	c.meter.Record(ctx, metering.Event{
+		Provider:  metering.ProviderGoogleSearch,
+		Operation: "search",
+		Units:     1,
+		Unit:      "queries",
+	})
 
 	var searchResp googleSearchResponse
 	if err := json.NewDecoder(resp.Body).Decode(&searchResp); err != nil {
@@ -72,12 +127,137 @@ func (c *GoogleSearchClient) SearchFilms(ctx context.Context, query string) ([]S
 
 	results := make([]SearchResult, 0, len(searchResp.Items))
 	for _, item := range searchResp.Items {
-		results = append(results, SearchResult{
-			Title:   item.Title,
-			Link:    item.Link,
-			Snippet: item.Snippet,
-		})
+		results = append(results, parseSearchItem(item))
 	}
 
 	return results, nil
 }
+
+var (
+	imdbTitleLink = regexp.MustCompile(`imdb\.com/(?:[a-z]{2}/)?title/(tt\d{7,9})`)
+	// "Inception (2010) - IMDb", "Heat (1995 film) - Wikipedia"
+	titleWithYear = regexp.MustCompile(`^(.{1,80}?)\s*\((\d{4})(?: film)?\)`)
+	// "Inception is a 2010 science fiction action film"
+	wikipediaFilmSnippet = regexp.MustCompile(`\bis an? (\d{4})\b[^.]{0,80}\bfilm\b`)
+)
+
+// parseSearchItem recognizes pages about a single film from, in order of
+// reliability, schema.org Movie data, an Open Graph title of a video.movie
+// page, a "Title (Year)" page title and a Wikipedia article whose snippet
+// introduces a film.
+func parseSearchItem(item googleSearchItem) SearchResult {
+	result := SearchResult{
+		Title:   item.Title,
+		Link:    item.Link,
+		Snippet: item.Snippet,
+	}
+
+	if match := imdbTitleLink.FindStringSubmatch(item.Link); match != nil {
+		result.IMDbID = match[1]
+	}
+	for _, tags := range item.Pagemap.Metatags {
+		if result.IMDbID == "" && strings.HasPrefix(tags["imdb:pageconst"], "tt") {
+			result.IMDbID = tags["imdb:pageconst"]
+		}
+	}
+
+	if len(item.Pagemap.Movie) > 0 && item.Pagemap.Movie[0].Name != "" {
+		movie := item.Pagemap.Movie[0]
+		result.FilmTitle = strings.TrimSpace(movie.Name)
+		result.Year = yearPrefix(movie.DatePublished)
+		if result.Year == 0 {
+			result.Year = yearPrefix(movie.DateCreated)
+		}
+		return result
+	}
+
+	for _, tags := range item.Pagemap.Metatags {
+		if tags["og:type"] != "video.movie" {
+			continue
+		}
+		if match := titleWithYear.FindStringSubmatch(tags["og:title"]); match != nil {
+			result.FilmTitle, result.Year = strings.TrimSpace(match[1]), yearPrefix(match[2])
+			return result
+		}
+	}
+
+	if match := titleWithYear.FindStringSubmatch(item.Title); match != nil {
+		result.FilmTitle, result.Year = strings.TrimSpace(match[1]), yearPrefix(match[2])
+		return result
+	}
+
+	if strings.HasSuffix(item.Title, " - Wikipedia") {
+		if match := wikipediaFilmSnippet.FindStringSubmatch(item.Snippet); match != nil {
+			result.FilmTitle = strings.TrimSuffix(item.Title, " - Wikipedia")
+			result.Year = yearPrefix(match[1])
+		}
+	}
+
+	return result
+}
+
+func yearPrefix(date string) int {
+	if len(date) < 4 {
+		return 0
+	}
+	year, err := strconv.Atoi(date[:4])
+	if err != nil || year < 1880 || year > 2100 {
+		return 0
+	}
+	return year
+}
+
+// FilmHit is one film named by one or more search results.
+type FilmHit struct {
+	Title   string
+	Year    int
+	IMDbID  string
+	Results []SearchResult
+}
+
+// GroupFilms deduplicates the films named by results. Results with the same
+// IMDb ID, or the same title and no conflicting year, are one film. Films
+// named by the most results come first.
+func GroupFilms(results []SearchResult) []*FilmHit {
+	var hits []*FilmHit
+	for _, r := range results {
+		if r.FilmTitle == "" {
+			continue
+		}
+		key := filmKey(r.FilmTitle)
+
+		var hit *FilmHit
+		for _, h := range hits {
+			sameID := r.IMDbID != "" && h.IMDbID == r.IMDbID
+			sameTitle := filmKey(h.Title) == key && (h.Year == 0 || r.Year == 0 || h.Year == r.Year) &&
+				(h.IMDbID == "" || r.IMDbID == "" || h.IMDbID == r.IMDbID)
+			if sameID || sameTitle {
+				hit = h
+				break
+			}
+		}
+		if hit == nil {
+			hit = &FilmHit{Title: r.FilmTitle}
+			hits = append(hits, hit)
+		}
+		if hit.Year == 0 {
+			hit.Year = r.Year
+		}
+		if hit.IMDbID == "" {
+			hit.IMDbID = r.IMDbID
+		}
+		hit.Results = append(hit.Results, r)
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		return len(hits[i].Results) > len(hits[j].Results)
+	})
+	return hits
+}
+
+// filmKey compares titles case- and punctuation-insensitively.
+func filmKey(title string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
@@ -0,0 +1,144 @@
+package ai
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/kdimtricp/vshazam/internal/outbound"
+	"github.com/kdimtricp/vshazam/internal/outbound/cassette"
+)
+
+func newCassetteSearchClient(t *testing.T, name string) *GoogleSearchClient {
+	t.Helper()
+	client := NewGoogleSearchClient("test-key", "test-cse")
+	client.UseTransport(cassette.ForTest(t, filepath.Join("testdata", "cassettes", name)))
+	return client
+}
+
+func TestGoogleSearchClientCassette(t *testing.T) {
+	client := newCassetteSearchClient(t, "google_search_success.json")
+
+	results, err := client.SearchFilms(context.Background(), `"Inception" film 2010`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	if results[0].Link != "https://www.imdb.com/title/tt1375666/" {
+		t.Errorf("unexpected first result %+v", results[0])
+	}
+	for i, result := range results {
+		if result.FilmTitle != "Inception" || result.Year != 2010 {
+			t.Errorf("result %d: expected Inception (2010), got %q (%d)", i, result.FilmTitle, result.Year)
+		}
+	}
+	if results[0].IMDbID != "tt1375666" {
+		t.Errorf("expected IMDb ID tt1375666, got %q", results[0].IMDbID)
+	}
+
+	films := GroupFilms(results)
+	if len(films) != 1 || len(films[0].Results) != 3 || films[0].IMDbID != "tt1375666" {
+		t.Errorf("expected one film with three results, got %+v", films)
+	}
+}
+
+func TestParseSearchItem(t *testing.T) {
+	tests := []struct {
+		name   string
+		item   googleSearchItem
+		title  string
+		year   int
+		imdbID string
+	}{
+		{
+			name:   "titled page",
+			item:   googleSearchItem{Title: "Heat (1995) - IMDb", Link: "https://www.imdb.com/title/tt0113277/"},
+			title:  "Heat",
+			year:   1995,
+			imdbID: "tt0113277",
+		},
+		{
+			name:  "wikipedia film page",
+			item:  googleSearchItem{Title: "Heat (1995 film) - Wikipedia", Link: "https://en.wikipedia.org/wiki/Heat_(1995_film)"},
+			title: "Heat",
+			year:  1995,
+		},
+		{
+			name: "wikipedia snippet",
+			item: googleSearchItem{
+				Title:   "Amélie - Wikipedia",
+				Link:    "https://en.wikipedia.org/wiki/Am%C3%A9lie",
+				Snippet: "Amélie is a 2001 French romantic comedy film directed by Jean-Pierre Jeunet.",
+			},
+			title: "Amélie",
+			year:  2001,
+		},
+		{
+			name: "wikipedia article about something else",
+			item: googleSearchItem{
+				Title:   "Heat - Wikipedia",
+				Link:    "https://en.wikipedia.org/wiki/Heat",
+				Snippet: "In thermodynamics, heat is energy in transfer to or from a system.",
+			},
+		},
+		{
+			name:   "tv series",
+			item:   googleSearchItem{Title: "Breaking Bad (TV Series 2008–2013) - IMDb", Link: "https://www.imdb.com/title/tt0903747/"},
+			imdbID: "tt0903747",
+		},
+		{
+			name:   "localized imdb link",
+			item:   googleSearchItem{Title: "Heat", Link: "https://m.imdb.com/de/title/tt0113277/"},
+			imdbID: "tt0113277",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := parseSearchItem(tt.item)
+			if result.FilmTitle != tt.title || result.Year != tt.year || result.IMDbID != tt.imdbID {
+				t.Errorf("expected %q (%d) %q, got %q (%d) %q",
+					tt.title, tt.year, tt.imdbID, result.FilmTitle, result.Year, result.IMDbID)
+			}
+		})
+	}
+}
+
+func TestGroupFilms(t *testing.T) {
+	results := []SearchResult{
+		{Link: "a", FilmTitle: "Heat", Year: 1995, IMDbID: "tt0113277"},
+		{Link: "b", FilmTitle: "Heat", Year: 1995},
+		{Link: "c", FilmTitle: "Heat", Year: 1972},
+		{Link: "d", FilmTitle: "heat"},
+		{Link: "e"},
+		{Link: "f", FilmTitle: "Ronin", Year: 1998},
+	}
+
+	films := GroupFilms(results)
+	if len(films) != 3 {
+		t.Fatalf("expected 3 films, got %d: %+v", len(films), films)
+	}
+	if films[0].Title != "Heat" || films[0].Year != 1995 || films[0].IMDbID != "tt0113277" || len(films[0].Results) != 3 {
+		t.Errorf("unexpected first film %+v", films[0])
+	}
+}
+
+func TestGoogleSearchClientCassetteQuota(t *testing.T) {
+	client := newCassetteSearchClient(t, "google_search_quota.json")
+
+	_, err := client.SearchFilms(context.Background(), `"Inception" film 2010`)
+	if !outbound.IsQuota(err) {
+		t.Errorf("expected quota error, got %v", err)
+	}
+}
+
+func TestGoogleSearchClientCassetteMalformed(t *testing.T) {
+	client := newCassetteSearchClient(t, "google_search_malformed.json")
+
+	if _, err := client.SearchFilms(context.Background(), `"Inception" film 2010`); err == nil {
+		t.Error("expected error for truncated response")
+	}
+}
@@ -6,31 +6,74 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"html"
 	"io"
 	"io/ioutil"
+	"math"
 	"net/http"
-	"time"
+	"regexp"
+	"strings"
 
+	"github.com/kdimtricp/vshazam/internal/metering"
+	"github.com/kdimtricp/vshazam/internal/outbound"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 )
 
-const googleVisionAPIURL = "https://vision.googleapis.com/v1/images:annotate"
+const defaultGoogleVisionBaseURL = "https://vision.googleapis.com/v1"
+
+// DefaultVisionFeatures are requested when no feature list is configured.
+// Cloud Vision bills every feature separately.
+var DefaultVisionFeatures = []string{
+	"LABEL_DETECTION",
+	"TEXT_DETECTION",
+	"FACE_DETECTION",
+	"IMAGE_PROPERTIES",
+	"WEB_DETECTION",
+	"LOGO_DETECTION",
+	"LANDMARK_DETECTION",
+	"OBJECT_LOCALIZATION",
+}
+
+// visionFeatureLimits holds the maxResults sent for each supported feature.
+var visionFeatureLimits = map[string]int{
+	"LABEL_DETECTION":     10,
+	"TEXT_DETECTION":      10,
+	"FACE_DETECTION":      10,
+	"IMAGE_PROPERTIES":    5,
+	"WEB_DETECTION":       10,
+	"LOGO_DETECTION":      5,
+	"LANDMARK_DETECTION":  5,
+	"OBJECT_LOCALIZATION": 10,
+}
+
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
 
 type GoogleVisionClient struct {
 	apiKey            string
-	httpClient        *http.Client
+	baseURL           string
+	httpClient        *outbound.Client
 	tokenSource       oauth2.TokenSource
 	useServiceAccount bool
+	meter             metering.Meter
+	features          []string
+}
+
+func googleVisionPolicy() outbound.Policy {
+	policy := outbound.DefaultPolicy()
+	policy.RequestsPerSecond = 10
+	policy.Burst = 10
+	return policy
 }
 
 func NewGoogleVisionClient(apiKey string) *GoogleVisionClient {
 	return &GoogleVisionClient{
-		apiKey: apiKey,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		apiKey:            apiKey,
+		baseURL:           defaultGoogleVisionBaseURL,
+		httpClient:        outbound.New("google_vision", googleVisionPolicy()),
 		useServiceAccount: false,
+		meter:             metering.Nop{},
+		features:          DefaultVisionFeatures,
 	}
 }
 
@@ -48,11 +91,12 @@ func NewGoogleVisionClientWithServiceAccount(serviceAccountPath string) (*Google
 	}
 
 	return &GoogleVisionClient{
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		baseURL:           defaultGoogleVisionBaseURL,
+		httpClient:        outbound.New("google_vision", googleVisionPolicy()),
 		tokenSource:       creds.TokenSource,
 		useServiceAccount: true,
+		meter:             metering.Nop{},
+		features:          DefaultVisionFeatures,
 	}, nil
 }
 
@@ -85,11 +129,63 @@ type googleError struct {
 }
 
 type annotateResponse struct {
-	LabelAnnotations          []labelAnnotation `json:"labelAnnotations"`
-	TextAnnotations           []textAnnotation  `json:"textAnnotations"`
-	FaceAnnotations           []faceAnnotation  `json:"faceAnnotations"`
-	ImagePropertiesAnnotation *imageProperties  `json:"imagePropertiesAnnotation"`
-	Error                     *googleError      `json:"error"`
+	LabelAnnotations           []labelAnnotation  `json:"labelAnnotations"`
+	TextAnnotations            []textAnnotation   `json:"textAnnotations"`
+	FaceAnnotations            []faceAnnotation   `json:"faceAnnotations"`
+	ImagePropertiesAnnotation  *imageProperties   `json:"imagePropertiesAnnotation"`
+	WebDetection               *webDetection      `json:"webDetection"`
+	LogoAnnotations            []entityAnnotation `json:"logoAnnotations"`
+	LandmarkAnnotations        []entityAnnotation `json:"landmarkAnnotations"`
+	LocalizedObjectAnnotations []localizedObject  `json:"localizedObjectAnnotations"`
+	Error                      *googleError       `json:"error"`
+}
+
+type webDetection struct {
+	WebEntities             []webEntity      `json:"webEntities"`
+	PagesWithMatchingImages []webPage        `json:"pagesWithMatchingImages"`
+	BestGuessLabels         []bestGuessLabel `json:"bestGuessLabels"`
+}
+
+type webEntity struct {
+	EntityID    string  `json:"entityId"`
+	Score       float64 `json:"score"`
+	Description string  `json:"description"`
+}
+
+type webPage struct {
+	URL       string  `json:"url"`
+	Score     float64 `json:"score"`
+	PageTitle string  `json:"pageTitle"`
+}
+
+type bestGuessLabel struct {
+	Label        string `json:"label"`
+	LanguageCode string `json:"languageCode"`
+}
+
+// entityAnnotation is shared by logo and landmark detection.
+type entityAnnotation struct {
+	Mid          string       `json:"mid"`
+	Description  string       `json:"description"`
+	Score        float64      `json:"score"`
+	BoundingPoly boundingPoly `json:"boundingPoly"`
+	Locations    []location   `json:"locations"`
+}
+
+type location struct {
+	LatLng latLng `json:"latLng"`
+}
+
+type latLng struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+type localizedObject struct {
+	Mid          string       `json:"mid"`
+	Name         string       `json:"name"`
+	Score        float64      `json:"score"`
+	BoundingPoly boundingPoly `json:"boundingPoly"`
 }
 
 type labelAnnotation struct {
@@ -99,8 +195,9 @@ type labelAnnotation struct {
 }
 
 type textAnnotation struct {
-	Description string `json:"description"`
-	Locale      string `json:"locale"`
+	Description  string       `json:"description"`
+	Locale       string       `json:"locale"`
+	BoundingPoly boundingPoly `json:"boundingPoly"`
 }
 
 type faceAnnotation struct {
@@ -109,7 +206,8 @@ type faceAnnotation struct {
 }
 
 type boundingPoly struct {
-	Vertices []vertex `json:"vertices"`
+	Vertices           []vertex           `json:"vertices"`
+	NormalizedVertices []normalizedVertex `json:"normalizedVertices"`
 }
 
 type vertex struct {
@@ -117,6 +215,11 @@ type vertex struct {
 	Y int `json:"y"`
 }
 
+type normalizedVertex struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
 type imageProperties struct {
 	DominantColors dominantColors `json:"dominantColors"`
 }
@@ -138,27 +241,80 @@ type color struct {
 }
 
 type VisionFeatures struct {
-	Labels []Label
-	Texts  []string
-	Faces  []FaceDetection
-	Colors []ColorInfo
+	Labels          []Label
+	Texts           []string
+	TextBlocks      []TextBlock
+	Faces           []FaceDetection
+	Colors          []ColorInfo
+	BestGuessLabels []string
+	WebEntities     []WebEntity
+	MatchingPages   []WebPage
+	Logos           []Logo
+	Landmarks       []Landmark
+	Objects         []DetectedObject
+}
+
+// UseBaseURL points the client at another Vision API root, e.g. a proxy or a
+// test server.
+func (c *GoogleVisionClient) UseBaseURL(baseURL string) {
+	c.baseURL = strings.TrimRight(baseURL, "/")
+}
+
+// UseTransport sends requests through rt instead of the default transport.
+func (c *GoogleVisionClient) UseTransport(rt http.RoundTripper) {
+	c.httpClient.UseTransport(rt)
+}
+
+// UseMeter records the feature units of every request with m and checks its
+// budget before each call.
+func (c *GoogleVisionClient) UseMeter(m metering.Meter) {
+	c.meter = m
+}
+
+// UseFeatures selects the Vision API features to request. Names are the API's
+// feature types, e.g. "WEB_DETECTION"; an empty list restores the defaults.
+func (c *GoogleVisionClient) UseFeatures(features []string) error {
+	if len(features) == 0 {
+		c.features = DefaultVisionFeatures
+		return nil
+	}
+
+	selected := make([]string, 0, len(features))
+	seen := make(map[string]bool)
+	for _, feature := range features {
+		feature = strings.ToUpper(strings.TrimSpace(feature))
+		if feature == "" || seen[feature] {
+			continue
+		}
+		if _, ok := visionFeatureLimits[feature]; !ok {
+			return fmt.Errorf("unsupported Google Vision feature %q", feature)
+		}
+		seen[feature] = true
+		selected = append(selected, feature)
+	}
+	c.features = selected
+	return nil
 }
 
 func (c *GoogleVisionClient) AnalyzeImage(ctx context.Context, imageData []byte) (*VisionFeatures, error) {
+	if err := c.meter.Allow(ctx, metering.ProviderGoogleVision); err != nil {
+		return nil, err
+	}
+
 	imageBase64 := base64.StdEncoding.EncodeToString(imageData)
 
+	features := make([]featureType, 0, len(c.features))
+	for _, feature := range c.features {
+		features = append(features, featureType{Type: feature, MaxResults: visionFeatureLimits[feature]})
+	}
+
 	reqBody := googleVisionRequest{
 		Requests: []imageRequest{
 			{
 				Image: imageContent{
 					Content: imageBase64,
 				},
-				Features: []featureType{
-					{Type: "LABEL_DETECTION", MaxResults: 10},
-					{Type: "TEXT_DETECTION", MaxResults: 10},
-					{Type: "FACE_DETECTION", MaxResults: 10},
-					{Type: "IMAGE_PROPERTIES", MaxResults: 5},
-				},
+				Features: features,
 			},
 		},
 	}
@@ -168,11 +324,9 @@ func (c *GoogleVisionClient) AnalyzeImage(ctx context.Context, imageData []byte)
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	var url string
-	if c.useServiceAccount {
-		url = googleVisionAPIURL
-	} else {
-		url = fmt.Sprintf("%s?key=%s", googleVisionAPIURL, c.apiKey)
+	url := c.baseURL + "/images:annotate"
+	if !c.useServiceAccount {
+		url = fmt.Sprintf("%s?key=%s", url, c.apiKey)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
@@ -192,7 +346,7 @@ func (c *GoogleVisionClient) AnalyzeImage(ctx context.Context, imageData []byte)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+		return nil, fmt.Errorf("Google Vision request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
@@ -219,7 +373,15 @@ func (c *GoogleVisionClient) AnalyzeImage(ctx context.Context, imageData []byte)
 		return nil, fmt.Errorf("Google Vision API error: %s", response.Error.Message)
 	}
 
-	features := &VisionFeatures{
+	// Cloud Vision bills one unit per feature per image.
+	c.meter.Record(ctx, metering.Event{
+		Provider:  metering.ProviderGoogleVision,
+		Operation: "annotate",
+		Units:     len(reqBody.Requests[0].Features),
+		Unit:      "units",
+	})
+
+	result := &VisionFeatures{
 		Labels: make([]Label, 0, len(response.LabelAnnotations)),
 		Texts:  make([]string, 0, len(response.TextAnnotations)),
 		Faces:  make([]FaceDetection, 0, len(response.FaceAnnotations)),
@@ -227,54 +389,40 @@ func (c *GoogleVisionClient) AnalyzeImage(ctx context.Context, imageData []byte)
 	}
 
 	for _, label := range response.LabelAnnotations {
-		features.Labels = append(features.Labels, Label{
+		result.Labels = append(result.Labels, Label{
 			Name:       label.Description,
 			Confidence: label.Score,
 		})
 	}
 
+	// The first annotation is the full text with its locale; the rest are
+	// individual words, which are regrouped into positioned lines.
+	var locale string
+	var words []OCRWord
 	for i, text := range response.TextAnnotations {
 		if i == 0 && len(response.TextAnnotations) > 1 {
+			locale = text.Locale
 			continue
 		}
-		features.Texts = append(features.Texts, text.Description)
+		result.Texts = append(result.Texts, text.Description)
+		if box, ok := text.BoundingPoly.box(); ok {
+			words = append(words, OCRWord{Text: text.Description, BoundingBox: box})
+		}
 	}
+	result.TextBlocks = GroupTextLines(words, locale)
 
 	for _, face := range response.FaceAnnotations {
-		if len(face.BoundingPoly.Vertices) >= 4 {
-			minX, minY := face.BoundingPoly.Vertices[0].X, face.BoundingPoly.Vertices[0].Y
-			maxX, maxY := minX, minY
-
-			for _, v := range face.BoundingPoly.Vertices {
-				if v.X < minX {
-					minX = v.X
-				}
-				if v.X > maxX {
-					maxX = v.X
-				}
-				if v.Y < minY {
-					minY = v.Y
-				}
-				if v.Y > maxY {
-					maxY = v.Y
-				}
-			}
-
-			features.Faces = append(features.Faces, FaceDetection{
-				BoundingBox: BoundingBox{
-					X:      minX,
-					Y:      minY,
-					Width:  maxX - minX,
-					Height: maxY - minY,
-				},
-				Confidence: face.DetectionConfidence,
+		if box, ok := face.BoundingPoly.box(); ok {
+			result.Faces = append(result.Faces, FaceDetection{
+				BoundingBox: box,
+				Confidence:  face.DetectionConfidence,
 			})
 		}
 	}
 
 	if response.ImagePropertiesAnnotation != nil {
 		for _, c := range response.ImagePropertiesAnnotation.DominantColors.Colors {
-			features.Colors = append(features.Colors, ColorInfo{
+			result.Colors = append(result.Colors, ColorInfo{
 				Color:      fmt.Sprintf("rgb(%d,%d,%d)", c.Color.Red, c.Color.Green, c.Color.Blue),
 				Score:      c.Score,
 				PixelRatio: c.PixelFraction,
@@ -282,5 +430,111 @@ func (c *GoogleVisionClient) AnalyzeImage(ctx context.Context, imageData []byte)
 		}
 	}
 
-	return features, nil
+	if web := response.WebDetection; web != nil {
+		for _, guess := range web.BestGuessLabels {
+			if label := strings.TrimSpace(guess.Label); label != "" {
+				result.BestGuessLabels = append(result.BestGuessLabels, label)
+			}
+		}
+		for _, entity := range web.WebEntities {
+			if entity.Description == "" {
+				continue
+			}
+			result.WebEntities = append(result.WebEntities, WebEntity{
+				ID:          entity.EntityID,
+				Description: entity.Description,
+				Score:       entity.Score,
+			})
+		}
+		for _, page := range web.PagesWithMatchingImages {
+			result.MatchingPages = append(result.MatchingPages, WebPage{
+				URL:   page.URL,
+				Title: strings.TrimSpace(html.UnescapeString(htmlTag.ReplaceAllString(page.PageTitle, ""))),
+				Score: page.Score,
+			})
+		}
+	}
+
+	for _, logo := range response.LogoAnnotations {
+		box, _ := logo.BoundingPoly.box()
+		result.Logos = append(result.Logos, Logo{
+			Name:        logo.Description,
+			Confidence:  logo.Score,
+			BoundingBox: box,
+		})
+	}
+
+	for _, landmark := range response.LandmarkAnnotations {
+		item := Landmark{
+			Name:       landmark.Description,
+			Confidence: landmark.Score,
+		}
+		if len(landmark.Locations) > 0 {
+			item.Latitude = landmark.Locations[0].LatLng.Latitude
+			item.Longitude = landmark.Locations[0].LatLng.Longitude
+		}
+		result.Landmarks = append(result.Landmarks, item)
+	}
+
+	for _, object := range response.LocalizedObjectAnnotations {
+		result.Objects = append(result.Objects, DetectedObject{
+			Name:        object.Name,
+			Confidence:  object.Score,
+			BoundingBox: object.BoundingPoly.normalizedBox(),
+		})
+	}
+
+	return result, nil
+}
+
+// box returns the pixel bounding rectangle of the polygon.
+func (p boundingPoly) box() (BoundingBox, bool) {
+	if len(p.Vertices) < 4 {
+		return BoundingBox{}, false
+	}
+
+	minX, minY := p.Vertices[0].X, p.Vertices[0].Y
+	maxX, maxY := minX, minY
+
+	for _, v := range p.Vertices {
+		if v.X < minX {
+			minX = v.X
+		}
+		if v.X > maxX {
+			maxX = v.X
+		}
+		if v.Y < minY {
+			minY = v.Y
+		}
+		if v.Y > maxY {
+			maxY = v.Y
+		}
+	}
+
+	return BoundingBox{
+		X:      minX,
+		Y:      minY,
+		Width:  maxX - minX,
+		Height: maxY - minY,
+	}, true
+}
+
+// normalizedBox returns the bounding rectangle of the polygon's normalized
+// vertices.
+func (p boundingPoly) normalizedBox() NormalizedBox {
+	if len(p.NormalizedVertices) == 0 {
+		return NormalizedBox{}
+	}
+
+	minX, minY := p.NormalizedVertices[0].X, p.NormalizedVertices[0].Y
+	maxX, maxY := minX, minY
+
+	for _, v := range p.NormalizedVertices {
+		minX = math.Min(minX, v.X)
+		maxX = math.Max(maxX, v.X)
+		minY = math.Min(minY, v.Y)
+		maxY = math.Max(maxY, v.Y)
+	}
+
+	return NormalizedBox{X: minX, Y: minY, Width: maxX - minX, Height: maxY - minY}
 }
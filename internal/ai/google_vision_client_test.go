@@ -0,0 +1,118 @@
+package ai
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kdimtricp/vshazam/internal/outbound"
+	"github.com/kdimtricp/vshazam/internal/outbound/cassette"
+)
+
+func newCassetteVisionClient(t *testing.T, name string) *GoogleVisionClient {
+	t.Helper()
+	client := NewGoogleVisionClient("test-key")
+	client.UseTransport(cassette.ForTest(t, filepath.Join("testdata", "cassettes", name)))
+	return client
+}
+
+func TestGoogleVisionClientCassette(t *testing.T) {
+	client := newCassetteVisionClient(t, "google_vision_success.json")
+
+	features, err := client.AnalyzeImage(context.Background(), []byte("fake image data"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(features.Labels) != 2 || features.Labels[0].Name != "Suit" {
+		t.Errorf("unexpected labels %+v", features.Labels)
+	}
+	if len(features.Texts) != 1 || features.Texts[0] != "INCEPTION" {
+		t.Errorf("expected full-text annotation to be skipped, got %q", features.Texts)
+	}
+	if len(features.TextBlocks) != 1 || features.TextBlocks[0].Locale != "en" || features.TextBlocks[0].BoundingBox.Width != 260 {
+		t.Errorf("expected positioned OCR line with locale, got %+v", features.TextBlocks)
+	}
+	if len(features.Faces) != 1 {
+		t.Fatalf("expected 1 face, got %d", len(features.Faces))
+	}
+	if box := features.Faces[0].BoundingBox; box.X != 120 || box.Y != 80 || box.Width != 100 || box.Height != 120 {
+		t.Errorf("unexpected face bounding box %+v", box)
+	}
+	if len(features.Colors) != 1 || features.Colors[0].Color != "rgb(34,28,22)" {
+		t.Errorf("unexpected colors %+v", features.Colors)
+	}
+
+	if len(features.BestGuessLabels) != 1 || features.BestGuessLabels[0] != "inception movie 2010" {
+		t.Errorf("unexpected best guess labels %q", features.BestGuessLabels)
+	}
+	if len(features.WebEntities) != 3 || features.WebEntities[0].Description != "Inception" || features.WebEntities[0].ID != "/m/0661ql3" {
+		t.Errorf("unexpected web entities %+v", features.WebEntities)
+	}
+	if len(features.MatchingPages) != 1 || features.MatchingPages[0].Title != "Inception (2010) - IMDb" {
+		t.Errorf("expected page title without markup, got %+v", features.MatchingPages)
+	}
+	if len(features.Logos) != 1 || features.Logos[0].Name != "Warner Bros." || features.Logos[0].BoundingBox.Width != 80 {
+		t.Errorf("unexpected logos %+v", features.Logos)
+	}
+	if len(features.Landmarks) != 1 || features.Landmarks[0].Latitude != 48.8566 {
+		t.Errorf("unexpected landmarks %+v", features.Landmarks)
+	}
+	if len(features.Objects) != 1 {
+		t.Fatalf("expected 1 object, got %d", len(features.Objects))
+	}
+	if box := features.Objects[0].BoundingBox; box.X != 0.1 || box.Y != 0.6 || box.Width != 0.8 || box.Height < 0.39 || box.Height > 0.41 {
+		t.Errorf("unexpected object bounding box %+v", box)
+	}
+}
+
+func TestGoogleVisionClientUseFeatures(t *testing.T) {
+	client := NewGoogleVisionClient("test-key")
+
+	if err := client.UseFeatures([]string{" web_detection", "LOGO_DETECTION", "WEB_DETECTION"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(client.features) != 2 || client.features[0] != "WEB_DETECTION" || client.features[1] != "LOGO_DETECTION" {
+		t.Errorf("unexpected features %q", client.features)
+	}
+
+	if err := client.UseFeatures([]string{"SAFE_SEARCH"}); err == nil {
+		t.Error("expected error for unsupported feature")
+	}
+
+	if err := client.UseFeatures(nil); err != nil || len(client.features) != len(DefaultVisionFeatures) {
+		t.Errorf("expected defaults to be restored, got %q (%v)", client.features, err)
+	}
+}
+
+func TestGoogleVisionClientCassetteErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		cassette string
+		kind     outbound.Kind
+		message  string
+	}{
+		{name: "error in response body", cassette: "google_vision_error.json", kind: "", message: "Bad image data"},
+		{name: "quota exhausted", cassette: "google_vision_quota.json", kind: outbound.KindQuota, message: "Quota exceeded"},
+		{name: "invalid key", cassette: "google_vision_auth.json", kind: outbound.KindAuth, message: "API key not valid"},
+		{name: "malformed response", cassette: "google_vision_malformed.json", kind: "", message: "failed to unmarshal response"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newCassetteVisionClient(t, tt.cassette)
+
+			_, err := client.AnalyzeImage(context.Background(), []byte("fake image data"))
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if tt.kind != "" && outbound.KindOf(err) != tt.kind {
+				t.Errorf("expected %s error, got %s: %v", tt.kind, outbound.KindOf(err), err)
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("expected %q in %q", tt.message, err.Error())
+			}
+		})
+	}
+}
@@ -1,14 +1,20 @@
 package ai
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"image"
 	"log"
 	"time"
+
+	"github.com/kdimtricp/vshazam/internal/metering"
 )
 
 type OpenAIClientInterface interface {
 	GetFrameCaption(ctx context.Context, imageData []byte) (string, error)
+	DescribeFrame(ctx context.Context, imageData []byte) (*FrameDescription, error)
+	DescribeFrames(ctx context.Context, frames []FrameImage) (*SequenceDescription, error)
 }
 
 type GoogleVisionClientInterface interface {
@@ -19,10 +25,11 @@ type VisionServiceImpl struct {
 	openAIClient OpenAIClientInterface
 	googleClient GoogleVisionClientInterface
 	config       *Config
+	meter        metering.Meter
 }
 
 func NewVisionService(config *Config) (*VisionServiceImpl, error) {
-	if config.OpenAIAPIKey == "" && config.GoogleVisionKey == "" && config.GoogleVisionServiceAccount == "" {
+	if config.OpenAIAPIKey == "" && config.OpenAIBaseURL == "" && config.GoogleVisionKey == "" && config.GoogleVisionServiceAccount == "" {
 		return nil, fmt.Errorf("at least one API key is required (OpenAI, Google Vision API key, or Google Service Account)")
 	}
 
@@ -30,92 +37,247 @@ func NewVisionService(config *Config) (*VisionServiceImpl, error) {
 		config: config,
 	}
 
-	if config.OpenAIAPIKey != "" {
-		service.openAIClient = NewOpenAIClient(config.OpenAIAPIKey)
-		log.Printf("OpenAI Vision service enabled")
+	// A custom base URL enables OpenAI without a key, for local
+	// OpenAI-compatible servers.
+	if config.OpenAIAPIKey != "" || config.OpenAIBaseURL != "" {
+		client, err := NewOpenAIClientWithOptions(config.OpenAIAPIKey, OpenAIOptions{
+			BaseURL:        config.OpenAIBaseURL,
+			Model:          config.OpenAIModel,
+			APIVersion:     config.OpenAIAPIVersion,
+			MaxTokens:      config.OpenAIMaxTokens,
+			Temperature:    config.OpenAITemperature,
+			ImageDetail:    config.OpenAIImageDetail,
+			PromptTemplate: config.OpenAIPromptTemplate,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OpenAI client: %w", err)
+		}
+		service.openAIClient = client
+		log.Printf("OpenAI Vision service enabled (model: %s, endpoint: %s)", client.options.Model, client.options.BaseURL)
 	} else {
 		log.Printf("OpenAI Vision service disabled (no API key)")
 	}
 
+	var googleClient *GoogleVisionClient
 	if config.GoogleVisionServiceAccount != "" {
 		client, err := NewGoogleVisionClientWithServiceAccount(config.GoogleVisionServiceAccount)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create Google Vision client with service account: %w", err)
 		}
-		service.googleClient = client
+		googleClient = client
 		log.Printf("Google Vision service enabled (service account: %s)", config.GoogleVisionServiceAccount)
 	} else if config.GoogleVisionKey != "" {
-		service.googleClient = NewGoogleVisionClient(config.GoogleVisionKey)
+		googleClient = NewGoogleVisionClient(config.GoogleVisionKey)
 		log.Printf("Google Vision service enabled (API key)")
 	} else {
 		log.Printf("Google Vision service disabled (no API key or service account)")
 	}
+	if googleClient != nil {
+		if err := googleClient.UseFeatures(config.GoogleVisionFeatures); err != nil {
+			return nil, err
+		}
+		service.googleClient = googleClient
+	}
 
 	return service, nil
 }
 
+// NewVisionServiceWithClients creates a service around existing clients, for
+// callers that configure transports or endpoints themselves. Either client may
+// be nil to disable that provider.
+func NewVisionServiceWithClients(config *Config, openAIClient *OpenAIClient, googleClient *GoogleVisionClient) *VisionServiceImpl {
+	service := &VisionServiceImpl{config: config}
+	if openAIClient != nil {
+		service.openAIClient = openAIClient
+	}
+	if googleClient != nil {
+		service.googleClient = googleClient
+	}
+	return service
+}
+
+// UseMeter makes the service and the clients it created record usage with m.
+// Providers over budget are skipped, so analysis degrades to whichever
+// provider is still allowed.
+func (s *VisionServiceImpl) UseMeter(m metering.Meter) {
+	s.meter = m
+	if client, ok := s.openAIClient.(*OpenAIClient); ok {
+		client.UseMeter(m)
+	}
+	if client, ok := s.googleClient.(*GoogleVisionClient); ok {
+		client.UseMeter(m)
+	}
+}
+
 func (s *VisionServiceImpl) AnalyzeFrame(ctx context.Context, imageData []byte) (*FrameAnalysis, error) {
+	useOpenAI, useGoogle, err := s.providers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	analysis := &FrameAnalysis{
 		Timestamp: time.Now(),
 	}
 
-	// Only run OpenAI if client is available
-	if s.openAIClient != nil {
-		captionCh := make(chan struct {
-			caption string
-			err     error
-		}, 1)
-
-		go func() {
-			caption, err := s.openAIClient.GetFrameCaption(ctx, imageData)
-			captionCh <- struct {
-				caption string
-				err     error
-			}{caption, err}
-		}()
-
-		captionResult := <-captionCh
-		if captionResult.err != nil {
-			log.Printf("Error getting caption from OpenAI: %v", captionResult.err)
-		} else {
-			analysis.Caption = captionResult.caption
-		}
+	if useOpenAI {
+		s.describe(ctx, imageData, analysis)
 	}
 
-	// Only run Google Vision if client is available
-	if s.googleClient != nil {
-		visionCh := make(chan struct {
-			features *VisionFeatures
-			err      error
-		}, 1)
-
-		go func() {
-			features, err := s.googleClient.AnalyzeImage(ctx, imageData)
-			visionCh <- struct {
-				features *VisionFeatures
-				err      error
-			}{features, err}
-		}()
-
-		visionResult := <-visionCh
-		if visionResult.err != nil {
-			log.Printf("Error analyzing image with Google Vision: %v", visionResult.err)
-		} else if visionResult.features != nil {
-			analysis.Labels = visionResult.features.Labels
-			analysis.TextOCR = visionResult.features.Texts
-			analysis.Faces = visionResult.features.Faces
-			analysis.Colors = visionResult.features.Colors
-		}
+	if useGoogle {
+		s.detect(ctx, imageData, analysis)
 	}
 
 	analysis.Confidence = s.calculateConfidence(analysis)
 
-	// Return error if no analysis was performed
+	return analysis, nil
+}
+
+// providers reports which configured providers are within budget. It fails
+// when there are none.
+func (s *VisionServiceImpl) providers(ctx context.Context) (useOpenAI, useGoogle bool, err error) {
 	if s.openAIClient == nil && s.googleClient == nil {
-		return nil, fmt.Errorf("no AI services available")
+		return false, false, fmt.Errorf("no AI services available")
 	}
 
-	return analysis, nil
+	var budgetErr error
+	allow := func(provider string) bool {
+		if s.meter == nil {
+			return true
+		}
+		if err := s.meter.Allow(ctx, provider); err != nil {
+			budgetErr = err
+			return false
+		}
+		return true
+	}
+
+	useOpenAI = s.openAIClient != nil && allow(metering.ProviderOpenAI)
+	useGoogle = s.googleClient != nil && allow(metering.ProviderGoogleVision)
+	if !useOpenAI && !useGoogle {
+		return false, false, budgetErr
+	}
+	if budgetErr != nil {
+		log.Printf("Skipping provider: %v", budgetErr)
+	}
+	return useOpenAI, useGoogle, nil
+}
+
+// AnalyzeFrames analyzes several frames of one video. GPT sees all of them in
+// a single request and its consolidated identification is attached to the
+// first frame, while each frame keeps its own caption and visible text. Google
+// Vision still runs per frame. If the multi-frame request fails, every frame
+// is analyzed separately instead.
+func (s *VisionServiceImpl) AnalyzeFrames(ctx context.Context, frames []FrameImage) ([]*FrameAnalysis, error) {
+	useOpenAI, useGoogle, err := s.providers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var sequence *SequenceDescription
+	if useOpenAI && len(frames) > 1 {
+		var err error
+		sequence, err = s.openAIClient.DescribeFrames(ctx, frames)
+		if err != nil {
+			log.Printf("Multi-frame analysis from OpenAI failed, analyzing frames one by one: %v", err)
+		}
+	}
+
+	analyses := make([]*FrameAnalysis, 0, len(frames))
+	if sequence == nil {
+		for _, frame := range frames {
+			analysis, err := s.AnalyzeFrame(ctx, frame.Data)
+			if err != nil {
+				return nil, err
+			}
+			analyses = append(analyses, analysis)
+		}
+		return analyses, nil
+	}
+
+	for i, frame := range frames {
+		analysis := &FrameAnalysis{
+			Timestamp: time.Now(),
+		}
+		for _, note := range sequence.Frames {
+			if note.Frame == i+1 {
+				analysis.Caption = note.Caption
+				analysis.VisibleText = note.VisibleText
+				break
+			}
+		}
+		if i == 0 {
+			if analysis.Caption == "" {
+				analysis.Caption = sequence.Summary
+			}
+			analysis.Scene = sequence.Scene
+			analysis.Era = sequence.Era
+			analysis.Genres = sequence.Genres
+			analysis.Actors = sequence.Actors
+			analysis.FilmGuesses = sequence.FilmGuesses
+		}
+
+		if useGoogle {
+			s.detect(ctx, frame.Data, analysis)
+		}
+
+		analysis.Confidence = s.calculateConfidence(analysis)
+		analyses = append(analyses, analysis)
+	}
+
+	return analyses, nil
+}
+
+// detect fills the Google Vision part of the analysis.
+func (s *VisionServiceImpl) detect(ctx context.Context, imageData []byte, analysis *FrameAnalysis) {
+	features, err := s.googleClient.AnalyzeImage(ctx, imageData)
+	if err != nil {
+		log.Printf("Error analyzing image with Google Vision: %v", err)
+		return
+	}
+	if features != nil {
+		analysis.Labels = features.Labels
+		analysis.TextOCR = features.Texts
+		analysis.TextBlocks = features.TextBlocks
+		if config, _, err := image.DecodeConfig(bytes.NewReader(imageData)); err == nil {
+			ClassifyTextRegions(analysis.TextBlocks, config.Width, config.Height)
+		} else {
+			ClassifyTextRegions(analysis.TextBlocks, 0, 0)
+		}
+		analysis.Faces = features.Faces
+		analysis.Colors = features.Colors
+		analysis.BestGuessLabels = features.BestGuessLabels
+		analysis.WebEntities = features.WebEntities
+		analysis.MatchingPages = features.MatchingPages
+		analysis.Logos = features.Logos
+		analysis.Landmarks = features.Landmarks
+		analysis.Objects = features.Objects
+	}
+}
+
+// describe fills the GPT part of the analysis. The structured JSON caption is
+// tried first; if it fails the free-text caption is used instead.
+func (s *VisionServiceImpl) describe(ctx context.Context, imageData []byte, analysis *FrameAnalysis) {
+	if !s.config.OpenAIPlainCaption {
+		description, err := s.openAIClient.DescribeFrame(ctx, imageData)
+		if err == nil {
+			analysis.Caption = description.Caption
+			analysis.Scene = description.Scene
+			analysis.Era = description.Era
+			analysis.Genres = description.Genres
+			analysis.VisibleText = description.VisibleText
+			analysis.Actors = description.Actors
+			analysis.FilmGuesses = description.FilmGuesses
+			return
+		}
+		log.Printf("Structured caption from OpenAI failed, falling back to free text: %v", err)
+	}
+
+	caption, err := s.openAIClient.GetFrameCaption(ctx, imageData)
+	if err != nil {
+		log.Printf("Error getting caption from OpenAI: %v", err)
+		return
+	}
+	analysis.Caption = caption
 }
 
 func (s *VisionServiceImpl) calculateConfidence(analysis *FrameAnalysis) float64 {
@@ -0,0 +1,153 @@
+package ai
+
+import (
+	"sort"
+	"strings"
+)
+
+// Text regions of a frame. Burned-in subtitles, title cards and credits carry
+// very different information, so OCR lines are classified by position.
+const (
+	RegionSubtitle  = "subtitle"
+	RegionTitleCard = "title_card"
+	RegionCredits   = "credits"
+	RegionOther     = "other"
+)
+
+// TextBlock is one line of on-screen text with its position in the frame.
+type TextBlock struct {
+	Text        string      `json:"text"`
+	Locale      string      `json:"locale,omitempty"`
+	BoundingBox BoundingBox `json:"bounding_box"`
+	Region      string      `json:"region,omitempty"`
+}
+
+// OCRWord is a single word reported by an OCR provider.
+type OCRWord struct {
+	Text        string
+	BoundingBox BoundingBox
+}
+
+// GroupTextLines joins words whose vertical centers line up into lines, top
+// to bottom and left to right within a line.
+func GroupTextLines(words []OCRWord, locale string) []TextBlock {
+	if len(words) == 0 {
+		return nil
+	}
+
+	sorted := append([]OCRWord(nil), words...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return centerY(sorted[i].BoundingBox) < centerY(sorted[j].BoundingBox)
+	})
+
+	var lines [][]OCRWord
+	for _, word := range sorted {
+		if n := len(lines); n > 0 && sameLine(lines[n-1], word) {
+			lines[n-1] = append(lines[n-1], word)
+			continue
+		}
+		lines = append(lines, []OCRWord{word})
+	}
+
+	blocks := make([]TextBlock, 0, len(lines))
+	for _, line := range lines {
+		sort.SliceStable(line, func(i, j int) bool {
+			return line[i].BoundingBox.X < line[j].BoundingBox.X
+		})
+		texts := make([]string, 0, len(line))
+		box := line[0].BoundingBox
+		for _, word := range line {
+			texts = append(texts, word.Text)
+			box = unionBox(box, word.BoundingBox)
+		}
+		blocks = append(blocks, TextBlock{
+			Text:        strings.Join(texts, " "),
+			Locale:      locale,
+			BoundingBox: box,
+		})
+	}
+	return blocks
+}
+
+// ClassifyTextRegions sets the Region of every block of one frame. Frames are
+// letterboxed into a square, so the subtitle band starts at 60% of the frame
+// height rather than near the bottom edge. Four or more centered lines
+// stacked down the frame are treated as credits.
+func ClassifyTextRegions(blocks []TextBlock, width, height int) {
+	if width <= 0 || height <= 0 {
+		for i := range blocks {
+			blocks[i].Region = RegionOther
+		}
+		return
+	}
+
+	centered := 0
+	for _, block := range blocks {
+		if isCentered(block.BoundingBox, width) {
+			centered++
+		}
+	}
+	credits := centered >= 4 && textSpan(blocks) >= 0.4*float64(height)
+
+	for i := range blocks {
+		box := blocks[i].BoundingBox
+		y := centerY(box) / float64(height)
+		switch {
+		case credits && isCentered(box, width):
+			blocks[i].Region = RegionCredits
+		case y >= 0.6 && isCentered(box, width):
+			blocks[i].Region = RegionSubtitle
+		case y >= 0.3 && y < 0.6 && isCentered(box, width) && len(blocks) <= 3:
+			blocks[i].Region = RegionTitleCard
+		default:
+			blocks[i].Region = RegionOther
+		}
+	}
+}
+
+// TextInRegion returns the text of the blocks in region, top to bottom.
+func TextInRegion(blocks []TextBlock, region string) []string {
+	var texts []string
+	for _, block := range blocks {
+		if block.Region == region {
+			texts = append(texts, block.Text)
+		}
+	}
+	return texts
+}
+
+func sameLine(line []OCRWord, word OCRWord) bool {
+	last := line[len(line)-1].BoundingBox
+	tolerance := float64(max(last.Height, word.BoundingBox.Height)) / 2
+	diff := centerY(last) - centerY(word.BoundingBox)
+	return diff <= tolerance && diff >= -tolerance
+}
+
+// isCentered reports whether the box's horizontal center lies in the middle
+// 30% of the frame.
+func isCentered(box BoundingBox, width int) bool {
+	x := (float64(box.X) + float64(box.Width)/2) / float64(width)
+	return x >= 0.35 && x <= 0.65
+}
+
+func textSpan(blocks []TextBlock) float64 {
+	if len(blocks) == 0 {
+		return 0
+	}
+	top, bottom := blocks[0].BoundingBox.Y, blocks[0].BoundingBox.Y+blocks[0].BoundingBox.Height
+	for _, block := range blocks[1:] {
+		top = min(top, block.BoundingBox.Y)
+		bottom = max(bottom, block.BoundingBox.Y+block.BoundingBox.Height)
+	}
+	return float64(bottom - top)
+}
+
+func centerY(box BoundingBox) float64 {
+	return float64(box.Y) + float64(box.Height)/2
+}
+
+func unionBox(a, b BoundingBox) BoundingBox {
+	x0, y0 := min(a.X, b.X), min(a.Y, b.Y)
+	x1, y1 := max(a.X+a.Width, b.X+b.Width), max(a.Y+a.Height, b.Y+b.Height)
+	return BoundingBox{X: x0, Y: y0, Width: x1 - x0, Height: y1 - y0}
+}
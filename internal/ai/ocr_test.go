@@ -0,0 +1,76 @@
+package ai
+
+import "testing"
+
+func word(text string, x, y, w, h int) OCRWord {
+	return OCRWord{Text: text, BoundingBox: BoundingBox{X: x, Y: y, Width: w, Height: h}}
+}
+
+func TestGroupTextLines(t *testing.T) {
+	words := []OCRWord{
+		word("going", 260, 402, 60, 20),
+		word("Where", 180, 400, 70, 22),
+		word("INCEPTION", 150, 200, 210, 40),
+		word("you?", 330, 401, 50, 21),
+	}
+
+	blocks := GroupTextLines(words, "en")
+	if len(blocks) != 2 {
+		t.Fatalf("expected 2 lines, got %+v", blocks)
+	}
+	if blocks[0].Text != "INCEPTION" || blocks[1].Text != "Where going you?" {
+		t.Errorf("unexpected lines %q and %q", blocks[0].Text, blocks[1].Text)
+	}
+	if box := blocks[1].BoundingBox; box.X != 180 || box.Y != 400 || box.Width != 200 || box.Height != 22 {
+		t.Errorf("unexpected line box %+v", box)
+	}
+	if blocks[1].Locale != "en" {
+		t.Errorf("expected locale to be kept, got %q", blocks[1].Locale)
+	}
+}
+
+func TestClassifyTextRegions(t *testing.T) {
+	tests := []struct {
+		name    string
+		blocks  []TextBlock
+		regions []string
+	}{
+		{
+			name: "subtitle and corner text",
+			blocks: []TextBlock{
+				{Text: "CNN", BoundingBox: BoundingBox{X: 10, Y: 120, Width: 40, Height: 20}},
+				{Text: "I need you to trust me.", BoundingBox: BoundingBox{X: 140, Y: 350, Width: 230, Height: 20}},
+			},
+			regions: []string{RegionOther, RegionSubtitle},
+		},
+		{
+			name: "title card",
+			blocks: []TextBlock{
+				{Text: "INCEPTION", BoundingBox: BoundingBox{X: 150, Y: 230, Width: 210, Height: 40}},
+			},
+			regions: []string{RegionTitleCard},
+		},
+		{
+			name: "credits",
+			blocks: []TextBlock{
+				{Text: "Directed by", BoundingBox: BoundingBox{X: 200, Y: 100, Width: 110, Height: 16}},
+				{Text: "CHRISTOPHER NOLAN", BoundingBox: BoundingBox{X: 170, Y: 130, Width: 170, Height: 16}},
+				{Text: "Produced by", BoundingBox: BoundingBox{X: 200, Y: 250, Width: 110, Height: 16}},
+				{Text: "EMMA THOMAS", BoundingBox: BoundingBox{X: 190, Y: 280, Width: 130, Height: 16}},
+				{Text: "LEGENDARY", BoundingBox: BoundingBox{X: 420, Y: 300, Width: 80, Height: 16}},
+			},
+			regions: []string{RegionCredits, RegionCredits, RegionCredits, RegionCredits, RegionOther},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ClassifyTextRegions(tt.blocks, 512, 512)
+			for i, block := range tt.blocks {
+				if block.Region != tt.regions[i] {
+					t.Errorf("block %q: expected %s, got %s", block.Text, tt.regions[i], block.Region)
+				}
+			}
+		})
+	}
+}
@@ -8,91 +8,432 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
 	"time"
+
+	"github.com/kdimtricp/vshazam/internal/metering"
+	"github.com/kdimtricp/vshazam/internal/outbound"
 )
 
 const (
-	openAIAPIURL = "https://api.openai.com/v1/chat/completions"
-	openAIModel  = "gpt-4o"
+	defaultOpenAIBaseURL = "https://api.openai.com/v1"
+	defaultOpenAIModel   = "gpt-4o"
 )
 
+// defaultPromptTemplate is used when no custom template is configured. It is a
+// text/template executed with PromptData.
+const defaultPromptTemplate = `{{if .Frames -}}
+These {{.Frames}} frames were sampled in order from one video; each is preceded by its timestamp. Use what changes between them as context. Identify the single film they most likely all come from and list candidates in film_guesses with a confidence between 0 and 1; leave film_guesses empty rather than guessing wildly. Use null for an unknown year. In frames, add one note per frame (numbered from 1) with a short caption and any text visible in that frame.
+{{- else if .Structured -}}
+Analyze this video frame to help identify the film it comes from. Describe the scene, the apparent era and genres, any text visible in the frame and any actors you recognize. List the films you think the frame may be from in film_guesses with a confidence between 0 and 1; leave film_guesses empty rather than guessing wildly. Use null for an unknown year.
+{{- else -}}
+Analyze this video frame and provide a detailed description. Include:
+1. The scene setting and environment
+2. Any visible actors or people
+3. Notable objects or props
+4. Any visible text or titles
+5. The apparent genre and era of the film
+6. If you can identify the specific movie, mention it
+Be specific and detailed to help identify the film.
+{{- end}}
+{{- if .Title}}
+
+The uploader titled the video "{{.Title}}".{{end}}
+{{- if .Description}}
+Their description: {{.Description}}{{end}}`
+
+// PromptData is passed to the prompt template.
+type PromptData struct {
+	Title       string
+	Description string
+	// Structured is true when the answer must match the JSON schema.
+	Structured bool
+	// Frames is the number of images in a multi-frame request, or 0 when a
+	// single frame is analyzed.
+	Frames int
+}
+
+// OpenAIOptions points the client at any OpenAI-compatible chat completions
+// endpoint. Zero values fall back to the public OpenAI API defaults.
+type OpenAIOptions struct {
+	// BaseURL is the API root without /chat/completions, e.g.
+	// http://localhost:11434/v1 for Ollama or
+	// https://NAME.openai.azure.com/openai/deployments/DEPLOYMENT for Azure.
+	BaseURL string
+	Model   string
+	// APIVersion is sent as the api-version query parameter. Setting it also
+	// switches authentication to the Azure api-key header.
+	APIVersion  string
+	MaxTokens   int
+	Temperature *float64
+	// ImageDetail is low, high or auto.
+	ImageDetail    string
+	PromptTemplate string
+}
+
 type OpenAIClient struct {
 	apiKey     string
-	httpClient *http.Client
+	endpoint   string
+	options    OpenAIOptions
+	prompt     *template.Template
+	httpClient *outbound.Client
+	meter      metering.Meter
+}
+
+func openAIPolicy() outbound.Policy {
+	policy := outbound.DefaultPolicy()
+	policy.Timeout = 60 * time.Second
+	policy.RequestsPerSecond = 2
+	policy.Burst = 5
+	return policy
 }
 
 func NewOpenAIClient(apiKey string) *OpenAIClient {
-	return &OpenAIClient{
-		apiKey: apiKey,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+	client, _ := NewOpenAIClientWithOptions(apiKey, OpenAIOptions{})
+	return client
+}
+
+func NewOpenAIClientWithOptions(apiKey string, options OpenAIOptions) (*OpenAIClient, error) {
+	if options.BaseURL == "" {
+		options.BaseURL = defaultOpenAIBaseURL
+	}
+	if options.Model == "" {
+		options.Model = defaultOpenAIModel
+	}
+	if options.PromptTemplate == "" {
+		options.PromptTemplate = defaultPromptTemplate
+	}
+
+	prompt, err := template.New("prompt").Parse(options.PromptTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse prompt template: %w", err)
 	}
+
+	endpoint, err := url.Parse(strings.TrimRight(options.BaseURL, "/") + "/chat/completions")
+	if err != nil {
+		return nil, fmt.Errorf("invalid OpenAI base URL: %w", err)
+	}
+	if options.APIVersion != "" {
+		query := endpoint.Query()
+		query.Set("api-version", options.APIVersion)
+		endpoint.RawQuery = query.Encode()
+	}
+
+	return &OpenAIClient{
+		apiKey:     apiKey,
+		endpoint:   endpoint.String(),
+		options:    options,
+		prompt:     prompt,
+		httpClient: outbound.New("openai", openAIPolicy()),
+		meter:      metering.Nop{},
+	}, nil
+}
+
+// UseTransport sends requests through rt instead of the default transport.
+func (c *OpenAIClient) UseTransport(rt http.RoundTripper) {
+	c.httpClient.UseTransport(rt)
+}
+
+// UseMeter records token usage of every request with m and checks its budget
+// before each call.
+func (c *OpenAIClient) UseMeter(m metering.Meter) {
+	c.meter = m
+}
+
+// FrameDescription is the structured answer GPT returns in JSON mode.
+type FrameDescription struct {
+	Caption     string      `json:"caption"`
+	Scene       string      `json:"scene"`
+	Era         string      `json:"era"`
+	Genres      []string    `json:"genres"`
+	VisibleText []string    `json:"visible_text"`
+	Actors      []string    `json:"actors"`
+	FilmGuesses []FilmGuess `json:"film_guesses"`
+}
+
+// SequenceDescription is the consolidated JSON answer for several frames of
+// one video sent in a single request.
+type SequenceDescription struct {
+	Summary     string      `json:"summary"`
+	Scene       string      `json:"scene"`
+	Era         string      `json:"era"`
+	Genres      []string    `json:"genres"`
+	Actors      []string    `json:"actors"`
+	FilmGuesses []FilmGuess `json:"film_guesses"`
+	Frames      []FrameNote `json:"frames"`
+}
+
+// FrameNote is what GPT saw in one frame of a multi-frame request. Frame is
+// 1-based in request order.
+type FrameNote struct {
+	Frame       int      `json:"frame"`
+	Caption     string   `json:"caption"`
+	VisibleText []string `json:"visible_text"`
 }
 
 type openAIRequest struct {
-	Model    string          `json:"model"`
-	Messages []openAIMessage `json:"messages"`
+	Model          string                `json:"model"`
+	Messages       []openAIMessage       `json:"messages"`
+	MaxTokens      int                   `json:"max_tokens,omitempty"`
+	Temperature    *float64              `json:"temperature,omitempty"`
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
 }
 
 type openAIMessage struct {
-	Role    string               `json:"role"`
-	Content []openAIContentPart  `json:"content"`
+	Role    string              `json:"role"`
+	Content []openAIContentPart `json:"content"`
 }
 
 type openAIContentPart struct {
-	Type     string              `json:"type"`
-	Text     string              `json:"text,omitempty"`
-	ImageURL *openAIImageURL     `json:"image_url,omitempty"`
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	ImageURL *openAIImageURL `json:"image_url,omitempty"`
 }
 
 type openAIImageURL struct {
-	URL string `json:"url"`
+	URL    string `json:"url"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type openAIResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *openAIJSONSchema `json:"json_schema,omitempty"`
+}
+
+type openAIJSONSchema struct {
+	Name   string          `json:"name"`
+	Strict bool            `json:"strict"`
+	Schema json.RawMessage `json:"schema"`
 }
 
 type openAIResponse struct {
 	Choices []struct {
 		Message struct {
 			Content string `json:"content"`
+			Refusal string `json:"refusal"`
 		} `json:"message"`
 	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	} `json:"usage"`
 	Error *struct {
 		Message string `json:"message"`
 		Type    string `json:"type"`
 	} `json:"error"`
 }
 
+// frameDescriptionSchema mirrors FrameDescription. Strict mode requires every
+// property to be listed as required, so optional values are nullable instead.
+var frameDescriptionSchema = json.RawMessage(`{
+	"type": "object",
+	"additionalProperties": false,
+	"required": ["caption", "scene", "era", "genres", "visible_text", "actors", "film_guesses"],
+	"properties": {
+		"caption": {"type": "string", "description": "One paragraph describing the frame"},
+		"scene": {"type": "string", "description": "Setting and environment"},
+		"era": {"type": "string", "description": "Apparent time period, e.g. 1980s"},
+		"genres": {"type": "array", "items": {"type": "string"}},
+		"visible_text": {"type": "array", "items": {"type": "string"}},
+		"actors": {"type": "array", "items": {"type": "string"}},
+		"film_guesses": {
+			"type": "array",
+			"items": {
+				"type": "object",
+				"additionalProperties": false,
+				"required": ["title", "year", "confidence"],
+				"properties": {
+					"title": {"type": "string"},
+					"year": {"type": ["integer", "null"]},
+					"confidence": {"type": "number"}
+				}
+			}
+		}
+	}
+}`)
+
+var sequenceDescriptionSchema = json.RawMessage(`{
+	"type": "object",
+	"additionalProperties": false,
+	"required": ["summary", "scene", "era", "genres", "actors", "film_guesses", "frames"],
+	"properties": {
+		"summary": {"type": "string", "description": "What happens across the frames"},
+		"scene": {"type": "string"},
+		"era": {"type": "string"},
+		"genres": {"type": "array", "items": {"type": "string"}},
+		"actors": {"type": "array", "items": {"type": "string"}},
+		"film_guesses": {
+			"type": "array",
+			"items": {
+				"type": "object",
+				"additionalProperties": false,
+				"required": ["title", "year", "confidence"],
+				"properties": {
+					"title": {"type": "string"},
+					"year": {"type": ["integer", "null"]},
+					"confidence": {"type": "number"}
+				}
+			}
+		},
+		"frames": {
+			"type": "array",
+			"items": {
+				"type": "object",
+				"additionalProperties": false,
+				"required": ["frame", "caption", "visible_text"],
+				"properties": {
+					"frame": {"type": "integer"},
+					"caption": {"type": "string"},
+					"visible_text": {"type": "array", "items": {"type": "string"}}
+				}
+			}
+		}
+	}
+}`)
+
 func (c *OpenAIClient) GetFrameCaption(ctx context.Context, imageData []byte) (string, error) {
-	imageBase64 := base64.StdEncoding.EncodeToString(imageData)
-	
-	prompt := "Analyze this video frame and provide a detailed description. Include:\n" +
-		"1. The scene setting and environment\n" +
-		"2. Any visible actors or people\n" +
-		"3. Notable objects or props\n" +
-		"4. Any visible text or titles\n" +
-		"5. The apparent genre and era of the film\n" +
-		"6. If you can identify the specific movie, mention it\n" +
-		"Be specific and detailed to help identify the film."
-
-	reqBody := openAIRequest{
-		Model: openAIModel,
+	reqBody, err := c.newFrameRequest(ctx, []FrameImage{{Data: imageData}}, false, nil)
+	if err != nil {
+		return "", err
+	}
+	return c.complete(ctx, "caption", reqBody)
+}
+
+// DescribeFrame asks for a JSON answer matching frameDescriptionSchema.
+func (c *OpenAIClient) DescribeFrame(ctx context.Context, imageData []byte) (*FrameDescription, error) {
+	format := &openAIResponseFormat{
+		Type: "json_schema",
+		JSONSchema: &openAIJSONSchema{
+			Name:   "frame_description",
+			Strict: true,
+			Schema: frameDescriptionSchema,
+		},
+	}
+
+	reqBody, err := c.newFrameRequest(ctx, []FrameImage{{Data: imageData}}, false, format)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := c.complete(ctx, "describe_frame", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	var description FrameDescription
+	if err := json.Unmarshal([]byte(content), &description); err != nil {
+		return nil, fmt.Errorf("failed to parse structured caption: %w", err)
+	}
+	return &description, nil
+}
+
+// DescribeFrames sends several frames of one video in a single request, each
+// labelled with its timestamp, and asks for one consolidated identification
+// plus a note per frame.
+func (c *OpenAIClient) DescribeFrames(ctx context.Context, frames []FrameImage) (*SequenceDescription, error) {
+	format := &openAIResponseFormat{
+		Type: "json_schema",
+		JSONSchema: &openAIJSONSchema{
+			Name:   "sequence_description",
+			Strict: true,
+			Schema: sequenceDescriptionSchema,
+		},
+	}
+
+	reqBody, err := c.newFrameRequest(ctx, frames, true, format)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := c.complete(ctx, "describe_frames", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	var description SequenceDescription
+	if err := json.Unmarshal([]byte(content), &description); err != nil {
+		return nil, fmt.Errorf("failed to parse multi-frame description: %w", err)
+	}
+	return &description, nil
+}
+
+// renderPrompt executes the prompt template with the video details carried
+// by ctx.
+func (c *OpenAIClient) renderPrompt(ctx context.Context, structured bool, frames int) (string, error) {
+	info := VideoInfoFromContext(ctx)
+	data := PromptData{
+		Title:       info.Title,
+		Description: info.Description,
+		Structured:  structured,
+		Frames:      frames,
+	}
+
+	var prompt bytes.Buffer
+	if err := c.prompt.Execute(&prompt, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt: %w", err)
+	}
+	return prompt.String(), nil
+}
+
+// newFrameRequest builds a chat completion with the prompt followed by the
+// images. In multi mode every image is preceded by a "Frame N at M:SS" label.
+func (c *OpenAIClient) newFrameRequest(ctx context.Context, frames []FrameImage, multi bool, format *openAIResponseFormat) (openAIRequest, error) {
+	count := 0
+	if multi {
+		count = len(frames)
+	}
+	prompt, err := c.renderPrompt(ctx, format != nil, count)
+	if err != nil {
+		return openAIRequest{}, err
+	}
+
+	content := []openAIContentPart{
+		{
+			Type: "text",
+			Text: prompt,
+		},
+	}
+	for i, frame := range frames {
+		if multi {
+			content = append(content, openAIContentPart{
+				Type: "text",
+				Text: fmt.Sprintf("Frame %d at %s", i+1, formatTimestamp(frame.Timestamp)),
+			})
+		}
+		content = append(content, openAIContentPart{
+			Type: "image_url",
+			ImageURL: &openAIImageURL{
+				URL:    fmt.Sprintf("data:image/jpeg;base64,%s", base64.StdEncoding.EncodeToString(frame.Data)),
+				Detail: c.options.ImageDetail,
+			},
+		})
+	}
+
+	return openAIRequest{
+		Model: c.options.Model,
 		Messages: []openAIMessage{
 			{
-				Role: "user",
-				Content: []openAIContentPart{
-					{
-						Type: "text",
-						Text: prompt,
-					},
-					{
-						Type: "image_url",
-						ImageURL: &openAIImageURL{
-							URL: fmt.Sprintf("data:image/jpeg;base64,%s", imageBase64),
-						},
-					},
-				},
+				Role:    "user",
+				Content: content,
 			},
 		},
+		MaxTokens:      c.options.MaxTokens,
+		Temperature:    c.options.Temperature,
+		ResponseFormat: format,
+	}, nil
+}
+
+func formatTimestamp(seconds float64) string {
+	total := int(seconds)
+	return fmt.Sprintf("%d:%02d", total/60, total%60)
+}
+
+func (c *OpenAIClient) complete(ctx context.Context, operation string, reqBody openAIRequest) (string, error) {
+	if err := c.meter.Allow(ctx, metering.ProviderOpenAI); err != nil {
+		return "", err
 	}
 
 	jsonData, err := json.Marshal(reqBody)
@@ -100,17 +441,21 @@ func (c *OpenAIClient) GetFrameCaption(ctx context.Context, imageData []byte) (s
 		return "", fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", openAIAPIURL, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", c.endpoint, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
+	if c.options.APIVersion != "" {
+		req.Header.Set("api-key", c.apiKey)
+	} else if c.apiKey != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
+	}
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to make request: %w", err)
+		return "", fmt.Errorf("OpenAI request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
@@ -128,9 +473,23 @@ func (c *OpenAIClient) GetFrameCaption(ctx context.Context, imageData []byte) (s
 		return "", fmt.Errorf("OpenAI API error: %s", openAIResp.Error.Message)
 	}
 
+	c.meter.Record(ctx, metering.Event{
+		Provider:     metering.ProviderOpenAI,
+		Operation:    operation,
+		Units:        openAIResp.Usage.TotalTokens,
+		Unit:         "tokens",
+		InputTokens:  openAIResp.Usage.PromptTokens,
+		OutputTokens: openAIResp.Usage.CompletionTokens,
+	})
+
 	if len(openAIResp.Choices) == 0 {
 		return "", fmt.Errorf("no response from OpenAI")
 	}
 
-	return openAIResp.Choices[0].Message.Content, nil
-}
\ No newline at end of file
+	message := openAIResp.Choices[0].Message
+	if message.Refusal != "" {
+		return "", fmt.Errorf("OpenAI refused: %s", message.Refusal)
+	}
+
+	return message.Content, nil
+}
@@ -0,0 +1,172 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kdimtricp/vshazam/internal/outbound"
+	"github.com/kdimtricp/vshazam/internal/outbound/cassette"
+)
+
+func TestOpenAIClientCustomEndpoint(t *testing.T) {
+	var captured openAIRequest
+	var path, apiVersion, apiKey string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		apiVersion = r.URL.Query().Get("api-version")
+		apiKey = r.Header.Get("api-key")
+		if err := json.NewDecoder(r.Body).Decode(&captured); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		w.Write([]byte(`{"choices":[{"message":{"content":"A rooftop chase"}}]}`))
+	}))
+	defer server.Close()
+
+	temperature := 0.0
+	client, err := NewOpenAIClientWithOptions("secret", OpenAIOptions{
+		BaseURL:        server.URL + "/openai/deployments/vision/",
+		Model:          "llava",
+		APIVersion:     "2024-08-01-preview",
+		MaxTokens:      300,
+		Temperature:    &temperature,
+		ImageDetail:    "low",
+		PromptTemplate: `Describe a frame from "{{.Title}}"{{if .Structured}} as JSON{{end}}.`,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := WithVideoInfo(context.Background(), VideoInfo{Title: "Holiday clip"})
+	caption, err := client.GetFrameCaption(ctx, []byte("fake image data"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if caption != "A rooftop chase" {
+		t.Errorf("expected caption from server, got %q", caption)
+	}
+	if path != "/openai/deployments/vision/chat/completions" {
+		t.Errorf("unexpected request path %q", path)
+	}
+	if apiVersion != "2024-08-01-preview" || apiKey != "secret" {
+		t.Errorf("expected Azure-style auth, got api-version %q and api-key %q", apiVersion, apiKey)
+	}
+	if captured.Model != "llava" || captured.MaxTokens != 300 {
+		t.Errorf("unexpected model %q or max tokens %d", captured.Model, captured.MaxTokens)
+	}
+	if captured.Temperature == nil || *captured.Temperature != 0 {
+		t.Errorf("expected explicit zero temperature, got %v", captured.Temperature)
+	}
+
+	parts := captured.Messages[0].Content
+	if parts[0].Text != `Describe a frame from "Holiday clip".` {
+		t.Errorf("unexpected prompt %q", parts[0].Text)
+	}
+	if parts[1].ImageURL.Detail != "low" {
+		t.Errorf("expected low image detail, got %q", parts[1].ImageURL.Detail)
+	}
+}
+
+func TestOpenAIClientDefaultPrompt(t *testing.T) {
+	client := NewOpenAIClient("key")
+
+	prompt, err := client.renderPrompt(context.Background(), true, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(prompt, "film_guesses") || strings.Contains(prompt, "uploader") {
+		t.Errorf("unexpected structured prompt without video info:\n%s", prompt)
+	}
+
+	ctx := WithVideoInfo(context.Background(), VideoInfo{Title: "Clip", Description: "From my TV"})
+	prompt, err = client.renderPrompt(ctx, false, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(prompt, "Analyze this video frame and provide") || !strings.Contains(prompt, `titled the video "Clip"`) || !strings.Contains(prompt, "From my TV") {
+		t.Errorf("unexpected free-text prompt:\n%s", prompt)
+	}
+}
+
+func TestOpenAIClientInvalidTemplate(t *testing.T) {
+	if _, err := NewOpenAIClientWithOptions("key", OpenAIOptions{PromptTemplate: "{{.Title"}); err == nil {
+		t.Error("expected error for invalid prompt template")
+	}
+}
+
+func newCassetteOpenAIClient(t *testing.T, name string) *OpenAIClient {
+	t.Helper()
+	client := NewOpenAIClient("test-key")
+	client.UseTransport(cassette.ForTest(t, filepath.Join("testdata", "cassettes", name)))
+	return client
+}
+
+func TestOpenAIClientCassetteCaption(t *testing.T) {
+	client := newCassetteOpenAIClient(t, "openai_caption.json")
+
+	caption, err := client.GetFrameCaption(context.Background(), []byte("fake image data"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(caption, `"Inception" (2010)`) {
+		t.Errorf("unexpected caption %q", caption)
+	}
+}
+
+func TestOpenAIClientCassetteDescribe(t *testing.T) {
+	client := newCassetteOpenAIClient(t, "openai_describe.json")
+
+	description, err := client.DescribeFrame(context.Background(), []byte("fake image data"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(description.FilmGuesses) != 2 || description.FilmGuesses[0].Title != "Inception" || description.FilmGuesses[0].Year != 2010 {
+		t.Errorf("unexpected film guesses %+v", description.FilmGuesses)
+	}
+	if len(description.VisibleText) != 1 || description.VisibleText[0] != "INCEPTION" {
+		t.Errorf("unexpected visible text %v", description.VisibleText)
+	}
+}
+
+func TestOpenAIClientCassetteErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		cassette string
+		kind     outbound.Kind
+		message  string
+	}{
+		{name: "bad request", cassette: "openai_error.json", kind: outbound.KindPermanent, message: "Invalid image data"},
+		{name: "quota exhausted", cassette: "openai_quota.json", kind: outbound.KindQuota, message: "exceeded your current quota"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newCassetteOpenAIClient(t, tt.cassette)
+
+			_, err := client.GetFrameCaption(context.Background(), []byte("fake image data"))
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if kind := outbound.KindOf(err); kind != tt.kind {
+				t.Errorf("expected %s error, got %s: %v", tt.kind, kind, err)
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("expected provider message in %q", err.Error())
+			}
+		})
+	}
+}
+
+func TestOpenAIClientCassetteMalformed(t *testing.T) {
+	client := newCassetteOpenAIClient(t, "openai_malformed.json")
+
+	if _, err := client.DescribeFrame(context.Background(), []byte("fake image data")); err == nil {
+		t.Error("expected error for truncated structured output")
+	}
+}
@@ -0,0 +1,132 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// FilmReferenceSites restricts a query to pages that describe one film.
+var FilmReferenceSites = []string{"imdb.com/title", "en.wikipedia.org"}
+
+// SearchQuery is one Custom Search query: an exact phrase followed by extra
+// terms, an optional year and optional site restrictions.
+type SearchQuery struct {
+	Phrase string
+	Terms  []string
+	Year   int
+	Sites  []string
+}
+
+// TitleQuery searches the whole web for a film title, e.g. `"Heat" film 1995`.
+func TitleQuery(title string, year int) SearchQuery {
+	return SearchQuery{Phrase: title, Terms: []string{"film"}, Year: year}
+}
+
+// ReferenceQuery searches IMDb and Wikipedia for a film title, to find its
+// year and IMDb ID.
+func ReferenceQuery(title string, year int) SearchQuery {
+	return SearchQuery{Phrase: title, Terms: []string{"film"}, Year: year, Sites: FilmReferenceSites}
+}
+
+// QuoteQuery searches for pages quoting a line of dialogue.
+func QuoteQuery(line string) SearchQuery {
+	return SearchQuery{Phrase: line, Terms: []string{"movie", "quote"}}
+}
+
+// String formats the query for the q parameter. A query without a phrase is
+// empty.
+func (q SearchQuery) String() string {
+	phrase := strings.Join(strings.Fields(strings.ReplaceAll(q.Phrase, `"`, "")), " ")
+	if phrase == "" {
+		return ""
+	}
+
+	parts := []string{fmt.Sprintf("%q", phrase)}
+	parts = append(parts, q.Terms...)
+	if q.Year > 0 {
+		parts = append(parts, fmt.Sprint(q.Year))
+	}
+	sites := make([]string, 0, len(q.Sites))
+	for _, site := range q.Sites {
+		sites = append(sites, "site:"+site)
+	}
+	if len(sites) > 0 {
+		parts = append(parts, strings.Join(sites, " OR "))
+	}
+	return strings.Join(parts, " ")
+}
+
+// QueryBuilder composes the queries for one video from title guesses read on
+// screen or named in captions and from lines of dialogue. Queries are kept in
+// the order they were added, duplicates are dropped and at most max are
+// returned.
+type QueryBuilder struct {
+	max     int
+	queries []string
+	seen    map[string]bool
+}
+
+func NewQueryBuilder(max int) *QueryBuilder {
+	return &QueryBuilder{max: max, seen: make(map[string]bool)}
+}
+
+func (b *QueryBuilder) add(q SearchQuery) {
+	query := q.String()
+	if query == "" || b.seen[query] || len(b.queries) >= b.max {
+		return
+	}
+	b.seen[query] = true
+	b.queries = append(b.queries, query)
+}
+
+// AddTitle adds a web-wide query for a title guess.
+func (b *QueryBuilder) AddTitle(title string, year int) {
+	b.add(TitleQuery(title, year))
+}
+
+// AddReference adds a query for the title's IMDb and Wikipedia pages.
+func (b *QueryBuilder) AddReference(title string, year int) {
+	b.add(ReferenceQuery(title, year))
+}
+
+// AddQuote adds a query for a line of dialogue.
+func (b *QueryBuilder) AddQuote(line string) {
+	b.add(QuoteQuery(line))
+}
+
+func (b *QueryBuilder) Queries() []string {
+	return b.queries
+}
+
+// FilmSearcher is implemented by GoogleSearchClient.
+type FilmSearcher interface {
+	SearchFilms(ctx context.Context, query string) ([]SearchResult, error)
+}
+
+// maxConcurrentSearches bounds the queries in flight at once; the client's
+// rate limiter still spaces out the actual requests.
+const maxConcurrentSearches = 4
+
+// SearchAll runs the queries concurrently. The results and error of each
+// query are returned at the query's index.
+func SearchAll(ctx context.Context, searcher FilmSearcher, queries []string) ([][]SearchResult, []error) {
+	results := make([][]SearchResult, len(queries))
+	errs := make([]error, len(queries))
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, maxConcurrentSearches)
+	for i, query := range queries {
+		wg.Add(1)
+		go func(i int, query string) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			results[i], errs[i] = searcher.SearchFilms(ctx, query)
+		}(i, query)
+	}
+	wg.Wait()
+
+	return results, errs
+}
@@ -0,0 +1,73 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSearchQueryString(t *testing.T) {
+	tests := []struct {
+		query SearchQuery
+		want  string
+	}{
+		{TitleQuery("Heat", 1995), `"Heat" film 1995`},
+		{TitleQuery("Heat", 0), `"Heat" film`},
+		{ReferenceQuery("Heat", 0), `"Heat" film site:imdb.com/title OR site:en.wikipedia.org`},
+		{QuoteQuery(`  "Say hello to my   little friend!" `), `"Say hello to my little friend!" movie quote`},
+	}
+
+	for _, tt := range tests {
+		if got := tt.query.String(); got != tt.want {
+			t.Errorf("expected %s, got %s", tt.want, got)
+		}
+	}
+}
+
+func TestQueryBuilder(t *testing.T) {
+	b := NewQueryBuilder(3)
+	b.AddTitle("Heat", 1995)
+	b.AddTitle("Heat", 1995)
+	b.AddQuote("")
+	b.AddReference("Heat", 0)
+	b.AddQuote("Don't let yourself get attached to anything.")
+	b.AddTitle("Ronin", 1998)
+
+	want := []string{
+		`"Heat" film 1995`,
+		`"Heat" film site:imdb.com/title OR site:en.wikipedia.org`,
+		`"Don't let yourself get attached to anything." movie quote`,
+	}
+	if got := b.Queries(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+type fakeSearcher map[string][]SearchResult
+
+func (f fakeSearcher) SearchFilms(ctx context.Context, query string) ([]SearchResult, error) {
+	results, ok := f[query]
+	if !ok {
+		return nil, errors.New("no results")
+	}
+	return results, nil
+}
+
+func TestSearchAll(t *testing.T) {
+	searcher := fakeSearcher{
+		"a": {{Link: "https://a"}},
+		"c": {{Link: "https://c"}},
+	}
+
+	results, errs := SearchAll(context.Background(), searcher, []string{"a", "b", "c"})
+	if len(results) != 3 || len(errs) != 3 {
+		t.Fatalf("expected 3 results and errors, got %d and %d", len(results), len(errs))
+	}
+	if errs[0] != nil || errs[1] == nil || errs[2] != nil {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	if results[0][0].Link != "https://a" || results[2][0].Link != "https://c" {
+		t.Errorf("results out of order: %+v", results)
+	}
+}
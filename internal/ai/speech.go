@@ -0,0 +1,218 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kdimtricp/vshazam/internal/metering"
+	"github.com/kdimtricp/vshazam/internal/outbound"
+)
+
+// Speech-to-text providers.
+const (
+	// SpeechOpenAI is any OpenAI-compatible /audio/transcriptions endpoint.
+	SpeechOpenAI = "openai"
+	// SpeechWhisperCpp is the HTTP server bundled with whisper.cpp.
+	SpeechWhisperCpp = "whisper_cpp"
+)
+
+const (
+	defaultSpeechModel      = "whisper-1"
+	defaultWhisperCppURL    = "http://localhost:8080"
+	defaultSpeechMaxSeconds = 600
+)
+
+// SpeechOptions configures a SpeechClient. Zero values fall back to the
+// provider's defaults.
+type SpeechOptions struct {
+	Provider string
+	// BaseURL is the API root, e.g. https://api.openai.com/v1 or the address
+	// of a whisper.cpp server.
+	BaseURL string
+	Model   string
+	// Language is an ISO-639-1 hint such as "en". Empty lets the provider
+	// detect it.
+	Language string
+}
+
+// Transcript is the speech recognized in an audio track.
+type Transcript struct {
+	Language string          `json:"language"`
+	Duration float64         `json:"duration"`
+	Text     string          `json:"text"`
+	Segments []SpeechSegment `json:"segments"`
+}
+
+// SpeechSegment is one timed stretch of speech. Start and End are seconds
+// from the start of the audio.
+type SpeechSegment struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+}
+
+// SpeechClient sends audio to a speech-to-text provider.
+type SpeechClient struct {
+	apiKey     string
+	endpoint   string
+	options    SpeechOptions
+	httpClient *outbound.Client
+	meter      metering.Meter
+}
+
+// speechPolicy allows for long uploads and slow local models.
+func speechPolicy() outbound.Policy {
+	policy := outbound.DefaultPolicy()
+	policy.Timeout = 5 * time.Minute
+	policy.RequestsPerSecond = 1
+	policy.Burst = 2
+	return policy
+}
+
+func NewSpeechClient(apiKey string, options SpeechOptions) (*SpeechClient, error) {
+	if options.Provider == "" {
+		options.Provider = SpeechOpenAI
+	}
+
+	var path string
+	switch options.Provider {
+	case SpeechOpenAI:
+		if options.BaseURL == "" {
+			options.BaseURL = defaultOpenAIBaseURL
+		}
+		if options.Model == "" {
+			options.Model = defaultSpeechModel
+		}
+		path = "/audio/transcriptions"
+	case SpeechWhisperCpp:
+		if options.BaseURL == "" {
+			options.BaseURL = defaultWhisperCppURL
+		}
+		path = "/inference"
+	default:
+		return nil, fmt.Errorf("unknown speech provider %q", options.Provider)
+	}
+
+	return &SpeechClient{
+		apiKey:     apiKey,
+		endpoint:   strings.TrimRight(options.BaseURL, "/") + path,
+		options:    options,
+		httpClient: outbound.New(metering.ProviderSpeech, speechPolicy()),
+		meter:      metering.Nop{},
+	}, nil
+}
+
+// UseTransport sends requests through rt instead of the default transport.
+func (c *SpeechClient) UseTransport(rt http.RoundTripper) {
+	c.httpClient.UseTransport(rt)
+}
+
+// UseMeter records the transcribed minutes with m and checks its budget
+// before each call. Local whisper.cpp servers are not metered.
+func (c *SpeechClient) UseMeter(m metering.Meter) {
+	c.meter = m
+}
+
+// Transcribe sends a WAV file and returns the recognized speech with segment
+// timestamps.
+func (c *SpeechClient) Transcribe(ctx context.Context, audio []byte) (*Transcript, error) {
+	metered := c.options.Provider == SpeechOpenAI
+	if metered {
+		if err := c.meter.Allow(ctx, metering.ProviderSpeech); err != nil {
+			return nil, err
+		}
+	}
+
+	body, contentType, err := c.newForm(audio)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("transcription request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var transcript Transcript
+	if err := json.NewDecoder(resp.Body).Decode(&transcript); err != nil {
+		return nil, fmt.Errorf("failed to decode transcription: %w", err)
+	}
+
+	segments := transcript.Segments[:0]
+	for _, segment := range transcript.Segments {
+		segment.Text = strings.TrimSpace(segment.Text)
+		if segment.Text != "" {
+			segments = append(segments, segment)
+		}
+	}
+	transcript.Segments = segments
+	transcript.Text = strings.TrimSpace(transcript.Text)
+	if transcript.Duration == 0 && len(segments) > 0 {
+		transcript.Duration = segments[len(segments)-1].End
+	}
+
+	if metered {
+		c.meter.Record(ctx, metering.Event{
+			Provider:  metering.ProviderSpeech,
+			Operation: "transcribe",
+			Units:     int(math.Ceil(transcript.Duration)),
+			Unit:      "seconds",
+		})
+	}
+
+	return &transcript, nil
+}
+
+// newForm builds the multipart upload. Both providers accept verbose_json,
+// which carries segment timestamps.
+func (c *SpeechClient) newForm(audio []byte) ([]byte, string, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+
+	file, err := form.CreateFormFile("file", "audio.wav")
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create upload: %w", err)
+	}
+	if _, err := file.Write(audio); err != nil {
+		return nil, "", fmt.Errorf("failed to write audio: %w", err)
+	}
+
+	fields := [][2]string{{"response_format", "verbose_json"}}
+	if c.options.Provider == SpeechOpenAI {
+		fields = append(fields,
+			[2]string{"model", c.options.Model},
+			[2]string{"timestamp_granularities[]", "segment"})
+	} else {
+		fields = append(fields, [2]string{"temperature", "0"})
+	}
+	if c.options.Language != "" {
+		fields = append(fields, [2]string{"language", c.options.Language})
+	}
+	for _, field := range fields {
+		if err := form.WriteField(field[0], field[1]); err != nil {
+			return nil, "", fmt.Errorf("failed to write %s: %w", field[0], err)
+		}
+	}
+
+	if err := form.Close(); err != nil {
+		return nil, "", fmt.Errorf("failed to finish upload: %w", err)
+	}
+	return body.Bytes(), form.FormDataContentType(), nil
+}
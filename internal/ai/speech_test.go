@@ -0,0 +1,109 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kdimtricp/vshazam/internal/metering"
+)
+
+// recordingMeter keeps every recorded event.
+type recordingMeter struct {
+	events []metering.Event
+}
+
+func (m *recordingMeter) Allow(ctx context.Context, provider string) error { return nil }
+
+func (m *recordingMeter) Record(ctx context.Context, event metering.Event) {
+	m.events = append(m.events, event)
+}
+
+func TestSpeechClientProviders(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		path     string
+		model    string
+		metered  bool
+	}{
+		{name: "openai", provider: SpeechOpenAI, path: "/v1/audio/transcriptions", model: "whisper-1", metered: true},
+		{name: "whisper.cpp", provider: SpeechWhisperCpp, path: "/inference"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path, auth string
+			var form map[string][]string
+			var audio int64
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path = r.URL.Path
+				auth = r.Header.Get("Authorization")
+				if err := r.ParseMultipartForm(1 << 20); err != nil {
+					t.Errorf("failed to parse upload: %v", err)
+					return
+				}
+				form = r.MultipartForm.Value
+				if files := r.MultipartForm.File["file"]; len(files) == 1 {
+					audio = files[0].Size
+				}
+				w.Write([]byte(`{"language":"english","duration":61.2,"text":" I need you to trust me. ","segments":[
+					{"start":1.5,"end":3.0,"text":" I need you to trust me."},
+					{"start":3.0,"end":3.4,"text":"  "}
+				]}`))
+			}))
+			defer server.Close()
+
+			baseURL := server.URL
+			if tt.provider == SpeechOpenAI {
+				baseURL += "/v1/"
+			}
+			client, err := NewSpeechClient("secret", SpeechOptions{Provider: tt.provider, BaseURL: baseURL, Language: "en"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			meter := &recordingMeter{}
+			client.UseMeter(meter)
+
+			transcript, err := client.Transcribe(context.Background(), []byte("RIFF fake wav"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if path != tt.path || auth != "Bearer secret" || audio != 13 {
+				t.Errorf("unexpected request to %q with auth %q and %d bytes of audio", path, auth, audio)
+			}
+			if got := form["model"]; (tt.model == "" && got != nil) || (tt.model != "" && (len(got) != 1 || got[0] != tt.model)) {
+				t.Errorf("unexpected model field %v", got)
+			}
+			if form["response_format"][0] != "verbose_json" || form["language"][0] != "en" {
+				t.Errorf("unexpected form %v", form)
+			}
+
+			if transcript.Text != "I need you to trust me." || len(transcript.Segments) != 1 {
+				t.Fatalf("expected blank segments to be dropped, got %+v", transcript)
+			}
+			if segment := transcript.Segments[0]; segment.Start != 1.5 || segment.End != 3.0 || segment.Text != "I need you to trust me." {
+				t.Errorf("unexpected segment %+v", segment)
+			}
+
+			if !tt.metered {
+				if len(meter.events) != 0 {
+					t.Errorf("expected local transcription to be free, got %+v", meter.events)
+				}
+				return
+			}
+			if len(meter.events) != 1 || meter.events[0].Provider != metering.ProviderSpeech || meter.events[0].Units != 62 {
+				t.Errorf("expected 62 seconds to be metered, got %+v", meter.events)
+			}
+		})
+	}
+}
+
+func TestSpeechClientUnknownProvider(t *testing.T) {
+	if _, err := NewSpeechClient("", SpeechOptions{Provider: "dictaphone"}); err == nil {
+		t.Error("expected an error for an unknown provider")
+	}
+}
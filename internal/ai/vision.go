@@ -9,6 +9,39 @@ type VisionService interface {
 	AnalyzeFrame(ctx context.Context, imageData []byte) (*FrameAnalysis, error)
 }
 
+// BatchVisionService analyzes several frames of one video together so the
+// model can use temporal context.
+type BatchVisionService interface {
+	AnalyzeFrames(ctx context.Context, frames []FrameImage) ([]*FrameAnalysis, error)
+}
+
+// FrameImage is an encoded frame and its position in the video in seconds.
+type FrameImage struct {
+	Data      []byte
+	Timestamp float64
+}
+
+// VideoInfo describes the video a frame belongs to. Prompt templates can refer
+// to it to give the model extra context.
+type VideoInfo struct {
+	Title       string
+	Description string
+}
+
+type videoInfoKey struct{}
+
+// WithVideoInfo returns a context carrying info for AnalyzeFrame.
+func WithVideoInfo(ctx context.Context, info VideoInfo) context.Context {
+	return context.WithValue(ctx, videoInfoKey{}, info)
+}
+
+// VideoInfoFromContext returns the video info stored by WithVideoInfo, or the
+// zero value.
+func VideoInfoFromContext(ctx context.Context) VideoInfo {
+	info, _ := ctx.Value(videoInfoKey{}).(VideoInfo)
+	return info
+}
+
 type FrameAnalysis struct {
 	Caption    string          `json:"caption"`
 	Labels     []Label         `json:"labels"`
@@ -17,6 +50,74 @@ type FrameAnalysis struct {
 	Colors     []ColorInfo     `json:"colors"`
 	Confidence float64         `json:"confidence"`
 	Timestamp  time.Time       `json:"timestamp"`
+	// TextBlocks are the OCR lines with position, locale and region.
+	TextBlocks []TextBlock `json:"text_blocks,omitempty"`
+
+	// Structured fields are filled when GPT answered in JSON mode.
+	Scene       string      `json:"scene,omitempty"`
+	Era         string      `json:"era,omitempty"`
+	Genres      []string    `json:"genres,omitempty"`
+	VisibleText []string    `json:"visible_text,omitempty"`
+	Actors      []string    `json:"actors,omitempty"`
+	FilmGuesses []FilmGuess `json:"film_guesses,omitempty"`
+
+	// Google Vision web, logo, landmark and object detection.
+	BestGuessLabels []string         `json:"best_guess_labels,omitempty"`
+	WebEntities     []WebEntity      `json:"web_entities,omitempty"`
+	MatchingPages   []WebPage        `json:"matching_pages,omitempty"`
+	Logos           []Logo           `json:"logos,omitempty"`
+	Landmarks       []Landmark       `json:"landmarks,omitempty"`
+	Objects         []DetectedObject `json:"objects,omitempty"`
+}
+
+// FilmGuess is a film GPT believes the frame comes from.
+type FilmGuess struct {
+	Title      string  `json:"title"`
+	Year       int     `json:"year,omitempty"`
+	Confidence float64 `json:"confidence"`
+}
+
+// WebEntity is something Google found associated with the image on the web,
+// such as a film, an actor or a place. Scores are relative, not probabilities.
+type WebEntity struct {
+	ID          string  `json:"id,omitempty"`
+	Description string  `json:"description"`
+	Score       float64 `json:"score"`
+}
+
+// WebPage is a page that contains a full or partial match of the image.
+type WebPage struct {
+	URL   string  `json:"url"`
+	Title string  `json:"title,omitempty"`
+	Score float64 `json:"score,omitempty"`
+}
+
+type Logo struct {
+	Name        string      `json:"name"`
+	Confidence  float64     `json:"confidence"`
+	BoundingBox BoundingBox `json:"bounding_box"`
+}
+
+type Landmark struct {
+	Name       string  `json:"name"`
+	Confidence float64 `json:"confidence"`
+	Latitude   float64 `json:"latitude,omitempty"`
+	Longitude  float64 `json:"longitude,omitempty"`
+}
+
+// DetectedObject is a localized object. Its box is normalized to 0..1 of the
+// frame width and height.
+type DetectedObject struct {
+	Name        string        `json:"name"`
+	Confidence  float64       `json:"confidence"`
+	BoundingBox NormalizedBox `json:"bounding_box"`
+}
+
+type NormalizedBox struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
 }
 
 type Label struct {
@@ -27,6 +128,9 @@ type Label struct {
 type FaceDetection struct {
 	BoundingBox BoundingBox `json:"bounding_box"`
 	Confidence  float64     `json:"confidence"`
+	// ClusterID identifies the person across frames and videos once the
+	// face has been clustered.
+	ClusterID string `json:"cluster_id,omitempty"`
 }
 
 type BoundingBox struct {
@@ -44,6 +148,13 @@ type ColorInfo struct {
 
 type Config struct {
 	OpenAIAPIKey               string
+	OpenAIBaseURL              string
+	OpenAIModel                string
+	OpenAIAPIVersion           string
+	OpenAIMaxTokens            int
+	OpenAITemperature          *float64
+	OpenAIImageDetail          string
+	OpenAIPromptTemplate       string
 	GoogleVisionKey            string
 	GoogleVisionServiceAccount string
 	GoogleSearchAPIKey         string
@@ -51,11 +162,42 @@ type Config struct {
 	TMDbAPIKey                 string
 	MaxFramesPerVideo          int
 	FrameSize                  int
+	ConfidenceThreshold        float64
+	ConfidenceMargin           float64
+	MaxFramesAnalyze           int
+	// OpenAIPlainCaption disables the structured JSON caption and asks GPT
+	// for free text only.
+	OpenAIPlainCaption bool
+	// OpenAIMultiFrame sends each round of sampled frames to GPT in one
+	// request instead of one request per frame.
+	OpenAIMultiFrame bool
+	// GoogleVisionFeatures lists the Vision API features to request, e.g.
+	// WEB_DETECTION. Empty means DefaultVisionFeatures.
+	GoogleVisionFeatures []string
+	// SpeechProvider enables transcription of the audio track with openai or
+	// whisper_cpp. Empty disables it.
+	SpeechProvider   string
+	SpeechAPIKey     string
+	SpeechBaseURL    string
+	SpeechModel      string
+	SpeechLanguage   string
+	SpeechMaxSeconds float64
+	// TMDbLanguage and TMDbRegion localize TMDb titles, overviews and
+	// release dates, e.g. "de-DE" and "DE".
+	TMDbLanguage string
+	TMDbRegion   string
+	// IMDbDataset searches the imported IMDb datasets offline, and
+	// OMDbAPIKey enables OMDb, for titles TMDb does not resolve.
+	IMDbDataset bool
+	OMDbAPIKey  string
 }
 
 func NewConfig() *Config {
 	return &Config{
-		MaxFramesPerVideo: 5,
-		FrameSize:         512,
+		MaxFramesPerVideo:   5,
+		FrameSize:           512,
+		ConfidenceThreshold: 0.90,
+		ConfidenceMargin:    0.20,
+		MaxFramesAnalyze:    10,
 	}
 }
@@ -2,19 +2,34 @@ package ai
 
 import (
 	"context"
+	"errors"
 	"math"
 	"testing"
+
+	"github.com/kdimtricp/vshazam/internal/metering"
 )
 
 type mockOpenAIClient struct {
-	caption string
-	err     error
+	caption     string
+	description *FrameDescription
+	sequence    *SequenceDescription
+	err         error
+	calls       int
 }
 
 func (m *mockOpenAIClient) GetFrameCaption(ctx context.Context, imageData []byte) (string, error) {
+	m.calls++
 	return m.caption, m.err
 }
 
+func (m *mockOpenAIClient) DescribeFrame(ctx context.Context, imageData []byte) (*FrameDescription, error) {
+	m.calls++
+	if m.description == nil {
+		return nil, errors.New("structured output not supported")
+	}
+	return m.description, m.err
+}
+
 type mockGoogleVisionClient struct {
 	features *VisionFeatures
 	err      error
@@ -98,6 +113,169 @@ func TestVisionServiceAnalyzeFrame(t *testing.T) {
 	}
 }
 
+func (m *mockOpenAIClient) DescribeFrames(ctx context.Context, frames []FrameImage) (*SequenceDescription, error) {
+	m.calls++
+	if m.sequence == nil {
+		return nil, errors.New("multi-frame requests not supported")
+	}
+	return m.sequence, m.err
+}
+
+func TestVisionServiceAnalyzeFrames(t *testing.T) {
+	frames := []FrameImage{
+		{Data: []byte("frame 1"), Timestamp: 10},
+		{Data: []byte("frame 2"), Timestamp: 20},
+		{Data: []byte("frame 3"), Timestamp: 30},
+	}
+	client := &mockOpenAIClient{sequence: &SequenceDescription{
+		Summary:     "A heist unfolds in a dream",
+		Era:         "2010s",
+		FilmGuesses: []FilmGuess{{Title: "Inception", Year: 2010, Confidence: 0.9}},
+		Frames: []FrameNote{
+			{Frame: 2, Caption: "A spinning top", VisibleText: []string{"LIMBO"}},
+			{Frame: 1, Caption: "A hotel corridor"},
+		},
+	}}
+	service := &VisionServiceImpl{
+		openAIClient: client,
+		googleClient: &mockGoogleVisionClient{features: &VisionFeatures{Texts: []string{"EXIT"}}},
+		config:       &Config{},
+	}
+
+	analyses, err := service.AnalyzeFrames(context.Background(), frames)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if client.calls != 1 {
+		t.Errorf("expected a single OpenAI request, got %d", client.calls)
+	}
+	if len(analyses) != 3 {
+		t.Fatalf("expected 3 analyses, got %d", len(analyses))
+	}
+	if analyses[0].Caption != "A hotel corridor" || len(analyses[0].FilmGuesses) != 1 || analyses[0].Era != "2010s" {
+		t.Errorf("expected consolidated identification on the first frame, got %+v", analyses[0])
+	}
+	if analyses[1].Caption != "A spinning top" || len(analyses[1].VisibleText) != 1 || len(analyses[1].FilmGuesses) != 0 {
+		t.Errorf("expected per-frame note on the second frame, got %+v", analyses[1])
+	}
+	if analyses[2].Caption != "" || len(analyses[2].TextOCR) != 1 {
+		t.Errorf("expected only Google Vision data on the third frame, got %+v", analyses[2])
+	}
+}
+
+func TestVisionServiceAnalyzeFramesFallback(t *testing.T) {
+	client := &mockOpenAIClient{caption: "free text"}
+	service := &VisionServiceImpl{openAIClient: client, config: &Config{}}
+
+	analyses, err := service.AnalyzeFrames(context.Background(), []FrameImage{{Data: []byte("a")}, {Data: []byte("b")}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(analyses) != 2 || analyses[1].Caption != "free text" {
+		t.Errorf("expected per-frame fallback captions, got %+v", analyses)
+	}
+}
+
+// mockMeter refuses the listed providers.
+type mockMeter struct {
+	refused map[string]bool
+}
+
+func (m *mockMeter) Allow(ctx context.Context, provider string) error {
+	if m.refused[provider] {
+		return metering.ErrBudgetExceeded
+	}
+	return nil
+}
+
+func (m *mockMeter) Record(ctx context.Context, event metering.Event) {}
+
+func TestVisionServiceBudget(t *testing.T) {
+	client := &mockOpenAIClient{caption: "free text"}
+	service := &VisionServiceImpl{
+		openAIClient: client,
+		googleClient: &mockGoogleVisionClient{features: &VisionFeatures{Texts: []string{"EXIT"}}},
+		config:       &Config{OpenAIPlainCaption: true},
+		meter:        &mockMeter{refused: map[string]bool{metering.ProviderOpenAI: true}},
+	}
+
+	analysis, err := service.AnalyzeFrame(context.Background(), []byte("fake image data"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if client.calls != 0 || analysis.Caption != "" {
+		t.Errorf("expected OpenAI to be skipped over budget, got %d calls", client.calls)
+	}
+	if len(analysis.TextOCR) != 1 {
+		t.Errorf("expected Google Vision results, got %+v", analysis)
+	}
+
+	service.meter = &mockMeter{refused: map[string]bool{metering.ProviderOpenAI: true, metering.ProviderGoogleVision: true}}
+	if _, err := service.AnalyzeFrame(context.Background(), []byte("fake image data")); !errors.Is(err, metering.ErrBudgetExceeded) {
+		t.Errorf("expected budget error when every provider is refused, got %v", err)
+	}
+}
+
+func TestVisionServiceStructuredCaption(t *testing.T) {
+	description := &FrameDescription{
+		Caption:     "A man spins a top on a table",
+		Scene:       "Dim dining room",
+		Era:         "2010s",
+		Genres:      []string{"sci-fi", "thriller"},
+		Actors:      []string{"Leonardo DiCaprio"},
+		FilmGuesses: []FilmGuess{{Title: "Inception", Year: 2010, Confidence: 0.8}},
+	}
+
+	tests := []struct {
+		name            string
+		client          *mockOpenAIClient
+		plain           bool
+		expectedCaption string
+		expectedGuesses int
+	}{
+		{
+			name:            "structured caption",
+			client:          &mockOpenAIClient{caption: "free text", description: description},
+			expectedCaption: "A man spins a top on a table",
+			expectedGuesses: 1,
+		},
+		{
+			name:            "falls back to free text",
+			client:          &mockOpenAIClient{caption: "free text"},
+			expectedCaption: "free text",
+		},
+		{
+			name:            "plain caption configured",
+			client:          &mockOpenAIClient{caption: "free text", description: description},
+			plain:           true,
+			expectedCaption: "free text",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &VisionServiceImpl{
+				openAIClient: tt.client,
+				config:       &Config{OpenAIPlainCaption: tt.plain},
+			}
+
+			analysis, err := service.AnalyzeFrame(context.Background(), []byte("fake image data"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if analysis.Caption != tt.expectedCaption {
+				t.Errorf("expected caption %q, got %q", tt.expectedCaption, analysis.Caption)
+			}
+			if len(analysis.FilmGuesses) != tt.expectedGuesses {
+				t.Errorf("expected %d film guesses, got %d", tt.expectedGuesses, len(analysis.FilmGuesses))
+			}
+		})
+	}
+}
+
 func TestCalculateConfidence(t *testing.T) {
 	service := &VisionServiceImpl{config: &Config{}}
 
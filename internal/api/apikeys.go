@@ -0,0 +1,129 @@
+package api
+
+import (
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kdimtricp/vshazam/internal/auth"
+	"github.com/kdimtricp/vshazam/internal/models/user"
+)
+
+// recentKeyUses is how many audit log entries the settings page shows.
+const recentKeyUses = 50
+
+// keyExpiryDays are the lifetimes offered for new keys; 0 never expires.
+var keyExpiryDays = []int{30, 90, 365, 0}
+
+type apiKeysPage struct {
+	Keys     []*user.APIKeyDB
+	Uses     []*user.APIKeyUseDB
+	Names    map[string]string
+	Scopes   []string
+	Expiries []int
+	NewToken string
+	NewKey   *user.APIKeyDB
+	Error    string
+	Now      time.Time
+}
+
+// APIKeysPageHandler lists the signed-in user's API keys and their recent
+// uses.
+func (app *App) APIKeysPageHandler(w http.ResponseWriter, r *http.Request) {
+	app.renderAPIKeys(w, r, apiKeysPage{})
+}
+
+// CreateAPIKeyHandler issues a key and shows its token, which is never
+// displayed again.
+func (app *App) CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	days, _ := strconv.Atoi(r.FormValue("expires_days"))
+	ttl := time.Duration(days) * 24 * time.Hour
+
+	u := auth.UserFrom(r.Context())
+	token, key, err := app.Auth.CreateAPIKey(r.Context(), u, r.FormValue("name"), r.Form["scopes"], ttl)
+	if err != nil {
+		page := apiKeysPage{Error: err.Error()}
+		switch {
+		case errors.Is(err, auth.ErrKeyNameNeeded), errors.Is(err, auth.ErrNoScopes),
+			errors.Is(err, auth.ErrUnknownScope), errors.Is(err, auth.ErrAdminScope):
+		default:
+			log.Printf("Failed to create API key for %s: %v", u.ID, err)
+			page.Error = "Creating the key failed. Try again later."
+		}
+		app.renderAPIKeys(w, r, page)
+		return
+	}
+
+	app.renderAPIKeys(w, r, apiKeysPage{NewToken: token, NewKey: key})
+}
+
+func (app *App) RevokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	page := apiKeysPage{}
+	revoked, err := app.Auth.RevokeAPIKey(r.Context(), auth.UserFrom(r.Context()), chi.URLParam(r, "id"))
+	switch {
+	case err != nil:
+		log.Printf("Failed to revoke API key: %v", err)
+		page.Error = "Revoking the key failed. Try again later."
+	case !revoked:
+		page.Error = "Key not found or already revoked"
+	}
+	app.renderAPIKeys(w, r, page)
+}
+
+// renderAPIKeys renders the settings page, or only its keys section for
+// HTMX requests.
+func (app *App) renderAPIKeys(w http.ResponseWriter, r *http.Request, page apiKeysPage) {
+	u := auth.UserFrom(r.Context())
+	keys, err := app.Auth.ListAPIKeys(r.Context(), u)
+	if errors.Is(err, auth.ErrKeysDisabled) {
+		http.Error(w, "API keys are not available", http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error loading API keys", http.StatusInternalServerError)
+		return
+	}
+	uses, err := app.Auth.ListAPIKeyUses(r.Context(), u, recentKeyUses)
+	if err != nil {
+		http.Error(w, "Error loading API key uses", http.StatusInternalServerError)
+		return
+	}
+
+	page.Keys = keys
+	page.Uses = uses
+	page.Names = make(map[string]string, len(keys))
+	for _, key := range keys {
+		page.Names[key.ID] = key.Name
+	}
+	page.Expiries = keyExpiryDays
+	page.Now = time.Now()
+	for _, scope := range user.Scopes {
+		if scope != user.ScopeAdmin || u.IsAdmin() {
+			page.Scopes = append(page.Scopes, scope)
+		}
+	}
+
+	tmplPath := filepath.Join("web", "templates", "settings_keys.html")
+	tmpl, err := template.New("settings_keys.html").Funcs(template.FuncMap{
+		"join": strings.Join,
+	}).ParseFiles(tmplPath)
+	if err != nil {
+		http.Error(w, "Error loading template", http.StatusInternalServerError)
+		return
+	}
+
+	name := "settings_keys.html"
+	if r.Header.Get("HX-Request") == "true" {
+		name = "keys"
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.ExecuteTemplate(w, name, page); err != nil {
+		log.Printf("Failed to render API keys: %v", err)
+	}
+}
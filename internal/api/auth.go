@@ -0,0 +1,403 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"html/template"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/kdimtricp/vshazam/internal/auth"
+	"github.com/kdimtricp/vshazam/internal/database"
+	"github.com/kdimtricp/vshazam/internal/sharing"
+)
+
+const (
+	sessionCookie = "vshazam_session"
+	// csrfCookie is readable by scripts: web/static/csrf.js copies it into
+	// the csrfHeader of every HTMX request.
+	csrfCookie = "vshazam_csrf"
+	csrfHeader = "X-CSRF-Token"
+	csrfField  = "csrf_token"
+	// loginCSRFCookie protects the sign-in and registration forms, which are
+	// posted before there is a session to hold a CSRF token.
+	loginCSRFCookie = "vshazam_login_csrf"
+)
+
+// authenticate signs the request in from an "Authorization: Bearer" API key
+// or its session cookie. Requests without a valid session continue
+// anonymously; an invalid API key is refused outright.
+func (app *App) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token, ok := bearerToken(r); ok && app.Auth != nil {
+			app.authenticateAPIKey(w, r, token, next)
+			return
+		}
+
+		cookie, err := r.Cookie(sessionCookie)
+		if err != nil || app.Auth == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		u, session, err := app.Auth.Authenticate(r.Context(), cookie.Value)
+		if err != nil {
+			log.Printf("Failed to authenticate session: %v", err)
+		}
+		if u != nil {
+			r = r.WithContext(auth.WithUser(r.Context(), u, session))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authenticateAPIKey serves a request made with an API key and records it in
+// the key's audit log.
+func (app *App) authenticateAPIKey(w http.ResponseWriter, r *http.Request, token string, next http.Handler) {
+	u, key, err := app.Auth.AuthenticateAPIKey(r.Context(), token)
+	if err != nil {
+		log.Printf("Failed to authenticate API key: %v", err)
+		http.Error(w, "Authentication failed", http.StatusInternalServerError)
+		return
+	}
+	if u == nil {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		http.Error(w, "Invalid API key", http.StatusUnauthorized)
+		return
+	}
+
+	ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+	next.ServeHTTP(ww, r.WithContext(auth.WithAPIKey(r.Context(), u, key)))
+
+	status := ww.Status()
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if err := app.Auth.RecordAPIKeyUse(r.Context(), key, r.Method, r.URL.Path, status, remoteIP(r)); err != nil {
+		log.Printf("Failed to record use of API key %s: %v", key.Prefix, err)
+	}
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// remoteIP returns the client address without its port.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// requireUser sends anonymous requests to the login page. HTMX requests are
+// redirected with HX-Redirect so the whole page changes, not a fragment.
+func requireUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth.UserFrom(r.Context()) != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		switch {
+		case r.Header.Get("HX-Request") == "true":
+			w.Header().Set("HX-Redirect", "/login")
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.Method == http.MethodGet:
+			http.Redirect(w, r, "/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+		default:
+			http.Error(w, "Sign in required", http.StatusUnauthorized)
+		}
+	})
+}
+
+// requireScope refuses requests made with an API key that lacks scope.
+// Browser sessions may do anything their user may.
+func requireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if key := auth.APIKeyFrom(r.Context()); key != nil && !key.HasScope(scope) {
+				http.Error(w, "API key lacks the "+scope+" scope", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requireSession refuses requests made with an API key, for pages such as
+// key management that only a signed-in browser may use.
+func requireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth.APIKeyFrom(r.Context()) != nil {
+			http.Error(w, "Not available to API keys", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireAdmin refuses signed-in users who are not admins.
+func requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if u := auth.UserFrom(r.Context()); u == nil || !u.IsAdmin() {
+			http.Error(w, "Admins only", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// verifyCSRF rejects state-changing requests of a session unless they carry
+// the session's CSRF token in the X-CSRF-Token header or, for plain form
+// posts, the csrf_token field. Multipart bodies are not parsed here so the
+// upload size limit still applies first. Requests made with an API key carry
+// no cookies a browser could be tricked into sending and are let through.
+func verifyCSRF(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+		if auth.APIKeyFrom(r.Context()) != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token := r.Header.Get(csrfHeader)
+		if token == "" && isURLEncodedForm(r) {
+			token = r.PostFormValue(csrfField)
+		}
+		session := auth.SessionFrom(r.Context())
+		if session == nil || token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(session.CSRFToken)) != 1 {
+			http.Error(w, "Invalid CSRF token", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// verifyLoginCSRF refuses sign-in and registration forms whose csrf_token
+// does not match the loginCSRFCookie set with the form, so another site
+// cannot sign a browser in to an account of its choosing.
+func verifyLoginCSRF(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(loginCSRFCookie)
+		token := r.PostFormValue(csrfField)
+		if err != nil || token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(cookie.Value)) != 1 {
+			http.Error(w, "Invalid CSRF token", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// loginCSRFToken returns the token of the loginCSRFCookie, setting a new one
+// when the browser has none yet.
+func loginCSRFToken(w http.ResponseWriter, r *http.Request) (string, error) {
+	if cookie, err := r.Cookie(loginCSRFCookie); err == nil && cookie.Value != "" {
+		return cookie.Value, nil
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	http.SetCookie(w, &http.Cookie{
+		Name:     loginCSRFCookie,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	return token, nil
+}
+
+func isURLEncodedForm(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType == "application/x-www-form-urlencoded"
+}
+
+// videoScope returns the videos the signed-in user may see: all of them for
+// admins, their own uploads otherwise. A request opened through a share link
+// sees only the shared video.
+func videoScope(r *http.Request) database.VideoScope {
+	if link := sharing.LinkFrom(r.Context()); link != nil {
+		return database.SharedVideo(link.VideoID)
+	}
+	u := auth.UserFrom(r.Context())
+	switch {
+	case u == nil:
+		return database.VideoScope{}
+	case u.IsAdmin():
+		return database.AllVideos()
+	default:
+		return database.OwnedBy(u.ID)
+	}
+}
+
+// canSeeVideo reports whether the signed-in user may see the video with id,
+// for records such as faces and subtitle tracks that belong to a video.
+func (app *App) canSeeVideo(r *http.Request, id string) bool {
+	video, err := app.VideoRepo.GetVideoByID(id)
+	return err == nil && videoScope(r).Includes(video)
+}
+
+type accountPage struct {
+	Error             string
+	Email             string
+	Next              string
+	CSRFToken         string
+	MinPasswordLength int
+}
+
+func (app *App) LoginPageHandler(w http.ResponseWriter, r *http.Request) {
+	if auth.UserFrom(r.Context()) != nil {
+		http.Redirect(w, r, "/videos", http.StatusSeeOther)
+		return
+	}
+	app.renderAccountPage(w, r, "login.html", http.StatusOK, accountPage{Next: safeNext(r.URL.Query().Get("next"))})
+}
+
+func (app *App) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	email := r.FormValue("email")
+	next := safeNext(r.FormValue("next"))
+
+	session, err := app.Auth.Login(r.Context(), email, r.FormValue("password"))
+	if err != nil {
+		page := accountPage{Error: "Invalid email or password", Email: email, Next: next}
+		status := http.StatusUnauthorized
+		if !errors.Is(err, auth.ErrInvalidCredentials) {
+			log.Printf("Failed to sign in %q: %v", email, err)
+			page.Error = "Signing in failed. Try again later."
+			status = http.StatusInternalServerError
+		}
+		app.renderAccountPage(w, r, "login.html", status, page)
+		return
+	}
+
+	setSessionCookies(w, r, session)
+	http.Redirect(w, r, next, http.StatusSeeOther)
+}
+
+func (app *App) RegisterPageHandler(w http.ResponseWriter, r *http.Request) {
+	app.renderAccountPage(w, r, "register.html", http.StatusOK, accountPage{})
+}
+
+// RegisterHandler creates an account and signs it in.
+func (app *App) RegisterHandler(w http.ResponseWriter, r *http.Request) {
+	email := r.FormValue("email")
+
+	u, err := app.Auth.Register(r.Context(), email, r.FormValue("password"))
+	var session *auth.Session
+	if err == nil {
+		session, err = app.Auth.StartSession(r.Context(), u)
+	}
+	if err != nil {
+		page := accountPage{Error: err.Error(), Email: email}
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, auth.ErrEmailTaken):
+			status = http.StatusConflict
+		case errors.Is(err, auth.ErrInvalidEmail), errors.Is(err, auth.ErrWeakPassword):
+		default:
+			log.Printf("Failed to register %q: %v", email, err)
+			page.Error = "Creating the account failed. Try again later."
+			status = http.StatusInternalServerError
+		}
+		app.renderAccountPage(w, r, "register.html", status, page)
+		return
+	}
+
+	setSessionCookies(w, r, session)
+	http.Redirect(w, r, "/videos", http.StatusSeeOther)
+}
+
+func (app *App) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		if err := app.Auth.Logout(r.Context(), cookie.Value); err != nil {
+			log.Printf("Failed to end session: %v", err)
+		}
+	}
+	for _, name := range []string{sessionCookie, csrfCookie} {
+		http.SetCookie(w, &http.Cookie{Name: name, Value: "", Path: "/", MaxAge: -1})
+	}
+
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("HX-Redirect", "/login")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+func (app *App) renderAccountPage(w http.ResponseWriter, r *http.Request, name string, status int, page accountPage) {
+	tmplPath := filepath.Join("web", "templates", name)
+	tmpl, err := template.ParseFiles(tmplPath)
+	if err != nil {
+		http.Error(w, "Error loading template", http.StatusInternalServerError)
+		return
+	}
+
+	page.CSRFToken, err = loginCSRFToken(w, r)
+	if err != nil {
+		log.Printf("Failed to generate CSRF token: %v", err)
+		http.Error(w, "Error loading page", http.StatusInternalServerError)
+		return
+	}
+
+	page.MinPasswordLength = auth.MinPasswordLength
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := tmpl.Execute(w, page); err != nil {
+		log.Printf("Failed to render %s: %v", name, err)
+	}
+}
+
+// setSessionCookies stores the session token, out of reach of scripts, and
+// the CSRF token that csrf.js sends back.
+func setSessionCookies(w http.ResponseWriter, r *http.Request, session *auth.Session) {
+	maxAge := int(time.Until(session.ExpiresAt).Seconds())
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    session.Token,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookie,
+		Value:    session.CSRFToken,
+		Path:     "/",
+		MaxAge:   maxAge,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// safeNext returns next when it is a path on this site, so the login form
+// cannot be used to redirect elsewhere.
+func safeNext(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/videos"
+	}
+	return next
+}
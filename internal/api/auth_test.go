@@ -0,0 +1,239 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/kdimtricp/vshazam/internal/auth"
+	"github.com/kdimtricp/vshazam/internal/models"
+	"github.com/kdimtricp/vshazam/internal/models/user"
+)
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+func signedIn(req *http.Request, u *user.UserDB) *http.Request {
+	session := &user.SessionDB{UserID: u.ID, CSRFToken: "csrf-token"}
+	return req.WithContext(auth.WithUser(req.Context(), u, session))
+}
+
+func TestRequireUser(t *testing.T) {
+	rr := httptest.NewRecorder()
+	requireUser(okHandler).ServeHTTP(rr, httptest.NewRequest("GET", "/videos?q=x", nil))
+	if rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "/login?next=%2Fvideos%3Fq%3Dx" {
+		t.Errorf("expected a redirect to the login page, got %d %q", rr.Code, rr.Header().Get("Location"))
+	}
+
+	req := httptest.NewRequest("POST", "/upload", nil)
+	req.Header.Set("HX-Request", "true")
+	rr = httptest.NewRecorder()
+	requireUser(okHandler).ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized || rr.Header().Get("HX-Redirect") != "/login" {
+		t.Errorf("expected an HTMX redirect, got %d %v", rr.Code, rr.Header())
+	}
+
+	rr = httptest.NewRecorder()
+	requireUser(okHandler).ServeHTTP(rr, signedIn(httptest.NewRequest("GET", "/videos", nil), &user.UserDB{ID: "u1"}))
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected signed-in requests to pass, got %d", rr.Code)
+	}
+}
+
+func TestRequireAdmin(t *testing.T) {
+	for role, want := range map[string]int{user.RoleUser: http.StatusForbidden, user.RoleAdmin: http.StatusOK} {
+		rr := httptest.NewRecorder()
+		req := signedIn(httptest.NewRequest("GET", "/usage", nil), &user.UserDB{ID: "u1", Role: role})
+		requireAdmin(okHandler).ServeHTTP(rr, req)
+		if rr.Code != want {
+			t.Errorf("role %s: got %d, want %d", role, rr.Code, want)
+		}
+	}
+}
+
+func TestVerifyCSRF(t *testing.T) {
+	u := &user.UserDB{ID: "u1"}
+	form := func(values url.Values) *http.Request {
+		req := httptest.NewRequest("POST", "/identifications/1/feedback", strings.NewReader(values.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req
+	}
+	header := signedIn(httptest.NewRequest("POST", "/upload", nil), u)
+	header.Header.Set(csrfHeader, "csrf-token")
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		want int
+	}{
+		{"safe method", signedIn(httptest.NewRequest("GET", "/videos", nil), u), http.StatusOK},
+		{"missing token", signedIn(httptest.NewRequest("POST", "/logout", nil), u), http.StatusForbidden},
+		{"form field", signedIn(form(url.Values{"csrf_token": {"csrf-token"}}), u), http.StatusOK},
+		{"wrong form field", signedIn(form(url.Values{"csrf_token": {"guess"}}), u), http.StatusForbidden},
+		{"header", header, http.StatusOK},
+		{"no session", form(url.Values{"csrf_token": {"csrf-token"}}), http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		verifyCSRF(okHandler).ServeHTTP(rr, tt.req)
+		if rr.Code != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, rr.Code, tt.want)
+		}
+	}
+}
+
+func TestVerifyLoginCSRF(t *testing.T) {
+	form := func(token, cookie string) *http.Request {
+		values := url.Values{"email": {"a@example.com"}}
+		if token != "" {
+			values.Set("csrf_token", token)
+		}
+		req := httptest.NewRequest("POST", "/login", strings.NewReader(values.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if cookie != "" {
+			req.AddCookie(&http.Cookie{Name: loginCSRFCookie, Value: cookie})
+		}
+		return req
+	}
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		want int
+	}{
+		{"matching", form("login-token", "login-token"), http.StatusOK},
+		{"no cookie", form("login-token", ""), http.StatusForbidden},
+		{"no field", form("", "login-token"), http.StatusForbidden},
+		{"mismatch", form("guess", "login-token"), http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		verifyLoginCSRF(okHandler).ServeHTTP(rr, tt.req)
+		if rr.Code != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, rr.Code, tt.want)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	token, err := loginCSRFToken(rr, httptest.NewRequest("GET", "/login", nil))
+	cookies := rr.Result().Cookies()
+	if err != nil || token == "" || len(cookies) != 1 || cookies[0].Value != token || !cookies[0].HttpOnly {
+		t.Errorf("expected a new HttpOnly token cookie, got %q %+v %v", token, cookies, err)
+	}
+
+	req := httptest.NewRequest("GET", "/login", nil)
+	req.AddCookie(&http.Cookie{Name: loginCSRFCookie, Value: token})
+	rr = httptest.NewRecorder()
+	if again, _ := loginCSRFToken(rr, req); again != token || len(rr.Result().Cookies()) != 0 {
+		t.Errorf("expected the existing token to be kept, got %q", again)
+	}
+}
+
+func TestVideoScope(t *testing.T) {
+	owner := "u1"
+	mine := &models.Video{OwnerID: &owner}
+	legacy := &models.Video{}
+
+	req := httptest.NewRequest("GET", "/videos", nil)
+	if videoScope(req).Includes(mine) {
+		t.Error("expected anonymous requests to see no videos")
+	}
+
+	scope := videoScope(signedIn(req, &user.UserDB{ID: "u1", Role: user.RoleUser}))
+	if !scope.Includes(mine) || scope.Includes(legacy) {
+		t.Error("expected users to see only their own videos")
+	}
+	scope = videoScope(signedIn(req, &user.UserDB{ID: "u2", Role: user.RoleUser}))
+	if scope.Includes(mine) {
+		t.Error("expected other users' videos to be hidden")
+	}
+	scope = videoScope(signedIn(req, &user.UserDB{ID: "u3", Role: user.RoleAdmin}))
+	if !scope.Includes(mine) || !scope.Includes(legacy) {
+		t.Error("expected admins to see every video")
+	}
+}
+
+func TestSafeNext(t *testing.T) {
+	tests := map[string]string{
+		"/videos/1":           "/videos/1",
+		"":                    "/videos",
+		"https://example.com": "/videos",
+		"//example.com":       "/videos",
+		"/\\example.com":      "/videos",
+	}
+	for next, want := range tests {
+		if got := safeNext(next); got != want {
+			t.Errorf("safeNext(%q) = %q, want %q", next, got, want)
+		}
+	}
+}
+
+func withKey(req *http.Request, scopes ...string) *http.Request {
+	key := &user.APIKeyDB{ID: "k1", UserID: "u1", Scopes: scopes}
+	return req.WithContext(auth.WithAPIKey(req.Context(), &user.UserDB{ID: "u1"}, key))
+}
+
+func TestRequireScope(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *http.Request
+		want int
+	}{
+		{"session", signedIn(httptest.NewRequest("POST", "/upload", nil), &user.UserDB{ID: "u1"}), http.StatusOK},
+		{"granted", withKey(httptest.NewRequest("POST", "/upload", nil), user.ScopeUpload), http.StatusOK},
+		{"admin key", withKey(httptest.NewRequest("POST", "/upload", nil), user.ScopeAdmin), http.StatusOK},
+		{"missing scope", withKey(httptest.NewRequest("POST", "/upload", nil), user.ScopeRead), http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		requireScope(user.ScopeUpload)(okHandler).ServeHTTP(rr, tt.req)
+		if rr.Code != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, rr.Code, tt.want)
+		}
+	}
+}
+
+func TestAPIKeyRequests(t *testing.T) {
+	rr := httptest.NewRecorder()
+	verifyCSRF(okHandler).ServeHTTP(rr, withKey(httptest.NewRequest("POST", "/upload", nil), user.ScopeUpload))
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected API key requests to skip the CSRF check, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	requireSession(okHandler).ServeHTTP(rr, withKey(httptest.NewRequest("GET", "/settings/keys", nil), user.ScopeAdmin))
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("expected key management to refuse API keys, got %d", rr.Code)
+	}
+
+	app := &App{Auth: auth.NewService(nil)}
+	req := httptest.NewRequest("GET", "/videos", nil)
+	req.Header.Set("Authorization", "Bearer vsk_unknown")
+	rr = httptest.NewRecorder()
+	app.authenticate(okHandler).ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized || !strings.HasPrefix(rr.Header().Get("WWW-Authenticate"), "Bearer") {
+		t.Errorf("expected an invalid key to be refused, got %d %v", rr.Code, rr.Header())
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	tests := map[string]string{
+		"Bearer vsk_abc":  "vsk_abc",
+		"bearer  vsk_abc": "vsk_abc",
+		"Basic dXNlcg==":  "",
+		"Bearer":          "",
+		"":                "",
+	}
+	for header, want := range tests {
+		req := httptest.NewRequest("GET", "/videos", nil)
+		req.Header.Set("Authorization", header)
+		if got, _ := bearerToken(req); got != want {
+			t.Errorf("bearerToken(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kdimtricp/vshazam/internal/faces"
+)
+
+// FaceThumbnailHandler serves the cropped thumbnail of a detected face.
+func (app *App) FaceThumbnailHandler(w http.ResponseWriter, r *http.Request) {
+	if app.FaceRepo == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	record, err := app.FaceRepo.GetFaceByID(r.Context(), chi.URLParam(r, "id"))
+	if err != nil || record == nil || record.ThumbnailPath == "" || !app.canSeeVideo(r, record.VideoID) {
+		http.NotFound(w, r)
+		return
+	}
+
+	file, err := app.Storage.OpenFile(record.ThumbnailPath)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	io.Copy(w, file)
+}
+
+// videoAppearances returns the people detected in a video, or nil when faces
+// are not stored.
+func (app *App) videoAppearances(ctx context.Context, videoID string) []faces.Appearance {
+	if app.FaceRepo == nil {
+		return nil
+	}
+
+	records, err := app.FaceRepo.ListByVideoID(ctx, videoID)
+	if err != nil || len(records) == 0 {
+		return nil
+	}
+
+	var clusterIDs []string
+	for _, record := range records {
+		if record.ClusterID != nil {
+			clusterIDs = append(clusterIDs, *record.ClusterID)
+		}
+	}
+	otherVideos, err := app.FaceRepo.CountOtherVideos(ctx, videoID, clusterIDs)
+	if err != nil {
+		otherVideos = nil
+	}
+
+	return faces.Summarize(records, otherVideos)
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kdimtricp/vshazam/internal/identify"
+	"github.com/kdimtricp/vshazam/internal/mdb"
+	"github.com/kdimtricp/vshazam/internal/models/identification"
+)
+
+func (app *App) FeedbackHandler(w http.ResponseWriter, r *http.Request) {
+	if app.IdentificationRepo == nil {
+		app.renderError(w, "Feedback is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	record, err := app.IdentificationRepo.GetByID(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		app.renderError(w, "Error loading identification", http.StatusInternalServerError)
+		return
+	}
+	if record == nil || !app.canSeeVideo(r, record.VideoID) {
+		app.renderError(w, "Identification not found", http.StatusNotFound)
+		return
+	}
+
+	feedback := &identification.FeedbackDB{
+		IdentificationID: record.ID,
+		VideoID:          record.VideoID,
+		Verdict:          r.FormValue("verdict"),
+	}
+
+	switch feedback.Verdict {
+	case identification.VerdictCorrect:
+		if record.TopTitle == "" {
+			app.renderError(w, "There is no identified film to confirm", http.StatusBadRequest)
+			return
+		}
+		feedback.Title = record.TopTitle
+		feedback.Year = record.TopYear
+		feedback.TMDbID = record.TopTMDbID
+	case identification.VerdictWrong:
+	case identification.VerdictCorrected:
+		feedback.Title = strings.TrimSpace(r.FormValue("title"))
+		if feedback.Title == "" {
+			app.renderError(w, "Pick the correct film", http.StatusBadRequest)
+			return
+		}
+		feedback.TMDbID, _ = strconv.Atoi(r.FormValue("tmdb_id"))
+		feedback.Year, _ = strconv.Atoi(r.FormValue("year"))
+	default:
+		app.renderError(w, "Invalid verdict", http.StatusBadRequest)
+		return
+	}
+
+	// Confirmed films feed the reference library so similar clips are
+	// recognised next time.
+	var reference *identification.ReferenceFingerprintDB
+	if feedback.Verdict != identification.VerdictWrong && app.FrameRepo != nil {
+		frames, err := app.FrameRepo.GetByVideoID(r.Context(), record.VideoID)
+		if err != nil {
+			log.Printf("Failed to load frames for reference fingerprint of video %s: %v", record.VideoID, err)
+		} else if len(frames) > 0 {
+			reference = &identification.ReferenceFingerprintDB{
+				VideoID: record.VideoID,
+				TMDbID:  feedback.TMDbID,
+				Title:   feedback.Title,
+				Year:    feedback.Year,
+				Tokens:  identify.FingerprintRecords(frames),
+			}
+		}
+	}
+
+	if err := app.IdentificationRepo.AddFeedback(r.Context(), feedback, reference); err != nil {
+		app.renderError(w, "Failed to save feedback", http.StatusInternalServerError)
+		return
+	}
+
+	switch feedback.Verdict {
+	case identification.VerdictCorrect:
+		app.renderSuccess(w, "Thanks! Marked as correct.")
+	case identification.VerdictWrong:
+		app.renderSuccess(w, "Thanks! Marked as wrong. Use the search below to tell us the right film.")
+	default:
+		app.renderSuccess(w, "Thanks! Recorded as "+feedback.Title+".")
+	}
+}
+
+func (app *App) TMDbSearchHandler(w http.ResponseWriter, r *http.Request) {
+	if app.TMDbClient == nil {
+		w.Write([]byte("<p>TMDb search is not configured</p>"))
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	var movies []mdb.Movie
+	if len(query) >= 2 {
+		var err error
+		movies, err = app.TMDbClient.SearchMovies(r.Context(), query)
+		if err != nil {
+			w.Write([]byte("<p>Error searching TMDb</p>"))
+			return
+		}
+		if len(movies) > 8 {
+			movies = movies[:8]
+		}
+	}
+
+	tmplPath := filepath.Join("web", "templates", "_tmdb_options.html")
+	tmpl, err := template.New("_tmdb_options.html").Funcs(template.FuncMap{
+		"year": func(date string) string {
+			if len(date) < 4 {
+				return ""
+			}
+			return date[:4]
+		},
+	}).ParseFiles(tmplPath)
+	if err != nil {
+		w.Write([]byte("<p>Error loading search results</p>"))
+		return
+	}
+
+	data := struct {
+		IdentificationID string
+		Query            string
+		Movies           []mdb.Movie
+	}{
+		IdentificationID: r.URL.Query().Get("identification_id"),
+		Query:            query,
+		Movies:           movies,
+	}
+
+	if err := tmpl.Execute(w, data); err != nil {
+		w.Write([]byte("<p>Error rendering search results</p>"))
+		return
+	}
+}
+
+func (app *App) AccuracyReportHandler(w http.ResponseWriter, r *http.Request) {
+	if app.IdentificationRepo == nil {
+		http.Error(w, "Feedback is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	samples, err := app.IdentificationRepo.ListFeedbackSamples(r.Context())
+	if err != nil {
+		http.Error(w, "Error loading feedback", http.StatusInternalServerError)
+		return
+	}
+
+	tmplPath := filepath.Join("web", "templates", "accuracy_report.html")
+	tmpl, err := template.New("accuracy_report.html").Funcs(template.FuncMap{
+		"score": identify.FormatScore,
+	}).ParseFiles(tmplPath)
+	if err != nil {
+		http.Error(w, "Error loading template", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tmpl.Execute(w, identify.BuildAccuracyReport(samples)); err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kdimtricp/vshazam/internal/catalog"
+	"github.com/kdimtricp/vshazam/internal/database"
+	"github.com/kdimtricp/vshazam/internal/mdb"
+	"github.com/kdimtricp/vshazam/internal/models"
+	"github.com/kdimtricp/vshazam/internal/models/film"
+)
+
+// topBilled is how many cast members a film page shows.
+const topBilled = 10
+
+var filmFuncs = template.FuncMap{
+	"image":   mdb.ImageURL,
+	"runtime": formatRuntime,
+}
+
+// FilmsHandler lists the cataloged films, filtered by ?genre= and ?year=.
+func (app *App) FilmsHandler(w http.ResponseWriter, r *http.Request) {
+	if app.FilmRepo == nil {
+		http.Error(w, "The film catalog is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	filter := database.FilmFilter{Genre: strings.TrimSpace(r.URL.Query().Get("genre"))}
+	if year, err := strconv.Atoi(r.URL.Query().Get("year")); err == nil && year > 0 {
+		filter.Year = year
+	}
+
+	films, err := app.FilmRepo.List(r.Context(), filter)
+	if err != nil {
+		http.Error(w, "Error loading films", http.StatusInternalServerError)
+		return
+	}
+	genres, err := app.FilmRepo.ListGenres(r.Context())
+	if err != nil {
+		log.Printf("Failed to load genres: %v", err)
+	}
+
+	tmplPath := filepath.Join("web", "templates", "films.html")
+	tmpl, err := template.New("films.html").Funcs(filmFuncs).ParseFiles(tmplPath)
+	if err != nil {
+		http.Error(w, "Error loading template", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Films  []*film.FilmDB
+		Genres []string
+		Filter database.FilmFilter
+	}{
+		Films:  films,
+		Genres: genres,
+		Filter: filter,
+	}
+
+	if err := tmpl.Execute(w, data); err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+		return
+	}
+}
+
+// FilmHandler shows a cataloged film by its TMDb ID with the clips identified
+// as it. Films are only cataloged by identification, so any other ID is not
+// found.
+func (app *App) FilmHandler(w http.ResponseWriter, r *http.Request) {
+	if app.FilmRepo == nil {
+		http.Error(w, "The film catalog is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	tmdbID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || tmdbID <= 0 {
+		http.Error(w, "Invalid film ID", http.StatusBadRequest)
+		return
+	}
+
+	f, err := app.FilmRepo.GetByTMDbID(r.Context(), tmdbID)
+	if err != nil {
+		log.Printf("Failed to load film %d: %v", tmdbID, err)
+		http.Error(w, "Error loading film", http.StatusInternalServerError)
+		return
+	}
+	if f == nil {
+		http.Error(w, "Film not found", http.StatusNotFound)
+		return
+	}
+
+	credits, err := app.FilmRepo.ListCredits(r.Context(), f.ID)
+	if err != nil {
+		log.Printf("Failed to load credits of film %d: %v", tmdbID, err)
+	}
+	videos, err := app.FilmRepo.ListVideosByTMDbID(r.Context(), tmdbID, videoScope(r))
+	if err != nil {
+		log.Printf("Failed to load videos of film %d: %v", tmdbID, err)
+	}
+
+	tmplPath := filepath.Join("web", "templates", "film.html")
+	tmpl, err := template.New("film.html").Funcs(filmFuncs).ParseFiles(tmplPath)
+	if err != nil {
+		http.Error(w, "Error loading template", http.StatusInternalServerError)
+		return
+	}
+
+	details := catalog.ToDetails(f, credits)
+	data := struct {
+		Film      *film.FilmDB
+		Details   *mdb.FilmDetails
+		Cast      []mdb.CastMember
+		Directors []string
+		Videos    []models.Video
+	}{
+		Film:      f,
+		Details:   details,
+		Cast:      details.Credits.TopCast(topBilled),
+		Directors: details.Credits.Directors(),
+		Videos:    videos,
+	}
+
+	if err := tmpl.Execute(w, data); err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+		return
+	}
+}
+
+// formatRuntime renders minutes as "2h 28m".
+func formatRuntime(minutes int) string {
+	switch {
+	case minutes <= 0:
+		return ""
+	case minutes < 60:
+		return fmt.Sprintf("%dm", minutes)
+	default:
+		return fmt.Sprintf("%dh %02dm", minutes/60, minutes%60)
+	}
+}
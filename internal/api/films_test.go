@@ -0,0 +1,17 @@
+package api
+
+import "testing"
+
+func TestFormatRuntime(t *testing.T) {
+	tests := map[int]string{
+		0:   "",
+		45:  "45m",
+		60:  "1h 00m",
+		148: "2h 28m",
+	}
+	for minutes, want := range tests {
+		if got := formatRuntime(minutes); got != want {
+			t.Errorf("formatRuntime(%d) = %q, want %q", minutes, got, want)
+		}
+	}
+}
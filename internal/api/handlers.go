@@ -1,8 +1,13 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
+	"io"
+	"log"
+	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -10,20 +15,52 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/kdimtricp/vshazam/internal/ai"
+	"github.com/kdimtricp/vshazam/internal/auth"
 	"github.com/kdimtricp/vshazam/internal/database"
+	"github.com/kdimtricp/vshazam/internal/faces"
+	"github.com/kdimtricp/vshazam/internal/identify"
+	"github.com/kdimtricp/vshazam/internal/mdb"
+	"github.com/kdimtricp/vshazam/internal/metering"
 	"github.com/kdimtricp/vshazam/internal/models"
+	"github.com/kdimtricp/vshazam/internal/models/film"
+	"github.com/kdimtricp/vshazam/internal/models/identification"
+	"github.com/kdimtricp/vshazam/internal/models/subtitle"
+	"github.com/kdimtricp/vshazam/internal/models/transcript"
+	"github.com/kdimtricp/vshazam/internal/outbound"
+	"github.com/kdimtricp/vshazam/internal/sharing"
 	"github.com/kdimtricp/vshazam/internal/storage"
+	"github.com/kdimtricp/vshazam/internal/subtitles"
+	"github.com/kdimtricp/vshazam/internal/trash"
 )
 
 type App struct {
-	Storage        storage.Storage
-	DB             *database.DB
-	VideoRepo      *database.VideoRepository
-	FrameRepo      *database.FrameAnalysisRepo
-	MaxUploadSize  int64
-	VisionService  ai.VisionService
-	FrameExtractor *ai.FrameExtractor
-	AIConfig       *ai.Config
+	Storage            storage.Storage
+	DB                 *database.DB
+	VideoRepo          *database.VideoRepository
+	FrameRepo          *database.FrameAnalysisRepo
+	IdentificationRepo *database.IdentificationRepo
+	UsageRepo          *database.UsageRepo
+	FaceRepo           *database.FaceRepo
+	TranscriptRepo     *database.TranscriptRepo
+	SubtitleRepo       *database.SubtitleRepo
+	FilmRepo           *database.FilmRepo
+	Subtitles          *subtitles.Service
+	MaxUploadSize      int64
+	VisionService      ai.VisionService
+	FrameExtractor     *ai.FrameExtractor
+	AIConfig           *ai.Config
+	Identifier         *identify.Identifier
+	TMDbClient         *mdb.TMDbClient
+	Meter              *metering.Tracker
+	Auth               *auth.Service
+	Sharing            *sharing.Service
+	Trash              *trash.Service
+	RateLimits         RateLimits
+	// StorageQuota caps the bytes each user may store and
+	// DailyIdentifyQuota the identifications each user may start a day.
+	// Zero means unlimited.
+	StorageQuota       int64
+	DailyIdentifyQuota int
 }
 
 func PingHandler(w http.ResponseWriter, r *http.Request) {
@@ -31,6 +68,22 @@ func PingHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("pong"))
 }
 
+// ProvidersHandler reports the circuit state of every external API client.
+func ProvidersHandler(w http.ResponseWriter, r *http.Request) {
+	statuses := outbound.Providers()
+
+	status := http.StatusOK
+	for _, provider := range statuses {
+		if !provider.Healthy {
+			status = http.StatusServiceUnavailable
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(statuses)
+}
+
 func HomeHandler(w http.ResponseWriter, r *http.Request) {
 	tmplPath := filepath.Join("web", "templates", "base.html")
 	tmpl, err := template.ParseFiles(tmplPath)
@@ -100,29 +153,71 @@ func (app *App) UploadHandler(w http.ResponseWriter, r *http.Request) {
 
 	description := r.FormValue("description")
 
+	sidecars, err := parseSidecars(r)
+	if err != nil {
+		app.renderError(w, "Invalid subtitles: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The size is measured from the received file rather than taken from the
+	// request, since it counts against the storage quota.
+	size, err := fileSize(file)
+	if err != nil {
+		app.renderError(w, "Failed to read file", http.StatusBadRequest)
+		return
+	}
+
+	if !app.checkStorageQuota(w, r, size) {
+		return
+	}
+
 	filename, err := app.Storage.SaveFile(file, storage.FileInfo{
 		Filename:    header.Filename,
 		ContentType: contentType,
-		Size:        header.Size,
+		Size:        size,
 	})
 	if err != nil {
 		app.renderError(w, "Failed to save file", http.StatusInternalServerError)
 		return
 	}
 
-	video := models.NewVideo(title, description, filename, contentType, header.Size)
-	if err := app.VideoRepo.InsertVideo(video); err != nil {
+	video := models.NewVideo(title, description, filename, contentType, size)
+	if u := auth.UserFrom(r.Context()); u != nil {
+		video.OwnerID = &u.ID
+	}
+	if !app.insertVideo(w, r, video) {
 		app.Storage.DeleteFile(filename)
-		app.renderError(w, "Failed to save video information", http.StatusInternalServerError)
 		return
 	}
 
+	if app.Subtitles != nil {
+		videoPath, err := app.Storage.LocalPath(filename)
+		if err != nil {
+			videoPath = ""
+		}
+		if _, err := app.Subtitles.Import(r.Context(), video.ID, videoPath, sidecars); err != nil {
+			log.Printf("Failed to import subtitles of video %s: %v", video.ID, err)
+		}
+	}
+
 	app.renderSuccess(w, "Video uploaded successfully!")
 	w.Header().Set("HX-Trigger", "videoUploaded")
 }
 
+// fileSize returns the length of file and rewinds it.
+func fileSize(file multipart.File) (int64, error) {
+	size, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
 func (app *App) VideoListPartialHandler(w http.ResponseWriter, r *http.Request) {
-	videos, err := app.VideoRepo.ListVideos()
+	videos, err := app.VideoRepo.ListVideos(videoScope(r))
 	if err != nil {
 		w.Write([]byte("<p>Error loading videos</p>"))
 		return
@@ -144,8 +239,19 @@ func (app *App) VideoListPartialHandler(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+// ListVideosHandler lists the user's videos, or with ?director= those
+// identified as films by that director. Admins see every user's videos.
 func (app *App) ListVideosHandler(w http.ResponseWriter, r *http.Request) {
-	videos, err := app.VideoRepo.ListVideos()
+	director := strings.TrimSpace(r.URL.Query().Get("director"))
+
+	var videos []models.Video
+	var err error
+	if director != "" && app.FilmRepo != nil {
+		videos, err = app.FilmRepo.ListVideosByCrew(r.Context(), director, film.JobDirector, videoScope(r))
+	} else {
+		director = ""
+		videos, err = app.VideoRepo.ListVideos(videoScope(r))
+	}
 	if err != nil {
 		http.Error(w, "Error loading videos", http.StatusInternalServerError)
 		return
@@ -162,10 +268,12 @@ func (app *App) ListVideosHandler(w http.ResponseWriter, r *http.Request) {
 		Videos   []models.Video
 		Query    string
 		IsSearch bool
+		Director string
 	}{
 		Videos:   videos,
 		Query:    "",
 		IsSearch: false,
+		Director: director,
 	}
 
 	if err := tmpl.Execute(w, data); err != nil {
@@ -186,20 +294,69 @@ func (app *App) WatchVideoHandler(w http.ResponseWriter, r *http.Request) {
 		app.renderError(w, "Error loading video", http.StatusInternalServerError)
 		return
 	}
+	if !videoScope(r).Includes(video) {
+		app.renderError(w, "Video not found", http.StatusNotFound)
+		return
+	}
+	link := sharing.LinkFrom(r.Context())
+	if link != nil && !app.countShareView(w, r, link) {
+		return
+	}
 
 	tmplPath := filepath.Join("web", "templates", "video.html")
-	tmpl, err := template.ParseFiles(tmplPath)
+	detailsPath := filepath.Join("web", "templates", "_video_details.html")
+	tmpl, err := template.New("video.html").Funcs(template.FuncMap{
+		"timecode": formatTimecode,
+	}).ParseFiles(tmplPath, detailsPath)
 	if err != nil {
 		http.Error(w, "Error loading template", http.StatusInternalServerError)
 		return
 	}
 
+	// Subtitle tracks and face thumbnails are served to signed-in users
+	// only, so shared pages leave them out.
+	var tracks []*subtitle.TrackDB
+	if app.SubtitleRepo != nil && link == nil {
+		tracks, err = app.SubtitleRepo.ListByVideoID(r.Context(), video.ID)
+		if err != nil {
+			log.Printf("Failed to load subtitle tracks of video %s: %v", video.ID, err)
+		}
+	}
+
+	var cues []*transcript.CueDB
+	if app.TranscriptRepo != nil {
+		cues, err = app.TranscriptRepo.ListByVideoID(r.Context(), video.ID)
+		if err != nil {
+			log.Printf("Failed to load transcript of video %s: %v", video.ID, err)
+		}
+	}
+
 	data := struct {
 		Video         *models.Video
 		FormattedSize string
+		People        []faces.Appearance
+		Transcript    []*transcript.CueDB
+		Subtitles     []*subtitle.TrackDB
+		Shared        bool
+		StreamURL     template.URL
+		ResultsURL    template.URL
+		Sharing       bool
 	}{
 		Video:         video,
 		FormattedSize: storage.FormatFileSize(video.Size),
+		Transcript:    cues,
+		Subtitles:     tracks,
+		Shared:        link != nil,
+		StreamURL:     template.URL("/stream/" + video.ID),
+		Sharing:       app.Sharing != nil,
+	}
+	if link != nil {
+		data.StreamURL = app.viewURL("/stream/"+video.ID, link)
+		if link.ShowsResults() {
+			data.ResultsURL = app.viewURL("/identify/"+video.ID, link)
+		}
+	} else {
+		data.People = app.videoAppearances(r.Context(), video.ID)
 	}
 
 	if err := tmpl.Execute(w, data); err != nil {
@@ -208,6 +365,18 @@ func (app *App) WatchVideoHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// formatTimecode renders seconds as M:SS, or H:MM:SS for long videos.
+func formatTimecode(seconds float64) string {
+	total := int(seconds)
+	if total >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", total/3600, total%3600/60, total%60)
+	}
+	return fmt.Sprintf("%d:%02d", total/60, total%60)
+}
+
+// StreamVideoHandler serves the video file. Players fetch it in many range
+// requests; allowSharedView verifies the share link and view token on each
+// of them, and none counts as a view.
 func (app *App) StreamVideoHandler(w http.ResponseWriter, r *http.Request) {
 	videoID := chi.URLParam(r, "id")
 	if videoID == "" {
@@ -216,7 +385,7 @@ func (app *App) StreamVideoHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	video, err := app.VideoRepo.GetVideoByID(videoID)
-	if err != nil {
+	if err != nil || !videoScope(r).Includes(video) {
 		http.NotFound(w, r)
 		return
 	}
@@ -243,10 +412,166 @@ func (app *App) StreamVideoHandler(w http.ResponseWriter, r *http.Request) {
 	http.ServeContent(w, r, video.Filename, stat.ModTime(), file)
 }
 
+// IdentificationHandler shows the latest stored identification of a video.
+// It never starts a run; IdentifyHandler does, on a POST.
+func (app *App) IdentificationHandler(w http.ResponseWriter, r *http.Request) {
+	video, err := app.VideoRepo.GetVideoByID(chi.URLParam(r, "id"))
+	if err != nil || !videoScope(r).Includes(video) {
+		app.renderError(w, "Video not found", http.StatusNotFound)
+		return
+	}
+
+	if link := sharing.LinkFrom(r.Context()); link != nil {
+		app.sharedIdentificationHandler(w, r, video, link)
+		return
+	}
+	app.renderStoredIdentification(w, r, video, template.URL("/videos/"+video.ID), false)
+}
+
+// renderStoredIdentification renders the latest identification stored for
+// video. videoURL links back to the video page.
+func (app *App) renderStoredIdentification(w http.ResponseWriter, r *http.Request, video *models.Video, videoURL template.URL, shared bool) {
+	var record *identification.IdentificationDB
+	var candidates []*identify.Candidate
+	if app.IdentificationRepo != nil {
+		var err error
+		record, err = app.IdentificationRepo.GetLatestByVideoID(r.Context(), video.ID)
+		if err != nil {
+			log.Printf("Failed to load identification of video %s: %v", video.ID, err)
+			app.renderError(w, "Error loading identification", http.StatusInternalServerError)
+			return
+		}
+	}
+	if record != nil && len(record.Candidates) > 0 {
+		if err := json.Unmarshal(record.Candidates, &candidates); err != nil {
+			log.Printf("Failed to decode candidates of identification %s: %v", record.ID, err)
+		}
+	}
+
+	tmplPath := filepath.Join("web", "templates", "identification.html")
+	tmpl, err := template.New("identification.html").Funcs(template.FuncMap{
+		"score": identify.FormatScore,
+	}).ParseFiles(tmplPath)
+	if err != nil {
+		http.Error(w, "Error loading template", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Video          *models.Video
+		VideoURL       template.URL
+		Identification *identification.IdentificationDB
+		Candidates     []*identify.Candidate
+		Shared         bool
+	}{
+		Video:          video,
+		VideoURL:       videoURL,
+		Identification: record,
+		Candidates:     candidates,
+		Shared:         shared,
+	}
+
+	if err := tmpl.Execute(w, data); err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+		return
+	}
+}
+
+// IdentifyHandler runs a new, paid identification of a video and shows its
+// result. HTMX requests get their errors in #identify-messages.
+func (app *App) IdentifyHandler(w http.ResponseWriter, r *http.Request) {
+	videoID := chi.URLParam(r, "id")
+	if videoID == "" {
+		app.renderError(w, "Video ID is required", http.StatusBadRequest)
+		return
+	}
+
+	video, err := app.VideoRepo.GetVideoByID(videoID)
+	if err != nil || !videoScope(r).Includes(video) {
+		app.renderError(w, "Video not found", http.StatusNotFound)
+		return
+	}
+
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("HX-Retarget", "#identify-messages")
+	}
+
+	if app.Identifier == nil {
+		app.renderError(w, "Film identification is not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	videoPath, err := app.Storage.LocalPath(video.Filename)
+	if err != nil {
+		app.renderError(w, "Video file not found", http.StatusNotFound)
+		return
+	}
+
+	if !app.reserveIdentifyQuota(w, r, video.ID) {
+		return
+	}
+
+	ctx := ai.WithVideoInfo(r.Context(), ai.VideoInfo{Title: video.Title, Description: video.Description})
+	result, err := app.Identifier.Identify(ctx, video.ID, videoPath)
+	if err != nil {
+		if errors.Is(err, metering.ErrBudgetExceeded) {
+			app.renderError(w, "The AI budget has been used up. Try again later.", http.StatusTooManyRequests)
+			return
+		}
+		app.renderError(w, "Failed to identify film", http.StatusInternalServerError)
+		return
+	}
+
+	var identificationID string
+	if app.IdentificationRepo != nil {
+		record, err := result.Record()
+		if err == nil {
+			if u := auth.UserFrom(r.Context()); u != nil {
+				record.RequestedBy = &u.ID
+			}
+			err = app.IdentificationRepo.Create(r.Context(), record)
+		}
+		if err != nil {
+			log.Printf("Failed to save identification for video %s: %v", video.ID, err)
+		} else {
+			identificationID = record.ID
+		}
+	}
+
+	// The result replaces the whole page, not the error target.
+	w.Header().Del("HX-Retarget")
+
+	tmplPath := filepath.Join("web", "templates", "identify.html")
+	tmpl, err := template.New("identify.html").Funcs(template.FuncMap{
+		"score": identify.FormatScore,
+	}).ParseFiles(tmplPath)
+	if err != nil {
+		http.Error(w, "Error loading template", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Video            *models.Video
+		Result           *identify.Result
+		IdentificationID string
+		TMDbEnabled      bool
+	}{
+		Video:            video,
+		Result:           result,
+		IdentificationID: identificationID,
+		TMDbEnabled:      app.TMDbClient != nil,
+	}
+
+	if err := tmpl.Execute(w, data); err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+		return
+	}
+}
+
 func (app *App) SearchHandler(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query().Get("q")
 
-	videos, err := app.VideoRepo.SearchVideos(query)
+	videos, err := app.VideoRepo.SearchVideos(query, videoScope(r))
 	if err != nil {
 		http.Error(w, "Error searching videos", http.StatusInternalServerError)
 		return
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kdimtricp/vshazam/internal/auth"
+	"github.com/kdimtricp/vshazam/internal/models"
+	"github.com/kdimtricp/vshazam/internal/models/identification"
+	"github.com/kdimtricp/vshazam/internal/ratelimit"
+	"github.com/kdimtricp/vshazam/internal/storage"
+)
+
+// RateLimits holds the limiter of each route group. Default applies to
+// every request, the others on top of it. A nil limiter allows everything.
+type RateLimits struct {
+	Default  *ratelimit.Limiter
+	Upload   *ratelimit.Limiter
+	Identify *ratelimit.Limiter
+}
+
+// rateLimit refuses clients that exhausted their bucket in l with 429.
+func (app *App) rateLimit(l *ratelimit.Limiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ok, wait := l.Allow(clientKey(r)); !ok {
+				app.renderTooManyRequests(w, "Too many requests. Slow down and try again shortly.", wait)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientKey identifies who a request is counted against: its API key, its
+// signed-in user, or else its IP address.
+func clientKey(r *http.Request) string {
+	if key := auth.APIKeyFrom(r.Context()); key != nil {
+		return "key:" + key.ID
+	}
+	if u := auth.UserFrom(r.Context()); u != nil {
+		return "user:" + u.ID
+	}
+	return "ip:" + remoteIP(r)
+}
+
+// renderTooManyRequests answers 429 with Retry-After in whole seconds and an
+// alert fragment that web/static/errors.js swaps into the HTMX target.
+func (app *App) renderTooManyRequests(w http.ResponseWriter, message string, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	app.renderError(w, message, http.StatusTooManyRequests)
+}
+
+// checkStorageQuota renders an error and returns false when storing size more
+// bytes would take the signed-in user over StorageQuota. Admins have no
+// quota. It only refuses uploads early; insertVideo enforces the quota.
+func (app *App) checkStorageQuota(w http.ResponseWriter, r *http.Request, size int64) bool {
+	u := auth.UserFrom(r.Context())
+	if app.StorageQuota <= 0 || u == nil || u.IsAdmin() {
+		return true
+	}
+
+	used, err := app.VideoRepo.TotalSizeByOwner(u.ID)
+	if err != nil {
+		log.Printf("Failed to check storage quota of %s: %v", u.ID, err)
+		app.renderError(w, "Failed to check storage quota", http.StatusInternalServerError)
+		return false
+	}
+	if used+size > app.StorageQuota {
+		app.renderStorageQuotaExceeded(w, used)
+		return false
+	}
+	return true
+}
+
+// insertVideo saves video, within the StorageQuota of the signed-in user
+// unless they are an admin. It renders the error and returns false when the
+// video is not saved.
+func (app *App) insertVideo(w http.ResponseWriter, r *http.Request, video *models.Video) bool {
+	u := auth.UserFrom(r.Context())
+	if app.StorageQuota <= 0 || u == nil || u.IsAdmin() {
+		if err := app.VideoRepo.InsertVideo(video); err != nil {
+			app.renderError(w, "Failed to save video information", http.StatusInternalServerError)
+			return false
+		}
+		return true
+	}
+
+	used, inserted, err := app.VideoRepo.InsertVideoWithinQuota(video, app.StorageQuota)
+	if err != nil {
+		log.Printf("Failed to save video of %s: %v", u.ID, err)
+		app.renderError(w, "Failed to save video information", http.StatusInternalServerError)
+		return false
+	}
+	if !inserted {
+		app.renderStorageQuotaExceeded(w, used)
+		return false
+	}
+	return true
+}
+
+func (app *App) renderStorageQuotaExceeded(w http.ResponseWriter, used int64) {
+	app.renderError(w, fmt.Sprintf("Storage quota exceeded: %s of %s used. Delete videos and empty the trash to make room.",
+		storage.FormatFileSize(used), storage.FormatFileSize(app.StorageQuota)), http.StatusRequestEntityTooLarge)
+}
+
+// reserveIdentifyQuota takes one of the signed-in user's DailyIdentifyQuota
+// identifications for today (UTC) before videoID is identified, or renders a
+// 429 and returns false when none are left. The slot is kept whether or not
+// the run succeeds, as failed runs spend API calls too. Admins have no quota.
+func (app *App) reserveIdentifyQuota(w http.ResponseWriter, r *http.Request, videoID string) bool {
+	u := auth.UserFrom(r.Context())
+	if app.DailyIdentifyQuota <= 0 || app.IdentificationRepo == nil || u == nil || u.IsAdmin() {
+		return true
+	}
+
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	request := &identification.RequestDB{UserID: u.ID, VideoID: videoID, CreatedAt: now}
+	reserved, err := app.IdentificationRepo.ReserveRequest(r.Context(), request, today, app.DailyIdentifyQuota)
+	if err != nil {
+		log.Printf("Failed to check identification quota of %s: %v", u.ID, err)
+		app.renderError(w, "Failed to check identification quota", http.StatusInternalServerError)
+		return false
+	}
+	if !reserved {
+		app.renderTooManyRequests(w, fmt.Sprintf("You have used all %d identifications for today. The quota resets at midnight UTC.",
+			app.DailyIdentifyQuota), today.AddDate(0, 0, 1).Sub(now))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kdimtricp/vshazam/internal/auth"
+	"github.com/kdimtricp/vshazam/internal/models/user"
+	"github.com/kdimtricp/vshazam/internal/ratelimit"
+)
+
+func TestRateLimit(t *testing.T) {
+	app := &App{}
+	handler := app.rateLimit(ratelimit.NewLimiter(ratelimit.Policy{RequestsPerSecond: 1.0 / 60, Burst: 1}))(okHandler)
+
+	request := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/upload", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("HX-Request", "true")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := request("10.0.0.1:1234"); rr.Code != http.StatusOK {
+		t.Fatalf("expected the first request to pass, got %d", rr.Code)
+	}
+	rr := request("10.0.0.1:5678")
+	if rr.Code != http.StatusTooManyRequests || rr.Header().Get("Retry-After") != "60" {
+		t.Errorf("expected 429 with Retry-After 60, got %d %q", rr.Code, rr.Header().Get("Retry-After"))
+	}
+	if body := rr.Body.String(); body == "" || body[:4] != "<div" {
+		t.Errorf("expected an alert fragment, got %q", body)
+	}
+	if rr := request("10.0.0.2:1234"); rr.Code != http.StatusOK {
+		t.Errorf("expected other clients to pass, got %d", rr.Code)
+	}
+}
+
+func TestClientKey(t *testing.T) {
+	req := httptest.NewRequest("GET", "/videos", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	if got := clientKey(req); got != "ip:10.0.0.1" {
+		t.Errorf("anonymous: got %q", got)
+	}
+	if got := clientKey(signedIn(req, &user.UserDB{ID: "u1"})); got != "user:u1" {
+		t.Errorf("session: got %q", got)
+	}
+	keyed := req.WithContext(auth.WithAPIKey(req.Context(), &user.UserDB{ID: "u1"}, &user.APIKeyDB{ID: "k1"}))
+	if got := clientKey(keyed); got != "key:k1" {
+		t.Errorf("API key: got %q", got)
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/kdimtricp/vshazam/internal/models/user"
 )
 
 func NewRouter(app *App) http.Handler {
@@ -12,19 +13,82 @@ func NewRouter(app *App) http.Handler {
 
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
+	r.Use(app.authenticate)
+	r.Use(app.rateLimit(app.RateLimits.Default))
 
 	r.Get("/", HomeHandler)
 	r.Get("/ping", PingHandler)
+	r.Get("/health/providers", ProvidersHandler)
 
-	r.Get("/upload", app.UploadPageHandler)
-	r.Post("/upload", app.UploadHandler)
-	r.Get("/videos/partial", app.VideoListPartialHandler)
+	r.Get("/login", app.LoginPageHandler)
+	r.With(verifyLoginCSRF).Post("/login", app.LoginHandler)
+	r.Get("/register", app.RegisterPageHandler)
+	r.With(verifyLoginCSRF).Post("/register", app.RegisterHandler)
 
-	r.Get("/videos", app.ListVideosHandler)
-	r.Get("/videos/{id}", app.WatchVideoHandler)
-	r.Get("/stream/{id}", app.StreamVideoHandler)
+	// Videos and their identification also open through signed share links
+	// without an account. The stream and results open only with the view
+	// token of a counted page view.
+	r.With(app.allowShared, requireScope(user.ScopeRead)).Get("/videos/{id}", app.WatchVideoHandler)
+	r.With(app.allowSharedView, requireScope(user.ScopeRead)).Get("/stream/{id}", app.StreamVideoHandler)
+	r.With(app.allowSharedView, requireScope(user.ScopeRead)).Get("/identify/{id}", app.IdentificationHandler)
 
-	r.Get("/search", app.SearchHandler)
+	// Everything else needs an account, signed in through the browser or
+	// with an API key. Videos are scoped to their owner inside the handlers;
+	// API keys are further limited to the scopes they were granted.
+	r.Group(func(r chi.Router) {
+		r.Use(requireUser)
+		r.Use(verifyCSRF)
+
+		r.Post("/logout", app.LogoutHandler)
+
+		r.Group(func(r chi.Router) {
+			r.Use(requireSession)
+			r.Get("/settings/keys", app.APIKeysPageHandler)
+			r.Post("/settings/keys", app.CreateAPIKeyHandler)
+			r.Post("/settings/keys/{id}/revoke", app.RevokeAPIKeyHandler)
+			r.Get("/videos/{id}/shares", app.SharesHandler)
+			r.Post("/videos/{id}/shares", app.CreateShareHandler)
+			r.Post("/shares/{id}/revoke", app.RevokeShareHandler)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(requireScope(user.ScopeUpload))
+			r.Get("/upload", app.UploadPageHandler)
+			r.With(app.rateLimit(app.RateLimits.Upload)).Post("/upload", app.UploadHandler)
+			r.Get("/videos/{id}/edit", app.EditVideoHandler)
+			r.Get("/videos/{id}/details", app.VideoDetailsHandler)
+			r.Put("/videos/{id}", app.UpdateVideoHandler)
+			r.Delete("/videos/{id}", app.DeleteVideoHandler)
+			r.Get("/trash", app.TrashHandler)
+			r.Post("/trash/{id}/restore", app.RestoreVideoHandler)
+			r.Delete("/trash/{id}", app.PurgeVideoHandler)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(requireScope(user.ScopeRead))
+			r.Get("/videos/partial", app.VideoListPartialHandler)
+			r.Get("/videos", app.ListVideosHandler)
+			r.Get("/faces/{id}/thumbnail", app.FaceThumbnailHandler)
+			r.Get("/subtitles/{id}", app.SubtitleTrackHandler)
+			r.Get("/films", app.FilmsHandler)
+			r.Get("/films/{id}", app.FilmHandler)
+			r.Get("/tmdb/search", app.TMDbSearchHandler)
+			r.Get("/search", app.SearchHandler)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(requireScope(user.ScopeIdentify))
+			r.With(app.rateLimit(app.RateLimits.Identify)).Post("/identify/{id}", app.IdentifyHandler)
+			r.Post("/identifications/{id}/feedback", app.FeedbackHandler)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(requireAdmin)
+			r.Use(requireScope(user.ScopeAdmin))
+			r.Get("/feedback/report", app.AccuracyReportHandler)
+			r.Get("/usage", app.UsageReportHandler)
+		})
+	})
 
 	fileServer := http.FileServer(http.Dir("./web/static"))
 	r.Handle("/static/*", http.StripPrefix("/static", fileServer))
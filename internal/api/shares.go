@@ -0,0 +1,215 @@
+package api
+
+import (
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kdimtricp/vshazam/internal/auth"
+	"github.com/kdimtricp/vshazam/internal/models"
+	"github.com/kdimtricp/vshazam/internal/models/share"
+	"github.com/kdimtricp/vshazam/internal/sharing"
+)
+
+type shareExpiry struct {
+	Label string
+	Hours int
+}
+
+// shareExpiries are the lifetimes offered for new share links.
+var shareExpiries = []shareExpiry{
+	{"1 hour", 1},
+	{"1 day", 24},
+	{"7 days", 7 * 24},
+	{"30 days", 30 * 24},
+}
+
+// allowShared lets requests that carry a valid share link for the {id} video
+// through without an account. Everything else must be signed in.
+func (app *App) allowShared(next http.Handler) http.Handler {
+	return app.shared(next, func(r *http.Request) (*share.LinkDB, error) {
+		link, err := app.Sharing.Verify(r.Context(), r.URL.Query(), chi.URLParam(r, "id"))
+		if err == nil && !link.ViewsLeft() {
+			return nil, sharing.ErrViewsUsedUp
+		}
+		return link, err
+	})
+}
+
+// allowSharedView is allowShared for the stream and results behind a shared
+// video page: they also need the view token the page was rendered with.
+func (app *App) allowSharedView(next http.Handler) http.Handler {
+	return app.shared(next, func(r *http.Request) (*share.LinkDB, error) {
+		return app.Sharing.VerifyView(r.Context(), r.URL.Query(), chi.URLParam(r, "id"))
+	})
+}
+
+func (app *App) shared(next http.Handler, verify func(r *http.Request) (*share.LinkDB, error)) http.Handler {
+	signedIn := requireUser(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if app.Sharing == nil || !sharing.HasLink(r.URL.Query()) {
+			signedIn.ServeHTTP(w, r)
+			return
+		}
+		link, err := verify(r)
+		if err != nil {
+			app.renderShareError(w, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(sharing.WithLink(r.Context(), link)))
+	})
+}
+
+// countShareView counts the video page opened through a share link. It renders the
+// error and returns false when the link has no views left.
+func (app *App) countShareView(w http.ResponseWriter, r *http.Request, link *share.LinkDB) bool {
+	if err := app.Sharing.CountView(r.Context(), link); err != nil {
+		app.renderShareError(w, err)
+		return false
+	}
+	return true
+}
+
+func (app *App) renderShareError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, sharing.ErrInvalidLink):
+		app.renderError(w, "This link is not valid", http.StatusNotFound)
+	case errors.Is(err, sharing.ErrLinkExpired), errors.Is(err, sharing.ErrLinkRevoked),
+		errors.Is(err, sharing.ErrViewsUsedUp), errors.Is(err, sharing.ErrViewExpired):
+		app.renderError(w, "This link is no longer available", http.StatusGone)
+	default:
+		log.Printf("Failed to open share link: %v", err)
+		app.renderError(w, "Error opening link", http.StatusInternalServerError)
+	}
+}
+
+// shareURL returns the path and signed query of a shared page, e.g.
+// "/stream/{id}?share=...".
+func (app *App) shareURL(path string, link *share.LinkDB) template.URL {
+	return template.URL(path + "?" + app.Sharing.Query(link).Encode())
+}
+
+// viewURL returns the path and signed query, with a view token, of the
+// stream or results behind a counted view of a shared page.
+func (app *App) viewURL(path string, link *share.LinkDB) template.URL {
+	return template.URL(path + "?" + app.Sharing.ViewQuery(link).Encode())
+}
+
+type shareView struct {
+	*share.LinkDB
+	URL    string
+	Active bool
+}
+
+type sharesPage struct {
+	VideoID  string
+	Links    []shareView
+	Expiries []shareExpiry
+	Error    string
+}
+
+// SharesHandler lists the share links of a video for its owner.
+func (app *App) SharesHandler(w http.ResponseWriter, r *http.Request) {
+	videoID := chi.URLParam(r, "id")
+	if !app.canSeeVideo(r, videoID) {
+		app.renderError(w, "Video not found", http.StatusNotFound)
+		return
+	}
+	app.renderShares(w, r, videoID, "")
+}
+
+func (app *App) CreateShareHandler(w http.ResponseWriter, r *http.Request) {
+	videoID := chi.URLParam(r, "id")
+	if !app.canSeeVideo(r, videoID) {
+		app.renderError(w, "Video not found", http.StatusNotFound)
+		return
+	}
+
+	hours, _ := strconv.Atoi(r.FormValue("expires_hours"))
+	maxViews, _ := strconv.Atoi(r.FormValue("max_views"))
+	access := share.AccessVideo
+	if r.FormValue("results") == "on" {
+		access = share.AccessResults
+	}
+
+	message := ""
+	_, err := app.Sharing.Create(r.Context(), videoID, auth.UserFrom(r.Context()).ID, sharing.Options{
+		Access:   access,
+		TTL:      time.Duration(hours) * time.Hour,
+		MaxViews: maxViews,
+	})
+	switch {
+	case errors.Is(err, sharing.ErrInvalidShare):
+		message = "Pick an expiry of at most 90 days and a view limit of 0 or more"
+	case err != nil:
+		log.Printf("Failed to create share link for video %s: %v", videoID, err)
+		message = "Creating the link failed. Try again later."
+	}
+	app.renderShares(w, r, videoID, message)
+}
+
+// RevokeShareHandler stops a share link from opening. Anyone who may see
+// the video may revoke its links.
+func (app *App) RevokeShareHandler(w http.ResponseWriter, r *http.Request) {
+	link, err := app.Sharing.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil || link == nil || !app.canSeeVideo(r, link.VideoID) {
+		app.renderError(w, "Link not found", http.StatusNotFound)
+		return
+	}
+
+	message := ""
+	if _, err := app.Sharing.Revoke(r.Context(), link.ID); err != nil {
+		log.Printf("Failed to revoke share link %s: %v", link.ID, err)
+		message = "Revoking the link failed. Try again later."
+	}
+	app.renderShares(w, r, link.VideoID, message)
+}
+
+func (app *App) renderShares(w http.ResponseWriter, r *http.Request, videoID, message string) {
+	links, err := app.Sharing.List(r.Context(), videoID)
+	if err != nil {
+		app.renderError(w, "Error loading share links", http.StatusInternalServerError)
+		return
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	page := sharesPage{VideoID: videoID, Expiries: shareExpiries, Error: message}
+	for _, link := range links {
+		page.Links = append(page.Links, shareView{
+			LinkDB: link,
+			URL:    scheme + "://" + r.Host + string(app.shareURL("/videos/"+videoID, link)),
+			Active: app.Sharing.Active(link),
+		})
+	}
+
+	tmplPath := filepath.Join("web", "templates", "_share_links.html")
+	tmpl, err := template.ParseFiles(tmplPath)
+	if err != nil {
+		http.Error(w, "Error loading template", http.StatusInternalServerError)
+		return
+	}
+	if err := tmpl.Execute(w, page); err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+		return
+	}
+}
+
+// sharedIdentificationHandler shows the latest stored identification of a
+// video opened through a share link. Shared visitors never start a new,
+// paid identification run. Views are counted on the video page the link
+// opens, not here.
+func (app *App) sharedIdentificationHandler(w http.ResponseWriter, r *http.Request, video *models.Video, link *share.LinkDB) {
+	if !link.ShowsResults() {
+		app.renderError(w, "This link does not include identification results", http.StatusForbidden)
+		return
+	}
+	app.renderStoredIdentification(w, r, video, app.shareURL("/videos/"+video.ID, link), true)
+}
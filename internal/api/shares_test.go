@@ -0,0 +1,136 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kdimtricp/vshazam/internal/models/share"
+	"github.com/kdimtricp/vshazam/internal/sharing"
+)
+
+type memoryShareStore struct {
+	link *share.LinkDB
+}
+
+func (m *memoryShareStore) CreateLink(ctx context.Context, link *share.LinkDB) error {
+	link.ID = "link-1"
+	m.link = link
+	return nil
+}
+
+func (m *memoryShareStore) GetLink(ctx context.Context, id string) (*share.LinkDB, error) {
+	if m.link == nil || m.link.ID != id {
+		return nil, nil
+	}
+	return m.link, nil
+}
+
+func (m *memoryShareStore) ListLinks(ctx context.Context, videoID string) ([]*share.LinkDB, error) {
+	return []*share.LinkDB{m.link}, nil
+}
+
+func (m *memoryShareStore) RevokeLink(ctx context.Context, id string, at time.Time) (bool, error) {
+	m.link.RevokedAt = &at
+	return true, nil
+}
+
+func (m *memoryShareStore) CountView(ctx context.Context, id string) (bool, error) {
+	if !m.link.ViewsLeft() {
+		return false, nil
+	}
+	m.link.Views++
+	return true, nil
+}
+
+func TestAllowShared(t *testing.T) {
+	app := &App{Sharing: sharing.NewService(&memoryShareStore{}, "secret")}
+	link, err := app.Sharing.Create(context.Background(), "video-1", "user-1", sharing.Options{Access: share.AccessVideo})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r := chi.NewRouter()
+	r.With(app.allowSharedView).Get("/stream/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if sharing.LinkFrom(r.Context()) == nil {
+			t.Error("expected the share link in the request context")
+		}
+		w.WriteHeader(http.StatusPartialContent)
+	})
+
+	tests := []struct {
+		name        string
+		url         string
+		rangeHeader string
+		want        int
+	}{
+		{"signed range request", string(app.viewURL("/stream/video-1", link)), "bytes=1024-", http.StatusPartialContent},
+		{"without view token", string(app.shareURL("/stream/video-1", link)), "", http.StatusNotFound},
+		{"other video", string(app.viewURL("/stream/video-2", link)), "", http.StatusNotFound},
+		{"no signature", "/stream/video-1?share=link-1", "", http.StatusNotFound},
+		{"anonymous", "/stream/video-1", "", http.StatusSeeOther},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.url, nil)
+		if tt.rangeHeader != "" {
+			req.Header.Set("Range", tt.rangeHeader)
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		if rr.Code != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, rr.Code, tt.want)
+		}
+	}
+
+	app.Sharing.Revoke(context.Background(), link.ID)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", string(app.viewURL("/stream/video-1", link)), nil))
+	if rr.Code != http.StatusGone {
+		t.Errorf("expected a revoked link to be gone, got %d", rr.Code)
+	}
+}
+
+func TestAllowShared_ViewLimit(t *testing.T) {
+	app := &App{Sharing: sharing.NewService(&memoryShareStore{}, "secret")}
+	ctx := context.Background()
+	link, err := app.Sharing.Create(ctx, "video-1", "user-1", sharing.Options{Access: share.AccessResults, MaxViews: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	r := chi.NewRouter()
+	r.With(app.allowShared).Get("/videos/{id}", ok)
+	r.With(app.allowSharedView).Get("/stream/{id}", ok)
+	r.With(app.allowSharedView).Get("/identify/{id}", ok)
+
+	// The one view is counted when the page is opened; its view token keeps
+	// the stream working.
+	viewed := string(app.viewURL("/stream/video-1", link))
+	if err := app.Sharing.CountView(ctx, link); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		url  string
+		want int
+	}{
+		{"page without views left", string(app.shareURL("/videos/video-1", link)), http.StatusGone},
+		{"stream without view token", string(app.shareURL("/stream/video-1", link)), http.StatusNotFound},
+		{"results without view token", string(app.shareURL("/identify/video-1", link)), http.StatusNotFound},
+		{"stream of the counted view", viewed, http.StatusOK},
+	}
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest("GET", tt.url, nil))
+		if rr.Code != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, rr.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kdimtricp/vshazam/internal/subtitles"
+)
+
+// maxSubtitleSize bounds a single uploaded subtitle file.
+const maxSubtitleSize = 5 << 20
+
+// SubtitleTrackHandler serves a subtitle track as WebVTT for <track>.
+func (app *App) SubtitleTrackHandler(w http.ResponseWriter, r *http.Request) {
+	if app.SubtitleRepo == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	track, err := app.SubtitleRepo.GetTrackByID(r.Context(), chi.URLParam(r, "id"))
+	if err != nil || track == nil || !app.canSeeVideo(r, track.VideoID) {
+		http.NotFound(w, r)
+		return
+	}
+
+	file, err := app.Storage.OpenFile(track.FilePath)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", "text/vtt; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	io.Copy(w, file)
+}
+
+// parseSidecars reads the optional subtitle files sent with an upload.
+func parseSidecars(r *http.Request) ([]*subtitles.Sidecar, error) {
+	if r.MultipartForm == nil {
+		return nil, nil
+	}
+
+	var sidecars []*subtitles.Sidecar
+	for _, header := range r.MultipartForm.File["subtitles"] {
+		if header.Size > maxSubtitleSize {
+			return nil, fmt.Errorf("%s is larger than 5 MB", header.Filename)
+		}
+		file, err := header.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s", header.Filename)
+		}
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s", header.Filename)
+		}
+
+		sidecar, err := subtitles.ParseSidecar(header.Filename, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a valid SRT or WebVTT file", header.Filename)
+		}
+		sidecars = append(sidecars, sidecar)
+	}
+	return sidecars, nil
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/kdimtricp/vshazam/internal/metering"
+)
+
+func (app *App) UsageReportHandler(w http.ResponseWriter, r *http.Request) {
+	if app.UsageRepo == nil {
+		http.Error(w, "Usage accounting is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	now := time.Now()
+	records, err := app.UsageRepo.ListSince(r.Context(), metering.ReportWindow(now))
+	if err != nil {
+		http.Error(w, "Error loading usage", http.StatusInternalServerError)
+		return
+	}
+
+	budget := metering.DefaultBudget()
+	if app.Meter != nil {
+		budget = app.Meter.Budget()
+	}
+
+	tmplPath := filepath.Join("web", "templates", "usage.html")
+	tmpl, err := template.New("usage.html").Funcs(template.FuncMap{
+		"usd": func(amount float64) string {
+			return fmt.Sprintf("$%.4f", amount)
+		},
+		"percent": func(fraction float64) string {
+			return fmt.Sprintf("%.0f%%", fraction*100)
+		},
+	}).ParseFiles(tmplPath)
+	if err != nil {
+		http.Error(w, "Error loading template", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tmpl.Execute(w, metering.BuildReport(records, budget, now)); err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+		return
+	}
+}
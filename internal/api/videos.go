@@ -0,0 +1,189 @@
+package api
+
+import (
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kdimtricp/vshazam/internal/database"
+	"github.com/kdimtricp/vshazam/internal/models"
+)
+
+// maxTitleLength bounds edited titles; uploads are not limited further.
+const maxTitleLength = 200
+
+type videoDetails struct {
+	Video  *models.Video
+	Shared bool
+	Error  string
+}
+
+// ownVideo returns the {id} video when the signed-in user may see it, or
+// renders a 404 and returns nil.
+func (app *App) ownVideo(w http.ResponseWriter, r *http.Request) *models.Video {
+	video, err := app.VideoRepo.GetVideoByID(chi.URLParam(r, "id"))
+	if err != nil || !videoScope(r).Includes(video) {
+		app.renderError(w, "Video not found", http.StatusNotFound)
+		return nil
+	}
+	return video
+}
+
+// EditVideoHandler swaps the title and description on the watch page for an
+// edit form.
+func (app *App) EditVideoHandler(w http.ResponseWriter, r *http.Request) {
+	if video := app.ownVideo(w, r); video != nil {
+		app.renderVideoDetails(w, "video-edit", videoDetails{Video: video})
+	}
+}
+
+// VideoDetailsHandler renders the title and description, closing the edit
+// form without saving.
+func (app *App) VideoDetailsHandler(w http.ResponseWriter, r *http.Request) {
+	if video := app.ownVideo(w, r); video != nil {
+		app.renderVideoDetails(w, "video-details", videoDetails{Video: video})
+	}
+}
+
+// UpdateVideoHandler saves an edited title and description.
+func (app *App) UpdateVideoHandler(w http.ResponseWriter, r *http.Request) {
+	video := app.ownVideo(w, r)
+	if video == nil {
+		return
+	}
+
+	video.Title = strings.TrimSpace(r.FormValue("title"))
+	video.Description = strings.TrimSpace(r.FormValue("description"))
+	switch {
+	case video.Title == "":
+		app.renderVideoDetails(w, "video-edit", videoDetails{Video: video, Error: "Title is required"})
+		return
+	case len(video.Title) > maxTitleLength:
+		app.renderVideoDetails(w, "video-edit", videoDetails{Video: video, Error: "Title is too long"})
+		return
+	}
+
+	if err := app.VideoRepo.UpdateVideoDetails(video.ID, video.Title, video.Description); err != nil {
+		log.Printf("Failed to update video %s: %v", video.ID, err)
+		app.renderVideoDetails(w, "video-edit", videoDetails{Video: video, Error: "Saving failed. Try again later."})
+		return
+	}
+	app.renderVideoDetails(w, "video-details", videoDetails{Video: video})
+}
+
+func (app *App) renderVideoDetails(w http.ResponseWriter, name string, data videoDetails) {
+	tmplPath := filepath.Join("web", "templates", "_video_details.html")
+	tmpl, err := template.ParseFiles(tmplPath)
+	if err != nil {
+		app.renderError(w, "Error loading template", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.ExecuteTemplate(w, name, data); err != nil {
+		log.Printf("Failed to render video details: %v", err)
+	}
+}
+
+// DeleteVideoHandler moves a video to the trash, from where it can be
+// restored until it is purged. HTMX requests are sent on to the video list.
+func (app *App) DeleteVideoHandler(w http.ResponseWriter, r *http.Request) {
+	video := app.ownVideo(w, r)
+	if video == nil {
+		return
+	}
+	if err := app.Trash.Trash(video.ID); err != nil {
+		log.Printf("Failed to trash video %s: %v", video.ID, err)
+		app.renderError(w, "Error deleting video", http.StatusInternalServerError)
+		return
+	}
+
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("HX-Redirect", "/videos")
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type trashItem struct {
+	models.Video
+	PurgeAt time.Time
+}
+
+// TrashHandler lists the signed-in user's trashed videos and when each will
+// be purged.
+func (app *App) TrashHandler(w http.ResponseWriter, r *http.Request) {
+	videos, err := app.VideoRepo.ListTrash(videoScope(r))
+	if err != nil {
+		http.Error(w, "Error loading trash", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Videos        []trashItem
+		RetentionDays int
+	}{
+		RetentionDays: int(app.Trash.Retention() / (24 * time.Hour)),
+	}
+	for _, video := range videos {
+		data.Videos = append(data.Videos, trashItem{Video: video, PurgeAt: app.Trash.PurgeAt(video.DeletedAt.Time)})
+	}
+
+	tmplPath := filepath.Join("web", "templates", "trash.html")
+	tmpl, err := template.ParseFiles(tmplPath)
+	if err != nil {
+		http.Error(w, "Error loading template", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tmpl.Execute(w, data); err != nil {
+		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+		return
+	}
+}
+
+// trashedVideo returns the {id} video when it is in the trash and the
+// signed-in user may see it, or renders a 404 and returns nil.
+func (app *App) trashedVideo(w http.ResponseWriter, r *http.Request) *models.Video {
+	video, err := app.VideoRepo.GetTrashedVideoByID(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Printf("Failed to load trashed video: %v", err)
+	}
+	if video == nil || !videoScope(r).Includes(video) {
+		app.renderError(w, "Video not found in the trash", http.StatusNotFound)
+		return nil
+	}
+	return video
+}
+
+// RestoreVideoHandler takes a video out of the trash.
+func (app *App) RestoreVideoHandler(w http.ResponseWriter, r *http.Request) {
+	video := app.trashedVideo(w, r)
+	if video == nil {
+		return
+	}
+	if _, err := app.Trash.Restore(video.ID); err != nil {
+		log.Printf("Failed to restore video %s: %v", video.ID, err)
+		app.renderError(w, "Error restoring video", http.StatusInternalServerError)
+		return
+	}
+	app.renderSuccess(w, video.Title+" was restored")
+}
+
+// PurgeVideoHandler deletes a trashed video for good without waiting for
+// the retention period to end.
+func (app *App) PurgeVideoHandler(w http.ResponseWriter, r *http.Request) {
+	video := app.trashedVideo(w, r)
+	if video == nil {
+		return
+	}
+	if err := app.Trash.Purge(r.Context(), video.ID); err != nil && !errors.Is(err, database.ErrVideoNotFound) {
+		log.Printf("Failed to purge video %s: %v", video.ID, err)
+		app.renderError(w, "Error deleting video", http.StatusInternalServerError)
+		return
+	}
+	app.renderSuccess(w, video.Title+" was deleted permanently")
+}
@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/kdimtricp/vshazam/internal/models/user"
+)
+
+// apiKeyPrefix starts every API key so leaked keys are easy to spot.
+const apiKeyPrefix = "vsk_"
+
+// displayPrefixLength is how much of a key is kept in clear.
+const displayPrefixLength = 12
+
+var (
+	ErrNoScopes      = errors.New("pick at least one scope")
+	ErrUnknownScope  = errors.New("unknown scope")
+	ErrAdminScope    = errors.New("only admins can create admin keys")
+	ErrKeysDisabled  = errors.New("API keys are not configured")
+	ErrKeyNameNeeded = errors.New("name the key")
+)
+
+// KeyStore is implemented by database.APIKeyRepo.
+type KeyStore interface {
+	CreateAPIKey(ctx context.Context, key *user.APIKeyDB) error
+	GetAPIKeyByHash(ctx context.Context, hash string) (*user.APIKeyDB, error)
+	ListAPIKeys(ctx context.Context, userID string) ([]*user.APIKeyDB, error)
+	RevokeAPIKey(ctx context.Context, userID, id string, at time.Time) (bool, error)
+	RecordAPIKeyUse(ctx context.Context, use *user.APIKeyUseDB) error
+	ListAPIKeyUses(ctx context.Context, userID string, limit int) ([]*user.APIKeyUseDB, error)
+}
+
+// UseAPIKeys enables API keys stored in keys.
+func (s *Service) UseAPIKeys(keys KeyStore) {
+	s.keys = keys
+}
+
+// CreateAPIKey issues a key for u with the given scopes. A zero ttl never
+// expires. The returned token is shown to the user once; only its hash is
+// stored.
+func (s *Service) CreateAPIKey(ctx context.Context, u *user.UserDB, name string, scopes []string, ttl time.Duration) (string, *user.APIKeyDB, error) {
+	if s.keys == nil {
+		return "", nil, ErrKeysDisabled
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, ErrKeyNameNeeded
+	}
+	if len(scopes) == 0 {
+		return "", nil, ErrNoScopes
+	}
+	for _, scope := range scopes {
+		if !knownScope(scope) {
+			return "", nil, ErrUnknownScope
+		}
+		if scope == user.ScopeAdmin && !u.IsAdmin() {
+			return "", nil, ErrAdminScope
+		}
+	}
+
+	random, err := randomToken()
+	if err != nil {
+		return "", nil, err
+	}
+	token := apiKeyPrefix + random
+
+	key := &user.APIKeyDB{
+		UserID:    u.ID,
+		Name:      name,
+		Prefix:    token[:displayPrefixLength],
+		KeyHash:   hashToken(token),
+		Scopes:    scopes,
+		CreatedAt: s.now(),
+	}
+	if ttl > 0 {
+		expires := key.CreatedAt.Add(ttl)
+		key.ExpiresAt = &expires
+	}
+	if err := s.keys.CreateAPIKey(ctx, key); err != nil {
+		return "", nil, err
+	}
+	return token, key, nil
+}
+
+// AuthenticateAPIKey returns the user and key for a bearer token, or nil when
+// the key is unknown, revoked or expired.
+func (s *Service) AuthenticateAPIKey(ctx context.Context, token string) (*user.UserDB, *user.APIKeyDB, error) {
+	if s.keys == nil || !strings.HasPrefix(token, apiKeyPrefix) {
+		return nil, nil, nil
+	}
+	key, err := s.keys.GetAPIKeyByHash(ctx, hashToken(token))
+	if err != nil || key == nil || !key.Active(s.now()) {
+		return nil, nil, err
+	}
+	u, err := s.store.GetUserByID(ctx, key.UserID)
+	if err != nil || u == nil {
+		return nil, nil, err
+	}
+	return u, key, nil
+}
+
+// ListAPIKeys returns u's keys, newest first.
+func (s *Service) ListAPIKeys(ctx context.Context, u *user.UserDB) ([]*user.APIKeyDB, error) {
+	if s.keys == nil {
+		return nil, ErrKeysDisabled
+	}
+	return s.keys.ListAPIKeys(ctx, u.ID)
+}
+
+// RevokeAPIKey revokes u's key with id. It reports false when u has no such
+// active key.
+func (s *Service) RevokeAPIKey(ctx context.Context, u *user.UserDB, id string) (bool, error) {
+	if s.keys == nil {
+		return false, ErrKeysDisabled
+	}
+	return s.keys.RevokeAPIKey(ctx, u.ID, id, s.now())
+}
+
+// RecordAPIKeyUse adds a request made with key to the audit log.
+func (s *Service) RecordAPIKeyUse(ctx context.Context, key *user.APIKeyDB, method, path string, status int, remoteIP string) error {
+	if s.keys == nil {
+		return ErrKeysDisabled
+	}
+	return s.keys.RecordAPIKeyUse(ctx, &user.APIKeyUseDB{
+		KeyID:     key.ID,
+		UserID:    key.UserID,
+		Method:    method,
+		Path:      path,
+		Status:    status,
+		RemoteIP:  remoteIP,
+		CreatedAt: s.now(),
+	})
+}
+
+// ListAPIKeyUses returns the latest requests made with any of u's keys.
+func (s *Service) ListAPIKeyUses(ctx context.Context, u *user.UserDB, limit int) ([]*user.APIKeyUseDB, error) {
+	if s.keys == nil {
+		return nil, ErrKeysDisabled
+	}
+	return s.keys.ListAPIKeyUses(ctx, u.ID, limit)
+}
+
+func knownScope(scope string) bool {
+	for _, s := range user.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
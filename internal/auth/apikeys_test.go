@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kdimtricp/vshazam/internal/models/user"
+)
+
+type memoryKeyStore struct {
+	keys []*user.APIKeyDB
+	uses []*user.APIKeyUseDB
+}
+
+func (m *memoryKeyStore) CreateAPIKey(ctx context.Context, key *user.APIKeyDB) error {
+	key.ID = key.Prefix
+	m.keys = append(m.keys, key)
+	return nil
+}
+
+func (m *memoryKeyStore) GetAPIKeyByHash(ctx context.Context, hash string) (*user.APIKeyDB, error) {
+	for _, key := range m.keys {
+		if key.KeyHash == hash {
+			return key, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memoryKeyStore) ListAPIKeys(ctx context.Context, userID string) ([]*user.APIKeyDB, error) {
+	var keys []*user.APIKeyDB
+	for _, key := range m.keys {
+		if key.UserID == userID {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (m *memoryKeyStore) RevokeAPIKey(ctx context.Context, userID, id string, at time.Time) (bool, error) {
+	for _, key := range m.keys {
+		if key.ID == id && key.UserID == userID && key.RevokedAt == nil {
+			key.RevokedAt = &at
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memoryKeyStore) RecordAPIKeyUse(ctx context.Context, use *user.APIKeyUseDB) error {
+	m.uses = append(m.uses, use)
+	return nil
+}
+
+func (m *memoryKeyStore) ListAPIKeyUses(ctx context.Context, userID string, limit int) ([]*user.APIKeyUseDB, error) {
+	return m.uses, nil
+}
+
+func TestService_CreateAPIKey(t *testing.T) {
+	store := newMemoryStore()
+	service := NewService(store)
+	service.UseAPIKeys(&memoryKeyStore{})
+	ctx := context.Background()
+
+	admin, _ := service.Register(ctx, "ada@example.com", "correct horse")
+	member, _ := service.Register(ctx, "bob@example.com", "battery staple")
+
+	token, key, err := service.CreateAPIKey(ctx, member, " ingest ", []string{user.ScopeUpload}, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(token, apiKeyPrefix) || !strings.HasPrefix(token, key.Prefix) {
+		t.Errorf("unexpected token %q for prefix %q", token, key.Prefix)
+	}
+	if key.Name != "ingest" || key.KeyHash == token || key.ExpiresAt != nil {
+		t.Errorf("unexpected key %+v", key)
+	}
+
+	tests := []struct {
+		name   string
+		u      *user.UserDB
+		key    string
+		scopes []string
+		want   error
+	}{
+		{"no name", member, " ", []string{user.ScopeRead}, ErrKeyNameNeeded},
+		{"no scopes", member, "ci", nil, ErrNoScopes},
+		{"unknown scope", member, "ci", []string{"delete"}, ErrUnknownScope},
+		{"admin scope", member, "ci", []string{user.ScopeAdmin}, ErrAdminScope},
+		{"admin scope for admins", admin, "ci", []string{user.ScopeAdmin}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := service.CreateAPIKey(ctx, tt.u, tt.key, tt.scopes, time.Hour)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if _, _, err := NewService(store).CreateAPIKey(ctx, member, "ci", []string{user.ScopeRead}, 0); !errors.Is(err, ErrKeysDisabled) {
+		t.Errorf("expected keys to be disabled without a KeyStore, got %v", err)
+	}
+}
+
+func TestService_AuthenticateAPIKey(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	keys := &memoryKeyStore{}
+	service := NewService(newMemoryStore())
+	service.UseAPIKeys(keys)
+	service.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	u, _ := service.Register(ctx, "ada@example.com", "correct horse")
+	token, key, err := service.CreateAPIKey(ctx, u, "ci", []string{user.ScopeRead}, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, gotKey, err := service.AuthenticateAPIKey(ctx, token)
+	if err != nil || got == nil || got.ID != u.ID || gotKey.ID != key.ID {
+		t.Fatalf("expected the key to authenticate, got %v %v %v", got, gotKey, err)
+	}
+	for _, bad := range []string{"", "vsk_unknown", token[len(apiKeyPrefix):]} {
+		if got, _, _ := service.AuthenticateAPIKey(ctx, bad); got != nil {
+			t.Errorf("expected %q to be refused", bad)
+		}
+	}
+
+	if err := service.RecordAPIKeyUse(ctx, key, "GET", "/videos", 200, "10.0.0.1"); err != nil || len(keys.uses) != 1 {
+		t.Errorf("expected the use to be recorded, got %v", err)
+	}
+
+	now = now.Add(25 * time.Hour)
+	if got, _, _ := service.AuthenticateAPIKey(ctx, token); got != nil {
+		t.Error("expected an expired key to be refused")
+	}
+	now = now.Add(-25 * time.Hour)
+
+	other, _ := service.Register(ctx, "bob@example.com", "battery staple")
+	if revoked, _ := service.RevokeAPIKey(ctx, other, key.ID); revoked {
+		t.Error("expected other users not to revoke the key")
+	}
+	if revoked, _ := service.RevokeAPIKey(ctx, u, key.ID); !revoked {
+		t.Error("expected the owner to revoke the key")
+	}
+	if got, _, _ := service.AuthenticateAPIKey(ctx, token); got != nil {
+		t.Error("expected a revoked key to be refused")
+	}
+}
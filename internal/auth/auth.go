@@ -0,0 +1,207 @@
+// Package auth manages password accounts and the browser sessions they sign
+// in with. Passwords are hashed with bcrypt; session tokens are random and
+// only their SHA-256 is stored.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/kdimtricp/vshazam/internal/models/user"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// DefaultSessionTTL is how long a sign-in lasts.
+const DefaultSessionTTL = 14 * 24 * time.Hour
+
+// Passwords must be MinPasswordLength to MaxPasswordLength bytes long. bcrypt
+// ignores everything past 72 bytes, so longer passwords are refused rather
+// than silently truncated.
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 72
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrEmailTaken         = errors.New("an account with this email already exists")
+	ErrInvalidEmail       = errors.New("invalid email address")
+	ErrWeakPassword       = fmt.Errorf("password must be %d to %d characters", MinPasswordLength, MaxPasswordLength)
+)
+
+// Store is implemented by database.UserRepo.
+type Store interface {
+	// RegisterUser creates u, as an admin when it is the first user.
+	RegisterUser(ctx context.Context, u *user.UserDB) error
+	GetUserByID(ctx context.Context, id string) (*user.UserDB, error)
+	GetUserByEmail(ctx context.Context, email string) (*user.UserDB, error)
+	CreateSession(ctx context.Context, s *user.SessionDB) error
+	GetSession(ctx context.Context, id string) (*user.SessionDB, error)
+	DeleteSession(ctx context.Context, id string) error
+	DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error)
+}
+
+type Service struct {
+	store      Store
+	keys       KeyStore
+	sessionTTL time.Duration
+	now        func() time.Time
+}
+
+func NewService(store Store) *Service {
+	return &Service{
+		store:      store,
+		sessionTTL: DefaultSessionTTL,
+		now:        time.Now,
+	}
+}
+
+// UseSessionTTL changes how long new sessions last.
+func (s *Service) UseSessionTTL(ttl time.Duration) {
+	s.sessionTTL = ttl
+}
+
+// Session is a new sign-in. Token goes into the session cookie and is not
+// stored anywhere else.
+type Session struct {
+	Token     string
+	CSRFToken string
+	ExpiresAt time.Time
+	User      *user.UserDB
+}
+
+// Register creates an account. The first account becomes an admin, so a
+// fresh installation can be managed without touching the database.
+func (s *Service) Register(ctx context.Context, email, password string) (*user.UserDB, error) {
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	if len(password) < MinPasswordLength || len(password) > MaxPasswordLength {
+		return nil, ErrWeakPassword
+	}
+
+	existing, err := s.store.GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrEmailTaken
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("hashing password: %w", err)
+	}
+
+	u := &user.UserDB{Email: email, PasswordHash: string(hash)}
+	if err := s.store.RegisterUser(ctx, u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// Login checks the password and starts a session. Unknown emails and wrong
+// passwords both return ErrInvalidCredentials.
+func (s *Service) Login(ctx context.Context, email, password string) (*Session, error) {
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	u, err := s.store.GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil || bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return s.StartSession(ctx, u)
+}
+
+// StartSession signs u in without a password, e.g. right after Register.
+// Expired sessions of every user are purged on the way.
+func (s *Service) StartSession(ctx context.Context, u *user.UserDB) (*Session, error) {
+	now := s.now()
+	if _, err := s.store.DeleteExpiredSessions(ctx, now); err != nil {
+		return nil, err
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	csrf, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	record := &user.SessionDB{
+		ID:        hashToken(token),
+		UserID:    u.ID,
+		CSRFToken: csrf,
+		ExpiresAt: now.Add(s.sessionTTL),
+		CreatedAt: now,
+	}
+	if err := s.store.CreateSession(ctx, record); err != nil {
+		return nil, err
+	}
+	return &Session{Token: token, CSRFToken: csrf, ExpiresAt: record.ExpiresAt, User: u}, nil
+}
+
+// Authenticate returns the user and session for a session token, or nil
+// when the token is unknown or expired.
+func (s *Service) Authenticate(ctx context.Context, token string) (*user.UserDB, *user.SessionDB, error) {
+	if token == "" {
+		return nil, nil, nil
+	}
+	record, err := s.store.GetSession(ctx, hashToken(token))
+	if err != nil || record == nil {
+		return nil, nil, err
+	}
+	if !s.now().Before(record.ExpiresAt) {
+		return nil, nil, nil
+	}
+	u, err := s.store.GetUserByID(ctx, record.UserID)
+	if err != nil || u == nil {
+		return nil, nil, err
+	}
+	return u, record, nil
+}
+
+// Logout ends the session with token.
+func (s *Service) Logout(ctx context.Context, token string) error {
+	if token == "" {
+		return nil
+	}
+	return s.store.DeleteSession(ctx, hashToken(token))
+}
+
+func normalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return "", ErrInvalidEmail
+	}
+	return email, nil
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
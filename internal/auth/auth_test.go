@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kdimtricp/vshazam/internal/models/user"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type memoryStore struct {
+	users    map[string]*user.UserDB
+	sessions map[string]*user.SessionDB
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{users: make(map[string]*user.UserDB), sessions: make(map[string]*user.SessionDB)}
+}
+
+func (m *memoryStore) RegisterUser(ctx context.Context, u *user.UserDB) error {
+	u.ID = u.Email
+	u.Role = user.RoleUser
+	if len(m.users) == 0 {
+		u.Role = user.RoleAdmin
+	}
+	m.users[u.ID] = u
+	return nil
+}
+
+func (m *memoryStore) GetUserByID(ctx context.Context, id string) (*user.UserDB, error) {
+	return m.users[id], nil
+}
+
+func (m *memoryStore) GetUserByEmail(ctx context.Context, email string) (*user.UserDB, error) {
+	return m.users[email], nil
+}
+
+func (m *memoryStore) CreateSession(ctx context.Context, s *user.SessionDB) error {
+	m.sessions[s.ID] = s
+	return nil
+}
+
+func (m *memoryStore) GetSession(ctx context.Context, id string) (*user.SessionDB, error) {
+	return m.sessions[id], nil
+}
+
+func (m *memoryStore) DeleteSession(ctx context.Context, id string) error {
+	delete(m.sessions, id)
+	return nil
+}
+
+func (m *memoryStore) DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error) {
+	var n int64
+	for id, s := range m.sessions {
+		if s.ExpiresAt.Before(now) {
+			delete(m.sessions, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func TestService_Register(t *testing.T) {
+	store := newMemoryStore()
+	service := NewService(store)
+	ctx := context.Background()
+
+	admin, err := service.Register(ctx, "  Ada@Example.com ", "correct horse")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if admin.Email != "ada@example.com" || !admin.IsAdmin() {
+		t.Errorf("expected the first account to be an admin, got %+v", admin)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(admin.PasswordHash), []byte("correct horse")) != nil {
+		t.Error("expected the password to be stored as a bcrypt hash")
+	}
+
+	second, err := service.Register(ctx, "bob@example.com", "battery staple")
+	if err != nil || second.IsAdmin() {
+		t.Errorf("expected a regular user, got %+v, %v", second, err)
+	}
+
+	tests := []struct {
+		email, password string
+		want            error
+	}{
+		{"ada@example.com", "another password", ErrEmailTaken},
+		{"not an email", "long enough", ErrInvalidEmail},
+		{"Carol <carol@example.com>", "long enough", ErrInvalidEmail},
+		{"carol@example.com", "short", ErrWeakPassword},
+	}
+	for _, tt := range tests {
+		if _, err := service.Register(ctx, tt.email, tt.password); !errors.Is(err, tt.want) {
+			t.Errorf("Register(%q) error = %v, want %v", tt.email, err, tt.want)
+		}
+	}
+}
+
+func TestService_Sessions(t *testing.T) {
+	store := newMemoryStore()
+	service := NewService(store)
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if _, err := service.Register(ctx, "ada@example.com", "correct horse"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := service.Login(ctx, "ada@example.com", "wrong horse"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("expected invalid credentials for a wrong password, got %v", err)
+	}
+	if _, err := service.Login(ctx, "nobody@example.com", "correct horse"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("expected invalid credentials for an unknown email, got %v", err)
+	}
+
+	session, err := service.Login(ctx, "ADA@example.com", "correct horse")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if session.Token == "" || session.CSRFToken == "" || session.Token == session.CSRFToken {
+		t.Fatalf("expected distinct random tokens, got %+v", session)
+	}
+	if _, stored := store.sessions[session.Token]; stored {
+		t.Error("expected only the token's hash to be stored")
+	}
+
+	u, record, err := service.Authenticate(ctx, session.Token)
+	if err != nil || u == nil || u.Email != "ada@example.com" || record.CSRFToken != session.CSRFToken {
+		t.Errorf("unexpected authentication %+v, %+v, %v", u, record, err)
+	}
+	if u, _, _ := service.Authenticate(ctx, "forged"); u != nil {
+		t.Error("expected an unknown token to be rejected")
+	}
+
+	now = now.Add(DefaultSessionTTL + time.Minute)
+	if u, _, _ := service.Authenticate(ctx, session.Token); u != nil {
+		t.Error("expected an expired session to be rejected")
+	}
+
+	fresh, err := service.Login(ctx, "ada@example.com", "correct horse")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(store.sessions) != 1 {
+		t.Errorf("expected the expired session to be purged, got %d sessions", len(store.sessions))
+	}
+	if err := service.Logout(ctx, fresh.Token); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u, _, _ := service.Authenticate(ctx, fresh.Token); u != nil {
+		t.Error("expected a logged out session to be rejected")
+	}
+}
@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/kdimtricp/vshazam/internal/models/user"
+)
+
+type contextKey struct{}
+
+type signedIn struct {
+	user    *user.UserDB
+	session *user.SessionDB
+	key     *user.APIKeyDB
+}
+
+// WithUser returns a context carrying the signed-in user and their session.
+func WithUser(ctx context.Context, u *user.UserDB, session *user.SessionDB) context.Context {
+	return context.WithValue(ctx, contextKey{}, signedIn{user: u, session: session})
+}
+
+// UserFrom returns the signed-in user, or nil for anonymous requests.
+func UserFrom(ctx context.Context) *user.UserDB {
+	v, _ := ctx.Value(contextKey{}).(signedIn)
+	return v.user
+}
+
+// SessionFrom returns the session the request was signed in with, or nil.
+func SessionFrom(ctx context.Context) *user.SessionDB {
+	v, _ := ctx.Value(contextKey{}).(signedIn)
+	return v.session
+}
+
+// WithAPIKey returns a context carrying the user an API key belongs to.
+func WithAPIKey(ctx context.Context, u *user.UserDB, key *user.APIKeyDB) context.Context {
+	return context.WithValue(ctx, contextKey{}, signedIn{user: u, key: key})
+}
+
+// APIKeyFrom returns the API key the request was made with, or nil for
+// browser sessions and anonymous requests.
+func APIKeyFrom(ctx context.Context) *user.APIKeyDB {
+	v, _ := ctx.Value(contextKey{}).(signedIn)
+	return v.key
+}
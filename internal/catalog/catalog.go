@@ -0,0 +1,292 @@
+// Package catalog keeps a local copy of TMDb film metadata. Films are
+// cataloged when identification resolves a candidate to them, refreshed on a
+// schedule and served locally first, so browsing and identification keep
+// working when TMDb is unreachable.
+package catalog
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/kdimtricp/vshazam/internal/mdb"
+	"github.com/kdimtricp/vshazam/internal/models/film"
+)
+
+// DefaultMaxAge is how long a cataloged film is used before it is fetched
+// from TMDb again.
+const DefaultMaxAge = 30 * 24 * time.Hour
+
+// refreshBatch bounds the films refreshed in one scheduled run.
+const refreshBatch = 50
+
+// searchLimit bounds the local matches returned for one title.
+const searchLimit = 5
+
+type Store interface {
+	GetByTMDbID(ctx context.Context, tmdbID int) (*film.FilmDB, error)
+	SearchByTitle(ctx context.Context, title string, limit int) ([]*film.FilmDB, error)
+	Upsert(ctx context.Context, f *film.FilmDB, credits []*film.CreditDB) error
+	ListStale(ctx context.Context, before time.Time, limit int) ([]*film.FilmDB, error)
+}
+
+// Source is implemented by mdb.TMDbClient.
+type Source interface {
+	GetFilm(ctx context.Context, tmdbID string) (*mdb.FilmDetails, error)
+	SearchMovies(ctx context.Context, query string) ([]mdb.Movie, error)
+}
+
+// tvSource is the part of mdb.TMDbClient that looks up shows. Shows are not
+// cataloged; these calls pass straight through.
+type tvSource interface {
+	SearchTV(ctx context.Context, query string) ([]mdb.TVShow, error)
+	GetEpisode(ctx context.Context, tvID, season, episode int) (*mdb.Episode, error)
+}
+
+// alternativeTitleSource is the part of mdb.TMDbClient that lists a film's
+// alternative titles, which are not cataloged either.
+type alternativeTitleSource interface {
+	GetAlternativeTitles(ctx context.Context, movieID int) ([]mdb.AlternativeTitle, error)
+}
+
+type Catalog struct {
+	store  Store
+	source Source
+	maxAge time.Duration
+	now    func() time.Time
+}
+
+// New creates a catalog. source may be nil, in which case only films already
+// in the catalog are found.
+func New(store Store, source Source) *Catalog {
+	return &Catalog{
+		store:  store,
+		source: source,
+		maxAge: DefaultMaxAge,
+		now:    time.Now,
+	}
+}
+
+// UseMaxAge refetches cataloged films once they are older than maxAge.
+func (c *Catalog) UseMaxAge(maxAge time.Duration) {
+	c.maxAge = maxAge
+}
+
+// Resolve returns the cataloged film with tmdbID, fetching it from TMDb when
+// it is missing or stale. When TMDb fails, a stale copy is returned as is.
+func (c *Catalog) Resolve(ctx context.Context, tmdbID int) (*film.FilmDB, error) {
+	cached, err := c.store.GetByTMDbID(ctx, tmdbID)
+	if err != nil {
+		return nil, err
+	}
+	if cached != nil && (c.source == nil || c.now().Sub(cached.SyncedAt) < c.maxAge) {
+		return cached, nil
+	}
+	if c.source == nil {
+		return nil, nil
+	}
+
+	fetched, err := c.fetch(ctx, tmdbID)
+	if err != nil {
+		if cached != nil {
+			log.Printf("Failed to refresh film %d, using the copy from %s: %v", tmdbID, cached.SyncedAt.Format(time.RFC3339), err)
+			return cached, nil
+		}
+		return nil, err
+	}
+	return fetched, nil
+}
+
+// fetch copies a film's details and credits from TMDb into the catalog.
+func (c *Catalog) fetch(ctx context.Context, tmdbID int) (*film.FilmDB, error) {
+	details, err := c.source.GetFilm(ctx, strconv.Itoa(tmdbID))
+	if err != nil {
+		return nil, err
+	}
+
+	f, credits := FromDetails(details)
+	f.SyncedAt = c.now()
+	if err := c.store.Upsert(ctx, f, credits); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// SearchMovies returns cataloged films titled query, and searches TMDb only
+// when there are none. It lets the identification scorer use the catalog in
+// place of a TMDb client.
+func (c *Catalog) SearchMovies(ctx context.Context, query string) ([]mdb.Movie, error) {
+	local, err := c.store.SearchByTitle(ctx, query, searchLimit)
+	if err != nil {
+		log.Printf("Failed to search the film catalog for %q: %v", query, err)
+	}
+	if len(local) > 0 || c.source == nil {
+		movies := make([]mdb.Movie, 0, len(local))
+		for _, f := range local {
+			movies = append(movies, ToMovie(f))
+		}
+		return movies, nil
+	}
+	return c.source.SearchMovies(ctx, query)
+}
+
+func (c *Catalog) SearchTV(ctx context.Context, query string) ([]mdb.TVShow, error) {
+	tv, ok := c.source.(tvSource)
+	if !ok {
+		return nil, nil
+	}
+	return tv.SearchTV(ctx, query)
+}
+
+func (c *Catalog) GetEpisode(ctx context.Context, tvID, season, episode int) (*mdb.Episode, error) {
+	tv, ok := c.source.(tvSource)
+	if !ok {
+		return nil, nil
+	}
+	return tv.GetEpisode(ctx, tvID, season, episode)
+}
+
+func (c *Catalog) GetAlternativeTitles(ctx context.Context, movieID int) ([]mdb.AlternativeTitle, error) {
+	source, ok := c.source.(alternativeTitleSource)
+	if !ok {
+		return nil, nil
+	}
+	return source.GetAlternativeTitles(ctx, movieID)
+}
+
+// Refresh refetches up to limit films synced more than maxAge ago. It
+// returns how many were refreshed; failures are logged and left for the next
+// run.
+func (c *Catalog) Refresh(ctx context.Context, limit int) (int, error) {
+	if c.source == nil {
+		return 0, nil
+	}
+	stale, err := c.store.ListStale(ctx, c.now().Add(-c.maxAge), limit)
+	if err != nil {
+		return 0, err
+	}
+
+	refreshed := 0
+	for _, f := range stale {
+		if err := ctx.Err(); err != nil {
+			return refreshed, err
+		}
+		if _, err := c.fetch(ctx, f.TMDbID); err != nil {
+			log.Printf("Failed to refresh film %d (%s): %v", f.TMDbID, f.Title, err)
+			continue
+		}
+		refreshed++
+	}
+	return refreshed, nil
+}
+
+// Run refreshes stale films every interval until ctx is done.
+func (c *Catalog) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			refreshed, err := c.Refresh(ctx, refreshBatch)
+			if err != nil {
+				log.Printf("Failed to refresh the film catalog: %v", err)
+			} else if refreshed > 0 {
+				log.Printf("Refreshed %d films in the catalog", refreshed)
+			}
+		}
+	}
+}
+
+// FromDetails converts TMDb film details into a catalog row and its credits.
+func FromDetails(details *mdb.FilmDetails) (*film.FilmDB, []*film.CreditDB) {
+	f := &film.FilmDB{
+		TMDbID:       details.ID,
+		IMDbID:       details.IMDbID,
+		Title:        details.Title,
+		ReleaseDate:  details.ReleaseDate,
+		Overview:     details.Overview,
+		Runtime:      details.Runtime,
+		PosterPath:   details.PosterPath,
+		BackdropPath: details.BackdropPath,
+		VoteAverage:  details.VoteAverage,
+		Genres:       make([]string, 0, len(details.Genres)),
+	}
+	if len(details.ReleaseDate) >= 4 {
+		f.Year, _ = strconv.Atoi(details.ReleaseDate[:4])
+	}
+	for _, genre := range details.Genres {
+		f.Genres = append(f.Genres, genre.Name)
+	}
+
+	credits := make([]*film.CreditDB, 0, len(details.Credits.Cast)+len(details.Credits.Crew))
+	for _, member := range details.Credits.Cast {
+		credits = append(credits, &film.CreditDB{
+			Role:      film.RoleCast,
+			Name:      member.Name,
+			Character: member.Character,
+			Position:  member.Order,
+		})
+	}
+	for i, member := range details.Credits.Crew {
+		credits = append(credits, &film.CreditDB{
+			Role:       film.RoleCrew,
+			Name:       member.Name,
+			Job:        member.Job,
+			Department: member.Department,
+			Position:   i,
+		})
+	}
+	return f, credits
+}
+
+// ToDetails converts a cataloged film and its credits back into TMDb film
+// details.
+func ToDetails(f *film.FilmDB, credits []*film.CreditDB) *mdb.FilmDetails {
+	details := &mdb.FilmDetails{
+		ID:           f.TMDbID,
+		IMDbID:       f.IMDbID,
+		Title:        f.Title,
+		ReleaseDate:  f.ReleaseDate,
+		Overview:     f.Overview,
+		PosterPath:   f.PosterPath,
+		BackdropPath: f.BackdropPath,
+		VoteAverage:  f.VoteAverage,
+		Runtime:      f.Runtime,
+	}
+	for _, name := range f.Genres {
+		details.Genres = append(details.Genres, mdb.Genre{Name: name})
+	}
+	for _, credit := range credits {
+		switch credit.Role {
+		case film.RoleCast:
+			details.Credits.Cast = append(details.Credits.Cast, mdb.CastMember{
+				Name:      credit.Name,
+				Character: credit.Character,
+				Order:     credit.Position,
+			})
+		case film.RoleCrew:
+			details.Credits.Crew = append(details.Credits.Crew, mdb.CrewMember{
+				Name:       credit.Name,
+				Job:        credit.Job,
+				Department: credit.Department,
+			})
+		}
+	}
+	return details
+}
+
+// ToMovie converts a cataloged film into a TMDb search result.
+func ToMovie(f *film.FilmDB) mdb.Movie {
+	return mdb.Movie{
+		ID:          f.TMDbID,
+		Title:       f.Title,
+		ReleaseDate: f.ReleaseDate,
+		Overview:    f.Overview,
+		PosterPath:  f.PosterPath,
+		VoteAverage: f.VoteAverage,
+	}
+}
@@ -0,0 +1,227 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kdimtricp/vshazam/internal/mdb"
+	"github.com/kdimtricp/vshazam/internal/models/film"
+)
+
+type memoryStore struct {
+	films   map[int]*film.FilmDB
+	credits map[int][]*film.CreditDB
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{films: make(map[int]*film.FilmDB), credits: make(map[int][]*film.CreditDB)}
+}
+
+func (m *memoryStore) GetByTMDbID(ctx context.Context, tmdbID int) (*film.FilmDB, error) {
+	return m.films[tmdbID], nil
+}
+
+func (m *memoryStore) SearchByTitle(ctx context.Context, title string, limit int) ([]*film.FilmDB, error) {
+	var films []*film.FilmDB
+	for _, f := range m.films {
+		if strings.EqualFold(f.Title, title) {
+			films = append(films, f)
+		}
+	}
+	return films, nil
+}
+
+func (m *memoryStore) Upsert(ctx context.Context, f *film.FilmDB, credits []*film.CreditDB) error {
+	m.films[f.TMDbID] = f
+	m.credits[f.TMDbID] = credits
+	return nil
+}
+
+func (m *memoryStore) ListStale(ctx context.Context, before time.Time, limit int) ([]*film.FilmDB, error) {
+	var films []*film.FilmDB
+	for _, f := range m.films {
+		if f.SyncedAt.Before(before) && len(films) < limit {
+			films = append(films, f)
+		}
+	}
+	return films, nil
+}
+
+type fakeSource struct {
+	details  map[string]*mdb.FilmDetails
+	err      error
+	fetches  int
+	searches []string
+}
+
+func (f *fakeSource) GetFilm(ctx context.Context, tmdbID string) (*mdb.FilmDetails, error) {
+	f.fetches++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.details[tmdbID], nil
+}
+
+func (f *fakeSource) SearchMovies(ctx context.Context, query string) ([]mdb.Movie, error) {
+	f.searches = append(f.searches, query)
+	return []mdb.Movie{{ID: 949, Title: "Heat", ReleaseDate: "1995-12-15"}}, nil
+}
+
+var inception = &mdb.FilmDetails{
+	ID:          27205,
+	IMDbID:      "tt1375666",
+	Title:       "Inception",
+	ReleaseDate: "2010-07-15",
+	Runtime:     148,
+	Genres:      []mdb.Genre{{ID: 28, Name: "Action"}, {ID: 878, Name: "Science Fiction"}},
+	Credits: mdb.Credits{
+		Cast: []mdb.CastMember{{Name: "Leonardo DiCaprio", Character: "Dom Cobb", Order: 0}},
+		Crew: []mdb.CrewMember{{Name: "Christopher Nolan", Job: "Director", Department: "Directing"}},
+	},
+}
+
+func newTestCatalog(source *fakeSource) (*Catalog, *memoryStore, *time.Time) {
+	store := newMemoryStore()
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	c := New(store, source)
+	c.now = func() time.Time { return now }
+	return c, store, &now
+}
+
+func TestFromDetails(t *testing.T) {
+	f, credits := FromDetails(inception)
+
+	if f.TMDbID != 27205 || f.IMDbID != "tt1375666" || f.Year != 2010 || f.Runtime != 148 {
+		t.Errorf("unexpected film %+v", f)
+	}
+	if len(f.Genres) != 2 || f.Genres[1] != "Science Fiction" {
+		t.Errorf("unexpected genres %v", f.Genres)
+	}
+	if len(credits) != 2 || credits[0].Role != film.RoleCast || credits[1].Job != film.JobDirector {
+		t.Errorf("unexpected credits %+v", credits)
+	}
+}
+
+func TestToDetails(t *testing.T) {
+	details := ToDetails(FromDetails(inception))
+
+	if details.ID != 27205 || details.Title != inception.Title || details.Runtime != 148 {
+		t.Errorf("unexpected details %+v", details)
+	}
+	if len(details.Genres) != 2 || details.Genres[1].Name != "Science Fiction" {
+		t.Errorf("unexpected genres %+v", details.Genres)
+	}
+	if directors := details.Credits.Directors(); len(directors) != 1 || directors[0] != "Christopher Nolan" {
+		t.Errorf("unexpected directors %v", directors)
+	}
+	if len(details.Credits.Cast) != 1 {
+		t.Errorf("unexpected cast %+v", details.Credits.Cast)
+	}
+}
+
+func TestCatalog_ResolveCachesFilms(t *testing.T) {
+	source := &fakeSource{details: map[string]*mdb.FilmDetails{"27205": inception}}
+	c, store, now := newTestCatalog(source)
+	ctx := context.Background()
+
+	f, err := c.Resolve(ctx, 27205)
+	if err != nil || f == nil || f.Title != "Inception" {
+		t.Fatalf("unexpected film %+v, %v", f, err)
+	}
+	if len(store.credits[27205]) != 2 {
+		t.Errorf("expected credits to be stored, got %+v", store.credits[27205])
+	}
+
+	*now = now.Add(24 * time.Hour)
+	if _, err := c.Resolve(ctx, 27205); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if source.fetches != 1 {
+		t.Errorf("expected a fresh film to be served from the catalog, got %d fetches", source.fetches)
+	}
+
+	*now = now.Add(DefaultMaxAge)
+	if _, err := c.Resolve(ctx, 27205); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if source.fetches != 2 {
+		t.Errorf("expected a stale film to be refetched, got %d fetches", source.fetches)
+	}
+}
+
+func TestCatalog_ResolveFallsBackToStaleCopy(t *testing.T) {
+	source := &fakeSource{details: map[string]*mdb.FilmDetails{"27205": inception}}
+	c, _, now := newTestCatalog(source)
+	ctx := context.Background()
+
+	if _, err := c.Resolve(ctx, 27205); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	*now = now.Add(2 * DefaultMaxAge)
+	source.err = errors.New("connection refused")
+
+	f, err := c.Resolve(ctx, 27205)
+	if err != nil || f == nil || f.Title != "Inception" {
+		t.Errorf("expected the stale copy when TMDb is unreachable, got %+v, %v", f, err)
+	}
+	if _, err := c.Resolve(ctx, 949); err == nil {
+		t.Error("expected an error for a film that was never cataloged")
+	}
+}
+
+func TestCatalog_SearchMoviesLocalFirst(t *testing.T) {
+	source := &fakeSource{details: map[string]*mdb.FilmDetails{"27205": inception}}
+	c, _, _ := newTestCatalog(source)
+	ctx := context.Background()
+
+	if _, err := c.Resolve(ctx, 27205); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	movies, err := c.SearchMovies(ctx, "INCEPTION")
+	if err != nil || len(movies) != 1 || movies[0].ID != 27205 || movies[0].ReleaseDate != "2010-07-15" {
+		t.Errorf("expected the cataloged film, got %+v, %v", movies, err)
+	}
+	if len(source.searches) != 0 {
+		t.Errorf("expected no TMDb search, got %v", source.searches)
+	}
+
+	movies, err = c.SearchMovies(ctx, "Heat")
+	if err != nil || len(movies) != 1 || movies[0].ID != 949 {
+		t.Errorf("expected TMDb results for an uncataloged title, got %+v, %v", movies, err)
+	}
+
+	offline := New(newMemoryStore(), nil)
+	if movies, err := offline.SearchMovies(ctx, "Heat"); err != nil || len(movies) != 0 {
+		t.Errorf("expected no results without TMDb, got %+v, %v", movies, err)
+	}
+	if shows, err := offline.SearchTV(ctx, "Heat"); err != nil || shows != nil {
+		t.Errorf("expected no shows without TMDb, got %+v, %v", shows, err)
+	}
+}
+
+func TestCatalog_Refresh(t *testing.T) {
+	source := &fakeSource{details: map[string]*mdb.FilmDetails{"27205": inception}}
+	c, store, now := newTestCatalog(source)
+	ctx := context.Background()
+
+	if _, err := c.Resolve(ctx, 27205); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if refreshed, err := c.Refresh(ctx, 10); err != nil || refreshed != 0 {
+		t.Errorf("expected nothing to refresh, got %d, %v", refreshed, err)
+	}
+
+	*now = now.Add(DefaultMaxAge + time.Hour)
+	if refreshed, err := c.Refresh(ctx, 10); err != nil || refreshed != 1 {
+		t.Errorf("expected one film refreshed, got %d, %v", refreshed, err)
+	}
+	if !store.films[27205].SyncedAt.Equal(*now) {
+		t.Errorf("expected the sync time to advance, got %s", store.films[27205].SyncedAt)
+	}
+}
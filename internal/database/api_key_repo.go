@@ -0,0 +1,100 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kdimtricp/vshazam/internal/models/user"
+	"gorm.io/gorm"
+)
+
+// APIKeyRepo stores API keys and the audit trail of their use.
+type APIKeyRepo struct {
+	db *DB
+}
+
+func NewAPIKeyRepo(db *DB) *APIKeyRepo {
+	return &APIKeyRepo{db: db}
+}
+
+func (r *APIKeyRepo) CreateAPIKey(ctx context.Context, key *user.APIKeyDB) error {
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = time.Now()
+	}
+	if err := r.db.GORM().WithContext(ctx).Create(key).Error; err != nil {
+		return fmt.Errorf("failed to create API key: %w", err)
+	}
+	return nil
+}
+
+// GetAPIKeyByHash returns nil when no key has hash, revoked or not.
+func (r *APIKeyRepo) GetAPIKeyByHash(ctx context.Context, hash string) (*user.APIKeyDB, error) {
+	var key user.APIKeyDB
+	result := r.db.GORM().WithContext(ctx).First(&key, "key_hash = ?", hash)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get API key: %w", result.Error)
+	}
+	return &key, nil
+}
+
+// ListAPIKeys returns the user's keys, newest first, including revoked and
+// expired ones.
+func (r *APIKeyRepo) ListAPIKeys(ctx context.Context, userID string) ([]*user.APIKeyDB, error) {
+	var keys []*user.APIKeyDB
+	result := r.db.GORM().WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&keys)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", result.Error)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey revokes the user's key with id and reports whether there was
+// such a key still active.
+func (r *APIKeyRepo) RevokeAPIKey(ctx context.Context, userID, id string, at time.Time) (bool, error) {
+	result := r.db.GORM().WithContext(ctx).
+		Model(&user.APIKeyDB{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", at)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to revoke API key: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// RecordAPIKeyUse appends use to the audit trail and updates the key's last
+// use.
+func (r *APIKeyRepo) RecordAPIKeyUse(ctx context.Context, use *user.APIKeyUseDB) error {
+	if use.CreatedAt.IsZero() {
+		use.CreatedAt = time.Now()
+	}
+	return r.db.GORM().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(use).Error; err != nil {
+			return fmt.Errorf("failed to record API key use: %w", err)
+		}
+		if err := tx.Model(&user.APIKeyDB{}).Where("id = ?", use.KeyID).Update("last_used_at", use.CreatedAt).Error; err != nil {
+			return fmt.Errorf("failed to update API key last use: %w", err)
+		}
+		return nil
+	})
+}
+
+// ListAPIKeyUses returns the user's most recent key uses, newest first.
+func (r *APIKeyRepo) ListAPIKeyUses(ctx context.Context, userID string, limit int) ([]*user.APIKeyUseDB, error) {
+	var uses []*user.APIKeyUseDB
+	result := r.db.GORM().WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&uses)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list API key uses: %w", result.Error)
+	}
+	return uses, nil
+}
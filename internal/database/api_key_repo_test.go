@@ -0,0 +1,63 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kdimtricp/vshazam/internal/models/user"
+)
+
+func TestAPIKeyRepo_KeysAndUses(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	users := NewUserRepo(db)
+	repo := NewAPIKeyRepo(db)
+	ctx := context.Background()
+
+	owner := &user.UserDB{Email: "ada@example.com", PasswordHash: "hash"}
+	if err := users.CreateUser(ctx, owner); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	key := &user.APIKeyDB{UserID: owner.ID, Name: "ingest", Prefix: "vsk_abcdefgh", KeyHash: "hash-1", Scopes: []string{user.ScopeUpload}}
+	if err := repo.CreateAPIKey(ctx, key); err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+
+	found, err := repo.GetAPIKeyByHash(ctx, "hash-1")
+	if err != nil || found == nil || found.ID != key.ID || !found.HasScope(user.ScopeUpload) || found.HasScope(user.ScopeRead) {
+		t.Errorf("unexpected key %+v, %v", found, err)
+	}
+	if missing, err := repo.GetAPIKeyByHash(ctx, "unknown"); err != nil || missing != nil {
+		t.Errorf("expected nil for an unknown hash, got %+v, %v", missing, err)
+	}
+
+	use := &user.APIKeyUseDB{KeyID: key.ID, UserID: owner.ID, Method: "POST", Path: "/upload", Status: 200, RemoteIP: "10.0.0.1"}
+	if err := repo.RecordAPIKeyUse(ctx, use); err != nil {
+		t.Fatalf("Failed to record use: %v", err)
+	}
+	uses, err := repo.ListAPIKeyUses(ctx, owner.ID, 10)
+	if err != nil || len(uses) != 1 || uses[0].Path != "/upload" {
+		t.Errorf("unexpected uses %+v, %v", uses, err)
+	}
+
+	keys, err := repo.ListAPIKeys(ctx, owner.ID)
+	if err != nil || len(keys) != 1 || keys[0].LastUsedAt == nil {
+		t.Errorf("expected the key with its last use, got %+v, %v", keys, err)
+	}
+
+	revoked, err := repo.RevokeAPIKey(ctx, "00000000-0000-0000-0000-000000000000", key.ID, time.Now())
+	if err != nil || revoked {
+		t.Errorf("expected other users not to revoke the key, got %v, %v", revoked, err)
+	}
+	revoked, err = repo.RevokeAPIKey(ctx, owner.ID, key.ID, time.Now())
+	if err != nil || !revoked {
+		t.Errorf("expected the key to be revoked, got %v, %v", revoked, err)
+	}
+	found, _ = repo.GetAPIKeyByHash(ctx, "hash-1")
+	if found == nil || found.Active(time.Now()) {
+		t.Errorf("expected a revoked key, got %+v", found)
+	}
+}
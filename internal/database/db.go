@@ -4,7 +4,16 @@ import (
 	"database/sql"
 	"fmt"
 
+	"github.com/kdimtricp/vshazam/internal/models/api_usage"
+	"github.com/kdimtricp/vshazam/internal/models/face"
+	"github.com/kdimtricp/vshazam/internal/models/film"
 	"github.com/kdimtricp/vshazam/internal/models/frame_analysis"
+	"github.com/kdimtricp/vshazam/internal/models/identification"
+	"github.com/kdimtricp/vshazam/internal/models/imdb_dataset"
+	"github.com/kdimtricp/vshazam/internal/models/share"
+	"github.com/kdimtricp/vshazam/internal/models/subtitle"
+	"github.com/kdimtricp/vshazam/internal/models/transcript"
+	"github.com/kdimtricp/vshazam/internal/models/user"
 	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -58,7 +67,30 @@ func NewDB(config Config) (*DB, error) {
 
 	db := &DB{gormDB: gormDB, conn: sqlDB, dbType: config.Type}
 
-	if err := gormDB.AutoMigrate(&models.Video{}, &frame_analysis.FrameAnalysisDB{}); err != nil {
+	if err := gormDB.AutoMigrate(
+		&models.Video{},
+		&frame_analysis.FrameAnalysisDB{},
+		&identification.IdentificationDB{},
+		&identification.FeedbackDB{},
+		&identification.ReferenceFingerprintDB{},
+		&identification.RequestDB{},
+		&api_usage.UsageDB{},
+		&face.FaceClusterDB{},
+		&face.FaceDB{},
+		&transcript.CueDB{},
+		&subtitle.TrackDB{},
+		&film.FilmDB{},
+		&film.CreditDB{},
+		&imdb_dataset.TitleDB{},
+		&imdb_dataset.AkaDB{},
+		&imdb_dataset.PrincipalDB{},
+		&imdb_dataset.NameDB{},
+		&user.UserDB{},
+		&user.SessionDB{},
+		&user.APIKeyDB{},
+		&user.APIKeyUseDB{},
+		&share.LinkDB{},
+	); err != nil {
 		return nil, fmt.Errorf("failed to auto migrate: %w", err)
 	}
 
@@ -82,3 +114,9 @@ func (db *DB) Conn() *sql.DB {
 func (db *DB) GORM() *gorm.DB {
 	return db.gormDB
 }
+
+// withGORM returns a DB that runs its queries on g, e.g. a transaction, so
+// repositories can take part in it.
+func (db *DB) withGORM(g *gorm.DB) *DB {
+	return &DB{gormDB: g, conn: db.conn, dbType: db.dbType}
+}
@@ -0,0 +1,195 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kdimtricp/vshazam/internal/models/face"
+	"gorm.io/gorm"
+)
+
+type FaceRepo struct {
+	db *DB
+}
+
+func NewFaceRepo(db *DB) *FaceRepo {
+	return &FaceRepo{db: db}
+}
+
+func (r *FaceRepo) CreateFace(ctx context.Context, record *face.FaceDB) error {
+	if record.ID == "" {
+		record.ID = uuid.New().String()
+	}
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now()
+	}
+
+	result := r.db.GORM().WithContext(ctx).Create(record)
+	if result.Error != nil {
+		return fmt.Errorf("failed to insert face: %w", result.Error)
+	}
+	return nil
+}
+
+func (r *FaceRepo) GetFaceByID(ctx context.Context, id string) (*face.FaceDB, error) {
+	var record face.FaceDB
+	result := r.db.GORM().WithContext(ctx).First(&record, "id = ?", id)
+
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+
+	return &record, nil
+}
+
+func (r *FaceRepo) ListByVideoID(ctx context.Context, videoID string) ([]*face.FaceDB, error) {
+	var records []*face.FaceDB
+	result := r.db.GORM().WithContext(ctx).
+		Where("video_id = ?", videoID).
+		Order("frame_number ASC").
+		Find(&records)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return records, nil
+}
+
+// SaveCluster inserts a new cluster or updates the centroid and face count of
+// an existing one.
+func (r *FaceRepo) SaveCluster(ctx context.Context, cluster *face.FaceClusterDB) error {
+	now := time.Now()
+	if cluster.CreatedAt.IsZero() {
+		cluster.CreatedAt = now
+	}
+	cluster.UpdatedAt = now
+
+	if cluster.ID == "" {
+		cluster.ID = uuid.New().String()
+		if err := r.db.GORM().WithContext(ctx).Create(cluster).Error; err != nil {
+			return fmt.Errorf("failed to insert face cluster: %w", err)
+		}
+		return nil
+	}
+
+	if err := r.db.GORM().WithContext(ctx).Save(cluster).Error; err != nil {
+		return fmt.Errorf("failed to update face cluster: %w", err)
+	}
+	return nil
+}
+
+// DeleteByVideoID deletes the faces of a video and takes them out of their
+// clusters in one transaction: each affected cluster's centroid and face count
+// are recomputed from the faces left in it, and clusters left empty are
+// deleted. It returns the thumbnail paths of the deleted faces.
+func (r *FaceRepo) DeleteByVideoID(ctx context.Context, videoID string) ([]string, error) {
+	var thumbnails []string
+	err := r.db.GORM().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var records []*face.FaceDB
+		if err := tx.Where("video_id = ?", videoID).Find(&records).Error; err != nil {
+			return err
+		}
+
+		var clusterIDs []string
+		seen := make(map[string]bool)
+		for _, record := range records {
+			if record.ThumbnailPath != "" {
+				thumbnails = append(thumbnails, record.ThumbnailPath)
+			}
+			if record.ClusterID != nil && !seen[*record.ClusterID] {
+				seen[*record.ClusterID] = true
+				clusterIDs = append(clusterIDs, *record.ClusterID)
+			}
+		}
+
+		if err := tx.Where("video_id = ?", videoID).Delete(&face.FaceDB{}).Error; err != nil {
+			return err
+		}
+		return refreshClusters(tx, clusterIDs)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete faces: %w", err)
+	}
+	return thumbnails, nil
+}
+
+// refreshClusters recomputes the given clusters from the faces they still
+// contain and deletes those that are empty.
+func refreshClusters(tx *gorm.DB, clusterIDs []string) error {
+	for _, id := range clusterIDs {
+		var remaining []*face.FaceDB
+		if err := tx.Select("embedding").Where("cluster_id = ?", id).Find(&remaining).Error; err != nil {
+			return err
+		}
+		if len(remaining) == 0 {
+			if err := tx.Delete(&face.FaceClusterDB{}, "id = ?", id).Error; err != nil {
+				return err
+			}
+			continue
+		}
+
+		var cluster face.FaceClusterDB
+		if err := tx.First(&cluster, "id = ?", id).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				continue
+			}
+			return err
+		}
+		embeddings := make([][]float64, 0, len(remaining))
+		for _, record := range remaining {
+			if len(record.Embedding) > 0 {
+				embeddings = append(embeddings, record.Embedding)
+			}
+		}
+		cluster.Recompute(embeddings)
+		cluster.UpdatedAt = time.Now()
+		if err := tx.Save(&cluster).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *FaceRepo) ListClusters(ctx context.Context) ([]*face.FaceClusterDB, error) {
+	var clusters []*face.FaceClusterDB
+	result := r.db.GORM().WithContext(ctx).Order("created_at ASC").Find(&clusters)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return clusters, nil
+}
+
+// CountOtherVideos returns, for each cluster, how many videos other than
+// videoID contain one of its faces.
+func (r *FaceRepo) CountOtherVideos(ctx context.Context, videoID string, clusterIDs []string) (map[string]int, error) {
+	counts := make(map[string]int, len(clusterIDs))
+	if len(clusterIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		ClusterID string
+		Videos    int
+	}
+	result := r.db.GORM().WithContext(ctx).
+		Model(&face.FaceDB{}).
+		Select("cluster_id, COUNT(DISTINCT video_id) AS videos").
+		Where("cluster_id IN ? AND video_id <> ?", clusterIDs, videoID).
+		Group("cluster_id").
+		Scan(&rows)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	for _, row := range rows {
+		counts[row.ClusterID] = row.Videos
+	}
+	return counts, nil
+}
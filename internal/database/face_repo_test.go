@@ -0,0 +1,134 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kdimtricp/vshazam/internal/models"
+	"github.com/kdimtricp/vshazam/internal/models/face"
+)
+
+func TestFaceRepo_ClustersAcrossVideos(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	videoRepo := NewVideoRepository(db)
+	repo := NewFaceRepo(db)
+	ctx := context.Background()
+
+	var videos []*models.Video
+	for _, title := range []string{"First", "Second"} {
+		video := models.NewVideo(title, "Test", title+".mp4", "video/mp4", 1024)
+		if err := videoRepo.InsertVideo(video); err != nil {
+			t.Fatalf("Failed to insert video: %v", err)
+		}
+		videos = append(videos, video)
+	}
+
+	cluster := &face.FaceClusterDB{Centroid: []float64{0.6, 0.8}, FaceCount: 1}
+	if err := repo.SaveCluster(ctx, cluster); err != nil {
+		t.Fatalf("Failed to create cluster: %v", err)
+	}
+	cluster.FaceCount = 3
+	if err := repo.SaveCluster(ctx, cluster); err != nil {
+		t.Fatalf("Failed to update cluster: %v", err)
+	}
+
+	clusterID := cluster.ID
+	records := []*face.FaceDB{
+		{VideoID: videos[0].ID, FrameNumber: 1, Width: 40, Height: 40, ClusterID: &clusterID},
+		{VideoID: videos[0].ID, FrameNumber: 0, Width: 40, Height: 40, ClusterID: &clusterID},
+		{VideoID: videos[1].ID, FrameNumber: 0, Width: 40, Height: 40, ClusterID: &clusterID},
+		{VideoID: videos[1].ID, FrameNumber: 2, Width: 10, Height: 10},
+	}
+	for _, record := range records {
+		if err := repo.CreateFace(ctx, record); err != nil {
+			t.Fatalf("Failed to create face: %v", err)
+		}
+	}
+
+	clusters, err := repo.ListClusters(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list clusters: %v", err)
+	}
+	if len(clusters) != 1 || clusters[0].FaceCount != 3 || len(clusters[0].Centroid) != 2 {
+		t.Errorf("unexpected clusters %+v", clusters)
+	}
+
+	faces, err := repo.ListByVideoID(ctx, videos[0].ID)
+	if err != nil {
+		t.Fatalf("Failed to list faces: %v", err)
+	}
+	if len(faces) != 2 || faces[0].FrameNumber != 0 {
+		t.Errorf("expected 2 faces ordered by frame, got %+v", faces)
+	}
+
+	counts, err := repo.CountOtherVideos(ctx, videos[0].ID, []string{clusterID})
+	if err != nil {
+		t.Fatalf("Failed to count videos: %v", err)
+	}
+	if counts[clusterID] != 1 {
+		t.Errorf("expected the person in 1 other video, got %d", counts[clusterID])
+	}
+
+	found, err := repo.GetFaceByID(ctx, records[3].ID)
+	if err != nil || found == nil || found.ClusterID != nil {
+		t.Errorf("expected unclustered face, got %+v (%v)", found, err)
+	}
+}
+
+func TestFaceRepo_DeleteByVideoID(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	videoRepo := NewVideoRepository(db)
+	repo := NewFaceRepo(db)
+	ctx := context.Background()
+
+	var videos []*models.Video
+	for _, title := range []string{"First", "Second"} {
+		video := models.NewVideo(title, "Test", title+".mp4", "video/mp4", 1024)
+		if err := videoRepo.InsertVideo(video); err != nil {
+			t.Fatalf("Failed to insert video: %v", err)
+		}
+		videos = append(videos, video)
+	}
+
+	shared := &face.FaceClusterDB{Centroid: []float64{0.6, 0.8}, FaceCount: 2}
+	alone := &face.FaceClusterDB{Centroid: []float64{1, 0}, FaceCount: 1}
+	for _, cluster := range []*face.FaceClusterDB{shared, alone} {
+		if err := repo.SaveCluster(ctx, cluster); err != nil {
+			t.Fatalf("Failed to create cluster: %v", err)
+		}
+	}
+
+	records := []*face.FaceDB{
+		{VideoID: videos[0].ID, Width: 40, Height: 40, ThumbnailPath: "a.jpg", Embedding: []float64{1, 0}, ClusterID: &shared.ID},
+		{VideoID: videos[0].ID, FrameNumber: 1, Width: 40, Height: 40, ThumbnailPath: "b.jpg", Embedding: []float64{1, 0}, ClusterID: &alone.ID},
+		{VideoID: videos[1].ID, Width: 40, Height: 40, ThumbnailPath: "c.jpg", Embedding: []float64{0, 1}, ClusterID: &shared.ID},
+	}
+	for _, record := range records {
+		if err := repo.CreateFace(ctx, record); err != nil {
+			t.Fatalf("Failed to create face: %v", err)
+		}
+	}
+
+	thumbnails, err := repo.DeleteByVideoID(ctx, videos[0].ID)
+	if err != nil {
+		t.Fatalf("Failed to delete faces: %v", err)
+	}
+	if len(thumbnails) != 2 {
+		t.Errorf("expected the deleted faces' thumbnails, got %v", thumbnails)
+	}
+
+	clusters, err := repo.ListClusters(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list clusters: %v", err)
+	}
+	if len(clusters) != 1 || clusters[0].ID != shared.ID {
+		t.Fatalf("expected only the shared cluster to be left, got %+v", clusters)
+	}
+	if clusters[0].FaceCount != 1 || clusters[0].Centroid[0] != 0 || clusters[0].Centroid[1] != 1 {
+		t.Errorf("expected the cluster recomputed from the remaining face, got %+v", clusters[0])
+	}
+}
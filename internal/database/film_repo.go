@@ -0,0 +1,237 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kdimtricp/vshazam/internal/models"
+	"github.com/kdimtricp/vshazam/internal/models/film"
+	"gorm.io/gorm"
+)
+
+type FilmRepo struct {
+	db *DB
+}
+
+func NewFilmRepo(db *DB) *FilmRepo {
+	return &FilmRepo{db: db}
+}
+
+// Upsert stores a film by its TMDb ID and replaces its credits. An existing
+// film keeps its ID and creation time.
+func (r *FilmRepo) Upsert(ctx context.Context, f *film.FilmDB, credits []*film.CreditDB) error {
+	if f.SyncedAt.IsZero() {
+		f.SyncedAt = time.Now()
+	}
+	if f.Genres == nil {
+		f.Genres = []string{}
+	}
+
+	return r.db.GORM().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing film.FilmDB
+		err := tx.Select("id", "created_at").Where("tmdb_id = ?", f.TMDbID).First(&existing).Error
+		switch {
+		case err == nil:
+			f.ID = existing.ID
+			f.CreatedAt = existing.CreatedAt
+		case err == gorm.ErrRecordNotFound:
+			if f.ID == "" {
+				f.ID = uuid.New().String()
+			}
+			if f.CreatedAt.IsZero() {
+				f.CreatedAt = f.SyncedAt
+			}
+		default:
+			return fmt.Errorf("failed to look up film: %w", err)
+		}
+
+		if err := tx.Save(f).Error; err != nil {
+			return fmt.Errorf("failed to save film: %w", err)
+		}
+		if err := tx.Where("film_id = ?", f.ID).Delete(&film.CreditDB{}).Error; err != nil {
+			return fmt.Errorf("failed to delete film credits: %w", err)
+		}
+		if len(credits) == 0 {
+			return nil
+		}
+		for _, credit := range credits {
+			credit.FilmID = f.ID
+			if credit.ID == "" {
+				credit.ID = uuid.New().String()
+			}
+		}
+		if err := tx.CreateInBatches(credits, 100).Error; err != nil {
+			return fmt.Errorf("failed to insert film credits: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *FilmRepo) GetByID(ctx context.Context, id string) (*film.FilmDB, error) {
+	return r.first(ctx, "id = ?", id)
+}
+
+func (r *FilmRepo) GetByTMDbID(ctx context.Context, tmdbID int) (*film.FilmDB, error) {
+	return r.first(ctx, "tmdb_id = ?", tmdbID)
+}
+
+func (r *FilmRepo) first(ctx context.Context, query string, args ...interface{}) (*film.FilmDB, error) {
+	var f film.FilmDB
+	result := r.db.GORM().WithContext(ctx).Where(query, args...).First(&f)
+
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+
+	return &f, nil
+}
+
+// SearchByTitle returns films whose title equals title, ignoring case, most
+// popular first.
+func (r *FilmRepo) SearchByTitle(ctx context.Context, title string, limit int) ([]*film.FilmDB, error) {
+	var films []*film.FilmDB
+	result := r.db.GORM().WithContext(ctx).
+		Where("LOWER(title) = LOWER(?)", title).
+		Order("vote_average DESC").
+		Limit(limit).
+		Find(&films)
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to search films: %w", result.Error)
+	}
+
+	return films, nil
+}
+
+// ListCredits returns the cast in billing order followed by the crew.
+func (r *FilmRepo) ListCredits(ctx context.Context, filmID string) ([]*film.CreditDB, error) {
+	var credits []*film.CreditDB
+	result := r.db.GORM().WithContext(ctx).
+		Where("film_id = ?", filmID).
+		Order("role, position").
+		Find(&credits)
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to query film credits: %w", result.Error)
+	}
+
+	return credits, nil
+}
+
+// ListStale returns up to limit films last synced before the given time,
+// oldest first.
+func (r *FilmRepo) ListStale(ctx context.Context, before time.Time, limit int) ([]*film.FilmDB, error) {
+	var films []*film.FilmDB
+	result := r.db.GORM().WithContext(ctx).
+		Where("synced_at < ?", before).
+		Order("synced_at").
+		Limit(limit).
+		Find(&films)
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to query stale films: %w", result.Error)
+	}
+
+	return films, nil
+}
+
+// FilmFilter narrows List. Zero values match every film.
+type FilmFilter struct {
+	Genre string
+	Year  int
+}
+
+// List returns cataloged films matching filter, ordered by title.
+func (r *FilmRepo) List(ctx context.Context, filter FilmFilter) ([]*film.FilmDB, error) {
+	db := r.db.GORM().WithContext(ctx)
+	if filter.Year > 0 {
+		db = db.Where("year = ?", filter.Year)
+	}
+	if filter.Genre != "" {
+		if r.db.dbType == "postgres" {
+			db = db.Where("genres @> jsonb_build_array(?::text)", filter.Genre)
+		} else {
+			db = db.Where("EXISTS (SELECT 1 FROM json_each(films.genres) WHERE value = ?)", filter.Genre)
+		}
+	}
+
+	var films []*film.FilmDB
+	result := db.Order("title, year").Find(&films)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list films: %w", result.Error)
+	}
+
+	return films, nil
+}
+
+// ListGenres returns every genre of a cataloged film, sorted.
+func (r *FilmRepo) ListGenres(ctx context.Context) ([]string, error) {
+	var films []*film.FilmDB
+	result := r.db.GORM().WithContext(ctx).Select("genres").Find(&films)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list genres: %w", result.Error)
+	}
+
+	seen := make(map[string]bool)
+	var genres []string
+	for _, f := range films {
+		for _, genre := range f.Genres {
+			if !seen[genre] {
+				seen[genre] = true
+				genres = append(genres, genre)
+			}
+		}
+	}
+	sort.Strings(genres)
+	return genres, nil
+}
+
+// latestIdentification restricts an identifications alias i to each video's
+// most recent run.
+const latestIdentification = "i.created_at = (SELECT MAX(created_at) FROM identifications WHERE video_id = i.video_id)"
+
+// ListVideosByTMDbID returns the videos whose latest identification is the
+// film with tmdbID.
+func (r *FilmRepo) ListVideosByTMDbID(ctx context.Context, tmdbID int, scope VideoScope) ([]models.Video, error) {
+	var videos []models.Video
+	result := scope.apply(r.db.GORM().WithContext(ctx)).
+		Where(`id IN (
+			SELECT i.video_id FROM identifications i
+			WHERE i.top_tmdb_id = ? AND `+latestIdentification+`
+		)`, tmdbID).
+		Order("upload_time DESC").
+		Find(&videos)
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to query videos by film: %w", result.Error)
+	}
+
+	return videos, nil
+}
+
+// ListVideosByCrew returns the videos whose latest identification names a
+// film that person worked on in job, such as film.JobDirector.
+func (r *FilmRepo) ListVideosByCrew(ctx context.Context, name, job string, scope VideoScope) ([]models.Video, error) {
+	var videos []models.Video
+	result := scope.apply(r.db.GORM().WithContext(ctx)).
+		Where(`id IN (
+			SELECT i.video_id FROM identifications i
+			JOIN films f ON f.tmdb_id = i.top_tmdb_id
+			JOIN film_credits c ON c.film_id = f.id
+			WHERE LOWER(c.name) = LOWER(?) AND c.role = ? AND c.job = ? AND `+latestIdentification+`
+		)`, name, film.RoleCrew, job).
+		Order("upload_time DESC").
+		Find(&videos)
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to query videos by crew: %w", result.Error)
+	}
+
+	return videos, nil
+}
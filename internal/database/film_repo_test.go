@@ -0,0 +1,126 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kdimtricp/vshazam/internal/models"
+	"github.com/kdimtricp/vshazam/internal/models/film"
+	"github.com/kdimtricp/vshazam/internal/models/identification"
+)
+
+func TestFilmRepo_UpsertAndBrowse(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	videoRepo := NewVideoRepository(db)
+	identificationRepo := NewIdentificationRepo(db)
+	repo := NewFilmRepo(db)
+	ctx := context.Background()
+
+	synced := time.Now().Add(-48 * time.Hour)
+	inception := &film.FilmDB{TMDbID: 27205, IMDbID: "tt1375666", Title: "Inception", Year: 2010, Genres: []string{"Action"}, SyncedAt: synced}
+	credits := []*film.CreditDB{
+		{Role: film.RoleCast, Name: "Leonardo DiCaprio", Character: "Dom Cobb"},
+		{Role: film.RoleCrew, Name: "Christopher Nolan", Job: film.JobDirector, Department: "Directing"},
+	}
+	if err := repo.Upsert(ctx, inception, credits); err != nil {
+		t.Fatalf("Failed to upsert film: %v", err)
+	}
+	id := inception.ID
+
+	updated := &film.FilmDB{TMDbID: 27205, Title: "Inception", Year: 2010, Runtime: 148}
+	if err := repo.Upsert(ctx, updated, credits[1:]); err != nil {
+		t.Fatalf("Failed to update film: %v", err)
+	}
+	if updated.ID != id {
+		t.Errorf("expected the film to keep its ID, got %s and %s", id, updated.ID)
+	}
+
+	stored, err := repo.GetByTMDbID(ctx, 27205)
+	if err != nil || stored == nil || stored.Runtime != 148 {
+		t.Fatalf("unexpected film %+v, %v", stored, err)
+	}
+	storedCredits, err := repo.ListCredits(ctx, id)
+	if err != nil || len(storedCredits) != 1 || storedCredits[0].Name != "Christopher Nolan" {
+		t.Errorf("expected credits to be replaced, got %+v, %v", storedCredits, err)
+	}
+
+	found, err := repo.SearchByTitle(ctx, "INCEPTION", 5)
+	if err != nil || len(found) != 1 {
+		t.Errorf("expected a case-insensitive title match, got %+v, %v", found, err)
+	}
+	stale, err := repo.ListStale(ctx, time.Now().Add(time.Hour), 10)
+	if err != nil || len(stale) != 1 {
+		t.Errorf("expected the film to be stale, got %+v, %v", stale, err)
+	}
+
+	video := models.NewVideo("Spinning top", "Test", "clip.mp4", "video/mp4", 1024)
+	other := models.NewVideo("Bank heist", "Test", "heist.mp4", "video/mp4", 1024)
+	for _, v := range []*models.Video{video, other} {
+		if err := videoRepo.InsertVideo(v); err != nil {
+			t.Fatalf("Failed to insert video: %v", err)
+		}
+	}
+	records := []*identification.IdentificationDB{
+		{VideoID: video.ID, TopTitle: "Inception", TopTMDbID: 27205, CreatedAt: time.Now()},
+		{VideoID: other.ID, TopTitle: "Inception", TopTMDbID: 27205, CreatedAt: time.Now().Add(-time.Hour)},
+		{VideoID: other.ID, TopTitle: "Heat", TopTMDbID: 949, CreatedAt: time.Now()},
+	}
+	for _, record := range records {
+		if err := identificationRepo.Create(ctx, record); err != nil {
+			t.Fatalf("Failed to create identification: %v", err)
+		}
+	}
+
+	videos, err := repo.ListVideosByCrew(ctx, "christopher nolan", film.JobDirector, AllVideos())
+	if err != nil {
+		t.Fatalf("Failed to list videos: %v", err)
+	}
+	if len(videos) != 1 || videos[0].ID != video.ID {
+		t.Errorf("expected only the video last identified as Inception, got %+v", videos)
+	}
+
+	byFilm, err := repo.ListVideosByTMDbID(ctx, 27205, AllVideos())
+	if err != nil || len(byFilm) != 1 || byFilm[0].ID != video.ID {
+		t.Errorf("expected only the video last identified as Inception, got %+v, %v", byFilm, err)
+	}
+}
+
+func TestFilmRepo_ListFilters(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewFilmRepo(db)
+	ctx := context.Background()
+
+	films := []*film.FilmDB{
+		{TMDbID: 27205, Title: "Inception", Year: 2010, Genres: []string{"Action", "Science Fiction"}},
+		{TMDbID: 949, Title: "Heat", Year: 1995, Genres: []string{"Action", "Crime"}},
+		{TMDbID: 157336, Title: "Interstellar", Year: 2014, Genres: []string{"Science Fiction"}},
+	}
+	for _, f := range films {
+		if err := repo.Upsert(ctx, f, nil); err != nil {
+			t.Fatalf("Failed to upsert film: %v", err)
+		}
+	}
+
+	all, err := repo.List(ctx, FilmFilter{})
+	if err != nil || len(all) != 3 || all[0].Title != "Heat" {
+		t.Errorf("expected every film ordered by title, got %+v, %v", all, err)
+	}
+	action, err := repo.List(ctx, FilmFilter{Genre: "Action"})
+	if err != nil || len(action) != 2 {
+		t.Errorf("expected two action films, got %+v, %v", action, err)
+	}
+	scifi2014, err := repo.List(ctx, FilmFilter{Genre: "Science Fiction", Year: 2014})
+	if err != nil || len(scifi2014) != 1 || scifi2014[0].Title != "Interstellar" {
+		t.Errorf("expected Interstellar, got %+v, %v", scifi2014, err)
+	}
+
+	genres, err := repo.ListGenres(ctx)
+	if err != nil || len(genres) != 3 || genres[0] != "Action" || genres[2] != "Science Fiction" {
+		t.Errorf("unexpected genres %v, %v", genres, err)
+	}
+}
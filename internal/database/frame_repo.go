@@ -32,6 +32,9 @@ func (r *FrameAnalysisRepo) Create(ctx context.Context, analysis *frame_analysis
 		DoUpdates: clause.AssignmentColumns([]string{
 			"gpt_caption", "vision_labels", "ocr_text",
 			"face_count", "analysis_time", "raw_response",
+			"best_guess_labels", "web_entities", "matching_pages",
+			"logos", "landmarks", "objects",
+			"frame_time", "text_blocks",
 		}),
 	}).Create(analysis)
 
@@ -68,5 +71,8 @@ func (r *FrameAnalysisRepo) GetByID(ctx context.Context, id string) (*frame_anal
 
 func (r *FrameAnalysisRepo) DeleteByVideoID(ctx context.Context, videoID string) error {
 	result := r.db.GORM().WithContext(ctx).Where("video_id = ?", videoID).Delete(&frame_analysis.FrameAnalysisDB{})
-	return result.Error
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete frame analyses: %w", result.Error)
+	}
+	return nil
 }
@@ -3,6 +3,7 @@ package database
 import (
 	"context"
 	"encoding/json"
+	"strings"
 	"testing"
 	"time"
 
@@ -116,6 +117,65 @@ func TestFrameAnalysisRepo_Create_Upsert(t *testing.T) {
 	}
 }
 
+func TestFrameAnalysisRepo_UpsertReplacesDetections(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	videoRepo := NewVideoRepository(db)
+	frameRepo := NewFrameAnalysisRepo(db)
+
+	video := models.NewVideo("Test Video", "Test", "test.mp4", "video/mp4", 1024)
+	if err := videoRepo.InsertVideo(video); err != nil {
+		t.Fatalf("Failed to insert video: %v", err)
+	}
+
+	ctx := context.Background()
+	for i, guess := range []string{"Old guess", "New guess"} {
+		analysis := &frame_analysis.FrameAnalysisDB{
+			VideoID:         video.ID,
+			FrameNumber:     1,
+			VisionLabels:    json.RawMessage(`[]`),
+			AnalysisTime:    time.Now(),
+			RawResponse:     json.RawMessage(`{}`),
+			BestGuessLabels: []string{guess},
+			WebEntities:     json.RawMessage(`[{"description": "` + guess + `", "score": 0.9}]`),
+			MatchingPages:   json.RawMessage(`[{"title": "` + guess + `"}]`),
+			Logos:           json.RawMessage(`[{"description": "` + guess + `"}]`),
+			Landmarks:       json.RawMessage(`[{"description": "` + guess + `"}]`),
+			Objects:         json.RawMessage(`[{"name": "` + guess + `"}]`),
+			FrameTime:       float64(10 * (i + 1)),
+			TextBlocks:      json.RawMessage(`[{"text": "` + guess + `", "region": "top"}]`),
+		}
+		if err := frameRepo.Create(ctx, analysis); err != nil {
+			t.Fatalf("Failed to upsert analysis: %v", err)
+		}
+	}
+
+	analyses, err := frameRepo.GetByVideoID(ctx, video.ID)
+	if err != nil || len(analyses) != 1 {
+		t.Fatalf("Expected 1 analysis after upsert, got %d, %v", len(analyses), err)
+	}
+	stored := analyses[0]
+	if len(stored.BestGuessLabels) != 1 || stored.BestGuessLabels[0] != "New guess" {
+		t.Errorf("Expected the new best guess, got %v", stored.BestGuessLabels)
+	}
+	if stored.FrameTime != 20 {
+		t.Errorf("Expected the frame time of the second run, got %v", stored.FrameTime)
+	}
+	for name, raw := range map[string]json.RawMessage{
+		"web_entities":   stored.WebEntities,
+		"matching_pages": stored.MatchingPages,
+		"logos":          stored.Logos,
+		"landmarks":      stored.Landmarks,
+		"objects":        stored.Objects,
+		"text_blocks":    stored.TextBlocks,
+	} {
+		if !strings.Contains(string(raw), "New guess") {
+			t.Errorf("Expected %s from the second run, got %s", name, raw)
+		}
+	}
+}
+
 func TestFrameAnalysisRepo_GetByVideoID(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -0,0 +1,185 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kdimtricp/vshazam/internal/models/identification"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdentificationRepo struct {
+	db *DB
+}
+
+func NewIdentificationRepo(db *DB) *IdentificationRepo {
+	return &IdentificationRepo{db: db}
+}
+
+func (r *IdentificationRepo) Create(ctx context.Context, record *identification.IdentificationDB) error {
+	if record.ID == "" {
+		record.ID = uuid.New().String()
+	}
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now()
+	}
+
+	result := r.db.GORM().WithContext(ctx).Create(record)
+	if result.Error != nil {
+		return fmt.Errorf("failed to insert identification: %w", result.Error)
+	}
+	return nil
+}
+
+// ReserveRequest records request when its user started fewer than limit
+// identifications at or after since, and reports whether it did. The count
+// and the insert run in one transaction holding a lock on the user, so
+// concurrent requests cannot both take the last slot.
+func (r *IdentificationRepo) ReserveRequest(ctx context.Context, request *identification.RequestDB, since time.Time, limit int) (bool, error) {
+	if request.ID == "" {
+		request.ID = uuid.New().String()
+	}
+	if request.CreatedAt.IsZero() {
+		request.CreatedAt = time.Now()
+	}
+
+	reserved := false
+	err := r.db.GORM().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// SQLite serializes writers on its own.
+		if tx.Dialector.Name() == "postgres" {
+			if err := tx.Exec("SELECT id FROM users WHERE id = ? FOR UPDATE", request.UserID).Error; err != nil {
+				return err
+			}
+		}
+		var count int64
+		if err := tx.Model(&identification.RequestDB{}).
+			Where("user_id = ? AND created_at >= ?", request.UserID, since).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count >= int64(limit) {
+			return nil
+		}
+		reserved = true
+		return tx.Create(request).Error
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve identification: %w", err)
+	}
+	return reserved, nil
+}
+
+func (r *IdentificationRepo) GetByID(ctx context.Context, id string) (*identification.IdentificationDB, error) {
+	var record identification.IdentificationDB
+	result := r.db.GORM().WithContext(ctx).First(&record, "id = ?", id)
+
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+
+	return &record, nil
+}
+
+func (r *IdentificationRepo) GetLatestByVideoID(ctx context.Context, videoID string) (*identification.IdentificationDB, error) {
+	var record identification.IdentificationDB
+	result := r.db.GORM().WithContext(ctx).
+		Where("video_id = ?", videoID).
+		Order("created_at DESC").
+		First(&record)
+
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+
+	return &record, nil
+}
+
+// AddFeedback stores a user verdict. When reference is non-nil the confirmed
+// film fingerprint is saved in the same transaction, replacing any earlier
+// fingerprint for the video.
+func (r *IdentificationRepo) AddFeedback(ctx context.Context, feedback *identification.FeedbackDB, reference *identification.ReferenceFingerprintDB) error {
+	if feedback.ID == "" {
+		feedback.ID = uuid.New().String()
+	}
+	if feedback.CreatedAt.IsZero() {
+		feedback.CreatedAt = time.Now()
+	}
+
+	return r.db.GORM().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(feedback).Error; err != nil {
+			return fmt.Errorf("failed to insert feedback: %w", err)
+		}
+
+		if reference == nil {
+			return nil
+		}
+		if reference.ID == "" {
+			reference.ID = uuid.New().String()
+		}
+		if reference.Tokens == nil {
+			reference.Tokens = []string{}
+		}
+		reference.CreatedAt = feedback.CreatedAt
+
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "video_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"tmdb_id", "title", "year", "tokens", "created_at"}),
+		}).Create(reference).Error
+		if err != nil {
+			return fmt.Errorf("failed to save reference fingerprint: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *IdentificationRepo) GetFeedback(ctx context.Context, identificationID string) ([]*identification.FeedbackDB, error) {
+	var feedback []*identification.FeedbackDB
+	result := r.db.GORM().WithContext(ctx).
+		Where("identification_id = ?", identificationID).
+		Order("created_at").
+		Find(&feedback)
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to query feedback: %w", result.Error)
+	}
+
+	return feedback, nil
+}
+
+// ListFeedbackSamples returns every verdict joined with the score of the
+// identification it judged, oldest first.
+func (r *IdentificationRepo) ListFeedbackSamples(ctx context.Context) ([]identification.FeedbackSample, error) {
+	var samples []identification.FeedbackSample
+	result := r.db.GORM().WithContext(ctx).
+		Table("identification_feedback f").
+		Select("f.verdict, f.created_at, i.top_score, i.top_title").
+		Joins("JOIN identifications i ON i.id = f.identification_id").
+		Order("f.created_at").
+		Scan(&samples)
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to query feedback samples: %w", result.Error)
+	}
+
+	return samples, nil
+}
+
+func (r *IdentificationRepo) ListReferences(ctx context.Context) ([]*identification.ReferenceFingerprintDB, error) {
+	var references []*identification.ReferenceFingerprintDB
+	result := r.db.GORM().WithContext(ctx).Order("created_at DESC").Find(&references)
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to query reference fingerprints: %w", result.Error)
+	}
+
+	return references, nil
+}
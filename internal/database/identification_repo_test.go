@@ -0,0 +1,180 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kdimtricp/vshazam/internal/models"
+	"github.com/kdimtricp/vshazam/internal/models/identification"
+	"github.com/kdimtricp/vshazam/internal/models/user"
+)
+
+func TestIdentificationRepo_CreateAndGetLatest(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	videoRepo := NewVideoRepository(db)
+	repo := NewIdentificationRepo(db)
+
+	video := models.NewVideo("Test Video", "Test", "test.mp4", "video/mp4", 1024)
+	if err := videoRepo.InsertVideo(video); err != nil {
+		t.Fatalf("Failed to insert video: %v", err)
+	}
+
+	ctx := context.Background()
+	first := &identification.IdentificationDB{
+		VideoID:    video.ID,
+		TopTitle:   "Heat",
+		TopScore:   0.4,
+		Candidates: json.RawMessage(`[]`),
+		CreatedAt:  time.Now().Add(-time.Hour),
+	}
+	second := &identification.IdentificationDB{
+		VideoID:    video.ID,
+		TopTitle:   "Inception",
+		TopYear:    2010,
+		TopTMDbID:  27205,
+		TopScore:   0.95,
+		Candidates: json.RawMessage(`[]`),
+	}
+
+	for _, record := range []*identification.IdentificationDB{first, second} {
+		if err := repo.Create(ctx, record); err != nil {
+			t.Fatalf("Failed to create identification: %v", err)
+		}
+	}
+
+	latest, err := repo.GetLatestByVideoID(ctx, video.ID)
+	if err != nil {
+		t.Fatalf("Failed to get latest identification: %v", err)
+	}
+	if latest == nil || latest.ID != second.ID {
+		t.Errorf("Expected latest identification %s, got %+v", second.ID, latest)
+	}
+
+	missing, err := repo.GetByID(ctx, "00000000-0000-0000-0000-000000000000")
+	if err != nil {
+		t.Errorf("Expected no error for non-existent ID, got %v", err)
+	}
+	if missing != nil {
+		t.Error("Expected nil result for non-existent ID")
+	}
+}
+
+func TestIdentificationRepo_AddFeedback(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	videoRepo := NewVideoRepository(db)
+	repo := NewIdentificationRepo(db)
+
+	video := models.NewVideo("Test Video", "Test", "test.mp4", "video/mp4", 1024)
+	if err := videoRepo.InsertVideo(video); err != nil {
+		t.Fatalf("Failed to insert video: %v", err)
+	}
+
+	ctx := context.Background()
+	record := &identification.IdentificationDB{
+		VideoID:    video.ID,
+		TopTitle:   "Heat",
+		TopScore:   0.6,
+		Candidates: json.RawMessage(`[]`),
+	}
+	if err := repo.Create(ctx, record); err != nil {
+		t.Fatalf("Failed to create identification: %v", err)
+	}
+
+	feedback := &identification.FeedbackDB{
+		IdentificationID: record.ID,
+		VideoID:          video.ID,
+		Verdict:          identification.VerdictCorrected,
+		TMDbID:           27205,
+		Title:            "Inception",
+		Year:             2010,
+	}
+	reference := &identification.ReferenceFingerprintDB{
+		VideoID: video.ID,
+		TMDbID:  27205,
+		Title:   "Inception",
+		Year:    2010,
+		Tokens:  []string{"cobb", "label:city"},
+	}
+
+	if err := repo.AddFeedback(ctx, feedback, reference); err != nil {
+		t.Fatalf("Failed to add feedback: %v", err)
+	}
+
+	stored, err := repo.GetFeedback(ctx, record.ID)
+	if err != nil {
+		t.Fatalf("Failed to get feedback: %v", err)
+	}
+	if len(stored) != 1 || stored[0].Title != "Inception" {
+		t.Errorf("Expected corrected feedback for Inception, got %+v", stored)
+	}
+
+	references, err := repo.ListReferences(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list references: %v", err)
+	}
+	if len(references) != 1 || len(references[0].Tokens) != 2 {
+		t.Errorf("Expected 1 reference with 2 tokens, got %+v", references)
+	}
+
+	samples, err := repo.ListFeedbackSamples(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list feedback samples: %v", err)
+	}
+	if len(samples) != 1 || samples[0].TopScore != 0.6 || samples[0].Verdict != identification.VerdictCorrected {
+		t.Errorf("Unexpected feedback samples: %+v", samples)
+	}
+}
+
+func TestIdentificationRepo_ReserveRequest(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	users := NewUserRepo(db)
+	repo := NewIdentificationRepo(db)
+	ctx := context.Background()
+
+	requester := &user.UserDB{Email: "ada@example.com", PasswordHash: "hash"}
+	if err := users.CreateUser(ctx, requester); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	videoID := "00000000-0000-0000-0000-000000000001"
+	since := time.Now().Add(-time.Hour)
+
+	old := &identification.RequestDB{UserID: requester.ID, VideoID: videoID, CreatedAt: since.Add(-time.Minute)}
+	if reserved, err := repo.ReserveRequest(ctx, old, since.Add(-2*time.Hour), 3); err != nil || !reserved {
+		t.Fatalf("expected the earlier request to be reserved, got %v, %v", reserved, err)
+	}
+
+	results := make(chan bool, 5)
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reserved, err := repo.ReserveRequest(ctx, &identification.RequestDB{UserID: requester.ID, VideoID: videoID}, since, 3)
+			if err != nil {
+				t.Errorf("Failed to reserve request: %v", err)
+			}
+			results <- reserved
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	reserved := 0
+	for ok := range results {
+		if ok {
+			reserved++
+		}
+	}
+	if reserved != 3 {
+		t.Errorf("expected 3 of 5 concurrent requests to fit the quota, got %d", reserved)
+	}
+}
@@ -0,0 +1,130 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kdimtricp/vshazam/internal/models/imdb_dataset"
+	"gorm.io/gorm/clause"
+)
+
+// imdbBatchSize bounds the rows sent in one INSERT while importing.
+const imdbBatchSize = 500
+
+// IMDbRepo stores the local copy of the IMDb datasets.
+type IMDbRepo struct {
+	db *DB
+}
+
+func NewIMDbRepo(db *DB) *IMDbRepo {
+	return &IMDbRepo{db: db}
+}
+
+// SaveTitles inserts titles, replacing rows already imported from an older
+// dump.
+func (r *IMDbRepo) SaveTitles(ctx context.Context, titles []*imdb_dataset.TitleDB) error {
+	if err := r.upsert(ctx, titles); err != nil {
+		return fmt.Errorf("failed to save IMDb titles: %w", err)
+	}
+	return nil
+}
+
+func (r *IMDbRepo) SaveAkas(ctx context.Context, akas []*imdb_dataset.AkaDB) error {
+	if err := r.upsert(ctx, akas); err != nil {
+		return fmt.Errorf("failed to save IMDb akas: %w", err)
+	}
+	return nil
+}
+
+func (r *IMDbRepo) SavePrincipals(ctx context.Context, principals []*imdb_dataset.PrincipalDB) error {
+	if err := r.upsert(ctx, principals); err != nil {
+		return fmt.Errorf("failed to save IMDb principals: %w", err)
+	}
+	return nil
+}
+
+func (r *IMDbRepo) SaveNames(ctx context.Context, names []*imdb_dataset.NameDB) error {
+	if err := r.upsert(ctx, names); err != nil {
+		return fmt.Errorf("failed to save IMDb names: %w", err)
+	}
+	return nil
+}
+
+func (r *IMDbRepo) upsert(ctx context.Context, rows interface{}) error {
+	return r.db.GORM().WithContext(ctx).
+		Clauses(clause.OnConflict{UpdateAll: true}).
+		CreateInBatches(rows, imdbBatchSize).Error
+}
+
+// SearchTitles returns up to limit titles whose normalized primary title
+// contains any of fragments, those closest to length first. Fragments must
+// already be normalized the way SearchTitle is.
+func (r *IMDbRepo) SearchTitles(ctx context.Context, fragments []string, length, limit int) ([]*imdb_dataset.TitleDB, error) {
+	if len(fragments) == 0 {
+		return nil, nil
+	}
+	query, args := containsAny("search_title", fragments)
+
+	var titles []*imdb_dataset.TitleDB
+	result := r.db.GORM().WithContext(ctx).
+		Where(query, args...).
+		Order(clause.Expr{SQL: "ABS(LENGTH(search_title) - ?), tconst", Vars: []interface{}{length}}).
+		Limit(limit).
+		Find(&titles)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to search IMDb titles: %w", result.Error)
+	}
+
+	return titles, nil
+}
+
+// SearchAkas returns up to limit alternative titles containing any of
+// fragments, those closest to length first.
+func (r *IMDbRepo) SearchAkas(ctx context.Context, fragments []string, length, limit int) ([]*imdb_dataset.AkaDB, error) {
+	if len(fragments) == 0 {
+		return nil, nil
+	}
+	query, args := containsAny("search_title", fragments)
+
+	var akas []*imdb_dataset.AkaDB
+	result := r.db.GORM().WithContext(ctx).
+		Where(query, args...).
+		Order(clause.Expr{SQL: "ABS(LENGTH(search_title) - ?), tconst", Vars: []interface{}{length}}).
+		Limit(limit).
+		Find(&akas)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to search IMDb akas: %w", result.Error)
+	}
+
+	return akas, nil
+}
+
+// GetTitles returns the titles with the given tconsts. Unknown tconsts are
+// skipped.
+func (r *IMDbRepo) GetTitles(ctx context.Context, tconsts []string) ([]*imdb_dataset.TitleDB, error) {
+	if len(tconsts) == 0 {
+		return nil, nil
+	}
+
+	var titles []*imdb_dataset.TitleDB
+	result := r.db.GORM().WithContext(ctx).Where("tconst IN ?", tconsts).Find(&titles)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get IMDb titles: %w", result.Error)
+	}
+
+	return titles, nil
+}
+
+// containsAny builds "column LIKE ? OR ..." matching any of fragments.
+// Without a trigram index this scans the table, which is acceptable for an
+// offline fallback of a few hundred thousand films.
+func containsAny(column string, fragments []string) (string, []interface{}) {
+	clauses := make([]string, 0, len(fragments))
+	args := make([]interface{}, 0, len(fragments))
+	for _, fragment := range fragments {
+		clauses = append(clauses, column+" LIKE ?")
+		args = append(args, "%"+fragment+"%")
+	}
+	return strings.Join(clauses, " OR "), args
+}
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kdimtricp/vshazam/internal/models/imdb_dataset"
+)
+
+func TestIMDbRepo_SaveAndSearch(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewIMDbRepo(db)
+	ctx := context.Background()
+
+	titles := []*imdb_dataset.TitleDB{
+		{TConst: "tt1375666", TitleType: "movie", PrimaryTitle: "Inception", SearchTitle: "inception", StartYear: 2010, Genres: []string{"Action"}},
+		{TConst: "tt5295894", TitleType: "movie", PrimaryTitle: "Inception: The Cobol Job", SearchTitle: "inception the cobol job", StartYear: 2010, Genres: []string{}},
+		{TConst: "tt0113277", TitleType: "movie", PrimaryTitle: "Heat", SearchTitle: "heat", StartYear: 1995, Genres: []string{}},
+	}
+	if err := repo.SaveTitles(ctx, titles); err != nil {
+		t.Fatalf("Failed to save titles: %v", err)
+	}
+	titles[2].RuntimeMinutes = 170
+	if err := repo.SaveTitles(ctx, titles[2:]); err != nil {
+		t.Fatalf("Failed to re-import titles: %v", err)
+	}
+	akas := []*imdb_dataset.AkaDB{
+		{TConst: "tt1375666", Ordering: 2, Title: "El origen", SearchTitle: "el origen", Region: "AR"},
+	}
+	if err := repo.SaveAkas(ctx, akas); err != nil {
+		t.Fatalf("Failed to save akas: %v", err)
+	}
+
+	found, err := repo.SearchTitles(ctx, []string{"ince", "ption"}, len("inception"), 10)
+	if err != nil || len(found) != 2 || found[0].TConst != "tt1375666" {
+		t.Errorf("expected both Inception titles, closest in length first, got %+v, %v", found, err)
+	}
+	foundAkas, err := repo.SearchAkas(ctx, []string{"origen"}, len("el origen"), 10)
+	if err != nil || len(foundAkas) != 1 || foundAkas[0].TConst != "tt1375666" {
+		t.Errorf("expected the Argentine title, got %+v, %v", foundAkas, err)
+	}
+
+	byID, err := repo.GetTitles(ctx, []string{"tt0113277", "tt0000000"})
+	if err != nil || len(byID) != 1 || byID[0].RuntimeMinutes != 170 {
+		t.Errorf("expected the re-imported Heat, got %+v, %v", byID, err)
+	}
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kdimtricp/vshazam/internal/models/share"
+	"gorm.io/gorm"
+)
+
+// ShareRepo stores signed share links.
+type ShareRepo struct {
+	db *DB
+}
+
+func NewShareRepo(db *DB) *ShareRepo {
+	return &ShareRepo{db: db}
+}
+
+func (r *ShareRepo) CreateLink(ctx context.Context, link *share.LinkDB) error {
+	if link.CreatedAt.IsZero() {
+		link.CreatedAt = time.Now()
+	}
+	if err := r.db.GORM().WithContext(ctx).Create(link).Error; err != nil {
+		return fmt.Errorf("failed to create share link: %w", err)
+	}
+	return nil
+}
+
+// GetLink returns nil when there is no link with id.
+func (r *ShareRepo) GetLink(ctx context.Context, id string) (*share.LinkDB, error) {
+	var link share.LinkDB
+	result := r.db.GORM().WithContext(ctx).First(&link, "id = ?", id)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get share link: %w", result.Error)
+	}
+	return &link, nil
+}
+
+// ListLinks returns the links of a video, newest first.
+func (r *ShareRepo) ListLinks(ctx context.Context, videoID string) ([]*share.LinkDB, error) {
+	var links []*share.LinkDB
+	result := r.db.GORM().WithContext(ctx).
+		Where("video_id = ?", videoID).
+		Order("created_at DESC").
+		Find(&links)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list share links: %w", result.Error)
+	}
+	return links, nil
+}
+
+// RevokeLink reports false when the link does not exist or was revoked
+// before.
+func (r *ShareRepo) RevokeLink(ctx context.Context, id string, at time.Time) (bool, error) {
+	result := r.db.GORM().WithContext(ctx).
+		Model(&share.LinkDB{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", at)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to revoke share link: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// CountView adds a view to the link unless it is revoked or its views are
+// used up, and reports whether it did. The check and the increment are one
+// statement, so concurrent visitors cannot exceed MaxViews.
+func (r *ShareRepo) CountView(ctx context.Context, id string) (bool, error) {
+	result := r.db.GORM().WithContext(ctx).
+		Model(&share.LinkDB{}).
+		Where("id = ? AND revoked_at IS NULL AND (max_views = 0 OR views < max_views)", id).
+		Update("views", gorm.Expr("views + 1"))
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to count share link view: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
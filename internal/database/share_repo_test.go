@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kdimtricp/vshazam/internal/models"
+	"github.com/kdimtricp/vshazam/internal/models/share"
+	"github.com/kdimtricp/vshazam/internal/models/user"
+)
+
+func TestShareRepo_Links(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	users := NewUserRepo(db)
+	videos := NewVideoRepository(db)
+	repo := NewShareRepo(db)
+	ctx := context.Background()
+
+	owner := &user.UserDB{Email: "ada@example.com", PasswordHash: "hash"}
+	if err := users.CreateUser(ctx, owner); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	video := models.NewVideo("Clip", "", "clip.mp4", "video/mp4", 1024)
+	if err := videos.InsertVideo(video); err != nil {
+		t.Fatalf("Failed to insert video: %v", err)
+	}
+
+	link := &share.LinkDB{VideoID: video.ID, CreatedBy: owner.ID, Access: share.AccessVideo, ExpiresAt: time.Now().Add(time.Hour), MaxViews: 2}
+	if err := repo.CreateLink(ctx, link); err != nil {
+		t.Fatalf("Failed to create link: %v", err)
+	}
+
+	for i, want := range []bool{true, true, false} {
+		counted, err := repo.CountView(ctx, link.ID)
+		if err != nil || counted != want {
+			t.Errorf("view %d: got %v, %v, want %v", i+1, counted, err, want)
+		}
+	}
+
+	found, err := repo.GetLink(ctx, link.ID)
+	if err != nil || found == nil || found.Views != 2 || found.ViewsLeft() {
+		t.Errorf("unexpected link %+v, %v", found, err)
+	}
+	if missing, err := repo.GetLink(ctx, "00000000-0000-0000-0000-000000000000"); err != nil || missing != nil {
+		t.Errorf("expected nil for an unknown link, got %+v, %v", missing, err)
+	}
+
+	links, err := repo.ListLinks(ctx, video.ID)
+	if err != nil || len(links) != 1 {
+		t.Errorf("expected one link, got %v, %v", links, err)
+	}
+
+	if revoked, err := repo.RevokeLink(ctx, link.ID, time.Now()); err != nil || !revoked {
+		t.Errorf("expected the link to be revoked, got %v, %v", revoked, err)
+	}
+	if revoked, err := repo.RevokeLink(ctx, link.ID, time.Now()); err != nil || revoked {
+		t.Errorf("expected a second revocation to do nothing, got %v, %v", revoked, err)
+	}
+}
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kdimtricp/vshazam/internal/models/subtitle"
+	"gorm.io/gorm"
+)
+
+type SubtitleRepo struct {
+	db *DB
+}
+
+func NewSubtitleRepo(db *DB) *SubtitleRepo {
+	return &SubtitleRepo{db: db}
+}
+
+func (r *SubtitleRepo) CreateTrack(ctx context.Context, track *subtitle.TrackDB) error {
+	if track.ID == "" {
+		track.ID = uuid.New().String()
+	}
+	if track.CreatedAt.IsZero() {
+		track.CreatedAt = time.Now()
+	}
+
+	result := r.db.GORM().WithContext(ctx).Create(track)
+	if result.Error != nil {
+		return fmt.Errorf("failed to insert subtitle track: %w", result.Error)
+	}
+	return nil
+}
+
+func (r *SubtitleRepo) GetTrackByID(ctx context.Context, id string) (*subtitle.TrackDB, error) {
+	var track subtitle.TrackDB
+	result := r.db.GORM().WithContext(ctx).First(&track, "id = ?", id)
+
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+
+	return &track, nil
+}
+
+// ListByVideoID returns the video's tracks, embedded streams first in stream
+// order, then uploaded files.
+func (r *SubtitleRepo) ListByVideoID(ctx context.Context, videoID string) ([]*subtitle.TrackDB, error) {
+	var tracks []*subtitle.TrackDB
+	result := r.db.GORM().WithContext(ctx).
+		Where("video_id = ?", videoID).
+		Order("source ASC, stream_index ASC, created_at ASC").
+		Find(&tracks)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return tracks, nil
+}
@@ -0,0 +1,51 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kdimtricp/vshazam/internal/models"
+	"github.com/kdimtricp/vshazam/internal/models/subtitle"
+)
+
+func TestSubtitleRepo_Tracks(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	videoRepo := NewVideoRepository(db)
+	repo := NewSubtitleRepo(db)
+	ctx := context.Background()
+
+	video := models.NewVideo("Subtitled clip", "Test", "clip.mp4", "video/mp4", 1024)
+	if err := videoRepo.InsertVideo(video); err != nil {
+		t.Fatalf("Failed to insert video: %v", err)
+	}
+
+	tracks := []*subtitle.TrackDB{
+		{VideoID: video.ID, Source: subtitle.SourceSidecar, Language: "de", Label: "clip.de.srt", StreamIndex: -1, FilePath: "b.vtt"},
+		{VideoID: video.ID, Source: subtitle.SourceEmbedded, Language: "eng", Label: "English", StreamIndex: 2, FilePath: "a.vtt", CueCount: 12},
+	}
+	for _, track := range tracks {
+		if err := repo.CreateTrack(ctx, track); err != nil {
+			t.Fatalf("Failed to create track: %v", err)
+		}
+	}
+
+	listed, err := repo.ListByVideoID(ctx, video.ID)
+	if err != nil {
+		t.Fatalf("Failed to list tracks: %v", err)
+	}
+	if len(listed) != 2 || listed[0].Source != subtitle.SourceEmbedded || listed[0].CueCount != 12 {
+		t.Errorf("expected embedded track first, got %+v", listed)
+	}
+
+	track, err := repo.GetTrackByID(ctx, tracks[0].ID)
+	if err != nil || track == nil || track.FilePath != "b.vtt" {
+		t.Errorf("unexpected track %+v, %v", track, err)
+	}
+
+	missing, err := repo.GetTrackByID(ctx, "00000000-0000-0000-0000-000000000000")
+	if err != nil || missing != nil {
+		t.Errorf("expected nil for a missing track, got %+v, %v", missing, err)
+	}
+}
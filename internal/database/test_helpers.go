@@ -55,6 +55,26 @@ func setupTestDB(t *testing.T) (*DB, func()) {
 	cleanup := func() {
 		db.GORM().Exec("TRUNCATE TABLE videos CASCADE")
 		db.GORM().Exec("TRUNCATE TABLE frame_analyses CASCADE")
+		db.GORM().Exec("TRUNCATE TABLE identifications CASCADE")
+		db.GORM().Exec("TRUNCATE TABLE identification_feedback CASCADE")
+		db.GORM().Exec("TRUNCATE TABLE reference_fingerprints CASCADE")
+		db.GORM().Exec("TRUNCATE TABLE identification_requests CASCADE")
+		db.GORM().Exec("TRUNCATE TABLE api_usage CASCADE")
+		db.GORM().Exec("TRUNCATE TABLE faces CASCADE")
+		db.GORM().Exec("TRUNCATE TABLE face_clusters CASCADE")
+		db.GORM().Exec("TRUNCATE TABLE transcript_cues CASCADE")
+		db.GORM().Exec("TRUNCATE TABLE subtitle_tracks CASCADE")
+		db.GORM().Exec("TRUNCATE TABLE film_credits CASCADE")
+		db.GORM().Exec("TRUNCATE TABLE imdb_titles CASCADE")
+		db.GORM().Exec("TRUNCATE TABLE imdb_akas CASCADE")
+		db.GORM().Exec("TRUNCATE TABLE imdb_principals CASCADE")
+		db.GORM().Exec("TRUNCATE TABLE imdb_names CASCADE")
+		db.GORM().Exec("TRUNCATE TABLE films CASCADE")
+		db.GORM().Exec("TRUNCATE TABLE share_links CASCADE")
+		db.GORM().Exec("TRUNCATE TABLE api_key_uses CASCADE")
+		db.GORM().Exec("TRUNCATE TABLE api_keys CASCADE")
+		db.GORM().Exec("TRUNCATE TABLE sessions CASCADE")
+		db.GORM().Exec("TRUNCATE TABLE users CASCADE")
 		db.Close()
 
 		if err := pgContainer.Terminate(ctx); err != nil {
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kdimtricp/vshazam/internal/models/transcript"
+	"gorm.io/gorm"
+)
+
+type TranscriptRepo struct {
+	db *DB
+}
+
+func NewTranscriptRepo(db *DB) *TranscriptRepo {
+	return &TranscriptRepo{db: db}
+}
+
+// ReplaceCues swaps the video's cues from source for cues in one
+// transaction, so re-running a step never duplicates its track.
+func (r *TranscriptRepo) ReplaceCues(ctx context.Context, videoID, source string, cues []*transcript.CueDB) error {
+	now := time.Now()
+	for _, cue := range cues {
+		if cue.ID == "" {
+			cue.ID = uuid.New().String()
+		}
+		if cue.CreatedAt.IsZero() {
+			cue.CreatedAt = now
+		}
+		cue.VideoID = videoID
+		cue.Source = source
+	}
+
+	return r.db.GORM().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("video_id = ? AND source = ?", videoID, source).Delete(&transcript.CueDB{}).Error; err != nil {
+			return fmt.Errorf("failed to delete cues: %w", err)
+		}
+		if len(cues) == 0 {
+			return nil
+		}
+		if err := tx.Create(cues).Error; err != nil {
+			return fmt.Errorf("failed to insert cues: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *TranscriptRepo) ListByVideoID(ctx context.Context, videoID string) ([]*transcript.CueDB, error) {
+	var cues []*transcript.CueDB
+	result := r.db.GORM().WithContext(ctx).
+		Where("video_id = ?", videoID).
+		Order("start_time ASC").
+		Find(&cues)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return cues, nil
+}
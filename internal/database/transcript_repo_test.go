@@ -0,0 +1,54 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kdimtricp/vshazam/internal/models"
+	"github.com/kdimtricp/vshazam/internal/models/transcript"
+)
+
+func TestTranscriptRepo_ReplaceCuesAndSearch(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	videoRepo := NewVideoRepository(db)
+	repo := NewTranscriptRepo(db)
+	ctx := context.Background()
+
+	video := models.NewVideo("Rooftop clip", "Test", "clip.mp4", "video/mp4", 1024)
+	if err := videoRepo.InsertVideo(video); err != nil {
+		t.Fatalf("Failed to insert video: %v", err)
+	}
+
+	first := []*transcript.CueDB{
+		{Start: 12, End: 14, Text: "An idea is like a virus."},
+		{Start: 2, End: 4, Text: "What is the most resilient parasite?"},
+	}
+	if err := repo.ReplaceCues(ctx, video.ID, transcript.SourceOCR, first); err != nil {
+		t.Fatalf("Failed to store cues: %v", err)
+	}
+
+	second := []*transcript.CueDB{
+		{Start: 5, End: 7, Text: "You mustn't be afraid to dream a little bigger."},
+	}
+	if err := repo.ReplaceCues(ctx, video.ID, transcript.SourceOCR, second); err != nil {
+		t.Fatalf("Failed to replace cues: %v", err)
+	}
+
+	cues, err := repo.ListByVideoID(ctx, video.ID)
+	if err != nil {
+		t.Fatalf("Failed to list cues: %v", err)
+	}
+	if len(cues) != 1 || cues[0].Source != transcript.SourceOCR || cues[0].VideoID != video.ID {
+		t.Fatalf("expected replaced cue only, got %+v", cues)
+	}
+
+	results, err := videoRepo.SearchVideos("dream a little", AllVideos())
+	if err != nil {
+		t.Fatalf("Failed to search videos: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != video.ID {
+		t.Errorf("expected transcript text to match the video, got %+v", results)
+	}
+}
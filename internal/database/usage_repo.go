@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kdimtricp/vshazam/internal/models/api_usage"
+)
+
+type UsageRepo struct {
+	db *DB
+}
+
+func NewUsageRepo(db *DB) *UsageRepo {
+	return &UsageRepo{db: db}
+}
+
+func (r *UsageRepo) Create(ctx context.Context, record *api_usage.UsageDB) error {
+	if record.ID == "" {
+		record.ID = uuid.New().String()
+	}
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now()
+	}
+
+	result := r.db.GORM().WithContext(ctx).Create(record)
+	if result.Error != nil {
+		return fmt.Errorf("failed to insert api usage: %w", result.Error)
+	}
+	return nil
+}
+
+// SpendSince returns the total estimated cost of calls made at or after since.
+func (r *UsageRepo) SpendSince(ctx context.Context, since time.Time) (float64, error) {
+	var total float64
+	result := r.db.GORM().WithContext(ctx).
+		Model(&api_usage.UsageDB{}).
+		Where("created_at >= ?", since).
+		Select("COALESCE(SUM(cost_usd), 0)").
+		Scan(&total)
+
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to sum api usage: %w", result.Error)
+	}
+	return total, nil
+}
+
+// ListSince returns every call made at or after since, oldest first.
+func (r *UsageRepo) ListSince(ctx context.Context, since time.Time) ([]*api_usage.UsageDB, error) {
+	var records []*api_usage.UsageDB
+	result := r.db.GORM().WithContext(ctx).
+		Where("created_at >= ?", since).
+		Order("created_at").
+		Find(&records)
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to query api usage: %w", result.Error)
+	}
+	return records, nil
+}
@@ -0,0 +1,132 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kdimtricp/vshazam/internal/models/user"
+	"gorm.io/gorm"
+)
+
+// UserRepo stores accounts and their sign-in sessions.
+type UserRepo struct {
+	db *DB
+}
+
+func NewUserRepo(db *DB) *UserRepo {
+	return &UserRepo{db: db}
+}
+
+func (r *UserRepo) CreateUser(ctx context.Context, u *user.UserDB) error {
+	if u.CreatedAt.IsZero() {
+		u.CreatedAt = time.Now()
+	}
+	if err := r.db.GORM().WithContext(ctx).Create(u).Error; err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	return nil
+}
+
+// RegisterUser creates u as an admin when there are no users yet and as a
+// regular user otherwise. The count and the insert run in one transaction
+// that locks the users table, so two registrations on a fresh install cannot
+// both become admin.
+func (r *UserRepo) RegisterUser(ctx context.Context, u *user.UserDB) error {
+	if u.CreatedAt.IsZero() {
+		u.CreatedAt = time.Now()
+	}
+	err := r.db.GORM().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// SQLite serializes writers on its own; Postgres needs a lock that
+		// also conflicts with other registrations reading the count.
+		if tx.Dialector.Name() == "postgres" {
+			if err := tx.Exec("LOCK TABLE users IN SHARE ROW EXCLUSIVE MODE").Error; err != nil {
+				return err
+			}
+		}
+		var count int64
+		if err := tx.Model(&user.UserDB{}).Count(&count).Error; err != nil {
+			return err
+		}
+		u.Role = user.RoleUser
+		if count == 0 {
+			u.Role = user.RoleAdmin
+		}
+		return tx.Create(u).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to register user: %w", err)
+	}
+	return nil
+}
+
+// GetUserByID returns nil when there is no such user.
+func (r *UserRepo) GetUserByID(ctx context.Context, id string) (*user.UserDB, error) {
+	return r.findUser(ctx, "id = ?", id)
+}
+
+// GetUserByEmail returns nil when no user signs in with email. Emails are
+// stored lower case.
+func (r *UserRepo) GetUserByEmail(ctx context.Context, email string) (*user.UserDB, error) {
+	return r.findUser(ctx, "email = ?", email)
+}
+
+func (r *UserRepo) findUser(ctx context.Context, query string, arg interface{}) (*user.UserDB, error) {
+	var u user.UserDB
+	result := r.db.GORM().WithContext(ctx).First(&u, query, arg)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get user: %w", result.Error)
+	}
+	return &u, nil
+}
+
+func (r *UserRepo) CountUsers(ctx context.Context) (int64, error) {
+	var count int64
+	if err := r.db.GORM().WithContext(ctx).Model(&user.UserDB{}).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+	return count, nil
+}
+
+func (r *UserRepo) CreateSession(ctx context.Context, s *user.SessionDB) error {
+	if s.CreatedAt.IsZero() {
+		s.CreatedAt = time.Now()
+	}
+	if err := r.db.GORM().WithContext(ctx).Create(s).Error; err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	return nil
+}
+
+// GetSession returns nil when there is no session with id, expired or not.
+func (r *UserRepo) GetSession(ctx context.Context, id string) (*user.SessionDB, error) {
+	var s user.SessionDB
+	result := r.db.GORM().WithContext(ctx).First(&s, "id = ?", id)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get session: %w", result.Error)
+	}
+	return &s, nil
+}
+
+func (r *UserRepo) DeleteSession(ctx context.Context, id string) error {
+	if err := r.db.GORM().WithContext(ctx).Delete(&user.SessionDB{}, "id = ?", id).Error; err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpiredSessions removes sessions that expired before now and returns
+// how many there were.
+func (r *UserRepo) DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db.GORM().WithContext(ctx).Delete(&user.SessionDB{}, "expires_at < ?", now)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete expired sessions: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kdimtricp/vshazam/internal/models/user"
+)
+
+func TestUserRepo_UsersAndSessions(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewUserRepo(db)
+	ctx := context.Background()
+
+	u := &user.UserDB{Email: "ada@example.com", PasswordHash: "hash", Role: user.RoleAdmin}
+	if err := repo.CreateUser(ctx, u); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if err := repo.CreateUser(ctx, &user.UserDB{Email: "ada@example.com", PasswordHash: "other"}); err == nil {
+		t.Error("Expected an error for a duplicate email")
+	}
+
+	found, err := repo.GetUserByEmail(ctx, "ada@example.com")
+	if err != nil || found == nil || found.ID != u.ID || !found.IsAdmin() {
+		t.Errorf("unexpected user %+v, %v", found, err)
+	}
+	missing, err := repo.GetUserByID(ctx, "00000000-0000-0000-0000-000000000000")
+	if err != nil || missing != nil {
+		t.Errorf("expected nil for a missing user, got %+v, %v", missing, err)
+	}
+	if count, err := repo.CountUsers(ctx); err != nil || count != 1 {
+		t.Errorf("expected 1 user, got %d, %v", count, err)
+	}
+
+	now := time.Now()
+	live := &user.SessionDB{ID: "live", UserID: u.ID, CSRFToken: "csrf", ExpiresAt: now.Add(time.Hour)}
+	expired := &user.SessionDB{ID: "expired", UserID: u.ID, CSRFToken: "csrf", ExpiresAt: now.Add(-time.Hour)}
+	for _, s := range []*user.SessionDB{live, expired} {
+		if err := repo.CreateSession(ctx, s); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+	}
+
+	deleted, err := repo.DeleteExpiredSessions(ctx, now)
+	if err != nil || deleted != 1 {
+		t.Errorf("expected 1 expired session deleted, got %d, %v", deleted, err)
+	}
+	session, err := repo.GetSession(ctx, "live")
+	if err != nil || session == nil || session.UserID != u.ID {
+		t.Errorf("unexpected session %+v, %v", session, err)
+	}
+
+	if err := repo.DeleteSession(ctx, "live"); err != nil {
+		t.Fatalf("Failed to delete session: %v", err)
+	}
+	if session, err := repo.GetSession(ctx, "live"); err != nil || session != nil {
+		t.Errorf("expected the session to be gone, got %+v, %v", session, err)
+	}
+}
+
+func TestUserRepo_RegisterUserOneAdmin(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewUserRepo(db)
+	ctx := context.Background()
+
+	users := make([]*user.UserDB, 5)
+	var wg sync.WaitGroup
+	for i := range users {
+		users[i] = &user.UserDB{Email: fmt.Sprintf("user%d@example.com", i), PasswordHash: "hash"}
+		wg.Add(1)
+		go func(u *user.UserDB) {
+			defer wg.Done()
+			if err := repo.RegisterUser(ctx, u); err != nil {
+				t.Errorf("Failed to register user: %v", err)
+			}
+		}(users[i])
+	}
+	wg.Wait()
+
+	admins := 0
+	for _, u := range users {
+		if u.IsAdmin() {
+			admins++
+		}
+	}
+	if admins != 1 {
+		t.Errorf("expected exactly one admin, got %d", admins)
+	}
+}
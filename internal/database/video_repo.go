@@ -1,12 +1,70 @@
 package database
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/kdimtricp/vshazam/internal/models"
+	"github.com/kdimtricp/vshazam/internal/models/identification"
+	"github.com/kdimtricp/vshazam/internal/models/share"
+	"github.com/kdimtricp/vshazam/internal/models/subtitle"
+	"github.com/kdimtricp/vshazam/internal/models/transcript"
 	"gorm.io/gorm"
 )
 
+// ErrVideoNotFound is returned by PurgeVideo for unknown videos.
+var ErrVideoNotFound = errors.New("video not found")
+
+// VideoScope limits video queries to the videos one user may see. The zero
+// value matches no video.
+type VideoScope struct {
+	ownerID string
+	videoID string
+	all     bool
+}
+
+// AllVideos is the scope of admins.
+func AllVideos() VideoScope {
+	return VideoScope{all: true}
+}
+
+// OwnedBy is the scope of a user who sees only their own uploads.
+func OwnedBy(userID string) VideoScope {
+	return VideoScope{ownerID: userID}
+}
+
+// SharedVideo is the scope of a visitor who opened a share link to one
+// video.
+func SharedVideo(videoID string) VideoScope {
+	return VideoScope{videoID: videoID}
+}
+
+// Includes reports whether video is within the scope.
+func (s VideoScope) Includes(video *models.Video) bool {
+	switch {
+	case s.all:
+		return true
+	case s.videoID != "":
+		return video.ID == s.videoID
+	}
+	return s.ownerID != "" && video.OwnerID != nil && *video.OwnerID == s.ownerID
+}
+
+func (s VideoScope) apply(db *gorm.DB) *gorm.DB {
+	switch {
+	case s.all:
+		return db
+	case s.videoID != "":
+		return db.Where("videos.id = ?", s.videoID)
+	case s.ownerID == "":
+		return db.Where("1 = 0")
+	default:
+		return db.Where("videos.owner_id = ?", s.ownerID)
+	}
+}
+
 type VideoRepository struct {
 	db *DB
 }
@@ -23,6 +81,43 @@ func (r *VideoRepository) InsertVideo(video *models.Video) error {
 	return nil
 }
 
+// InsertVideoWithinQuota inserts video, which must have an owner, unless it
+// would take the owner's videos, trashed ones included, over quota bytes. It
+// returns the bytes the owner used before and whether it inserted video. The
+// sum and the insert run in one transaction holding a lock on the owner, so
+// concurrent uploads cannot both take the last of the quota.
+func (r *VideoRepository) InsertVideoWithinQuota(video *models.Video, quota int64) (int64, bool, error) {
+	if video.OwnerID == nil {
+		return 0, false, fmt.Errorf("failed to insert video: it has no owner")
+	}
+
+	var used int64
+	inserted := false
+	err := r.db.GORM().Transaction(func(tx *gorm.DB) error {
+		// SQLite serializes writers on its own.
+		if tx.Dialector.Name() == "postgres" {
+			if err := tx.Exec("SELECT id FROM users WHERE id = ? FOR UPDATE", *video.OwnerID).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Unscoped().Model(&models.Video{}).
+			Where("owner_id = ?", *video.OwnerID).
+			Select("COALESCE(SUM(size), 0)").
+			Scan(&used).Error; err != nil {
+			return err
+		}
+		if used+video.Size > quota {
+			return nil
+		}
+		inserted = true
+		return tx.Create(video).Error
+	})
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to insert video: %w", err)
+	}
+	return used, inserted, nil
+}
+
 func (r *VideoRepository) GetVideoByID(id string) (*models.Video, error) {
 	var video models.Video
 	result := r.db.GORM().First(&video, "id = ?", id)
@@ -35,34 +130,184 @@ func (r *VideoRepository) GetVideoByID(id string) (*models.Video, error) {
 	return &video, nil
 }
 
-func (r *VideoRepository) ListVideos() ([]models.Video, error) {
+func (r *VideoRepository) ListVideos(scope VideoScope) ([]models.Video, error) {
 	var videos []models.Video
-	result := r.db.GORM().Order("upload_time DESC").Find(&videos)
+	result := scope.apply(r.db.GORM()).Order("upload_time DESC").Find(&videos)
 	if result.Error != nil {
 		return nil, fmt.Errorf("failed to list videos: %w", result.Error)
 	}
 	return videos, nil
 }
 
-func (r *VideoRepository) SearchVideos(query string) ([]models.Video, error) {
+// TotalSizeByOwner sums the size of the videos ownerID uploaded. Videos in
+// the trash count until they are purged, as their files are still stored.
+func (r *VideoRepository) TotalSizeByOwner(ownerID string) (int64, error) {
+	var total int64
+	result := r.db.GORM().Unscoped().Model(&models.Video{}).
+		Where("owner_id = ?", ownerID).
+		Select("COALESCE(SUM(size), 0)").
+		Scan(&total)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to sum video sizes: %w", result.Error)
+	}
+	return total, nil
+}
+
+func (r *VideoRepository) SearchVideos(query string, scope VideoScope) ([]models.Video, error) {
 	if query == "" {
-		return r.ListVideos()
+		return r.ListVideos(scope)
 	}
 
 	var videos []models.Video
 	searchPattern := "%" + query + "%"
 
+	// Videos also match on their transcript, e.g. burned-in subtitles or
+	// dialogue. On postgres every word of the query must appear in a cue,
+	// using the full-text index.
 	db := r.db.GORM()
 	if r.db.dbType == "postgres" {
-		db = db.Where("title ILIKE ? OR description ILIKE ?", searchPattern, searchPattern)
+		db = db.Where("title ILIKE ? OR description ILIKE ? OR EXISTS (SELECT 1 FROM transcript_cues c WHERE c.video_id = videos.id AND to_tsvector('simple', c.text) @@ plainto_tsquery('simple', ?))",
+			searchPattern, searchPattern, query)
 	} else {
-		db = db.Where("LOWER(title) LIKE LOWER(?) OR LOWER(description) LIKE LOWER(?)", searchPattern, searchPattern)
+		db = db.Where("LOWER(title) LIKE LOWER(?) OR LOWER(description) LIKE LOWER(?) OR EXISTS (SELECT 1 FROM transcript_cues c WHERE c.video_id = videos.id AND LOWER(c.text) LIKE LOWER(?))",
+			searchPattern, searchPattern, searchPattern)
 	}
 
-	result := db.Order("upload_time DESC").Limit(20).Find(&videos)
+	result := scope.apply(db).Order("upload_time DESC").Limit(20).Find(&videos)
 	if result.Error != nil {
 		return nil, fmt.Errorf("failed to search videos: %w", result.Error)
 	}
 
 	return videos, nil
 }
+
+// UpdateVideoDetails changes the title and description of a video.
+func (r *VideoRepository) UpdateVideoDetails(id, title, description string) error {
+	result := r.db.GORM().Model(&models.Video{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"title": title, "description": description})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update video: %w", result.Error)
+	}
+	return nil
+}
+
+// TrashVideo moves a video to the trash. Its rows and files stay until it
+// is purged.
+func (r *VideoRepository) TrashVideo(id string) error {
+	if err := r.db.GORM().Delete(&models.Video{}, "id = ?", id).Error; err != nil {
+		return fmt.Errorf("failed to trash video: %w", err)
+	}
+	return nil
+}
+
+// RestoreVideo takes a video out of the trash. It reports false when the
+// video is not in the trash.
+func (r *VideoRepository) RestoreVideo(id string) (bool, error) {
+	result := r.db.GORM().Unscoped().Model(&models.Video{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to restore video: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// GetTrashedVideoByID returns a video in the trash, or nil when there is no
+// such video in the trash.
+func (r *VideoRepository) GetTrashedVideoByID(id string) (*models.Video, error) {
+	var video models.Video
+	result := r.db.GORM().Unscoped().Where("deleted_at IS NOT NULL").First(&video, "id = ?", id)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get trashed video: %w", result.Error)
+	}
+	return &video, nil
+}
+
+// ListTrash returns the trashed videos within scope, most recently deleted
+// first.
+func (r *VideoRepository) ListTrash(scope VideoScope) ([]models.Video, error) {
+	var videos []models.Video
+	result := scope.apply(r.db.GORM().Unscoped()).
+		Where("deleted_at IS NOT NULL").
+		Order("deleted_at DESC").
+		Find(&videos)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list trash: %w", result.Error)
+	}
+	return videos, nil
+}
+
+// ListTrashedBefore returns up to limit videos trashed before cutoff.
+func (r *VideoRepository) ListTrashedBefore(cutoff time.Time, limit int) ([]models.Video, error) {
+	var videos []models.Video
+	result := r.db.GORM().Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Order("deleted_at").
+		Limit(limit).
+		Find(&videos)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list expired trash: %w", result.Error)
+	}
+	return videos, nil
+}
+
+// PurgeVideo deletes a video, trashed or not, and every row derived from it
+// in one transaction, recomputing the face clusters its faces belonged to. It
+// returns the storage paths of the video file, face thumbnails and subtitle
+// files, for the caller to delete once the rows are gone. API usage records
+// and identification quota requests are kept.
+func (r *VideoRepository) PurgeVideo(ctx context.Context, id string) ([]string, error) {
+	var paths []string
+	err := r.db.GORM().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var video models.Video
+		if err := tx.Unscoped().First(&video, "id = ?", id).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrVideoNotFound
+			}
+			return err
+		}
+		paths = append(paths, video.Filename)
+
+		// Faces are deleted through FaceRepo so their clusters shrink, and
+		// clusters left empty go, in this transaction.
+		thumbnails, err := NewFaceRepo(r.db.withGORM(tx)).DeleteByVideoID(ctx, id)
+		if err != nil {
+			return err
+		}
+		var tracks []string
+		if err := tx.Model(&subtitle.TrackDB{}).Where("video_id = ?", id).Pluck("file_path", &tracks).Error; err != nil {
+			return err
+		}
+		paths = append(paths, thumbnails...)
+		paths = append(paths, tracks...)
+
+		frames := NewFrameAnalysisRepo(r.db.withGORM(tx))
+		if err := frames.DeleteByVideoID(ctx, id); err != nil {
+			return err
+		}
+		for _, model := range []interface{}{
+			&identification.FeedbackDB{},
+			&identification.ReferenceFingerprintDB{},
+			&identification.IdentificationDB{},
+			&transcript.CueDB{},
+			&subtitle.TrackDB{},
+			&share.LinkDB{},
+		} {
+			if err := tx.Where("video_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Delete(&video).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrVideoNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to purge video: %w", err)
+	}
+	return paths, nil
+}
@@ -1,10 +1,17 @@
 package database
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/kdimtricp/vshazam/internal/models"
+	"github.com/kdimtricp/vshazam/internal/models/face"
+	"github.com/kdimtricp/vshazam/internal/models/frame_analysis"
+	"github.com/kdimtricp/vshazam/internal/models/user"
 )
 
 func TestVideoRepository_InsertVideo(t *testing.T) {
@@ -67,7 +74,7 @@ func TestVideoRepository_ListVideos(t *testing.T) {
 		t.Fatalf("Failed to insert video2: %v", err)
 	}
 
-	videos, err := repo.ListVideos()
+	videos, err := repo.ListVideos(AllVideos())
 	if err != nil {
 		t.Fatalf("Failed to list videos: %v", err)
 	}
@@ -97,7 +104,7 @@ func TestVideoRepository_SearchVideos(t *testing.T) {
 		}
 	}
 
-	results, err := repo.SearchVideos("action")
+	results, err := repo.SearchVideos("action", AllVideos())
 	if err != nil {
 		t.Fatalf("Failed to search videos: %v", err)
 	}
@@ -106,7 +113,7 @@ func TestVideoRepository_SearchVideos(t *testing.T) {
 		t.Errorf("Expected 2 results for 'action', got %d", len(results))
 	}
 
-	results, err = repo.SearchVideos("comedy")
+	results, err = repo.SearchVideos("comedy", AllVideos())
 	if err != nil {
 		t.Fatalf("Failed to search videos: %v", err)
 	}
@@ -130,7 +137,7 @@ func TestVideoRepository_SearchVideos_Empty(t *testing.T) {
 		t.Fatalf("Failed to insert video: %v", err)
 	}
 
-	results, err := repo.SearchVideos("")
+	results, err := repo.SearchVideos("", AllVideos())
 	if err != nil {
 		t.Fatalf("Failed to search with empty query: %v", err)
 	}
@@ -139,3 +146,186 @@ func TestVideoRepository_SearchVideos_Empty(t *testing.T) {
 		t.Errorf("Expected 1 result for empty query, got %d", len(results))
 	}
 }
+
+func TestVideoRepository_Scopes(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewVideoRepository(db)
+	users := NewUserRepo(db)
+	ctx := context.Background()
+
+	owner := &user.UserDB{Email: "owner@example.com", PasswordHash: "hash"}
+	if err := users.CreateUser(ctx, owner); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	mine := models.NewVideo("Action Movie", "Mine", "mine.mp4", "video/mp4", 1024)
+	mine.OwnerID = &owner.ID
+	legacy := models.NewVideo("Action Classic", "Uploaded before accounts", "legacy.mp4", "video/mp4", 1024)
+	for _, video := range []*models.Video{mine, legacy} {
+		if err := repo.InsertVideo(video); err != nil {
+			t.Fatalf("Failed to insert video: %v", err)
+		}
+	}
+
+	videos, err := repo.ListVideos(OwnedBy(owner.ID))
+	if err != nil || len(videos) != 1 || videos[0].ID != mine.ID {
+		t.Errorf("expected only the owner's video, got %v, %v", videos, err)
+	}
+	results, err := repo.SearchVideos("action", OwnedBy(owner.ID))
+	if err != nil || len(results) != 1 {
+		t.Errorf("expected search to be scoped to the owner, got %v, %v", results, err)
+	}
+	if all, err := repo.ListVideos(AllVideos()); err != nil || len(all) != 2 {
+		t.Errorf("expected admins to see every video, got %v, %v", all, err)
+	}
+	if none, err := repo.ListVideos(VideoScope{}); err != nil || len(none) != 0 {
+		t.Errorf("expected the zero scope to match nothing, got %v, %v", none, err)
+	}
+
+	if total, err := repo.TotalSizeByOwner(owner.ID); err != nil || total != 1024 {
+		t.Errorf("expected the owner's uploads to total 1024 bytes, got %d, %v", total, err)
+	}
+
+	if shared, err := repo.ListVideos(SharedVideo(legacy.ID)); err != nil || len(shared) != 1 || shared[0].ID != legacy.ID {
+		t.Errorf("expected only the shared video, got %v, %v", shared, err)
+	}
+
+	if !OwnedBy(owner.ID).Includes(mine) || OwnedBy(owner.ID).Includes(legacy) || !AllVideos().Includes(legacy) {
+		t.Error("unexpected Includes result")
+	}
+	if !SharedVideo(mine.ID).Includes(mine) || SharedVideo(mine.ID).Includes(legacy) {
+		t.Error("unexpected Includes result for a shared video")
+	}
+}
+
+func TestVideoRepository_InsertVideoWithinQuota(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewVideoRepository(db)
+	users := NewUserRepo(db)
+	ctx := context.Background()
+
+	owner := &user.UserDB{Email: "owner@example.com", PasswordHash: "hash"}
+	if err := users.CreateUser(ctx, owner); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	results := make(chan bool, 5)
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			video := models.NewVideo("Clip", "", "clip.mp4", "video/mp4", 1024)
+			video.OwnerID = &owner.ID
+			_, inserted, err := repo.InsertVideoWithinQuota(video, 3*1024)
+			if err != nil {
+				t.Errorf("Failed to insert video: %v", err)
+			}
+			results <- inserted
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	inserted := 0
+	for ok := range results {
+		if ok {
+			inserted++
+		}
+	}
+	if inserted != 3 {
+		t.Errorf("expected 3 of 5 concurrent uploads to fit the quota, got %d", inserted)
+	}
+
+	video := models.NewVideo("Clip", "", "clip.mp4", "video/mp4", 1)
+	video.OwnerID = &owner.ID
+	if used, ok, err := repo.InsertVideoWithinQuota(video, 3*1024); err != nil || ok || used != 3*1024 {
+		t.Errorf("expected a full quota to refuse the upload, got %d, %v, %v", used, ok, err)
+	}
+}
+
+func TestVideoRepository_Trash(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := NewVideoRepository(db)
+
+	video := models.NewVideo("Trashed", "", "trashed.mp4", "video/mp4", 1024)
+	if err := repo.InsertVideo(video); err != nil {
+		t.Fatalf("Failed to insert video: %v", err)
+	}
+
+	if err := repo.UpdateVideoDetails(video.ID, "Renamed", "Edited"); err != nil {
+		t.Fatalf("Failed to update video: %v", err)
+	}
+	if updated, err := repo.GetVideoByID(video.ID); err != nil || updated.Title != "Renamed" || updated.Description != "Edited" {
+		t.Errorf("expected the edited details, got %+v, %v", updated, err)
+	}
+
+	if err := repo.TrashVideo(video.ID); err != nil {
+		t.Fatalf("Failed to trash video: %v", err)
+	}
+	if _, err := repo.GetVideoByID(video.ID); err == nil {
+		t.Error("expected a trashed video to be hidden")
+	}
+	if trashed, err := repo.ListTrash(AllVideos()); err != nil || len(trashed) != 1 {
+		t.Errorf("expected one trashed video, got %v, %v", trashed, err)
+	}
+	if expired, err := repo.ListTrashedBefore(time.Now().Add(-time.Hour), 10); err != nil || len(expired) != 0 {
+		t.Errorf("expected no expired videos, got %v, %v", expired, err)
+	}
+
+	if restored, err := repo.RestoreVideo(video.ID); err != nil || !restored {
+		t.Fatalf("expected the video to be restored, got %v, %v", restored, err)
+	}
+	if restored, _ := repo.RestoreVideo(video.ID); restored {
+		t.Error("expected a video outside the trash not to be restored again")
+	}
+	if trashed, err := repo.GetTrashedVideoByID(video.ID); err != nil || trashed != nil {
+		t.Errorf("expected the video to have left the trash, got %v, %v", trashed, err)
+	}
+
+	frames := NewFrameAnalysisRepo(db)
+	if err := frames.Create(ctx, &frame_analysis.FrameAnalysisDB{
+		VideoID:      video.ID,
+		FrameNumber:  1,
+		VisionLabels: json.RawMessage(`[]`),
+		AnalysisTime: time.Now(),
+		RawResponse:  json.RawMessage(`{}`),
+	}); err != nil {
+		t.Fatalf("Failed to create frame analysis: %v", err)
+	}
+	faces := NewFaceRepo(db)
+	cluster := &face.FaceClusterDB{Centroid: []float64{1, 0}, FaceCount: 1}
+	if err := faces.SaveCluster(ctx, cluster); err != nil {
+		t.Fatalf("Failed to create cluster: %v", err)
+	}
+	if err := faces.CreateFace(ctx, &face.FaceDB{VideoID: video.ID, Width: 40, Height: 40, ThumbnailPath: "faces/1.jpg", Embedding: []float64{1, 0}, ClusterID: &cluster.ID}); err != nil {
+		t.Fatalf("Failed to create face: %v", err)
+	}
+
+	paths, err := repo.PurgeVideo(ctx, video.ID)
+	if err != nil {
+		t.Fatalf("Failed to purge video: %v", err)
+	}
+	if len(paths) != 2 || paths[0] != "trashed.mp4" || paths[1] != "faces/1.jpg" {
+		t.Errorf("expected the video file and face thumbnail, got %v", paths)
+	}
+	if analyses, err := frames.GetByVideoID(ctx, video.ID); err != nil || len(analyses) != 0 {
+		t.Errorf("expected the frame analyses to be deleted, got %v, %v", analyses, err)
+	}
+	if trashed, _ := repo.GetTrashedVideoByID(video.ID); trashed != nil {
+		t.Error("expected the purged video to be gone")
+	}
+	if clusters, err := faces.ListClusters(ctx); err != nil || len(clusters) != 0 {
+		t.Errorf("expected the emptied face cluster to be deleted, got %+v, %v", clusters, err)
+	}
+	if _, err := repo.PurgeVideo(ctx, video.ID); !errors.Is(err, ErrVideoNotFound) {
+		t.Errorf("expected ErrVideoNotFound, got %v", err)
+	}
+}
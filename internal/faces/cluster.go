@@ -0,0 +1,41 @@
+package faces
+
+import (
+	"github.com/kdimtricp/vshazam/internal/models/face"
+)
+
+// DefaultThreshold is the cosine similarity above which a face joins an
+// existing cluster.
+const DefaultThreshold = 0.85
+
+// nearest returns the cluster whose centroid is most similar to embedding,
+// skipping clusters in exclude, or nil when none reaches threshold.
+func nearest(clusters []*face.FaceClusterDB, embedding []float64, threshold float64, exclude map[string]bool) *face.FaceClusterDB {
+	var best *face.FaceClusterDB
+	bestSim := threshold
+	for _, cluster := range clusters {
+		if exclude[cluster.ID] {
+			continue
+		}
+		if sim := cosine(cluster.Centroid, embedding); sim >= bestSim {
+			best, bestSim = cluster, sim
+		}
+	}
+	return best
+}
+
+// addToCluster folds embedding into the cluster's running mean centroid.
+func addToCluster(cluster *face.FaceClusterDB, embedding []float64) {
+	if len(cluster.Centroid) != len(embedding) || cluster.FaceCount == 0 {
+		cluster.Centroid = append([]float64(nil), embedding...)
+		cluster.FaceCount = 1
+		return
+	}
+
+	n := float64(cluster.FaceCount)
+	for i := range cluster.Centroid {
+		cluster.Centroid[i] = (cluster.Centroid[i]*n + embedding[i]) / (n + 1)
+	}
+	cluster.Centroid = normalize(cluster.Centroid)
+	cluster.FaceCount++
+}
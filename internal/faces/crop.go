@@ -0,0 +1,45 @@
+package faces
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+
+	"github.com/kdimtricp/vshazam/internal/ai"
+)
+
+// cropPadding widens the detected box so thumbnails include hair and chin,
+// which Google's tight face boxes cut off.
+const cropPadding = 0.2
+
+type subImager interface {
+	SubImage(r image.Rectangle) image.Image
+}
+
+// Crop cuts the face described by box out of an encoded frame and returns it
+// as a JPEG.
+func Crop(frame []byte, box ai.BoundingBox) ([]byte, error) {
+	img, _, err := image.Decode(bytes.NewReader(frame))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode frame: %w", err)
+	}
+
+	padX := int(float64(box.Width) * cropPadding)
+	padY := int(float64(box.Height) * cropPadding)
+	rect := image.Rect(box.X-padX, box.Y-padY, box.X+box.Width+padX, box.Y+box.Height+padY).Intersect(img.Bounds())
+	if rect.Empty() {
+		return nil, fmt.Errorf("face box %+v is outside the frame", box)
+	}
+
+	sub, ok := img.(subImager)
+	if !ok {
+		return nil, fmt.Errorf("unsupported image type %T", img)
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, sub.SubImage(rect), &jpeg.Options{Quality: 85}); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return buf.Bytes(), nil
+}
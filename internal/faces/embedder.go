@@ -0,0 +1,155 @@
+package faces
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"net/http"
+
+	"github.com/kdimtricp/vshazam/internal/outbound"
+)
+
+// Embedder turns a face thumbnail into a vector. Faces of the same person
+// should have a high cosine similarity.
+type Embedder interface {
+	Embed(ctx context.Context, thumbnail []byte) ([]float64, error)
+}
+
+// PixelEmbedder is a dependency-free embedder that compares downscaled
+// grayscale thumbnails. It groups repeated shots of the same face within a
+// video well, but is not a face recognition model; use HTTPEmbedder with a
+// real model to match people across different scenes.
+type PixelEmbedder struct {
+	Size int
+}
+
+func NewPixelEmbedder() *PixelEmbedder {
+	return &PixelEmbedder{Size: 16}
+}
+
+func (e *PixelEmbedder) Embed(ctx context.Context, thumbnail []byte) ([]float64, error) {
+	img, _, err := image.Decode(bytes.NewReader(thumbnail))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode thumbnail: %w", err)
+	}
+
+	size := e.Size
+	if size <= 0 {
+		size = 16
+	}
+	vector := downscaleGray(img, size)
+
+	mean := 0.0
+	for _, v := range vector {
+		mean += v
+	}
+	mean /= float64(len(vector))
+	for i := range vector {
+		vector[i] -= mean
+	}
+
+	return normalize(vector), nil
+}
+
+// downscaleGray averages the luminance of img over a size x size grid.
+func downscaleGray(img image.Image, size int) []float64 {
+	bounds := img.Bounds()
+	sums := make([]float64, size*size)
+	counts := make([]int, size*size)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		row := (y - bounds.Min.Y) * size / bounds.Dy()
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			col := (x - bounds.Min.X) * size / bounds.Dx()
+			r, g, b, _ := img.At(x, y).RGBA()
+			cell := row*size + col
+			sums[cell] += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+			counts[cell]++
+		}
+	}
+
+	for i := range sums {
+		if counts[i] > 0 {
+			sums[i] /= float64(counts[i])
+		}
+	}
+	return sums
+}
+
+// HTTPEmbedder sends each thumbnail as image/jpeg to an embedding service
+// that answers {"embedding": [...]}.
+type HTTPEmbedder struct {
+	url        string
+	httpClient *outbound.Client
+}
+
+func NewHTTPEmbedder(url string) *HTTPEmbedder {
+	return &HTTPEmbedder{
+		url:        url,
+		httpClient: outbound.New("face_embedding", outbound.DefaultPolicy()),
+	}
+}
+
+// UseTransport sends requests through rt instead of the default transport.
+func (e *HTTPEmbedder) UseTransport(rt http.RoundTripper) {
+	e.httpClient.UseTransport(rt)
+}
+
+func (e *HTTPEmbedder) Embed(ctx context.Context, thumbnail []byte) ([]float64, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", e.url, bytes.NewReader(thumbnail))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "image/jpeg")
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("executing request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Embedding []float64 `json:"embedding"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	if len(body.Embedding) == 0 {
+		return nil, fmt.Errorf("embedding service returned an empty vector")
+	}
+
+	return normalize(body.Embedding), nil
+}
+
+func normalize(vector []float64) []float64 {
+	norm := 0.0
+	for _, v := range vector {
+		norm += v * v
+	}
+	norm = math.Sqrt(norm)
+	if norm == 0 {
+		return vector
+	}
+	for i := range vector {
+		vector[i] /= norm
+	}
+	return vector
+}
+
+// cosine returns the cosine similarity of two normalized vectors, or 0 when
+// their dimensions differ.
+func cosine(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	dot := 0.0
+	for i := range a {
+		dot += a[i] * b[i]
+	}
+	return dot
+}
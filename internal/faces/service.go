@@ -0,0 +1,267 @@
+// Package faces stores the faces Google Vision detects in analyzed frames,
+// saves a thumbnail of each and groups them into clusters of the same person
+// across frames and, with a face recognition model, across the whole library.
+package faces
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/kdimtricp/vshazam/internal/ai"
+	"github.com/kdimtricp/vshazam/internal/models/face"
+	"github.com/kdimtricp/vshazam/internal/storage"
+)
+
+// minFaceSize is the smallest face, in pixels, worth embedding. Smaller crops
+// are stored without a cluster.
+const minFaceSize = 16
+
+type Store interface {
+	CreateFace(ctx context.Context, record *face.FaceDB) error
+	SaveCluster(ctx context.Context, cluster *face.FaceClusterDB) error
+	ListClusters(ctx context.Context) ([]*face.FaceClusterDB, error)
+	DeleteByVideoID(ctx context.Context, videoID string) ([]string, error)
+}
+
+// Service persists faces and assigns them to clusters. Clusters are loaded
+// once and kept in memory; it is safe for concurrent use.
+//
+// By default a face only joins clusters started in the same video, since
+// PixelEmbedder cannot tell people apart across scenes. UseLibraryClustering
+// matches faces against every cluster in the library.
+type Service struct {
+	storage      storage.Storage
+	store        Store
+	embedder     Embedder
+	threshold    float64
+	acrossVideos bool
+
+	mu       sync.Mutex
+	clusters []*face.FaceClusterDB
+	loaded   bool
+	byVideo  map[string][]*face.FaceClusterDB
+}
+
+func NewService(st storage.Storage, store Store, embedder Embedder) *Service {
+	return &Service{
+		storage:   st,
+		store:     store,
+		embedder:  embedder,
+		threshold: DefaultThreshold,
+		byVideo:   make(map[string][]*face.FaceClusterDB),
+	}
+}
+
+// UseThreshold sets the cosine similarity a face needs to join a cluster.
+func (s *Service) UseThreshold(threshold float64) {
+	s.threshold = threshold
+}
+
+// UseLibraryClustering lets faces join clusters from other videos. Only use
+// it with an embedder backed by a face recognition model.
+func (s *Service) UseLibraryClustering() {
+	s.acrossVideos = true
+}
+
+// Record stores the faces detected in one frame and sets the ClusterID of
+// each face it could cluster. Faces in the same frame are never put in the
+// same cluster, since they are different people.
+func (s *Service) Record(ctx context.Context, videoID string, frameNumber int, frame []byte, faces []ai.FaceDetection) error {
+	if len(faces) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.loaded {
+		clusters, err := s.store.ListClusters(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to load face clusters: %w", err)
+		}
+		s.clusters = clusters
+		s.loaded = true
+	}
+
+	taken := make(map[string]bool)
+	for i := range faces {
+		detection := &faces[i]
+		box := detection.BoundingBox
+		record := &face.FaceDB{
+			VideoID:     videoID,
+			FrameNumber: frameNumber,
+			X:           box.X,
+			Y:           box.Y,
+			Width:       box.Width,
+			Height:      box.Height,
+			Confidence:  detection.Confidence,
+		}
+
+		thumbnail, err := Crop(frame, box)
+		if err != nil {
+			log.Printf("Failed to crop face %d in frame %d of video %s: %v", i, frameNumber, videoID, err)
+		} else {
+			record.ThumbnailPath, err = s.saveThumbnail(thumbnail)
+			if err != nil {
+				log.Printf("Failed to save face thumbnail for video %s: %v", videoID, err)
+			}
+			if box.Width >= minFaceSize && box.Height >= minFaceSize {
+				s.cluster(ctx, record, thumbnail, taken)
+			}
+		}
+
+		if err := s.store.CreateFace(ctx, record); err != nil {
+			return err
+		}
+		if record.ClusterID != nil {
+			detection.ClusterID = *record.ClusterID
+		}
+	}
+
+	return nil
+}
+
+// Forget deletes the faces recorded for a video, and their thumbnails, before
+// the video is identified again. Their clusters shrink accordingly, so
+// repeated runs do not count the same faces twice.
+func (s *Service) Forget(ctx context.Context, videoID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	thumbnails, err := s.store.DeleteByVideoID(ctx, videoID)
+	if err != nil {
+		return err
+	}
+	// The store recomputed or deleted clusters, so reload them on the next
+	// Record.
+	s.reset(videoID)
+
+	for _, path := range thumbnails {
+		if err := s.storage.DeleteFile(path); err != nil {
+			log.Printf("Failed to delete face thumbnail %s of video %s: %v", path, videoID, err)
+		}
+	}
+	return nil
+}
+
+// Invalidate drops the clusters kept in memory once the faces of videoID were
+// deleted elsewhere, e.g. when the trash purged the video, so the next
+// Record reloads them.
+func (s *Service) Invalidate(videoID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reset(videoID)
+}
+
+func (s *Service) reset(videoID string) {
+	s.clusters = nil
+	s.loaded = false
+	delete(s.byVideo, videoID)
+}
+
+// cluster embeds the thumbnail and assigns the face to the nearest cluster,
+// creating one when nothing is similar enough.
+func (s *Service) cluster(ctx context.Context, record *face.FaceDB, thumbnail []byte, taken map[string]bool) {
+	embedding, err := s.embedder.Embed(ctx, thumbnail)
+	if err != nil {
+		log.Printf("Failed to embed face in frame %d of video %s: %v", record.FrameNumber, record.VideoID, err)
+		return
+	}
+	record.Embedding = embedding
+
+	candidates := s.byVideo[record.VideoID]
+	if s.acrossVideos {
+		candidates = s.clusters
+	}
+	cluster := nearest(candidates, embedding, s.threshold, taken)
+	if cluster == nil {
+		cluster = &face.FaceClusterDB{}
+	}
+	previous := *cluster
+	previous.Centroid = append([]float64(nil), cluster.Centroid...)
+	addToCluster(cluster, embedding)
+
+	if err := s.store.SaveCluster(ctx, cluster); err != nil {
+		log.Printf("Failed to save face cluster: %v", err)
+		*cluster = previous
+		return
+	}
+	if previous.ID == "" {
+		s.clusters = append(s.clusters, cluster)
+		s.byVideo[record.VideoID] = append(s.byVideo[record.VideoID], cluster)
+	}
+
+	id := cluster.ID
+	record.ClusterID = &id
+	taken[id] = true
+}
+
+func (s *Service) saveThumbnail(thumbnail []byte) (string, error) {
+	file := thumbnailFile{bytes.NewReader(thumbnail)}
+	return s.storage.SaveFile(file, storage.FileInfo{
+		Filename:    "face.jpg",
+		ContentType: "image/jpeg",
+		Size:        int64(len(thumbnail)),
+	})
+}
+
+// thumbnailFile adapts an in-memory thumbnail to multipart.File.
+type thumbnailFile struct {
+	*bytes.Reader
+}
+
+func (thumbnailFile) Close() error { return nil }
+
+// Appearance is one person seen in a video: the frames they appear in, a
+// representative face and how many other videos they appear in.
+type Appearance struct {
+	ClusterID   string
+	Face        *face.FaceDB
+	Frames      []int
+	OtherVideos int
+}
+
+// FrameCount is the number of distinct frames the person appears in.
+func (a Appearance) FrameCount() int {
+	return len(a.Frames)
+}
+
+// Summarize groups a video's faces, ordered by frame number, by cluster with
+// the most frequent person first. otherVideos maps cluster IDs to the number
+// of other videos they appear in.
+func Summarize(faces []*face.FaceDB, otherVideos map[string]int) []Appearance {
+	byCluster := make(map[string]*Appearance)
+	var order []string
+
+	for _, f := range faces {
+		if f.ClusterID == nil {
+			continue
+		}
+		id := *f.ClusterID
+		appearance, ok := byCluster[id]
+		if !ok {
+			appearance = &Appearance{ClusterID: id, Face: f, OtherVideos: otherVideos[id]}
+			byCluster[id] = appearance
+			order = append(order, id)
+		}
+		if f.Confidence > appearance.Face.Confidence {
+			appearance.Face = f
+		}
+		if n := len(appearance.Frames); n == 0 || appearance.Frames[n-1] != f.FrameNumber {
+			appearance.Frames = append(appearance.Frames, f.FrameNumber)
+		}
+	}
+
+	appearances := make([]Appearance, 0, len(order))
+	for _, id := range order {
+		appearances = append(appearances, *byCluster[id])
+	}
+	sort.SliceStable(appearances, func(i, j int) bool {
+		return len(appearances[i].Frames) > len(appearances[j].Frames)
+	})
+	return appearances
+}
@@ -0,0 +1,293 @@
+package faces
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kdimtricp/vshazam/internal/ai"
+	"github.com/kdimtricp/vshazam/internal/models/face"
+	"github.com/kdimtricp/vshazam/internal/storage"
+)
+
+type memoryStore struct {
+	faces    []*face.FaceDB
+	clusters []*face.FaceClusterDB
+}
+
+func (m *memoryStore) CreateFace(ctx context.Context, record *face.FaceDB) error {
+	m.faces = append(m.faces, record)
+	return nil
+}
+
+func (m *memoryStore) SaveCluster(ctx context.Context, cluster *face.FaceClusterDB) error {
+	if cluster.ID == "" {
+		cluster.ID = fmt.Sprintf("cluster-%d", len(m.clusters))
+		m.clusters = append(m.clusters, cluster)
+	}
+	return nil
+}
+
+func (m *memoryStore) ListClusters(ctx context.Context) ([]*face.FaceClusterDB, error) {
+	return m.clusters, nil
+}
+
+func (m *memoryStore) DeleteByVideoID(ctx context.Context, videoID string) ([]string, error) {
+	var thumbnails []string
+	var kept []*face.FaceDB
+	for _, record := range m.faces {
+		if record.VideoID == videoID {
+			thumbnails = append(thumbnails, record.ThumbnailPath)
+			continue
+		}
+		kept = append(kept, record)
+	}
+	m.faces = kept
+
+	var clusters []*face.FaceClusterDB
+	for _, cluster := range m.clusters {
+		var embeddings [][]float64
+		for _, record := range m.faces {
+			if record.ClusterID != nil && *record.ClusterID == cluster.ID {
+				embeddings = append(embeddings, record.Embedding)
+			}
+		}
+		if len(embeddings) > 0 {
+			cluster.Recompute(embeddings)
+			clusters = append(clusters, cluster)
+		}
+	}
+	m.clusters = clusters
+	return thumbnails, nil
+}
+
+type memoryStorage struct {
+	files map[string][]byte
+	saved int
+}
+
+func (m *memoryStorage) SaveFile(file multipart.File, info storage.FileInfo) (string, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return "", err
+	}
+	path := fmt.Sprintf("%d-%s", m.saved, info.Filename)
+	m.saved++
+	m.files[path] = data
+	return path, nil
+}
+
+func (m *memoryStorage) OpenFile(path string) (io.ReadSeekCloser, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *memoryStorage) DeleteFile(path string) error {
+	delete(m.files, path)
+	return nil
+}
+
+func (m *memoryStorage) LocalPath(path string) (string, error) {
+	return path, nil
+}
+
+// testFrame draws a horizontal gradient "face" at (10,10) and a vertical one
+// at (60,10) on a grey 100x60 frame.
+func testFrame(t *testing.T) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 100, 60))
+	for y := 0; y < 60; y++ {
+		for x := 0; x < 100; x++ {
+			img.Set(x, y, color.Gray{Y: 128})
+		}
+	}
+	for y := 0; y < 30; y++ {
+		for x := 0; x < 30; x++ {
+			img.Set(10+x, 10+y, color.Gray{Y: uint8(x * 8)})
+			img.Set(60+x, 10+y, color.Gray{Y: uint8(y * 8)})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatalf("failed to encode frame: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestServiceClustersFacesAcrossFrames(t *testing.T) {
+	store := &memoryStore{}
+	files := &memoryStorage{files: make(map[string][]byte)}
+	service := NewService(files, store, NewPixelEmbedder())
+
+	frame := testFrame(t)
+	left := ai.FaceDetection{BoundingBox: ai.BoundingBox{X: 10, Y: 10, Width: 30, Height: 30}, Confidence: 0.9}
+	right := ai.FaceDetection{BoundingBox: ai.BoundingBox{X: 60, Y: 10, Width: 30, Height: 30}, Confidence: 0.8}
+
+	first := []ai.FaceDetection{left, right}
+	if err := service.Record(context.Background(), "video-1", 0, frame, first); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second := []ai.FaceDetection{left}
+	if err := service.Record(context.Background(), "video-1", 1, frame, second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if first[0].ClusterID == "" || first[0].ClusterID == first[1].ClusterID {
+		t.Errorf("expected two people in the first frame, got clusters %q and %q", first[0].ClusterID, first[1].ClusterID)
+	}
+	if second[0].ClusterID != first[0].ClusterID {
+		t.Errorf("expected the same face to join cluster %q, got %q", first[0].ClusterID, second[0].ClusterID)
+	}
+	if len(store.faces) != 3 || len(store.clusters) != 2 {
+		t.Fatalf("expected 3 faces in 2 clusters, got %d and %d", len(store.faces), len(store.clusters))
+	}
+	if len(files.files) != 3 || store.faces[0].ThumbnailPath == "" {
+		t.Errorf("expected a thumbnail per face, got %d", len(files.files))
+	}
+	if store.clusters[0].FaceCount != 2 {
+		t.Errorf("expected the repeated face to be counted twice, got %d", store.clusters[0].FaceCount)
+	}
+
+	appearances := Summarize(store.faces, map[string]int{first[0].ClusterID: 3})
+	if len(appearances) != 2 {
+		t.Fatalf("expected 2 people, got %d", len(appearances))
+	}
+	if appearances[0].ClusterID != first[0].ClusterID || appearances[0].FrameCount() != 2 || appearances[0].OtherVideos != 3 {
+		t.Errorf("unexpected most frequent person %+v", appearances[0])
+	}
+}
+
+func TestServiceKeepsPixelClustersWithinAVideo(t *testing.T) {
+	store := &memoryStore{}
+	files := &memoryStorage{files: make(map[string][]byte)}
+	service := NewService(files, store, NewPixelEmbedder())
+	frame := testFrame(t)
+
+	first := []ai.FaceDetection{{BoundingBox: ai.BoundingBox{X: 10, Y: 10, Width: 30, Height: 30}, Confidence: 0.9}}
+	if err := service.Record(context.Background(), "video-1", 0, frame, first); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second := []ai.FaceDetection{{BoundingBox: ai.BoundingBox{X: 10, Y: 10, Width: 30, Height: 30}, Confidence: 0.9}}
+	if err := service.Record(context.Background(), "video-2", 0, frame, second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if first[0].ClusterID == "" || second[0].ClusterID == "" || first[0].ClusterID == second[0].ClusterID {
+		t.Errorf("expected separate clusters per video, got %q and %q", first[0].ClusterID, second[0].ClusterID)
+	}
+}
+
+func TestServiceForgetBeforeRerun(t *testing.T) {
+	store := &memoryStore{}
+	files := &memoryStorage{files: make(map[string][]byte)}
+	service := NewService(files, store, NewPixelEmbedder())
+	service.UseLibraryClustering()
+	frame := testFrame(t)
+
+	run := func() {
+		for frameNumber := 0; frameNumber < 2; frameNumber++ {
+			faces := []ai.FaceDetection{{BoundingBox: ai.BoundingBox{X: 10, Y: 10, Width: 30, Height: 30}, Confidence: 0.9}}
+			if err := service.Record(context.Background(), "video-1", frameNumber, frame, faces); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+	}
+
+	run()
+	other := []ai.FaceDetection{{BoundingBox: ai.BoundingBox{X: 10, Y: 10, Width: 30, Height: 30}, Confidence: 0.9}}
+	if err := service.Record(context.Background(), "video-2", 0, frame, other); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := service.Forget(context.Background(), "video-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(store.faces) != 1 || len(files.files) != 1 {
+		t.Fatalf("expected only video-2's face and thumbnail to be left, got %d and %d", len(store.faces), len(files.files))
+	}
+	if len(store.clusters) != 1 || store.clusters[0].FaceCount != 1 {
+		t.Fatalf("expected the cluster to shrink to one face, got %+v", store.clusters)
+	}
+
+	run()
+	if len(store.faces) != 3 || len(files.files) != 3 {
+		t.Errorf("expected the rerun to replace the faces, got %d faces and %d thumbnails", len(store.faces), len(files.files))
+	}
+	if len(store.clusters) != 1 || store.clusters[0].FaceCount != 3 {
+		t.Errorf("expected the person counted once per face, got %+v", store.clusters)
+	}
+
+	if err := service.Forget(context.Background(), "video-2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := service.Forget(context.Background(), "video-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(store.clusters) != 0 {
+		t.Errorf("expected empty clusters to be deleted, got %+v", store.clusters)
+	}
+}
+
+func TestServiceRecordsAfterPurge(t *testing.T) {
+	store := &memoryStore{}
+	files := &memoryStorage{files: make(map[string][]byte)}
+	service := NewService(files, store, NewPixelEmbedder())
+	service.UseLibraryClustering()
+	frame := testFrame(t)
+	detect := func() []ai.FaceDetection {
+		return []ai.FaceDetection{{BoundingBox: ai.BoundingBox{X: 10, Y: 10, Width: 30, Height: 30}, Confidence: 0.9}}
+	}
+
+	if err := service.Record(context.Background(), "video-1", 0, frame, detect()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The trash deletes the faces itself when it purges a video.
+	if _, err := store.DeleteByVideoID(context.Background(), "video-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	service.Invalidate("video-1")
+
+	faces := detect()
+	if err := service.Record(context.Background(), "video-2", 0, frame, faces); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(store.clusters) != 1 || store.clusters[0].FaceCount != 1 || faces[0].ClusterID != store.clusters[0].ID {
+		t.Errorf("expected the face in a new stored cluster, got %q in %+v", faces[0].ClusterID, store.clusters)
+	}
+}
+
+func TestCropOutsideFrame(t *testing.T) {
+	if _, err := Crop(testFrame(t), ai.BoundingBox{X: 200, Y: 200, Width: 20, Height: 20}); err == nil {
+		t.Error("expected error for a box outside the frame")
+	}
+}
+
+func TestHTTPEmbedder(t *testing.T) {
+	var contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		w.Write([]byte(`{"embedding": [3, 4]}`))
+	}))
+	defer server.Close()
+
+	embedding, err := NewHTTPEmbedder(server.URL).Embed(context.Background(), []byte("jpeg"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if contentType != "image/jpeg" {
+		t.Errorf("unexpected content type %q", contentType)
+	}
+	if len(embedding) != 2 || embedding[0] != 0.6 || embedding[1] != 0.8 {
+		t.Errorf("expected normalized embedding, got %v", embedding)
+	}
+}
@@ -0,0 +1,370 @@
+package identify
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/kdimtricp/vshazam/internal/ai"
+	"github.com/kdimtricp/vshazam/internal/titles"
+)
+
+// mention is a single raw sighting of a possible film title in one frame.
+type mention struct {
+	title  string
+	year   int
+	source EvidenceSource
+	frame  int
+	hedged bool
+	detail string
+	// season and episode are set when the title carried an episode marker.
+	season  int
+	episode int
+}
+
+var (
+	// "the movie "Inception" (2010)", "film titled “Heat”"
+	quotedAfterKeyword = regexp.MustCompile(`(?i)(?:movie|film|series|show)\s+(?:called\s+|titled\s+|named\s+|is\s+)?["“']([^"”']{2,80})["”'](?:\s*\((\d{4})\))?`)
+	// ""Inception" (2010)"
+	quotedWithYear = regexp.MustCompile(`["“]([^"”]{2,80})["”]\s*\((\d{4})\)`)
+	// "**Inception** (2010)", "*Heat* (1995)"
+	emphasisedWithYear = regexp.MustCompile(`\*{1,2}([^*\n]{2,80})\*{1,2}\s*\((\d{4})\)`)
+	// "from the movie Blade Runner 2049", "is the film The Dark Knight (2008)"
+	capitalisedAfterKeyword = regexp.MustCompile(`(?:from|is|be|of)\s+(?:the\s+)?(?:movie|film)\s+((?:[A-Z0-9][\w'’:&.-]*)(?:\s+(?:[A-Z0-9][\w'’:&.-]*|of|the|and|a|an|in|on|to|for|at))*)(?:\s*\((\d{4})\))?`)
+
+	sentenceSplitter = regexp.MustCompile(`[.!?\n]+\s`)
+	hedgeWords       = []string{"might", "could", "possibly", "perhaps", "may be", "reminiscent", "similar to", "resembles", "likely", "suggests"}
+	refusalWords     = []string{"cannot", "can't", "unable", "not able", "not possible"}
+)
+
+// extractCaptionMentions pulls film titles that GPT named in a frame caption.
+// The caption prompt explicitly asks the model to name the movie, so titles
+// appear in a handful of stable phrasings.
+func extractCaptionMentions(caption string, frame int) []mention {
+	if strings.TrimSpace(caption) == "" {
+		return nil
+	}
+
+	var mentions []mention
+	seen := make(map[string]bool)
+
+	for _, sentence := range sentenceSplitter.Split(caption, -1) {
+		lower := strings.ToLower(sentence)
+		if containsAny(lower, refusalWords) {
+			continue
+		}
+		hedged := containsAny(lower, hedgeWords)
+
+		for _, re := range []*regexp.Regexp{quotedAfterKeyword, quotedWithYear, emphasisedWithYear, capitalisedAfterKeyword} {
+			for _, match := range re.FindAllStringSubmatch(sentence, -1) {
+				title := cleanTitle(match[1])
+				key := normalizeTitle(title)
+				if key == "" || seen[key] || isGenericPhrase(key) {
+					continue
+				}
+				seen[key] = true
+				mentions = append(mentions, mention{
+					title:  title,
+					year:   parseYear(match[2]),
+					source: SourceCaption,
+					frame:  frame,
+					hedged: hedged,
+					detail: strings.TrimSpace(sentence),
+				})
+			}
+		}
+	}
+
+	return mentions
+}
+
+// extractOCRMentions turns on-screen text into title guesses. Google Vision
+// returns individual words after the full-text block, so words are joined back
+// into a phrase before being split into lines.
+func extractOCRMentions(texts []string, frame int) []mention {
+	if len(texts) == 0 {
+		return nil
+	}
+
+	var lines []string
+	if len(texts) == 1 {
+		lines = strings.Split(texts[0], "\n")
+	} else {
+		lines = []string{strings.Join(texts, " ")}
+	}
+
+	var mentions []mention
+	seen := make(map[string]bool)
+	for _, line := range lines {
+		line = strings.Join(strings.Fields(line), " ")
+		if !looksLikeTitle(line) {
+			continue
+		}
+		key := normalizeTitle(line)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		mentions = append(mentions, mention{
+			title:  line,
+			source: SourceOCR,
+			frame:  frame,
+			detail: line,
+		})
+	}
+
+	return mentions
+}
+
+// regionMentions returns title guesses from the OCR lines of one region.
+func regionMentions(blocks []ai.TextBlock, region string, frame int) []mention {
+	lines := ai.TextInRegion(blocks, region)
+	if len(lines) == 0 {
+		return nil
+	}
+	return extractOCRMentions([]string{strings.Join(lines, "\n")}, frame)
+}
+
+// ocrCorpus returns the normalized on-screen text of a frame, Google Vision
+// OCR joined with any text GPT reported reading, to check whether a
+// candidate title is visible on screen.
+func ocrCorpus(analysis *ai.FrameAnalysis) string {
+	text := append(append([]string{}, analysis.TextOCR...), analysis.VisibleText...)
+	return normalizeTitle(strings.Join(text, " "))
+}
+
+// filmGuessMentions converts the structured film guesses GPT returns in JSON
+// mode into mentions. Guesses below half confidence count as hedged.
+func filmGuessMentions(guesses []ai.FilmGuess, frame int) []mention {
+	var mentions []mention
+	seen := make(map[string]bool)
+	for _, guess := range guesses {
+		title := cleanTitle(guess.Title)
+		key := normalizeTitle(title)
+		if key == "" || seen[key] || isGenericPhrase(key) {
+			continue
+		}
+		seen[key] = true
+		mentions = append(mentions, mention{
+			title:  title,
+			year:   guess.Year,
+			source: SourceCaption,
+			frame:  frame,
+			hedged: guess.Confidence < 0.5,
+			detail: fmt.Sprintf("%s (%.0f%%)", title, guess.Confidence*100),
+		})
+	}
+	return mentions
+}
+
+// minWebEntityScore drops the long tail of loosely related web entities.
+// Google's entity scores are relative, but film titles for a recognizable
+// frame are usually well above it.
+const minWebEntityScore = 0.5
+
+// maxWebEntities is how many web entities per frame may become candidates.
+const maxWebEntities = 3
+
+var (
+	// "inception 2010 movie", "the dark knight film scene"
+	bestGuessNoise = map[string]bool{
+		"movie": true, "film": true, "scene": true, "still": true, "trailer": true,
+		"poster": true, "clip": true, "screenshot": true, "full": true, "hd": true,
+	}
+)
+
+// webMentions turns Google Vision web detection into title guesses. The
+// best-guess label is Google's own description of the image and often names
+// the film outright; web entities mix films with actors, places and objects,
+// so only the strongest few are kept and names the frame already explains as
+// a label or an actor are skipped.
+func webMentions(analysis *ai.FrameAnalysis, frame int) []mention {
+	var mentions []mention
+
+	explained := make(map[string]bool)
+	for _, label := range analysis.Labels {
+		explained[normalizeTitle(label.Name)] = true
+	}
+	for _, actor := range analysis.Actors {
+		explained[normalizeTitle(actor)] = true
+	}
+	for _, object := range analysis.Objects {
+		explained[normalizeTitle(object.Name)] = true
+	}
+
+	// Entities come first because they carry the title's proper casing.
+	seen := make(map[string]bool)
+	for _, entity := range analysis.WebEntities {
+		if len(seen) >= maxWebEntities {
+			break
+		}
+		if entity.Score < minWebEntityScore || !looksLikeTitle(entity.Description) {
+			continue
+		}
+		title := cleanTitle(entity.Description)
+		key := normalizeTitle(title)
+		if key == "" || seen[key] || explained[key] || isGenericPhrase(key) {
+			continue
+		}
+		seen[key] = true
+		mentions = append(mentions, mention{
+			title:  title,
+			source: SourceWebEntity,
+			frame:  frame,
+			hedged: true,
+			detail: fmt.Sprintf("%s (score %.2f)", title, entity.Score),
+		})
+	}
+
+	seen = make(map[string]bool)
+	for _, label := range analysis.BestGuessLabels {
+		title, year := cleanBestGuess(label)
+		key := normalizeTitle(title)
+		if key == "" || seen[key] || isGenericPhrase(key) {
+			continue
+		}
+		seen[key] = true
+		mentions = append(mentions, mention{
+			title:  title,
+			year:   year,
+			source: SourceWebEntity,
+			frame:  frame,
+			detail: label,
+		})
+	}
+
+	return mentions
+}
+
+// dialogueMentions returns the films named by web results for a quoted line
+// of dialogue. Only results recognized as pages about one film count; lists
+// of quotes, blogs and lyrics sites are ignored.
+func dialogueMentions(results []ai.SearchResult, line string) []mention {
+	var mentions []mention
+	seen := make(map[string]bool)
+	for _, hit := range ai.GroupFilms(results) {
+		title := cleanTitle(hit.Title)
+		key := normalizeTitle(title)
+		if key == "" || seen[key] || isGenericPhrase(key) || !looksLikeTitle(title) {
+			continue
+		}
+		seen[key] = true
+		mentions = append(mentions, mention{
+			title:  title,
+			year:   hit.Year,
+			source: SourceDialogue,
+			frame:  -1,
+			detail: fmt.Sprintf("%q is quoted on %q", line, hit.Results[0].Title),
+		})
+	}
+	return mentions
+}
+
+// cleanBestGuess strips words like "movie" or "scene" and a release year from
+// a best-guess label, returning the remaining title and the year.
+func cleanBestGuess(label string) (string, int) {
+	words := strings.Fields(label)
+	year := 0
+	for len(words) > 1 {
+		last := strings.ToLower(words[len(words)-1])
+		if y := parseYear(last); y > 0 && year == 0 {
+			year = y
+		} else if !bestGuessNoise[last] {
+			break
+		}
+		words = words[:len(words)-1]
+	}
+	return cleanTitle(strings.Join(words, " ")), year
+}
+
+func looksLikeTitle(line string) bool {
+	if len(line) < 3 || len(line) > 60 {
+		return false
+	}
+	words := strings.Fields(line)
+	if len(words) > 8 {
+		return false
+	}
+
+	letters, others := 0, 0
+	for _, r := range line {
+		switch {
+		case unicode.IsLetter(r):
+			letters++
+		case unicode.IsSpace(r):
+		default:
+			others++
+		}
+	}
+	return letters >= 3 && float64(letters)/float64(letters+others) >= 0.6
+}
+
+var genericPhrases = map[string]bool{
+	"movie": true, "film": true, "scene": true, "unknown": true, "specific movie": true,
+}
+
+func isGenericPhrase(key string) bool {
+	return genericPhrases[key]
+}
+
+// episodeMarker matches "S02E05", "S2 E5", "2x05" and "Season 2 Episode 5".
+var episodeMarker = regexp.MustCompile(`(?i)\b(?:s(\d{1,2})\s?e(\d{1,3})|(\d{1,2})x(\d{2,3})|season\s+(\d{1,2}),?\s+episode\s+(\d{1,3}))\b`)
+
+// splitEpisode removes an episode marker from a title, as in
+// "Breaking Bad S02E05" or "Breaking Bad - Season 2, Episode 5", and returns
+// the show title with the season and episode numbers. Titles without a
+// marker are returned unchanged with zero numbers.
+func splitEpisode(title string) (string, int, int) {
+	loc := episodeMarker.FindStringSubmatchIndex(title)
+	if loc == nil {
+		return title, 0, 0
+	}
+
+	var season, episode int
+	for i := 2; i < len(loc); i += 4 {
+		if loc[i] >= 0 {
+			season, _ = strconv.Atoi(title[loc[i]:loc[i+1]])
+			episode, _ = strconv.Atoi(title[loc[i+2]:loc[i+3]])
+			break
+		}
+	}
+	if season == 0 || episode == 0 {
+		return title, 0, 0
+	}
+
+	show := strings.TrimRight(title[:loc[0]], " -–—:,(|")
+	return cleanTitle(show), season, episode
+}
+
+func cleanTitle(title string) string {
+	title = strings.TrimSpace(title)
+	return strings.Trim(title, `"'“”*.,;: `)
+}
+
+func parseYear(s string) int {
+	if s == "" {
+		return 0
+	}
+	year, err := strconv.Atoi(s)
+	if err != nil || year < 1880 || year > 2100 {
+		return 0
+	}
+	return year
+}
+
+// normalizeTitle produces the comparison key used throughout scoring; see
+// titles.Normalize.
+func normalizeTitle(title string) string {
+	return titles.Normalize(title)
+}
+
+func containsAny(s string, words []string) bool {
+	for _, w := range words {
+		if strings.Contains(s, w) {
+			return true
+		}
+	}
+	return false
+}
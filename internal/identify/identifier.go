@@ -0,0 +1,563 @@
+package identify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/kdimtricp/vshazam/internal/ai"
+	"github.com/kdimtricp/vshazam/internal/metering"
+	"github.com/kdimtricp/vshazam/internal/models/film"
+	"github.com/kdimtricp/vshazam/internal/models/frame_analysis"
+	"github.com/kdimtricp/vshazam/internal/models/identification"
+	"github.com/kdimtricp/vshazam/internal/models/transcript"
+)
+
+type FrameExtractorInterface interface {
+	VideoDuration(videoPath string) (float64, error)
+	ExtractFrameAt(videoPath string, timestamp float64, size int) ([]byte, error)
+}
+
+type FrameAnalysisStore interface {
+	Create(ctx context.Context, analysis *frame_analysis.FrameAnalysisDB) error
+}
+
+// FaceRecorder stores the faces found in a frame and sets their ClusterID.
+// Forget drops the faces of an earlier run before a video is identified again.
+type FaceRecorder interface {
+	Record(ctx context.Context, videoID string, frameNumber int, frame []byte, faces []ai.FaceDetection) error
+	Forget(ctx context.Context, videoID string) error
+}
+
+// AudioExtractor pulls the audio track out of a video.
+type AudioExtractor interface {
+	ExtractAudio(videoPath string, maxSeconds float64) ([]byte, error)
+}
+
+// SpeechTranscriber turns audio into timed speech segments.
+type SpeechTranscriber interface {
+	Transcribe(ctx context.Context, audio []byte) (*ai.Transcript, error)
+}
+
+// FilmCatalog keeps a local copy of the films candidates resolve to.
+type FilmCatalog interface {
+	Resolve(ctx context.Context, tmdbID int) (*film.FilmDB, error)
+}
+
+type StopReason string
+
+const (
+	StopConfident StopReason = "confident"
+	StopMaxFrames StopReason = "max_frames"
+	StopNoFrames  StopReason = "no_frames"
+)
+
+// Result is the outcome of identifying one video.
+type Result struct {
+	VideoID    string
+	Candidates []*Candidate
+	Frames     []*ai.FrameAnalysis
+	Timestamps []float64
+	// Speech is the transcribed audio, or nil when transcription is disabled
+	// or failed.
+	Speech     *ai.Transcript
+	StopReason StopReason
+	Elapsed    time.Duration
+}
+
+// Top returns the best candidate, or nil when nothing was found.
+func (r *Result) Top() *Candidate {
+	if len(r.Candidates) == 0 {
+		return nil
+	}
+	return r.Candidates[0]
+}
+
+// Record converts the result into a row for the identifications table.
+func (r *Result) Record() (*identification.IdentificationDB, error) {
+	candidates := r.Candidates
+	if candidates == nil {
+		candidates = []*Candidate{}
+	}
+	encoded, err := json.Marshal(candidates)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode candidates: %w", err)
+	}
+
+	record := &identification.IdentificationDB{
+		VideoID:        r.VideoID,
+		Candidates:     encoded,
+		FramesAnalyzed: len(r.Frames),
+		StopReason:     string(r.StopReason),
+		CreatedAt:      time.Now(),
+	}
+	if top := r.Top(); top != nil {
+		record.TopTitle = top.Title
+		record.TopYear = top.Year
+		// TopTMDbID refers to a film; shows are kept in the candidates.
+		if top.MediaType == "" {
+			record.TopTMDbID = top.TMDbID
+		}
+		record.TopScore = top.Score
+	}
+	return record, nil
+}
+
+// StoppedEarly reports whether identification finished before using its
+// initial frame budget.
+func (r *Result) StoppedEarly() bool {
+	return r.StopReason == StopConfident
+}
+
+// Identifier runs the full pipeline: extract frames, analyze each one with the
+// vision service, persist the analyses and score film candidates.
+//
+// Frames are analyzed one at a time and candidates are rescored after each
+// frame. Identification stops as soon as the top candidate reaches
+// ConfidenceThreshold with ConfidenceMargin over the runner-up. If the initial
+// MaxFramesPerVideo frames are not conclusive, frames are requested at new
+// timestamps between the ones already sampled, up to MaxFramesAnalyze.
+type Identifier struct {
+	vision    ai.VisionService
+	extractor FrameExtractorInterface
+	store     FrameAnalysisStore
+	scorer    *Scorer
+	config    *ai.Config
+	faces     FaceRecorder
+	tracks    TranscriptStore
+	audio     AudioExtractor
+	speech    SpeechTranscriber
+	catalog   FilmCatalog
+}
+
+// NewIdentifier creates an identifier. store may be nil to skip persistence.
+func NewIdentifier(vision ai.VisionService, extractor FrameExtractorInterface, store FrameAnalysisStore, scorer *Scorer, config *ai.Config) *Identifier {
+	return &Identifier{
+		vision:    vision,
+		extractor: extractor,
+		store:     store,
+		scorer:    scorer,
+		config:    config,
+	}
+}
+
+// UseFaces stores detected faces with recorder before each frame is
+// persisted. Clustered faces then become part of the clip's fingerprint.
+func (id *Identifier) UseFaces(recorder FaceRecorder) {
+	id.faces = recorder
+}
+
+// UseTranscripts stores the burned-in subtitles read from the analyzed frames
+// as a timed track once identification finishes.
+func (id *Identifier) UseTranscripts(store TranscriptStore) {
+	id.tracks = store
+}
+
+// UseSpeech transcribes the video's audio before frames are analyzed. The
+// transcript is stored as a track and its lines of dialogue are searched for
+// as quotes while scoring.
+func (id *Identifier) UseSpeech(audio AudioExtractor, transcriber SpeechTranscriber) {
+	id.audio = audio
+	id.speech = transcriber
+}
+
+// UseCatalog stores the films that candidates resolved to once
+// identification finishes, and fills in their IMDb IDs.
+func (id *Identifier) UseCatalog(catalog FilmCatalog) {
+	id.catalog = catalog
+}
+
+func (id *Identifier) Identify(ctx context.Context, videoID, videoPath string) (*Result, error) {
+	start := time.Now()
+	ctx = metering.WithVideoID(ctx, videoID)
+
+	duration, err := id.extractor.VideoDuration(videoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get video duration: %w", err)
+	}
+	if duration <= 0 {
+		return nil, fmt.Errorf("invalid video duration: %f", duration)
+	}
+
+	if id.faces != nil {
+		if err := id.faces.Forget(ctx, videoID); err != nil {
+			log.Printf("Failed to clear earlier faces of video %s: %v", videoID, err)
+		}
+	}
+
+	initial, limit := id.frameBudget()
+	session := id.scorer.NewSession()
+	result := &Result{VideoID: videoID, StopReason: StopMaxFrames}
+
+	// Subtitle tracks are exact text, so their lines are searched before
+	// transcribed speech.
+	dialogue := DialogueQueries(id.subtitleLines(ctx, videoID), session.maxDialogue)
+	if speech := id.transcribe(ctx, videoID, videoPath); speech != nil {
+		result.Speech = speech
+		lines := make([]string, 0, len(speech.Segments))
+		for _, segment := range speech.Segments {
+			lines = append(lines, segment.Text)
+		}
+		dialogue = append(dialogue, DialogueQueries(lines, session.maxDialogue-len(dialogue))...)
+	}
+	if len(dialogue) > 0 {
+		session.UseDialogue(dialogue)
+	}
+
+	pending := evenTimestamps(duration, initial)
+	sampled := make([]float64, 0, limit)
+
+	batch := id.batchVision()
+
+	for len(sampled) < limit {
+		if len(pending) == 0 {
+			pending = nextTimestamps(sampled, duration, limit-len(sampled))
+			if len(pending) == 0 {
+				break
+			}
+			log.Printf("Evidence for video %s is weak after %d frames, sampling %d more", videoID, len(sampled), len(pending))
+		}
+
+		// Frames are analyzed one at a time, or a whole round at once when
+		// the vision service supports multi-frame requests.
+		take := 1
+		if batch != nil {
+			take = len(pending)
+		}
+		round := pending[:take]
+		pending = pending[take:]
+		sampled = append(sampled, round...)
+
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		var analyzed int
+		if batch != nil {
+			analyzed, err = id.analyzeRound(ctx, batch, videoID, videoPath, round, result)
+		} else {
+			var analysis *ai.FrameAnalysis
+			analysis, err = id.analyzeAt(ctx, videoID, videoPath, len(result.Frames), round[0])
+			if analysis != nil {
+				result.Frames = append(result.Frames, analysis)
+				result.Timestamps = append(result.Timestamps, round[0])
+				analyzed = 1
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if analyzed == 0 {
+			continue
+		}
+
+		candidates, err := session.Score(ctx, result.Frames)
+		if err != nil {
+			return nil, fmt.Errorf("failed to score candidates: %w", err)
+		}
+		result.Candidates = candidates
+
+		if id.decisive(candidates) {
+			result.StopReason = StopConfident
+			break
+		}
+	}
+
+	if len(result.Frames) == 0 {
+		result.StopReason = StopNoFrames
+	}
+
+	if id.tracks != nil && len(result.Frames) > 0 {
+		cues := SubtitleCues(result.Frames, result.Timestamps)
+		if err := id.tracks.ReplaceCues(ctx, videoID, transcript.SourceOCR, cues); err != nil {
+			log.Printf("Failed to store subtitle track of video %s: %v", videoID, err)
+		}
+	}
+
+	id.catalogFilms(ctx, result.Candidates)
+
+	result.Elapsed = time.Since(start)
+	log.Printf("Identified video %s from %d frames in %s (%s)", videoID, len(result.Frames), result.Elapsed, result.StopReason)
+	return result, nil
+}
+
+// catalogFilms resolves every film candidate with a TMDb ID in the catalog.
+// Failures are logged; the candidates are already scored.
+func (id *Identifier) catalogFilms(ctx context.Context, candidates []*Candidate) {
+	if id.catalog == nil {
+		return
+	}
+	for _, c := range candidates {
+		if c.TMDbID == 0 || c.MediaType != "" {
+			continue
+		}
+		f, err := id.catalog.Resolve(ctx, c.TMDbID)
+		if err != nil {
+			log.Printf("Failed to catalog film %d (%s): %v", c.TMDbID, c.Title, err)
+			continue
+		}
+		if f != nil && c.IMDbID == "" {
+			c.IMDbID = f.IMDbID
+		}
+	}
+}
+
+// subtitleLines returns the text of the video's imported subtitle tracks.
+func (id *Identifier) subtitleLines(ctx context.Context, videoID string) []string {
+	if id.tracks == nil {
+		return nil
+	}
+	cues, err := id.tracks.ListByVideoID(ctx, videoID)
+	if err != nil {
+		log.Printf("Failed to load subtitles of video %s: %v", videoID, err)
+		return nil
+	}
+
+	var lines []string
+	for _, cue := range cues {
+		if cue.Source == transcript.SourceSubtitle {
+			lines = append(lines, cue.Text)
+		}
+	}
+	return lines
+}
+
+// transcribe extracts and transcribes the audio track and stores it as a
+// track. Failures are logged and identification continues from the frames.
+func (id *Identifier) transcribe(ctx context.Context, videoID, videoPath string) *ai.Transcript {
+	if id.audio == nil || id.speech == nil {
+		return nil
+	}
+
+	audio, err := id.audio.ExtractAudio(videoPath, id.config.SpeechMaxSeconds)
+	if err != nil {
+		log.Printf("Failed to extract audio of video %s: %v", videoID, err)
+		return nil
+	}
+
+	speech, err := id.speech.Transcribe(ctx, audio)
+	if err != nil {
+		log.Printf("Failed to transcribe video %s: %v", videoID, err)
+		return nil
+	}
+	log.Printf("Transcribed %d speech segments from video %s", len(speech.Segments), videoID)
+
+	if id.tracks != nil {
+		if err := id.tracks.ReplaceCues(ctx, videoID, transcript.SourceSpeech, SpeechCues(speech)); err != nil {
+			log.Printf("Failed to store speech track of video %s: %v", videoID, err)
+		}
+	}
+	return speech
+}
+
+// frameBudget returns how many frames to sample up front and the hard limit
+// including any additional frames requested when evidence is weak.
+func (id *Identifier) frameBudget() (initial, limit int) {
+	initial = id.config.MaxFramesPerVideo
+	if initial <= 0 {
+		initial = 5
+	}
+	limit = id.config.MaxFramesAnalyze
+	if limit <= 0 {
+		limit = initial
+	}
+	if initial > limit {
+		initial = limit
+	}
+	return initial, limit
+}
+
+// decisive reports whether the leading candidate is confident enough, and far
+// enough ahead of the runner-up, to stop analyzing frames.
+func (id *Identifier) decisive(candidates []*Candidate) bool {
+	if len(candidates) == 0 || id.config.ConfidenceThreshold <= 0 {
+		return false
+	}
+	top := candidates[0].Score
+	if top < id.config.ConfidenceThreshold {
+		return false
+	}
+	runnerUp := 0.0
+	if len(candidates) > 1 {
+		runnerUp = candidates[1].Score
+	}
+	return top-runnerUp >= id.config.ConfidenceMargin
+}
+
+// analyzeAt extracts and analyzes one frame. Failures are logged and skipped;
+// only an exhausted API budget is returned, since later frames would fail too.
+func (id *Identifier) analyzeAt(ctx context.Context, videoID, videoPath string, frameNumber int, timestamp float64) (*ai.FrameAnalysis, error) {
+	image, err := id.extractor.ExtractFrameAt(videoPath, timestamp, id.config.FrameSize)
+	if err != nil {
+		log.Printf("Failed to extract frame at %.2fs of video %s: %v", timestamp, videoID, err)
+		return nil, nil
+	}
+
+	analysis, err := id.vision.AnalyzeFrame(ctx, image)
+	if err != nil {
+		if errors.Is(err, metering.ErrBudgetExceeded) {
+			return nil, err
+		}
+		log.Printf("Failed to analyze frame at %.2fs of video %s: %v", timestamp, videoID, err)
+		return nil, nil
+	}
+
+	id.persist(ctx, videoID, frameNumber, ai.FrameImage{Data: image, Timestamp: timestamp}, analysis)
+	return analysis, nil
+}
+
+// batchVision returns the vision service as a BatchVisionService when
+// multi-frame analysis is enabled and supported.
+func (id *Identifier) batchVision() ai.BatchVisionService {
+	if !id.config.OpenAIMultiFrame {
+		return nil
+	}
+	batch, _ := id.vision.(ai.BatchVisionService)
+	return batch
+}
+
+// analyzeRound extracts every frame of a round, analyzes them in one call and
+// appends the analyses to result. It returns how many frames were added.
+func (id *Identifier) analyzeRound(ctx context.Context, batch ai.BatchVisionService, videoID, videoPath string, timestamps []float64, result *Result) (int, error) {
+	images := make([]ai.FrameImage, 0, len(timestamps))
+	for _, timestamp := range timestamps {
+		image, err := id.extractor.ExtractFrameAt(videoPath, timestamp, id.config.FrameSize)
+		if err != nil {
+			log.Printf("Failed to extract frame at %.2fs of video %s: %v", timestamp, videoID, err)
+			continue
+		}
+		images = append(images, ai.FrameImage{Data: image, Timestamp: timestamp})
+	}
+	if len(images) == 0 {
+		return 0, nil
+	}
+
+	analyses, err := batch.AnalyzeFrames(ctx, images)
+	if err != nil {
+		if errors.Is(err, metering.ErrBudgetExceeded) {
+			return 0, err
+		}
+		log.Printf("Failed to analyze %d frames of video %s: %v", len(images), videoID, err)
+		return 0, nil
+	}
+
+	added := 0
+	for i, analysis := range analyses {
+		if analysis == nil || i >= len(images) {
+			continue
+		}
+		id.persist(ctx, videoID, len(result.Frames), images[i], analysis)
+		result.Frames = append(result.Frames, analysis)
+		result.Timestamps = append(result.Timestamps, images[i].Timestamp)
+		added++
+	}
+	return added, nil
+}
+
+// evenTimestamps spreads count timestamps evenly across the video, avoiding
+// the very first and last frames which are often black.
+func evenTimestamps(duration float64, count int) []float64 {
+	timestamps := make([]float64, 0, count)
+	interval := duration / float64(count+1)
+	for i := 1; i <= count; i++ {
+		timestamps = append(timestamps, interval*float64(i))
+	}
+	return timestamps
+}
+
+// nextTimestamps picks up to count new timestamps at the midpoints of the
+// widest gaps between the timestamps already sampled.
+func nextTimestamps(sampled []float64, duration float64, count int) []float64 {
+	points := append([]float64{0, duration}, sampled...)
+	sort.Float64s(points)
+
+	type gap struct{ start, end float64 }
+	gaps := make([]gap, 0, len(points)-1)
+	for i := 1; i < len(points); i++ {
+		if points[i]-points[i-1] > 0.5 {
+			gaps = append(gaps, gap{points[i-1], points[i]})
+		}
+	}
+	sort.SliceStable(gaps, func(i, j int) bool {
+		return gaps[i].end-gaps[i].start > gaps[j].end-gaps[j].start
+	})
+
+	timestamps := make([]float64, 0, count)
+	for _, g := range gaps {
+		if len(timestamps) >= count {
+			break
+		}
+		timestamps = append(timestamps, (g.start+g.end)/2)
+	}
+	return timestamps
+}
+
+func (id *Identifier) persist(ctx context.Context, videoID string, frameNumber int, frame ai.FrameImage, analysis *ai.FrameAnalysis) {
+	if id.faces != nil {
+		if err := id.faces.Record(ctx, videoID, frameNumber, frame.Data, analysis.Faces); err != nil {
+			log.Printf("Failed to store faces of frame %d of video %s: %v", frameNumber, videoID, err)
+		}
+	}
+	if id.store == nil {
+		return
+	}
+	record, err := toFrameRecord(videoID, frameNumber, frame.Timestamp, analysis)
+	if err != nil {
+		log.Printf("Failed to encode frame %d of video %s: %v", frameNumber, videoID, err)
+		return
+	}
+	if err := id.store.Create(ctx, record); err != nil {
+		log.Printf("Failed to store frame %d of video %s: %v", frameNumber, videoID, err)
+	}
+}
+
+func toFrameRecord(videoID string, frameNumber int, frameTime float64, analysis *ai.FrameAnalysis) (*frame_analysis.FrameAnalysisDB, error) {
+	labels, err := json.Marshal(analysis.Labels)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(analysis)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &frame_analysis.FrameAnalysisDB{
+		VideoID:         videoID,
+		FrameNumber:     frameNumber,
+		GPTCaption:      analysis.Caption,
+		VisionLabels:    labels,
+		OCRText:         analysis.TextOCR,
+		FaceCount:       len(analysis.Faces),
+		AnalysisTime:    analysis.Timestamp,
+		RawResponse:     raw,
+		BestGuessLabels: analysis.BestGuessLabels,
+		FrameTime:       frameTime,
+	}
+
+	columns := []struct {
+		dst *json.RawMessage
+		src interface{}
+		n   int
+	}{
+		{&record.WebEntities, analysis.WebEntities, len(analysis.WebEntities)},
+		{&record.MatchingPages, analysis.MatchingPages, len(analysis.MatchingPages)},
+		{&record.Logos, analysis.Logos, len(analysis.Logos)},
+		{&record.Landmarks, analysis.Landmarks, len(analysis.Landmarks)},
+		{&record.Objects, analysis.Objects, len(analysis.Objects)},
+		{&record.TextBlocks, analysis.TextBlocks, len(analysis.TextBlocks)},
+	}
+	for _, column := range columns {
+		if column.n == 0 {
+			continue
+		}
+		if *column.dst, err = json.Marshal(column.src); err != nil {
+			return nil, err
+		}
+	}
+
+	return record, nil
+}
@@ -0,0 +1,379 @@
+package identify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kdimtricp/vshazam/internal/ai"
+	"github.com/kdimtricp/vshazam/internal/mdb"
+	"github.com/kdimtricp/vshazam/internal/models/film"
+	"github.com/kdimtricp/vshazam/internal/models/frame_analysis"
+	"github.com/kdimtricp/vshazam/internal/models/transcript"
+)
+
+type mockFrameExtractor struct {
+	duration   float64
+	timestamps []float64
+}
+
+func (m *mockFrameExtractor) VideoDuration(videoPath string) (float64, error) {
+	return m.duration, nil
+}
+
+func (m *mockFrameExtractor) ExtractAudio(videoPath string, maxSeconds float64) ([]byte, error) {
+	return []byte("audio"), nil
+}
+
+func (m *mockFrameExtractor) ExtractFrameAt(videoPath string, timestamp float64, size int) ([]byte, error) {
+	m.timestamps = append(m.timestamps, timestamp)
+	return []byte(fmt.Sprintf("frame-%d", len(m.timestamps)-1)), nil
+}
+
+// mockVisionService returns a scripted analysis per call, in order.
+type mockVisionService struct {
+	analyses []*ai.FrameAnalysis
+	calls    int
+}
+
+func (m *mockVisionService) AnalyzeFrame(ctx context.Context, imageData []byte) (*ai.FrameAnalysis, error) {
+	defer func() { m.calls++ }()
+	if m.calls < len(m.analyses) {
+		return m.analyses[m.calls], nil
+	}
+	return &ai.FrameAnalysis{Caption: "A dark room."}, nil
+}
+
+// mockBatchVisionService records the size of every multi-frame request.
+type mockBatchVisionService struct {
+	mockVisionService
+	batches []int
+}
+
+func (m *mockBatchVisionService) AnalyzeFrames(ctx context.Context, frames []ai.FrameImage) ([]*ai.FrameAnalysis, error) {
+	m.batches = append(m.batches, len(frames))
+	analyses := make([]*ai.FrameAnalysis, 0, len(frames))
+	for _, frame := range frames {
+		analysis, _ := m.AnalyzeFrame(ctx, frame.Data)
+		analyses = append(analyses, analysis)
+	}
+	return analyses, nil
+}
+
+type mockFrameStore struct {
+	records []*frame_analysis.FrameAnalysisDB
+}
+
+func (m *mockFrameStore) Create(ctx context.Context, analysis *frame_analysis.FrameAnalysisDB) error {
+	m.records = append(m.records, analysis)
+	return nil
+}
+
+func newTestIdentifier(vision ai.VisionService, extractor FrameExtractorInterface, store FrameAnalysisStore) *Identifier {
+	tmdb := &mockTMDbClient{movies: map[string][]mdb.Movie{
+		"inception": {{ID: 27205, Title: "Inception", ReleaseDate: "2010-07-15"}},
+	}}
+	config := ai.NewConfig()
+	config.MaxFramesPerVideo = 3
+	config.MaxFramesAnalyze = 6
+	return NewIdentifier(vision, extractor, store, NewScorer(tmdb, nil, DefaultWeights()), config)
+}
+
+func TestIdentifier_StopsEarlyWhenConfident(t *testing.T) {
+	vision := &mockVisionService{analyses: []*ai.FrameAnalysis{
+		{Caption: `This frame is from the movie "Inception" (2010).`, TextOCR: []string{"INCEPTION"}},
+	}}
+	extractor := &mockFrameExtractor{duration: 120}
+	store := &mockFrameStore{}
+
+	result, err := newTestIdentifier(vision, extractor, store).Identify(context.Background(), "video-1", "/tmp/video.mp4")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !result.StoppedEarly() {
+		t.Errorf("expected early stop, got %s", result.StopReason)
+	}
+	if vision.calls != 1 {
+		t.Errorf("expected 1 frame analyzed, got %d", vision.calls)
+	}
+	if len(store.records) != 1 {
+		t.Errorf("expected 1 stored frame, got %d", len(store.records))
+	}
+	if top := result.Top(); top == nil || top.TMDbID != 27205 {
+		t.Errorf("expected Inception as top candidate, got %+v", top)
+	}
+}
+
+func TestIdentifier_SamplesMoreFramesWhenWeak(t *testing.T) {
+	vision := &mockVisionService{}
+	extractor := &mockFrameExtractor{duration: 120}
+
+	result, err := newTestIdentifier(vision, extractor, nil).Identify(context.Background(), "video-1", "/tmp/video.mp4")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.StopReason != StopMaxFrames {
+		t.Errorf("expected max frames stop, got %s", result.StopReason)
+	}
+	if len(result.Frames) != 6 {
+		t.Errorf("expected 6 frames analyzed, got %d", len(result.Frames))
+	}
+
+	seen := make(map[float64]bool)
+	for _, ts := range extractor.timestamps {
+		if seen[ts] {
+			t.Errorf("timestamp %.2f sampled twice", ts)
+		}
+		seen[ts] = true
+	}
+}
+
+func TestIdentifier_AnalyzesRoundsInOneRequest(t *testing.T) {
+	vision := &mockBatchVisionService{}
+	extractor := &mockFrameExtractor{duration: 120}
+	store := &mockFrameStore{}
+
+	identifier := newTestIdentifier(vision, extractor, store)
+	identifier.config.OpenAIMultiFrame = true
+
+	result, err := identifier.Identify(context.Background(), "video-1", "/tmp/video.mp4")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(vision.batches) != 2 || vision.batches[0] != 3 || vision.batches[1] != 3 {
+		t.Errorf("expected two rounds of 3 frames, got %v", vision.batches)
+	}
+	if len(result.Frames) != 6 || len(result.Timestamps) != 6 {
+		t.Errorf("expected 6 frames with timestamps, got %d and %d", len(result.Frames), len(result.Timestamps))
+	}
+	for i, record := range store.records {
+		if record.FrameNumber != i {
+			t.Errorf("expected stored frame %d to be numbered %d, got %d", i, i, record.FrameNumber)
+		}
+	}
+}
+
+func TestNextTimestamps(t *testing.T) {
+	sampled := []float64{30, 60, 90}
+	next := nextTimestamps(sampled, 120, 2)
+
+	if len(next) != 2 {
+		t.Fatalf("expected 2 timestamps, got %v", next)
+	}
+	for _, ts := range next {
+		for _, s := range sampled {
+			if ts == s {
+				t.Errorf("timestamp %.2f was already sampled", ts)
+			}
+		}
+		if ts <= 0 || ts >= 120 {
+			t.Errorf("timestamp %.2f is outside the video", ts)
+		}
+	}
+}
+
+func TestToFrameRecord_StoresVisionFeatures(t *testing.T) {
+	analysis := &ai.FrameAnalysis{
+		Caption:         "A man in a suit.",
+		BestGuessLabels: []string{"inception movie"},
+		WebEntities:     []ai.WebEntity{{Description: "Inception", Score: 1.3}},
+		Logos:           []ai.Logo{{Name: "Warner Bros.", Confidence: 0.88}},
+	}
+
+	record, err := toFrameRecord("video-1", 0, 42.5, analysis)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(record.BestGuessLabels) != 1 {
+		t.Errorf("expected best guess labels, got %q", record.BestGuessLabels)
+	}
+	if !strings.Contains(string(record.WebEntities), "Inception") || !strings.Contains(string(record.Logos), "Warner Bros.") {
+		t.Errorf("unexpected web entities %s or logos %s", record.WebEntities, record.Logos)
+	}
+	if record.Landmarks != nil || record.Objects != nil || record.MatchingPages != nil {
+		t.Error("expected empty detections to be stored as NULL")
+	}
+}
+
+type mockFaceRecorder struct {
+	frames    []int
+	forgotten []string
+}
+
+func (m *mockFaceRecorder) Forget(ctx context.Context, videoID string) error {
+	m.forgotten = append(m.forgotten, videoID)
+	return nil
+}
+
+func (m *mockFaceRecorder) Record(ctx context.Context, videoID string, frameNumber int, frame []byte, faces []ai.FaceDetection) error {
+	m.frames = append(m.frames, frameNumber)
+	for i := range faces {
+		faces[i].ClusterID = "person-1"
+	}
+	return nil
+}
+
+func TestIdentifier_RecordsFacesBeforePersisting(t *testing.T) {
+	vision := &mockVisionService{analyses: []*ai.FrameAnalysis{
+		{Caption: `This frame is from the movie "Inception" (2010).`, TextOCR: []string{"INCEPTION"}, Faces: []ai.FaceDetection{{Confidence: 0.9}}},
+	}}
+	store := &mockFrameStore{}
+	recorder := &mockFaceRecorder{}
+
+	identifier := newTestIdentifier(vision, &mockFrameExtractor{duration: 120}, store)
+	identifier.UseFaces(recorder)
+
+	result, err := identifier.Identify(context.Background(), "video-1", "/tmp/video.mp4")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(recorder.frames) != 1 || recorder.frames[0] != 0 {
+		t.Errorf("expected faces of frame 0 to be recorded, got %v", recorder.frames)
+	}
+	if len(recorder.forgotten) != 1 || recorder.forgotten[0] != "video-1" {
+		t.Errorf("expected earlier faces of the video to be forgotten, got %v", recorder.forgotten)
+	}
+	if !strings.Contains(string(store.records[0].RawResponse), "person-1") {
+		t.Error("expected the stored analysis to include the face cluster")
+	}
+
+	fingerprint := Fingerprint(result.Frames)
+	found := false
+	for _, token := range fingerprint {
+		if token == "face:person-1" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected face cluster in fingerprint %v", fingerprint)
+	}
+}
+
+type mockTranscriber struct {
+	transcript *ai.Transcript
+}
+
+func (m *mockTranscriber) Transcribe(ctx context.Context, audio []byte) (*ai.Transcript, error) {
+	return m.transcript, nil
+}
+
+type mockTranscriptStore struct {
+	cues map[string][]*transcript.CueDB
+}
+
+func (m *mockTranscriptStore) ReplaceCues(ctx context.Context, videoID, source string, cues []*transcript.CueDB) error {
+	m.cues[source] = cues
+	return nil
+}
+
+func (m *mockTranscriptStore) ListByVideoID(ctx context.Context, videoID string) ([]*transcript.CueDB, error) {
+	var all []*transcript.CueDB
+	for _, cues := range m.cues {
+		all = append(all, cues...)
+	}
+	return all, nil
+}
+
+func TestIdentifier_TranscribesSpeech(t *testing.T) {
+	vision := &mockVisionService{}
+	extractor := &mockFrameExtractor{duration: 120}
+	tracks := &mockTranscriptStore{cues: make(map[string][]*transcript.CueDB)}
+	speech := &ai.Transcript{Language: "english", Segments: []ai.SpeechSegment{
+		{Start: 4, End: 7, Text: "You mustn't be afraid to dream a little bigger, darling."},
+	}}
+
+	identifier := newTestIdentifier(vision, extractor, nil)
+	identifier.scorer.searchClient = &mockSearchClient{results: []ai.SearchResult{
+		{Title: "Inception (2010) - Quotes - IMDb", Link: "https://www.imdb.com/title/tt1375666/quotes/", FilmTitle: "Inception", Year: 2010, IMDbID: "tt1375666"},
+	}}
+	identifier.UseTranscripts(tracks)
+	identifier.UseSpeech(extractor, &mockTranscriber{transcript: speech})
+
+	result, err := identifier.Identify(context.Background(), "video-1", "/tmp/video.mp4")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.Speech != speech {
+		t.Error("expected the transcript on the result")
+	}
+	if cues := tracks.cues[transcript.SourceSpeech]; len(cues) != 1 || cues[0].Start != 4 {
+		t.Errorf("expected the speech track to be stored, got %+v", cues)
+	}
+	top := result.Top()
+	if top == nil || top.Title != "Inception" || !hasSource(top, SourceDialogue) {
+		t.Fatalf("expected dialogue to identify the film, got %+v", result.Candidates)
+	}
+}
+
+func TestIdentifier_SearchesSubtitleDialogue(t *testing.T) {
+	tracks := &mockTranscriptStore{cues: map[string][]*transcript.CueDB{
+		transcript.SourceSubtitle: {{Source: transcript.SourceSubtitle, Text: "Short."}, {Source: transcript.SourceSubtitle, Text: "You mustn't be afraid to dream a little bigger, darling."}},
+	}}
+	search := &recordingSearchClient{results: []ai.SearchResult{
+		{Title: "Inception (2010) - Quotes - IMDb", Link: "https://www.imdb.com/title/tt1375666/quotes/", FilmTitle: "Inception", Year: 2010, IMDbID: "tt1375666"},
+	}}
+
+	identifier := newTestIdentifier(&mockVisionService{}, &mockFrameExtractor{duration: 120}, nil)
+	identifier.scorer.searchClient = search
+	identifier.UseTranscripts(tracks)
+
+	result, err := identifier.Identify(context.Background(), "video-1", "/tmp/video.mp4")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(search.queries) == 0 || search.queries[0] != `"You mustn't be afraid to dream a little bigger, darling." movie quote` {
+		t.Errorf("expected the subtitle line to be searched as a quote, got %q", search.queries)
+	}
+	if top := result.Top(); top == nil || top.Title != "Inception" {
+		t.Fatalf("expected subtitles to identify the film, got %+v", result.Candidates)
+	}
+}
+
+type recordingSearchClient struct {
+	results []ai.SearchResult
+	queries []string
+}
+
+func (m *recordingSearchClient) SearchFilms(ctx context.Context, query string) ([]ai.SearchResult, error) {
+	m.queries = append(m.queries, query)
+	return m.results, nil
+}
+
+type mockCatalog struct {
+	resolved []int
+}
+
+func (m *mockCatalog) Resolve(ctx context.Context, tmdbID int) (*film.FilmDB, error) {
+	m.resolved = append(m.resolved, tmdbID)
+	return &film.FilmDB{TMDbID: tmdbID, IMDbID: "tt1375666", Title: "Inception"}, nil
+}
+
+func TestIdentifier_CatalogsResolvedFilms(t *testing.T) {
+	vision := &mockVisionService{analyses: []*ai.FrameAnalysis{
+		{Caption: `This frame is from the movie "Inception" (2010).`, TextOCR: []string{"INCEPTION", "EXIT"}},
+	}}
+	catalog := &mockCatalog{}
+
+	identifier := newTestIdentifier(vision, &mockFrameExtractor{duration: 120}, nil)
+	identifier.UseCatalog(catalog)
+
+	result, err := identifier.Identify(context.Background(), "video-1", "/tmp/video.mp4")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(catalog.resolved) != 1 || catalog.resolved[0] != 27205 {
+		t.Errorf("expected only the resolved film to be cataloged, got %v", catalog.resolved)
+	}
+	if top := result.Top(); top == nil || top.IMDbID != "tt1375666" {
+		t.Errorf("expected the IMDb ID from the catalog, got %+v", top)
+	}
+}
@@ -0,0 +1,52 @@
+package identify
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/kdimtricp/vshazam/internal/ai"
+	"github.com/kdimtricp/vshazam/internal/mdb"
+	"github.com/kdimtricp/vshazam/internal/outbound/cassette"
+)
+
+// TestPipelineReplay runs identification end to end with the real provider
+// clients replaying recorded responses, so no network access or keys are
+// needed.
+func TestPipelineReplay(t *testing.T) {
+	transport := cassette.ForTest(t, filepath.Join("testdata", "cassettes", "pipeline_inception.json"))
+
+	openAI := ai.NewOpenAIClient("test-key")
+	openAI.UseTransport(transport)
+	googleVision := ai.NewGoogleVisionClient("test-key")
+	googleVision.UseTransport(transport)
+	search := ai.NewGoogleSearchClient("test-key", "test-cse")
+	search.UseTransport(transport)
+	tmdb := mdb.NewTMDbClient("test-key")
+	tmdb.UseTransport(transport)
+
+	config := ai.NewConfig()
+	config.MaxFramesPerVideo = 3
+	config.MaxFramesAnalyze = 6
+	vision := ai.NewVisionServiceWithClients(config, openAI, googleVision)
+	identifier := NewIdentifier(vision, &mockFrameExtractor{duration: 120}, &mockFrameStore{}, NewScorer(tmdb, search, DefaultWeights()), config)
+
+	result, err := identifier.Identify(context.Background(), "video-1", "/tmp/video.mp4")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !result.StoppedEarly() {
+		t.Errorf("expected early stop, got %s", result.StopReason)
+	}
+	top := result.Top()
+	if top == nil || top.TMDbID != 27205 || top.Year != 2010 {
+		t.Fatalf("expected Inception (2010) as top candidate, got %+v", top)
+	}
+	if len(top.Evidence) == 0 {
+		t.Error("expected evidence for the top candidate")
+	}
+	if unused := transport.Unused(); len(unused) > 0 {
+		t.Errorf("expected every recorded request to be made, %d were not", len(unused))
+	}
+}
@@ -0,0 +1,143 @@
+package identify
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/kdimtricp/vshazam/internal/ai"
+	"github.com/kdimtricp/vshazam/internal/models/frame_analysis"
+	"github.com/kdimtricp/vshazam/internal/models/identification"
+)
+
+// ReferenceLibrary provides fingerprints of videos whose film was confirmed
+// through user feedback.
+type ReferenceLibrary interface {
+	ListReferences(ctx context.Context) ([]*identification.ReferenceFingerprintDB, error)
+}
+
+var fingerprintStopwords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "this": true, "that": true,
+	"from": true, "are": true, "was": true, "his": true, "her": true, "its": true,
+	"frame": true, "scene": true, "image": true, "film": true, "movie": true,
+}
+
+// Fingerprint reduces frame analyses to a sorted set of distinctive tokens:
+// on-screen words, vision labels and capitalised words from captions. Two
+// clips of the same film tend to share a large part of this set.
+func Fingerprint(frames []*ai.FrameAnalysis) []string {
+	tokens := make(map[string]bool)
+
+	for _, frame := range frames {
+		if frame == nil {
+			continue
+		}
+		for _, text := range frame.TextOCR {
+			for _, word := range strings.Fields(normalizeTitle(text)) {
+				addToken(tokens, word)
+			}
+		}
+		for _, label := range frame.Labels {
+			if key := normalizeTitle(label.Name); key != "" {
+				tokens["label:"+key] = true
+			}
+		}
+		for _, face := range frame.Faces {
+			if face.ClusterID != "" {
+				tokens["face:"+face.ClusterID] = true
+			}
+		}
+		for _, logo := range frame.Logos {
+			if key := normalizeTitle(logo.Name); key != "" {
+				tokens["logo:"+key] = true
+			}
+		}
+		for _, landmark := range frame.Landmarks {
+			if key := normalizeTitle(landmark.Name); key != "" {
+				tokens["landmark:"+key] = true
+			}
+		}
+		for _, word := range properNouns(frame.Caption) {
+			addToken(tokens, word)
+		}
+	}
+
+	result := make([]string, 0, len(tokens))
+	for token := range tokens {
+		result = append(result, token)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// FingerprintRecords builds a fingerprint from stored frame analyses, using the
+// full analysis kept in RawResponse when it is available.
+func FingerprintRecords(records []*frame_analysis.FrameAnalysisDB) []string {
+	frames := make([]*ai.FrameAnalysis, 0, len(records))
+	for _, record := range records {
+		var stored ai.FrameAnalysis
+		if len(record.RawResponse) > 0 && json.Unmarshal(record.RawResponse, &stored) == nil {
+			frames = append(frames, &stored)
+			continue
+		}
+		frame := &ai.FrameAnalysis{
+			Caption: record.GPTCaption,
+			TextOCR: record.OCRText,
+		}
+		if len(record.VisionLabels) > 0 {
+			_ = json.Unmarshal(record.VisionLabels, &frame.Labels)
+		}
+		frames = append(frames, frame)
+	}
+	return Fingerprint(frames)
+}
+
+func addToken(tokens map[string]bool, word string) {
+	if len(word) < 3 || fingerprintStopwords[word] {
+		return
+	}
+	tokens[word] = true
+}
+
+// properNouns returns capitalised words that do not start a sentence, which
+// in GPT captions are mostly names of people, places and titles.
+func properNouns(caption string) []string {
+	var words []string
+	sentenceStart := true
+	for _, field := range strings.Fields(caption) {
+		trimmed := strings.TrimFunc(field, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		if trimmed != "" && !sentenceStart {
+			if r := []rune(trimmed)[0]; unicode.IsUpper(r) {
+				words = append(words, strings.ToLower(trimmed))
+			}
+		}
+		sentenceStart = strings.HasSuffix(field, ".") || strings.HasSuffix(field, "!") ||
+			strings.HasSuffix(field, "?") || strings.HasSuffix(field, ":")
+	}
+	return words
+}
+
+// similarity is the Jaccard index of two sorted token sets.
+func similarity(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	i, j, shared := 0, 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			shared++
+			i++
+			j++
+		case a[i] < b[j]:
+			i++
+		default:
+			j++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
@@ -0,0 +1,97 @@
+package identify
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kdimtricp/vshazam/internal/ai"
+	"github.com/kdimtricp/vshazam/internal/models/identification"
+)
+
+type mockReferenceLibrary struct {
+	references []*identification.ReferenceFingerprintDB
+}
+
+func (m *mockReferenceLibrary) ListReferences(ctx context.Context) ([]*identification.ReferenceFingerprintDB, error) {
+	return m.references, nil
+}
+
+func TestFingerprint(t *testing.T) {
+	frames := []*ai.FrameAnalysis{
+		{
+			Caption: "A man in a suit stands in Paris. The city folds over Cobb.",
+			TextOCR: []string{"CAFE", "DEBUSSY"},
+			Labels:  []ai.Label{{Name: "Street", Confidence: 0.9}},
+		},
+	}
+
+	tokens := Fingerprint(frames)
+	expected := []string{"cafe", "cobb", "debussy", "label:street", "paris"}
+	if len(tokens) != len(expected) {
+		t.Fatalf("expected tokens %v, got %v", expected, tokens)
+	}
+	for i := range expected {
+		if tokens[i] != expected[i] {
+			t.Errorf("expected token %q at %d, got %q", expected[i], i, tokens[i])
+		}
+	}
+}
+
+func TestScorer_MatchesReferenceLibrary(t *testing.T) {
+	frames := []*ai.FrameAnalysis{
+		{TextOCR: []string{"CAFE", "DEBUSSY"}, Labels: []ai.Label{{Name: "Street", Confidence: 0.9}}},
+	}
+	library := &mockReferenceLibrary{references: []*identification.ReferenceFingerprintDB{
+		{Title: "Inception", Year: 2010, TMDbID: 27205, Tokens: []string{"cafe", "debussy", "label:street", "paris"}},
+		{Title: "Heat", Year: 1995, TMDbID: 949, Tokens: []string{"bank", "label:car"}},
+	}}
+
+	scorer := NewScorer(nil, nil, DefaultWeights())
+	scorer.UseReferences(library)
+
+	candidates, err := scorer.Score(context.Background(), frames)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var inception *Candidate
+	for _, c := range candidates {
+		if c.Title == "Heat" {
+			t.Errorf("expected Heat not to match, got %s", c.Explain())
+		}
+		if c.TMDbID == 27205 {
+			inception = c
+		}
+	}
+	if inception == nil || !hasSource(inception, SourceReference) {
+		t.Fatalf("expected Inception to be suggested by the reference library, got %v", candidates)
+	}
+}
+
+func TestBuildAccuracyReport(t *testing.T) {
+	monday := time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)
+	samples := []identification.FeedbackSample{
+		{Verdict: identification.VerdictCorrect, TopScore: 0.95, CreatedAt: monday},
+		{Verdict: identification.VerdictWrong, TopScore: 0.92, CreatedAt: monday.AddDate(0, 0, 2)},
+		{Verdict: identification.VerdictCorrected, TopScore: 0.3, CreatedAt: monday.AddDate(0, 0, 7)},
+	}
+
+	report := BuildAccuracyReport(samples)
+
+	if report.Overall.Total != 3 || report.Overall.Correct != 1 {
+		t.Errorf("unexpected overall stats: %+v", report.Overall)
+	}
+	if got := report.Buckets[3].Accuracy(); got != 0.5 {
+		t.Errorf("expected 50%% accuracy in the 90–100%% bucket, got %f", got)
+	}
+	if report.Buckets[0].Total != 1 {
+		t.Errorf("expected 1 sample in the lowest bucket, got %d", report.Buckets[0].Total)
+	}
+	if len(report.Weeks) != 2 {
+		t.Fatalf("expected 2 weeks, got %d", len(report.Weeks))
+	}
+	if !report.Weeks[0].Start.Equal(time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected week to start on Monday, got %s", report.Weeks[0].Start)
+	}
+}
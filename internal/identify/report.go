@@ -0,0 +1,108 @@
+package identify
+
+import (
+	"time"
+
+	"github.com/kdimtricp/vshazam/internal/models/identification"
+)
+
+// AccuracyStats counts verdicts for a slice of feedback.
+type AccuracyStats struct {
+	Total     int
+	Correct   int
+	Wrong     int
+	Corrected int
+	ScoreSum  float64
+}
+
+func (s *AccuracyStats) add(sample identification.FeedbackSample) {
+	s.Total++
+	s.ScoreSum += sample.TopScore
+	switch sample.Verdict {
+	case identification.VerdictCorrect:
+		s.Correct++
+	case identification.VerdictWrong:
+		s.Wrong++
+	case identification.VerdictCorrected:
+		s.Corrected++
+	}
+}
+
+// Accuracy is the share of judged identifications whose top candidate was right.
+func (s AccuracyStats) Accuracy() float64 {
+	if s.Total == 0 {
+		return 0
+	}
+	return float64(s.Correct) / float64(s.Total)
+}
+
+// MeanScore is the average confidence the pipeline reported. Comparing it with
+// Accuracy shows whether scores are calibrated.
+func (s AccuracyStats) MeanScore() float64 {
+	if s.Total == 0 {
+		return 0
+	}
+	return s.ScoreSum / float64(s.Total)
+}
+
+type ScoreBucket struct {
+	Label string
+	Min   float64
+	Max   float64
+	AccuracyStats
+}
+
+type Period struct {
+	Start time.Time
+	AccuracyStats
+}
+
+type AccuracyReport struct {
+	Overall AccuracyStats
+	Buckets []*ScoreBucket
+	Weeks   []*Period
+}
+
+// BuildAccuracyReport summarises feedback overall, by reported confidence and
+// by week. Samples are expected oldest first.
+func BuildAccuracyReport(samples []identification.FeedbackSample) *AccuracyReport {
+	report := &AccuracyReport{
+		Buckets: []*ScoreBucket{
+			{Label: "0–50%", Min: 0, Max: 0.5},
+			{Label: "50–70%", Min: 0.5, Max: 0.7},
+			{Label: "70–90%", Min: 0.7, Max: 0.9},
+			{Label: "90–100%", Min: 0.9, Max: 1.01},
+		},
+	}
+
+	weeks := make(map[time.Time]*Period)
+	for _, sample := range samples {
+		report.Overall.add(sample)
+
+		for _, bucket := range report.Buckets {
+			if sample.TopScore >= bucket.Min && sample.TopScore < bucket.Max {
+				bucket.add(sample)
+				break
+			}
+		}
+
+		start := weekStart(sample.CreatedAt)
+		period, ok := weeks[start]
+		if !ok {
+			period = &Period{Start: start}
+			weeks[start] = period
+			report.Weeks = append(report.Weeks, period)
+		}
+		period.add(sample)
+	}
+
+	return report
+}
+
+// weekStart truncates t to midnight UTC on the Monday of its week.
+func weekStart(t time.Time) time.Time {
+	t = t.UTC()
+	offset := (int(t.Weekday()) + 6) % 7
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return day.AddDate(0, 0, -offset)
+}
@@ -0,0 +1,1182 @@
+package identify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/kdimtricp/vshazam/internal/ai"
+	"github.com/kdimtricp/vshazam/internal/mdb"
+	"github.com/kdimtricp/vshazam/internal/models/identification"
+	"github.com/kdimtricp/vshazam/internal/outbound"
+	"github.com/kdimtricp/vshazam/internal/titles"
+)
+
+type TMDbClientInterface interface {
+	SearchMovies(ctx context.Context, query string) ([]mdb.Movie, error)
+}
+
+// TVClient is implemented by mdb.TMDbClient. When the scorer's TMDb client
+// also searches TV, candidates without an exact film match are looked up as
+// shows, and an episode marker such as "S02E05" resolves to the episode.
+type TVClient interface {
+	SearchTV(ctx context.Context, query string) ([]mdb.TVShow, error)
+	GetEpisode(ctx context.Context, tvID, season, episode int) (*mdb.Episode, error)
+}
+
+// AlternativeTitler is implemented by mdb.TMDbClient and catalog.Catalog.
+// When the scorer's TMDb client also lists alternative titles, a candidate
+// without an exact match is compared with the localized and working titles
+// of the leading results, so "El Origen" resolves to Inception.
+type AlternativeTitler interface {
+	GetAlternativeTitles(ctx context.Context, movieID int) ([]mdb.AlternativeTitle, error)
+}
+
+// AliasResolver is implemented by imdb.Dataset. It returns the primary title
+// of the film known under title, or "" when title is not an alias.
+type AliasResolver interface {
+	ResolveAlias(ctx context.Context, title string) (string, error)
+}
+
+type GoogleSearchClientInterface interface {
+	SearchFilms(ctx context.Context, query string) ([]ai.SearchResult, error)
+}
+
+type EvidenceSource string
+
+const (
+	SourceCaption   EvidenceSource = "caption"
+	SourceOCR       EvidenceSource = "ocr"
+	SourceLabel     EvidenceSource = "label"
+	SourceTMDb      EvidenceSource = "tmdb"
+	SourceMetadata  EvidenceSource = "metadata"
+	SourceWebSearch EvidenceSource = "web_search"
+	SourceReference EvidenceSource = "reference"
+	SourceWebEntity EvidenceSource = "web_entity"
+	SourceDialogue  EvidenceSource = "dialogue"
+)
+
+// Evidence is one reason a candidate gained or lost score. Weight is a
+// log-odds contribution, so evidence items add up before calibration.
+type Evidence struct {
+	Source EvidenceSource `json:"source"`
+	Frame  int            `json:"frame"`
+	Detail string         `json:"detail"`
+	Weight float64        `json:"weight"`
+}
+
+// FrameNumber is the 1-based frame the evidence came from, or 0 when the
+// evidence applies to the whole video.
+func (e Evidence) FrameNumber() int {
+	return e.Frame + 1
+}
+
+type Candidate struct {
+	Title    string `json:"title"`
+	Year     int    `json:"year,omitempty"`
+	TMDbID   int    `json:"tmdb_id,omitempty"`
+	IMDbID   string `json:"imdb_id,omitempty"`
+	Overview string `json:"overview,omitempty"`
+	// MediaType is mdb.MediaTV when TMDbID is a show; empty means a film.
+	MediaType    string     `json:"media_type,omitempty"`
+	Season       int        `json:"season,omitempty"`
+	Episode      int        `json:"episode,omitempty"`
+	EpisodeTitle string     `json:"episode_title,omitempty"`
+	Score        float64    `json:"score"`
+	Evidence     []Evidence `json:"evidence"`
+	Frames       []int      `json:"frames"`
+
+	key string
+}
+
+// LogOdds is the uncalibrated sum of all evidence weights.
+func (c *Candidate) LogOdds() float64 {
+	total := 0.0
+	for _, e := range c.Evidence {
+		total += e.Weight
+	}
+	return total
+}
+
+// IsEpisode reports whether the candidate names one episode of a show.
+func (c *Candidate) IsEpisode() bool {
+	return c.Season > 0 && c.Episode > 0
+}
+
+// DisplayTitle returns the title with the release year when known, followed
+// by the episode for TV candidates.
+func (c *Candidate) DisplayTitle() string {
+	title := c.Title
+	if c.Year > 0 {
+		title = fmt.Sprintf("%s (%d)", c.Title, c.Year)
+	}
+	if c.IsEpisode() {
+		title += " " + mdb.EpisodeCode(c.Season, c.Episode)
+		if c.EpisodeTitle != "" {
+			title += fmt.Sprintf(" %q", c.EpisodeTitle)
+		}
+	}
+	return title
+}
+
+// Explain renders a human-readable breakdown of how the score was reached.
+func (c *Candidate) Explain() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %.0f%% match\n", c.DisplayTitle(), c.Score*100)
+	for _, e := range c.Evidence {
+		if e.Frame >= 0 {
+			fmt.Fprintf(&b, "  %+.2f %s (frame %d): %s\n", e.Weight, e.Source, e.FrameNumber(), e.Detail)
+		} else {
+			fmt.Fprintf(&b, "  %+.2f %s: %s\n", e.Weight, e.Source, e.Detail)
+		}
+	}
+	return b.String()
+}
+
+func (c *Candidate) addEvidence(source EvidenceSource, frame int, weight float64, format string, args ...interface{}) {
+	c.Evidence = append(c.Evidence, Evidence{
+		Source: source,
+		Frame:  frame,
+		Detail: fmt.Sprintf(format, args...),
+		Weight: weight,
+	})
+}
+
+func (c *Candidate) addFrame(frame int) {
+	for _, f := range c.Frames {
+		if f == frame {
+			return
+		}
+	}
+	c.Frames = append(c.Frames, frame)
+	sort.Ints(c.Frames)
+}
+
+// Weights are log-odds contributions for each kind of evidence. Prior is the
+// starting log-odds of any candidate being the right film.
+type Weights struct {
+	Prior         float64
+	CaptionNamed  float64
+	CaptionHedged float64
+	OCRTitle      float64
+	OCRVisible    float64
+	LabelOverlap  float64
+	TMDbExact     float64
+	TMDbPartial   float64
+	TMDbNoMatch   float64
+	YearMatch     float64
+	YearMismatch  float64
+	// EpisodeMatch and EpisodeMismatch apply when an episode marker such as
+	// "S02E05" does or does not name an episode of the matched show.
+	EpisodeMatch    float64
+	EpisodeMismatch float64
+	WebHit          float64
+	WebAuthority    float64
+	MaxLabelBoosts  int
+	MaxWebHits      int
+	// WebBestGuess is Google Vision's best-guess label naming a title;
+	// WebEntity is scaled by the entity's score, capped at 1.
+	WebBestGuess float64
+	WebEntity    float64
+	// OCRTitleCard is text centered in the frame with little else on screen.
+	OCRTitleCard float64
+	// DialogueQuote is a web page attributing a transcribed line to a film.
+	DialogueQuote float64
+	// ReferenceMatch is scaled by fingerprint similarity to a confirmed video.
+	ReferenceMatch         float64
+	ReferenceMinSimilarity float64
+}
+
+func DefaultWeights() Weights {
+	return Weights{
+		Prior:           -3.0,
+		CaptionNamed:    2.2,
+		CaptionHedged:   1.0,
+		OCRTitle:        0.8,
+		OCRVisible:      1.2,
+		LabelOverlap:    0.15,
+		TMDbExact:       1.5,
+		TMDbPartial:     0.4,
+		TMDbNoMatch:     -1.5,
+		YearMatch:       0.8,
+		YearMismatch:    -0.8,
+		EpisodeMatch:    0.8,
+		EpisodeMismatch: -0.8,
+		WebHit:          0.35,
+		WebAuthority:    0.5,
+		MaxLabelBoosts:  3,
+		MaxWebHits:      5,
+		WebBestGuess:    1.2,
+		WebEntity:       0.6,
+		OCRTitleCard:    1.4,
+		DialogueQuote:   1.0,
+
+		ReferenceMatch:         4.0,
+		ReferenceMinSimilarity: 0.35,
+	}
+}
+
+// Scorer turns per-frame analyses into ranked, explainable film candidates.
+// Candidate titles come from GPT captions and OCR text, are cross-checked
+// against TMDb and Google Custom Search, and evidence is aggregated across
+// frames before a logistic calibration maps log-odds onto a 0..1 score.
+type Scorer struct {
+	tmdbClient   TMDbClientInterface
+	providers    []mdb.Provider
+	searchClient GoogleSearchClientInterface
+	references   ReferenceLibrary
+	aliases      AliasResolver
+	weights      Weights
+	maxLookups   int
+	maxSearches  int
+	maxDialogue  int
+	// maxAlternatives bounds the search results whose alternative titles
+	// are fetched for one candidate.
+	maxAlternatives int
+}
+
+// NewScorer creates a scorer. Either client may be nil, in which case that
+// cross-check is skipped.
+func NewScorer(tmdbClient TMDbClientInterface, searchClient GoogleSearchClientInterface, weights Weights) *Scorer {
+	return &Scorer{
+		tmdbClient:   tmdbClient,
+		searchClient: searchClient,
+		weights:      weights,
+		maxLookups:   5,
+		maxSearches:  3,
+		maxDialogue:  2,
+
+		maxAlternatives: 3,
+	}
+}
+
+// UseProviders adds metadata providers that are searched, in order, for
+// candidates TMDb does not resolve or when TMDb is not configured. Their
+// evidence is recorded as SourceMetadata and names the provider.
+func (s *Scorer) UseProviders(providers ...mdb.Provider) {
+	s.providers = append(s.providers, providers...)
+}
+
+// UseAliases renames candidates named by a localized or alternative title to
+// the film's primary title before they are looked up, e.g. "El Origen" to
+// "Inception", so both spellings pool their evidence.
+func (s *Scorer) UseAliases(resolver AliasResolver) {
+	s.aliases = resolver
+}
+
+// UseReferences lets the scorer match clips against fingerprints of videos
+// whose film was confirmed by user feedback.
+func (s *Scorer) UseReferences(library ReferenceLibrary) {
+	s.references = library
+}
+
+func (s *Scorer) Score(ctx context.Context, frames []*ai.FrameAnalysis) ([]*Candidate, error) {
+	return s.NewSession().Score(ctx, frames)
+}
+
+// Session scores the same video repeatedly as frames arrive. TMDb and web
+// search lookups are memoized for the lifetime of the session so rescoring
+// after every frame does not repeat paid API calls.
+type Session struct {
+	*Scorer
+	movies       map[string][]mdb.Movie
+	provided     map[string][]mdb.Movie
+	shows        map[string][]mdb.TVShow
+	episodes     map[string]*mdb.Episode
+	alternatives map[int][]mdb.AlternativeTitle
+	aliased      map[string]string
+	web          map[string][]ai.SearchResult
+	webMu        sync.Mutex
+	fingerprints []*identification.ReferenceFingerprintDB
+	loaded       bool
+	dialogue     []string
+}
+
+func (s *Scorer) NewSession() *Session {
+	return &Session{
+		Scorer:       s,
+		movies:       make(map[string][]mdb.Movie),
+		provided:     make(map[string][]mdb.Movie),
+		shows:        make(map[string][]mdb.TVShow),
+		episodes:     make(map[string]*mdb.Episode),
+		alternatives: make(map[int][]mdb.AlternativeTitle),
+		aliased:      make(map[string]string),
+		web:          make(map[string][]ai.SearchResult),
+	}
+}
+
+// UseDialogue adds lines transcribed from the video's audio. Each is searched
+// for as a quote and the films the results attribute it to become
+// candidates.
+func (s *Session) UseDialogue(lines []string) {
+	s.dialogue = lines
+}
+
+func (s *Session) Score(ctx context.Context, frames []*ai.FrameAnalysis) ([]*Candidate, error) {
+	candidates := s.collect(frames)
+	candidates = s.matchReferences(ctx, frames, candidates)
+	candidates = s.matchDialogue(ctx, candidates)
+	candidates = s.resolveAliases(ctx, candidates)
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	rank(candidates, s.weights.Prior)
+
+	if s.tmdbClient != nil || len(s.providers) > 0 {
+		for i, c := range candidates {
+			if i >= s.maxLookups {
+				break
+			}
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			s.crossCheck(ctx, c)
+		}
+		candidates = mergeByID(candidates)
+	}
+
+	s.addLabelEvidence(candidates, frames)
+	rank(candidates, s.weights.Prior)
+
+	if s.searchClient != nil {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		s.crossCheckWeb(ctx, candidates)
+	}
+
+	rank(candidates, s.weights.Prior)
+	return candidates, nil
+}
+
+// collect gathers caption and OCR mentions from every frame and groups them
+// by normalized title, folding titles OCR misread by a letter or two into
+// the one already seen. Repeated evidence from the same source is damped so a
+// title named in five frames does not score five times as much as one.
+func (s *Scorer) collect(frames []*ai.FrameAnalysis) []*Candidate {
+	byKey := make(map[string]*Candidate)
+	var order []*Candidate
+	counts := make(map[string]int)
+
+	add := func(m mention, weight float64, format string, args ...interface{}) {
+		if m.season == 0 {
+			m.title, m.season, m.episode = splitEpisode(m.title)
+		}
+		if title, year := titles.SplitYear(m.title); year > 0 {
+			m.title = title
+			if m.year == 0 {
+				m.year = year
+			}
+		}
+		key := normalizeTitle(m.title)
+		if key == "" {
+			return
+		}
+		c, ok := byKey[key]
+		if !ok {
+			if c = fuzzyMatch(order, key); c != nil {
+				byKey[key] = c
+				ok = true
+			}
+		}
+		if !ok {
+			c = &Candidate{Title: m.title, Year: m.year, Season: m.season, Episode: m.episode, key: key}
+			byKey[key] = c
+			order = append(order, c)
+		}
+		if c.Year == 0 && m.year > 0 {
+			c.Year = m.year
+		}
+		// The first episode marker seen for a title wins.
+		if !c.IsEpisode() && m.season > 0 {
+			c.Season, c.Episode = m.season, m.episode
+		}
+		countKey := c.key + "|" + string(m.source)
+		weight = damp(weight, counts[countKey])
+		counts[countKey]++
+		c.addEvidence(m.source, m.frame, weight, format, args...)
+		c.addFrame(m.frame)
+	}
+
+	for i, frame := range frames {
+		if frame == nil {
+			continue
+		}
+		// Structured captions list their guesses explicitly; the prose caption
+		// is only mined when GPT answered in free text.
+		mentions := filmGuessMentions(frame.FilmGuesses, i)
+		if len(frame.FilmGuesses) == 0 {
+			mentions = extractCaptionMentions(frame.Caption, i)
+		}
+		for _, m := range mentions {
+			if m.hedged {
+				add(m, s.weights.CaptionHedged, "GPT tentatively suggested %q", m.title)
+			} else {
+				add(m, s.weights.CaptionNamed, "GPT named %q", m.title)
+			}
+		}
+		// With positioned OCR, subtitles and credits are left out: they hold
+		// dialogue and names rather than titles.
+		if len(frame.TextBlocks) == 0 {
+			for _, m := range extractOCRMentions(frame.TextOCR, i) {
+				add(m, s.weights.OCRTitle, "on-screen text %q", m.detail)
+			}
+		} else {
+			for _, m := range regionMentions(frame.TextBlocks, ai.RegionTitleCard, i) {
+				add(m, s.weights.OCRTitleCard, "title card %q", m.detail)
+			}
+			for _, m := range regionMentions(frame.TextBlocks, ai.RegionOther, i) {
+				add(m, s.weights.OCRTitle, "on-screen text %q", m.detail)
+			}
+		}
+		for _, m := range webMentions(frame, i) {
+			if m.hedged {
+				add(m, s.weights.WebEntity*webEntityScore(frame, m.title), "web entity %s", m.detail)
+			} else {
+				add(m, s.weights.WebBestGuess, "Google best guess %q", m.detail)
+			}
+		}
+	}
+
+	// A caption or web guess whose title is also visible on screen is much
+	// stronger than either signal alone.
+	for _, c := range order {
+		if !(hasSource(c, SourceCaption) || hasSource(c, SourceWebEntity)) || hasSource(c, SourceOCR) {
+			continue
+		}
+		for i, frame := range frames {
+			if frame == nil {
+				continue
+			}
+			if corpus := ocrCorpus(frame); corpus != "" && containsPhrase(corpus, c.key) {
+				c.addEvidence(SourceOCR, i, s.weights.OCRVisible, "title %q visible on screen", c.Title)
+				c.addFrame(i)
+				break
+			}
+		}
+	}
+
+	return order
+}
+
+// fuzzyMatch returns the candidate whose key matches key within the edit
+// distance titles.Match allows, or nil.
+func fuzzyMatch(candidates []*Candidate, key string) *Candidate {
+	for _, c := range candidates {
+		if titles.Match(c.key, key) {
+			return c
+		}
+	}
+	return nil
+}
+
+// resolveAliases renames candidates the alias resolver knows under another
+// primary title and merges candidates that then share a title. Candidates
+// already resolved, e.g. by a reference fingerprint, are left alone.
+func (s *Session) resolveAliases(ctx context.Context, candidates []*Candidate) []*Candidate {
+	if s.aliases == nil {
+		return candidates
+	}
+	byKey := make(map[string]*Candidate)
+	merged := make([]*Candidate, 0, len(candidates))
+	for _, c := range candidates {
+		if c.TMDbID == 0 && c.IMDbID == "" {
+			if primary := s.resolveAlias(ctx, c.Title); primary != "" {
+				c.addEvidence(SourceMetadata, -1, 0, "%q is also known as %q", primary, c.Title)
+				c.Title = primary
+				c.key = normalizeTitle(primary)
+			}
+		}
+		existing, ok := byKey[c.key]
+		if !ok {
+			byKey[c.key] = c
+			merged = append(merged, c)
+			continue
+		}
+		existing.Evidence = append(existing.Evidence, c.Evidence...)
+		for _, f := range c.Frames {
+			existing.addFrame(f)
+		}
+		if existing.Year == 0 {
+			existing.Year = c.Year
+		}
+	}
+	return merged
+}
+
+// resolveAlias memoizes the resolver's answers, including misses. Failures
+// are logged and leave the title as it is.
+func (s *Session) resolveAlias(ctx context.Context, title string) string {
+	if primary, ok := s.aliased[title]; ok {
+		return primary
+	}
+	primary, err := s.aliases.ResolveAlias(ctx, title)
+	if err != nil {
+		log.Printf("Alias lookup for %q failed: %v", title, err)
+		return ""
+	}
+	if normalizeTitle(primary) == normalizeTitle(title) {
+		primary = ""
+	}
+	s.aliased[title] = primary
+	return primary
+}
+
+func (s *Session) searchMovies(ctx context.Context, title string) ([]mdb.Movie, error) {
+	if movies, ok := s.movies[title]; ok {
+		return movies, nil
+	}
+	movies, err := s.tmdbClient.SearchMovies(ctx, title)
+	if err != nil {
+		return nil, err
+	}
+	s.movies[title] = movies
+	return movies, nil
+}
+
+func (s *Session) searchProvider(ctx context.Context, p mdb.Provider, title string) ([]mdb.Movie, error) {
+	key := p.Name() + "\x00" + title
+	if movies, ok := s.provided[key]; ok {
+		return movies, nil
+	}
+	movies, err := p.SearchMovies(ctx, title)
+	if err != nil {
+		return nil, err
+	}
+	s.provided[key] = movies
+	return movies, nil
+}
+
+func (s *Session) searchTV(ctx context.Context, tv TVClient, title string) ([]mdb.TVShow, error) {
+	if shows, ok := s.shows[title]; ok {
+		return shows, nil
+	}
+	shows, err := tv.SearchTV(ctx, title)
+	if err != nil {
+		return nil, err
+	}
+	s.shows[title] = shows
+	return shows, nil
+}
+
+// getEpisode memoizes misses as nil so an episode that does not exist is
+// not requested again.
+func (s *Session) getEpisode(ctx context.Context, tv TVClient, tvID, season, episode int) (*mdb.Episode, error) {
+	key := fmt.Sprintf("%d/%d/%d", tvID, season, episode)
+	if ep, ok := s.episodes[key]; ok {
+		return ep, nil
+	}
+	ep, err := tv.GetEpisode(ctx, tvID, season, episode)
+	if err != nil && !outbound.IsPermanent(err) {
+		return nil, err
+	}
+	s.episodes[key] = ep
+	return ep, nil
+}
+
+// getAlternativeTitles memoizes a film's alternative titles by TMDb ID.
+func (s *Session) getAlternativeTitles(ctx context.Context, alt AlternativeTitler, movieID int) ([]mdb.AlternativeTitle, error) {
+	if alternatives, ok := s.alternatives[movieID]; ok {
+		return alternatives, nil
+	}
+	alternatives, err := alt.GetAlternativeTitles(ctx, movieID)
+	if err != nil {
+		return nil, err
+	}
+	s.alternatives[movieID] = alternatives
+	return alternatives, nil
+}
+
+// searchWeb runs the queries that are not memoized yet concurrently. Failed
+// queries are logged and have no results.
+func (s *Session) searchWeb(ctx context.Context, queries []string) map[string][]ai.SearchResult {
+	found := make(map[string][]ai.SearchResult, len(queries))
+	var missing []string
+
+	s.webMu.Lock()
+	for _, query := range queries {
+		if results, ok := s.web[query]; ok {
+			found[query] = results
+		} else {
+			missing = append(missing, query)
+		}
+	}
+	s.webMu.Unlock()
+
+	if len(missing) == 0 {
+		return found
+	}
+
+	results, errs := ai.SearchAll(ctx, s.searchClient, missing)
+
+	s.webMu.Lock()
+	defer s.webMu.Unlock()
+	for i, query := range missing {
+		if errs[i] != nil {
+			log.Printf("Web search for %s failed: %v", query, errs[i])
+			continue
+		}
+		s.web[query] = results[i]
+		found[query] = results[i]
+	}
+	return found
+}
+
+// matchReferences compares the clip's fingerprint with every confirmed video
+// and adds evidence for the films whose fingerprints overlap enough.
+func (s *Session) matchReferences(ctx context.Context, frames []*ai.FrameAnalysis, candidates []*Candidate) []*Candidate {
+	if s.references == nil {
+		return candidates
+	}
+	if !s.loaded {
+		fingerprints, err := s.references.ListReferences(ctx)
+		if err != nil {
+			log.Printf("Failed to load reference fingerprints: %v", err)
+		}
+		s.fingerprints = fingerprints
+		s.loaded = true
+	}
+	if len(s.fingerprints) == 0 {
+		return candidates
+	}
+
+	fingerprint := Fingerprint(frames)
+	byKey := make(map[string]*Candidate, len(candidates))
+	for _, c := range candidates {
+		byKey[c.key] = c
+	}
+	matches := make(map[string]int)
+
+	for _, ref := range s.fingerprints {
+		sim := similarity(fingerprint, ref.Tokens)
+		if sim < s.weights.ReferenceMinSimilarity {
+			continue
+		}
+		key := normalizeTitle(ref.Title)
+		c, ok := byKey[key]
+		if !ok {
+			c = &Candidate{Title: ref.Title, Year: ref.Year, TMDbID: ref.TMDbID, key: key}
+			byKey[key] = c
+			candidates = append(candidates, c)
+		}
+		weight := damp(s.weights.ReferenceMatch*sim, matches[key])
+		matches[key]++
+		c.addEvidence(SourceReference, -1, weight, "%.0f%% fingerprint overlap with a confirmed clip of %q", sim*100, ref.Title)
+	}
+
+	return candidates
+}
+
+// matchDialogue searches the web for quoted lines of dialogue and adds
+// evidence for the films the results name. Searches are memoized, so
+// rescoring after every frame costs nothing extra.
+func (s *Session) matchDialogue(ctx context.Context, candidates []*Candidate) []*Candidate {
+	if s.searchClient == nil || len(s.dialogue) == 0 {
+		return candidates
+	}
+
+	byKey := make(map[string]*Candidate, len(candidates))
+	for _, c := range candidates {
+		byKey[c.key] = c
+	}
+	quotes := make(map[string]int)
+
+	lines := s.dialogue
+	if len(lines) > s.maxDialogue {
+		lines = lines[:s.maxDialogue]
+	}
+	builder := ai.NewQueryBuilder(len(lines))
+	for _, line := range lines {
+		builder.AddQuote(line)
+	}
+	found := s.searchWeb(ctx, builder.Queries())
+
+	for _, line := range lines {
+		results, ok := found[ai.QuoteQuery(line).String()]
+		if !ok {
+			continue
+		}
+		for _, m := range dialogueMentions(results, line) {
+			key := normalizeTitle(m.title)
+			c, ok := byKey[key]
+			if !ok {
+				c = &Candidate{Title: m.title, Year: m.year, key: key}
+				byKey[key] = c
+				candidates = append(candidates, c)
+			}
+			if c.Year == 0 && m.year > 0 {
+				c.Year = m.year
+			}
+			weight := damp(s.weights.DialogueQuote, quotes[key])
+			quotes[key]++
+			c.addEvidence(SourceDialogue, m.frame, weight, "%s", m.detail)
+		}
+	}
+
+	return candidates
+}
+
+// crossCheck resolves the candidate against TMDb and then the fallback
+// providers, stopping at the first that knows it. A candidate that every
+// provider answering without error has no results for is penalized once.
+func (s *Session) crossCheck(ctx context.Context, c *Candidate) {
+	var empty []string
+	source := SourceMetadata
+	if s.tmdbClient != nil {
+		resolved, answered := s.crossCheckTMDb(ctx, c)
+		if resolved {
+			return
+		}
+		if answered {
+			empty = append(empty, "TMDb")
+			source = SourceTMDb
+		}
+	}
+	for _, p := range s.providers {
+		resolved, answered := s.crossCheckProvider(ctx, p, c)
+		if resolved {
+			return
+		}
+		if answered {
+			empty = append(empty, p.Name())
+		}
+	}
+	if len(empty) > 0 {
+		c.addEvidence(source, -1, s.weights.TMDbNoMatch, "no %s results for %q", strings.Join(empty, " or "), c.Title)
+	}
+}
+
+// crossCheckTMDb reports whether TMDb resolved the candidate to a film or
+// show, and whether it answered at all.
+func (s *Session) crossCheckTMDb(ctx context.Context, c *Candidate) (resolved, answered bool) {
+	movies, err := s.searchMovies(ctx, c.Title)
+	if err != nil {
+		log.Printf("TMDb lookup for %q failed: %v", c.Title, err)
+		return false, false
+	}
+	var best *mdb.Movie
+	var exact bool
+	var alias string
+	if len(movies) > 0 {
+		best, exact = pickMovie(movies, c.key, c.Year)
+	}
+	if alt, ok := s.tmdbClient.(AlternativeTitler); ok && len(movies) > 0 && !exact && !c.IsEpisode() {
+		if movie, title := s.matchAlternativeTitle(ctx, alt, movies, c.key); movie != nil {
+			best, exact, alias = movie, true, title
+		}
+	}
+	if tv, ok := s.tmdbClient.(TVClient); ok && (!exact || c.IsEpisode()) {
+		if s.crossCheckTV(ctx, tv, c) {
+			return true, true
+		}
+	}
+	if len(movies) == 0 {
+		return false, true
+	}
+
+	switch {
+	case alias != "":
+		c.addEvidence(SourceTMDb, -1, s.weights.TMDbExact, "TMDb alternative title %q of %q (id %d)", alias, best.Title, best.ID)
+	case exact:
+		c.addEvidence(SourceTMDb, -1, s.weights.TMDbExact, "exact TMDb title match %q (id %d)", best.Title, best.ID)
+	default:
+		c.addEvidence(SourceTMDb, -1, s.weights.TMDbPartial, "closest TMDb result %q (id %d)", best.Title, best.ID)
+	}
+	s.resolveMovie(c, SourceTMDb, "TMDb", best)
+	c.TMDbID = best.ID
+	return true, true
+}
+
+// matchAlternativeTitle returns the first of the leading search results with
+// an alternative title matching key, and that title. Lookup failures are
+// logged and skipped.
+func (s *Session) matchAlternativeTitle(ctx context.Context, alt AlternativeTitler, movies []mdb.Movie, key string) (*mdb.Movie, string) {
+	for i := range movies {
+		if i >= s.maxAlternatives {
+			break
+		}
+		if movies[i].ID == 0 {
+			continue
+		}
+		alternatives, err := s.getAlternativeTitles(ctx, alt, movies[i].ID)
+		if err != nil {
+			log.Printf("TMDb alternative titles for %d failed: %v", movies[i].ID, err)
+			continue
+		}
+		for _, a := range alternatives {
+			if titles.Match(normalizeTitle(a.Title), key) {
+				return &movies[i], a.Title
+			}
+		}
+	}
+	return nil, ""
+}
+
+// crossCheckProvider is crossCheckTMDb for a fallback provider. Providers
+// only search films, and their results are identified by IMDb ID.
+func (s *Session) crossCheckProvider(ctx context.Context, p mdb.Provider, c *Candidate) (resolved, answered bool) {
+	movies, err := s.searchProvider(ctx, p, c.Title)
+	if err != nil {
+		log.Printf("%s lookup for %q failed: %v", p.Name(), c.Title, err)
+		return false, false
+	}
+	if len(movies) == 0 {
+		return false, true
+	}
+
+	best, exact := pickMovie(movies, c.key, c.Year)
+	if exact {
+		c.addEvidence(SourceMetadata, -1, s.weights.TMDbExact, "exact %s title match %q (%s)", p.Name(), best.Title, best.IMDbID)
+	} else {
+		c.addEvidence(SourceMetadata, -1, s.weights.TMDbPartial, "closest %s result %q (%s)", p.Name(), best.Title, best.IMDbID)
+	}
+	s.resolveMovie(c, SourceMetadata, p.Name(), best)
+	if best.ID > 0 {
+		c.TMDbID = best.ID
+	}
+	if best.IMDbID != "" {
+		c.IMDbID = best.IMDbID
+	}
+	return true, true
+}
+
+// resolveMovie weighs the candidate's year against the film it resolved to
+// and takes over the film's title, overview and year.
+func (s *Session) resolveMovie(c *Candidate, source EvidenceSource, provider string, best *mdb.Movie) {
+	year := releaseYear(best.ReleaseDate)
+	if c.Year > 0 && year > 0 {
+		if c.Year == year {
+			c.addEvidence(source, -1, s.weights.YearMatch, "release year %d agrees", year)
+		} else {
+			c.addEvidence(source, -1, s.weights.YearMismatch, "guessed year %d but %s says %d", c.Year, provider, year)
+		}
+	}
+
+	c.Title = best.Title
+	if best.Overview != "" {
+		c.Overview = best.Overview
+	}
+	if year > 0 {
+		c.Year = year
+	}
+}
+
+// crossCheckTV resolves the candidate to the show whose name matches its
+// title exactly and, when the candidate carries an episode marker, to that
+// episode. It reports false when no show matched.
+func (s *Session) crossCheckTV(ctx context.Context, tv TVClient, c *Candidate) bool {
+	shows, err := s.searchTV(ctx, tv, c.Title)
+	if err != nil {
+		log.Printf("TMDb TV lookup for %q failed: %v", c.Title, err)
+		return false
+	}
+	show := pickShow(shows, c.key)
+	if show == nil {
+		return false
+	}
+
+	c.addEvidence(SourceTMDb, -1, s.weights.TMDbExact, "exact TMDb TV show match %q (id %d)", show.Name, show.ID)
+	c.MediaType = mdb.MediaTV
+	c.TMDbID = show.ID
+	c.Title = show.Name
+	c.Overview = show.Overview
+	// A year seen on screen may belong to any season, so it is neither
+	// rewarded nor penalized.
+	if year := releaseYear(show.FirstAirDate); year > 0 {
+		c.Year = year
+	}
+
+	if !c.IsEpisode() {
+		return true
+	}
+	code := mdb.EpisodeCode(c.Season, c.Episode)
+	episode, err := s.getEpisode(ctx, tv, show.ID, c.Season, c.Episode)
+	switch {
+	case err != nil:
+		log.Printf("TMDb lookup for %s of %q failed: %v", code, show.Name, err)
+	case episode == nil:
+		c.addEvidence(SourceTMDb, -1, s.weights.EpisodeMismatch, "%q has no episode %s on TMDb", show.Name, code)
+	default:
+		c.addEvidence(SourceTMDb, -1, s.weights.EpisodeMatch, "episode %s %q exists", code, episode.Name)
+		c.EpisodeTitle = episode.Name
+		if episode.Overview != "" {
+			c.Overview = episode.Overview
+		}
+	}
+	return true
+}
+
+func (s *Scorer) addLabelEvidence(candidates []*Candidate, frames []*ai.FrameAnalysis) {
+	labels := make(map[string]bool)
+	for _, frame := range frames {
+		if frame == nil {
+			continue
+		}
+		for _, label := range frame.Labels {
+			if label.Confidence >= 0.7 {
+				labels[strings.ToLower(label.Name)] = true
+			}
+		}
+	}
+	if len(labels) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, c := range candidates {
+		if c.Overview == "" {
+			continue
+		}
+		overview := normalizeTitle(c.Overview)
+		boosts := 0
+		for _, name := range names {
+			if boosts >= s.weights.MaxLabelBoosts {
+				break
+			}
+			if containsPhrase(overview, normalizeTitle(name)) {
+				c.addEvidence(SourceLabel, -1, s.weights.LabelOverlap, "label %q appears in the plot summary", name)
+				boosts++
+			}
+		}
+	}
+}
+
+// crossCheckWeb searches the web for the leading candidates concurrently.
+// Without TMDb, IMDb and Wikipedia are also searched for candidates without a
+// year, since reference pages are then the only way to find one.
+func (s *Session) crossCheckWeb(ctx context.Context, candidates []*Candidate) {
+	if len(candidates) > s.maxSearches {
+		candidates = candidates[:s.maxSearches]
+	}
+
+	builder := ai.NewQueryBuilder(2 * len(candidates))
+	for _, c := range candidates {
+		builder.AddTitle(c.Title, c.Year)
+		if s.tmdbClient == nil && c.Year == 0 {
+			builder.AddReference(c.Title, c.Year)
+		}
+	}
+	found := s.searchWeb(ctx, builder.Queries())
+
+	for _, c := range candidates {
+		results := found[ai.TitleQuery(c.Title, c.Year).String()]
+		if s.tmdbClient == nil && c.Year == 0 {
+			results = append(results, found[ai.ReferenceQuery(c.Title, c.Year).String()]...)
+		}
+		s.applyWebResults(c, results)
+	}
+}
+
+// applyWebResults adds evidence for results that mention the candidate and
+// takes the year and IMDb ID from pages about the same film.
+func (s *Session) applyWebResults(c *Candidate, results []ai.SearchResult) {
+	key := normalizeTitle(c.Title)
+	for _, hit := range ai.GroupFilms(results) {
+		if normalizeTitle(hit.Title) != key || (c.Year > 0 && hit.Year > 0 && hit.Year != c.Year) {
+			continue
+		}
+		if c.IMDbID == "" {
+			c.IMDbID = hit.IMDbID
+		}
+		if c.Year == 0 {
+			c.Year = hit.Year
+		}
+		break
+	}
+
+	hits := 0
+	authority := false
+	seen := make(map[string]bool)
+	for _, r := range results {
+		if seen[r.Link] || !containsPhrase(normalizeTitle(r.Title), key) {
+			continue
+		}
+		seen[r.Link] = true
+		if hits < s.weights.MaxWebHits {
+			c.addEvidence(SourceWebSearch, -1, damp(s.weights.WebHit, hits), "search result %q", r.Title)
+			hits++
+		}
+		if !authority && isAuthoritativeFilmPage(r.Link) {
+			c.addEvidence(SourceWebSearch, -1, s.weights.WebAuthority, "reference page %s", r.Link)
+			authority = true
+		}
+	}
+}
+
+// rank calibrates every candidate and sorts them best first.
+func rank(candidates []*Candidate, prior float64) {
+	for _, c := range candidates {
+		c.Score = calibrate(prior + c.LogOdds())
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+}
+
+func calibrate(logOdds float64) float64 {
+	return 1 / (1 + math.Exp(-logOdds))
+}
+
+// webEntityScore returns the frame's score for the named web entity, capped
+// at 1.
+func webEntityScore(frame *ai.FrameAnalysis, title string) float64 {
+	key := normalizeTitle(title)
+	for _, entity := range frame.WebEntities {
+		if normalizeTitle(entity.Description) == key {
+			return math.Min(entity.Score, 1)
+		}
+	}
+	return 0
+}
+
+// damp gives the n-th repeat of the same evidence a harmonically shrinking weight.
+func damp(weight float64, n int) float64 {
+	return weight / float64(n+1)
+}
+
+// mergeByID folds candidates that resolved to the same film, e.g. an OCR
+// "INCEPTION" and a caption "Inception", into a single candidate. Films and
+// shows have separate ID spaces, and different episodes of one show stay
+// separate. Candidates only a fallback provider resolved are keyed by IMDb
+// ID.
+func mergeByID(candidates []*Candidate) []*Candidate {
+	type idKey struct {
+		mediaType       string
+		id              int
+		imdbID          string
+		season, episode int
+	}
+	byID := make(map[idKey]*Candidate)
+	merged := make([]*Candidate, 0, len(candidates))
+	for _, c := range candidates {
+		if c.TMDbID == 0 && c.IMDbID == "" {
+			merged = append(merged, c)
+			continue
+		}
+		key := idKey{c.MediaType, c.TMDbID, "", c.Season, c.Episode}
+		if c.TMDbID == 0 {
+			key.imdbID = c.IMDbID
+		}
+		existing, ok := byID[key]
+		if !ok {
+			byID[key] = c
+			merged = append(merged, c)
+			continue
+		}
+		for _, e := range c.Evidence {
+			if (e.Source == SourceTMDb || e.Source == SourceMetadata) && hasSource(existing, e.Source) {
+				continue
+			}
+			existing.Evidence = append(existing.Evidence, e)
+		}
+		for _, f := range c.Frames {
+			existing.addFrame(f)
+		}
+	}
+	return merged
+}
+
+// pickMovie returns the result matching key, preferring one released in
+// year, and whether it matched exactly. A title within OCR's edit distance
+// counts as exact when no result matches outright.
+func pickMovie(movies []mdb.Movie, key string, year int) (*mdb.Movie, bool) {
+	var exact []*mdb.Movie
+	for i := range movies {
+		if normalizeTitle(movies[i].Title) == key {
+			exact = append(exact, &movies[i])
+		}
+	}
+	if len(exact) == 0 {
+		for i := range movies {
+			if titles.Match(normalizeTitle(movies[i].Title), key) {
+				exact = append(exact, &movies[i])
+			}
+		}
+	}
+	if len(exact) > 0 {
+		for _, m := range exact {
+			if year > 0 && releaseYear(m.ReleaseDate) == year {
+				return m, true
+			}
+		}
+		return exact[0], true
+	}
+
+	for i := range movies {
+		title := normalizeTitle(movies[i].Title)
+		if containsPhrase(title, key) || containsPhrase(key, title) {
+			return &movies[i], false
+		}
+	}
+	return &movies[0], false
+}
+
+// pickShow returns the show whose name or original name matches key, or
+// nil. Unlike films, a partial match is not trusted: short show names match
+// too many unrelated titles.
+func pickShow(shows []mdb.TVShow, key string) *mdb.TVShow {
+	for i := range shows {
+		if normalizeTitle(shows[i].Name) == key || normalizeTitle(shows[i].OriginalName) == key {
+			return &shows[i]
+		}
+	}
+	return nil
+}
+
+func releaseYear(date string) int {
+	if len(date) < 4 {
+		return 0
+	}
+	return parseYear(date[:4])
+}
+
+func hasSource(c *Candidate, source EvidenceSource) bool {
+	for _, e := range c.Evidence {
+		if e.Source == source {
+			return true
+		}
+	}
+	return false
+}
+
+// containsPhrase reports whether needle occurs in haystack on word boundaries.
+func containsPhrase(haystack, needle string) bool {
+	if needle == "" {
+		return false
+	}
+	return strings.Contains(" "+haystack+" ", " "+needle+" ")
+}
+
+func isAuthoritativeFilmPage(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	host := strings.TrimPrefix(u.Hostname(), "www.")
+	switch {
+	case host == "imdb.com" || strings.HasSuffix(host, ".imdb.com"):
+		return strings.HasPrefix(u.Path, "/title/")
+	case strings.HasSuffix(host, "wikipedia.org"):
+		return strings.HasPrefix(u.Path, "/wiki/")
+	case host == "themoviedb.org":
+		return strings.HasPrefix(u.Path, "/movie/")
+	}
+	return false
+}
+
+// FormatScore renders a 0..1 score as a percentage string.
+func FormatScore(score float64) string {
+	return strconv.FormatFloat(score*100, 'f', 0, 64) + "%"
+}
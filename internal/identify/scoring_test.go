@@ -0,0 +1,593 @@
+package identify
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kdimtricp/vshazam/internal/ai"
+	"github.com/kdimtricp/vshazam/internal/mdb"
+	"github.com/kdimtricp/vshazam/internal/outbound"
+)
+
+type mockTMDbClient struct {
+	movies map[string][]mdb.Movie
+	calls  int
+}
+
+func (m *mockTMDbClient) SearchMovies(ctx context.Context, query string) ([]mdb.Movie, error) {
+	m.calls++
+	return m.movies[strings.ToLower(query)], nil
+}
+
+// mockProvider is a fallback mdb.Provider whose results carry only IMDb IDs.
+type mockProvider struct {
+	mockTMDbClient
+	name string
+}
+
+func (m *mockProvider) Name() string {
+	return m.name
+}
+
+// mockTVClient also searches TV. A missing episode is reported with a nil
+// episode and a permanent error, like TMDb's 404.
+type mockTVClient struct {
+	mockTMDbClient
+	shows    map[string][]mdb.TVShow
+	episodes map[string]*mdb.Episode
+}
+
+func (m *mockTVClient) SearchTV(ctx context.Context, query string) ([]mdb.TVShow, error) {
+	return m.shows[strings.ToLower(query)], nil
+}
+
+func (m *mockTVClient) GetEpisode(ctx context.Context, tvID, season, episode int) (*mdb.Episode, error) {
+	if ep, ok := m.episodes[mdb.EpisodeCode(season, episode)]; ok {
+		return ep, nil
+	}
+	return nil, &outbound.Error{Provider: "tmdb", Kind: outbound.KindPermanent, StatusCode: 404}
+}
+
+// mockAlternativeTitler also lists alternative titles, keyed by TMDb ID.
+type mockAlternativeTitler struct {
+	mockTMDbClient
+	alternatives map[int][]mdb.AlternativeTitle
+	lookups      int
+}
+
+func (m *mockAlternativeTitler) GetAlternativeTitles(ctx context.Context, movieID int) ([]mdb.AlternativeTitle, error) {
+	m.lookups++
+	return m.alternatives[movieID], nil
+}
+
+type mockAliasResolver struct {
+	aliases map[string]string
+}
+
+func (m *mockAliasResolver) ResolveAlias(ctx context.Context, title string) (string, error) {
+	return m.aliases[strings.ToLower(title)], nil
+}
+
+type mockSearchClient struct {
+	results []ai.SearchResult
+}
+
+func (m *mockSearchClient) SearchFilms(ctx context.Context, query string) ([]ai.SearchResult, error) {
+	return m.results, nil
+}
+
+func TestExtractCaptionMentions(t *testing.T) {
+	tests := []struct {
+		name          string
+		caption       string
+		expectedTitle string
+		expectedYear  int
+		hedged        bool
+	}{
+		{
+			name:          "quoted title after keyword",
+			caption:       `This frame is from the movie "Inception" (2010), directed by Christopher Nolan.`,
+			expectedTitle: "Inception",
+			expectedYear:  2010,
+		},
+		{
+			name:          "emphasised title with year",
+			caption:       "The scene appears in **The Dark Knight** (2008).",
+			expectedTitle: "The Dark Knight",
+			expectedYear:  2008,
+		},
+		{
+			name:          "capitalised title after keyword",
+			caption:       "This is a still from the film Blade Runner 2049 showing a desert.",
+			expectedTitle: "Blade Runner 2049",
+		},
+		{
+			name:          "hedged guess",
+			caption:       `It might be from the film "Heat" given the setting.`,
+			expectedTitle: "Heat",
+			hedged:        true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mentions := extractCaptionMentions(tt.caption, 0)
+			if len(mentions) == 0 {
+				t.Fatalf("expected a mention in %q", tt.caption)
+			}
+			m := mentions[0]
+			if m.title != tt.expectedTitle {
+				t.Errorf("expected title %q, got %q", tt.expectedTitle, m.title)
+			}
+			if m.year != tt.expectedYear {
+				t.Errorf("expected year %d, got %d", tt.expectedYear, m.year)
+			}
+			if m.hedged != tt.hedged {
+				t.Errorf("expected hedged=%v, got %v", tt.hedged, m.hedged)
+			}
+		})
+	}
+}
+
+func TestExtractCaptionMentions_Refusal(t *testing.T) {
+	caption := "I cannot identify the specific movie from this frame. The scene shows a city at night."
+	if mentions := extractCaptionMentions(caption, 0); len(mentions) != 0 {
+		t.Errorf("expected no mentions, got %v", mentions)
+	}
+}
+
+func TestNormalizeTitle(t *testing.T) {
+	tests := map[string]string{
+		"The Dark Knight":     "dark knight",
+		"INCEPTION":           "inception",
+		"Fast & Furious":      "fast and furious",
+		"Mission: Impossible": "mission impossible",
+		"A":                   "a",
+	}
+	for input, expected := range tests {
+		if got := normalizeTitle(input); got != expected {
+			t.Errorf("normalizeTitle(%q) = %q, want %q", input, got, expected)
+		}
+	}
+}
+
+func TestSplitEpisode(t *testing.T) {
+	tests := []struct {
+		input   string
+		title   string
+		season  int
+		episode int
+	}{
+		{"Breaking Bad S02E05", "Breaking Bad", 2, 5},
+		{"Breaking Bad - s2 e5", "Breaking Bad", 2, 5},
+		{"The Office 3x12", "The Office", 3, 12},
+		{"Lost: Season 4, Episode 5", "Lost", 4, 5},
+		{"Inception", "Inception", 0, 0},
+		{"1920x1080", "1920x1080", 0, 0},
+	}
+	for _, tt := range tests {
+		title, season, episode := splitEpisode(tt.input)
+		if title != tt.title || season != tt.season || episode != tt.episode {
+			t.Errorf("splitEpisode(%q) = %q, %d, %d; want %q, %d, %d",
+				tt.input, title, season, episode, tt.title, tt.season, tt.episode)
+		}
+	}
+}
+
+func TestScorer_AggregatesAcrossFrames(t *testing.T) {
+	tmdb := &mockTMDbClient{movies: map[string][]mdb.Movie{
+		"inception": {{ID: 27205, Title: "Inception", ReleaseDate: "2010-07-15", Overview: "A thief who steals secrets through dream-sharing technology."}},
+		"heat":      {{ID: 949, Title: "Heat", ReleaseDate: "1995-12-15"}},
+	}}
+	search := &mockSearchClient{results: []ai.SearchResult{
+		{Title: "Inception (2010) - IMDb", Link: "https://www.imdb.com/title/tt1375666/"},
+		{Title: "Inception - Wikipedia", Link: "https://en.wikipedia.org/wiki/Inception"},
+	}}
+
+	frames := []*ai.FrameAnalysis{
+		{Caption: `This frame is from the movie "Inception" (2010).`},
+		{Caption: `The hallway fight suggests the film "Inception".`, TextOCR: []string{"INCEPTION"}},
+		{Caption: `It could be the film "Heat".`},
+	}
+
+	scorer := NewScorer(tmdb, search, DefaultWeights())
+	candidates, err := scorer.Score(context.Background(), frames)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(candidates) < 2 {
+		t.Fatalf("expected at least 2 candidates, got %d", len(candidates))
+	}
+
+	top := candidates[0]
+	if top.TMDbID != 27205 {
+		t.Errorf("expected Inception to rank first, got %s", top.DisplayTitle())
+	}
+	if top.Score <= candidates[1].Score {
+		t.Errorf("expected top score %f to beat runner-up %f", top.Score, candidates[1].Score)
+	}
+	if top.Score < 0.9 {
+		t.Errorf("expected strong evidence to calibrate above 0.9, got %f", top.Score)
+	}
+	if len(top.Frames) != 2 {
+		t.Errorf("expected Inception to be seen in 2 frames, got %v", top.Frames)
+	}
+
+	for _, source := range []EvidenceSource{SourceCaption, SourceOCR, SourceTMDb, SourceWebSearch} {
+		if !hasSource(top, source) {
+			t.Errorf("expected %s evidence in explanation:\n%s", source, top.Explain())
+		}
+	}
+
+	if !strings.Contains(top.Explain(), "Inception (2010)") {
+		t.Errorf("expected explanation to name the film, got:\n%s", top.Explain())
+	}
+}
+
+func TestScorer_PenalisesUnknownTitles(t *testing.T) {
+	tmdb := &mockTMDbClient{movies: map[string][]mdb.Movie{}}
+	frames := []*ai.FrameAnalysis{
+		{TextOCR: []string{"EXIT"}},
+	}
+
+	scorer := NewScorer(tmdb, nil, DefaultWeights())
+	candidates, err := scorer.Score(context.Background(), frames)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(candidates) != 1 {
+		t.Fatalf("expected 1 candidate, got %d", len(candidates))
+	}
+	if candidates[0].Score > 0.1 {
+		t.Errorf("expected unmatched OCR text to score low, got %f", candidates[0].Score)
+	}
+}
+
+func TestScorer_FallsBackToProviders(t *testing.T) {
+	tmdb := &mockTMDbClient{movies: map[string][]mdb.Movie{}}
+	offline := &mockProvider{name: "IMDb dataset", mockTMDbClient: mockTMDbClient{movies: map[string][]mdb.Movie{
+		"el origen": {{IMDbID: "tt1375666", Title: "Inception", ReleaseDate: "2010"}},
+	}}}
+	frames := []*ai.FrameAnalysis{
+		{Caption: `This frame is from the movie "El Origen" (2010).`},
+		{TextOCR: []string{"INCEPTION"}},
+	}
+
+	scorer := NewScorer(tmdb, nil, DefaultWeights())
+	scorer.UseProviders(offline)
+	candidates, err := scorer.Score(context.Background(), frames)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(candidates) != 2 {
+		t.Fatalf("expected 2 candidates, got %d", len(candidates))
+	}
+
+	top := candidates[0]
+	if top.IMDbID != "tt1375666" || top.TMDbID != 0 || top.Title != "Inception" || top.Year != 2010 {
+		t.Errorf("expected the offline match to rank first, got %+v", top)
+	}
+	if !hasSource(top, SourceMetadata) || !strings.Contains(top.Explain(), "IMDb dataset") {
+		t.Errorf("expected evidence naming the provider:\n%s", top.Explain())
+	}
+	if strings.Contains(top.Explain(), "no TMDb") {
+		t.Errorf("expected no penalty once a provider resolved the title:\n%s", top.Explain())
+	}
+	if !strings.Contains(candidates[1].Explain(), "no TMDb or IMDb dataset results") {
+		t.Errorf("expected one penalty naming both providers:\n%s", candidates[1].Explain())
+	}
+
+	offlineOnly := NewScorer(nil, nil, DefaultWeights())
+	offlineOnly.UseProviders(offline)
+	candidates, err = offlineOnly.Score(context.Background(), frames[:1])
+	if err != nil || len(candidates) != 1 || candidates[0].IMDbID != "tt1375666" {
+		t.Errorf("expected providers to resolve titles without TMDb, got %+v, %v", candidates, err)
+	}
+}
+
+func TestScorer_NoEvidence(t *testing.T) {
+	scorer := NewScorer(nil, nil, DefaultWeights())
+	candidates, err := scorer.Score(context.Background(), []*ai.FrameAnalysis{{Caption: "A dark room."}, nil})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(candidates) != 0 {
+		t.Errorf("expected no candidates, got %d", len(candidates))
+	}
+}
+
+func TestScorer_UsesStructuredFilmGuesses(t *testing.T) {
+	frames := []*ai.FrameAnalysis{
+		{
+			Caption:     `A man spins a top, much like the film "Heat".`,
+			VisibleText: []string{"INCEPTION"},
+			FilmGuesses: []ai.FilmGuess{
+				{Title: "Inception", Year: 2010, Confidence: 0.8},
+				{Title: "Shutter Island", Confidence: 0.2},
+			},
+		},
+	}
+
+	scorer := NewScorer(nil, nil, DefaultWeights())
+	candidates, err := scorer.Score(context.Background(), frames)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(candidates) != 2 {
+		t.Fatalf("expected only the 2 structured guesses, got %d", len(candidates))
+	}
+
+	top := candidates[0]
+	if top.Title != "Inception" || top.Year != 2010 {
+		t.Errorf("expected Inception (2010) to rank first, got %s", top.DisplayTitle())
+	}
+	if !hasSource(top, SourceOCR) {
+		t.Errorf("expected text read by GPT to corroborate the guess:\n%s", top.Explain())
+	}
+	if !strings.Contains(candidates[1].Explain(), "tentatively") {
+		t.Errorf("expected low-confidence guess to count as hedged:\n%s", candidates[1].Explain())
+	}
+}
+
+func TestScorer_UsesWebDetection(t *testing.T) {
+	frames := []*ai.FrameAnalysis{
+		{
+			Labels:          []ai.Label{{Name: "Suit", Confidence: 0.9}},
+			BestGuessLabels: []string{"inception movie 2010"},
+			WebEntities: []ai.WebEntity{
+				{Description: "Inception", Score: 1.3},
+				{Description: "Suit", Score: 0.9},
+				{Description: "Leonardo DiCaprio", Score: 0.8},
+				{Description: "Dom Cobb", Score: 0.3},
+			},
+			Actors: []string{"Leonardo DiCaprio"},
+		},
+	}
+
+	scorer := NewScorer(nil, nil, DefaultWeights())
+	candidates, err := scorer.Score(context.Background(), frames)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(candidates) != 1 {
+		t.Fatalf("expected labels, actors and weak entities to be skipped, got %d candidates", len(candidates))
+	}
+	top := candidates[0]
+	if top.Title != "Inception" || top.Year != 2010 {
+		t.Errorf("expected Inception (2010), got %s", top.DisplayTitle())
+	}
+	if len(top.Evidence) != 2 || !hasSource(top, SourceWebEntity) {
+		t.Errorf("expected best guess and web entity evidence:\n%s", top.Explain())
+	}
+}
+
+func TestCleanBestGuess(t *testing.T) {
+	tests := []struct {
+		label string
+		title string
+		year  int
+	}{
+		{label: "inception movie 2010", title: "inception", year: 2010},
+		{label: "the dark knight film scene", title: "the dark knight"},
+		{label: "blade runner 2049", title: "blade runner", year: 2049},
+		{label: "movie", title: "movie"},
+	}
+
+	for _, tt := range tests {
+		title, year := cleanBestGuess(tt.label)
+		if title != tt.title || year != tt.year {
+			t.Errorf("cleanBestGuess(%q) = %q, %d; want %q, %d", tt.label, title, year, tt.title, tt.year)
+		}
+	}
+}
+
+func TestScorer_UsesTextRegions(t *testing.T) {
+	frames := []*ai.FrameAnalysis{
+		{TextBlocks: []ai.TextBlock{
+			{Text: "INCEPTION", Region: ai.RegionTitleCard},
+			{Text: "Heat Wave Warning", Region: ai.RegionOther},
+			{Text: "Where are you going", Region: ai.RegionSubtitle},
+			{Text: "Christopher Nolan", Region: ai.RegionCredits},
+		}},
+	}
+
+	scorer := NewScorer(nil, nil, DefaultWeights())
+	candidates, err := scorer.Score(context.Background(), frames)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(candidates) != 2 {
+		t.Fatalf("expected subtitles and credits to be skipped, got %d candidates", len(candidates))
+	}
+	if candidates[0].Title != "INCEPTION" || !strings.Contains(candidates[0].Explain(), "title card") {
+		t.Errorf("expected the title card to rank first:\n%s", candidates[0].Explain())
+	}
+}
+
+func TestScorer_SearchesDialogueAsQuotes(t *testing.T) {
+	search := &mockSearchClient{results: []ai.SearchResult{
+		{Title: "Inception (2010) - Quotes - IMDb", Link: "https://www.imdb.com/title/tt1375666/quotes/", FilmTitle: "Inception", Year: 2010, IMDbID: "tt1375666"},
+		{Title: "25 Best Movie Quotes About Dreams", Link: "https://example.com/quotes"},
+	}}
+	session := NewScorer(nil, search, DefaultWeights()).NewSession()
+	session.UseDialogue([]string{"You mustn't be afraid to dream a little bigger, darling."})
+
+	candidates, err := session.Score(context.Background(), []*ai.FrameAnalysis{{Caption: "A man in a suit."}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(candidates) != 1 {
+		t.Fatalf("expected only the titled quote page to become a candidate, got %d", len(candidates))
+	}
+	c := candidates[0]
+	if c.Title != "Inception" || c.Year != 2010 || !hasSource(c, SourceDialogue) {
+		t.Errorf("unexpected candidate:\n%s", c.Explain())
+	}
+}
+
+func TestScorer_ResolvesTVEpisodes(t *testing.T) {
+	tmdb := &mockTVClient{
+		mockTMDbClient: mockTMDbClient{movies: map[string][]mdb.Movie{
+			"breaking bad": {{ID: 530915, Title: "Breaking Bad: The Movie", ReleaseDate: "2019-10-11"}},
+			"inception":    {{ID: 27205, Title: "Inception", ReleaseDate: "2010-07-15"}},
+		}},
+		shows: map[string][]mdb.TVShow{
+			"breaking bad": {{ID: 1396, Name: "Breaking Bad", FirstAirDate: "2008-01-20"}},
+			"inception":    {{ID: 1396, Name: "Inception"}},
+		},
+		episodes: map[string]*mdb.Episode{
+			"S02E05": {ID: 62096, SeasonNumber: 2, EpisodeNumber: 5, Name: "Breakage", Overview: "Walt and Jesse become partners."},
+		},
+	}
+
+	frames := []*ai.FrameAnalysis{
+		{FilmGuesses: []ai.FilmGuess{{Title: "Breaking Bad S02E05", Confidence: 0.9}}},
+		{FilmGuesses: []ai.FilmGuess{{Title: "Inception", Confidence: 0.6}}},
+	}
+
+	candidates, err := NewScorer(tmdb, nil, DefaultWeights()).Score(context.Background(), frames)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	byTitle := make(map[string]*Candidate)
+	for _, c := range candidates {
+		byTitle[c.DisplayTitle()] = c
+	}
+
+	episode := byTitle[`Breaking Bad (2008) S02E05 "Breakage"`]
+	if episode == nil {
+		t.Fatalf("expected the episode to be resolved, got %v", byTitle)
+	}
+	if episode.MediaType != mdb.MediaTV || episode.TMDbID != 1396 || episode.Overview != "Walt and Jesse become partners." {
+		t.Errorf("unexpected episode candidate %+v", episode)
+	}
+
+	// An exact film match is not replaced by a show of the same name.
+	if film := byTitle["Inception (2010)"]; film == nil || film.MediaType != "" || film.TMDbID != 27205 {
+		t.Errorf("expected Inception to stay a film, got %v", byTitle)
+	}
+
+	candidates, err = NewScorer(tmdb, nil, DefaultWeights()).Score(context.Background(), []*ai.FrameAnalysis{
+		{FilmGuesses: []ai.FilmGuess{{Title: "Breaking Bad S09E01", Confidence: 0.9}}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	missing := candidates[0]
+	if !strings.Contains(missing.Explain(), "has no episode S09E01") {
+		t.Fatalf("expected a penalty for the missing episode:\n%s", missing.Explain())
+	}
+	if missing.Score >= episode.Score {
+		t.Errorf("expected the missing episode to score below the real one")
+	}
+}
+
+func TestScorer_MergesMisreadTitles(t *testing.T) {
+	tmdb := &mockTMDbClient{movies: map[string][]mdb.Movie{
+		"inception": {{ID: 27205, Title: "Inception", ReleaseDate: "2010-07-15"}},
+	}}
+	frames := []*ai.FrameAnalysis{
+		{FilmGuesses: []ai.FilmGuess{{Title: "Inception (2010)", Confidence: 0.9}}},
+		{TextOCR: []string{"INCEPTI0N"}},
+	}
+
+	candidates, err := NewScorer(tmdb, nil, DefaultWeights()).Score(context.Background(), frames)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(candidates) != 1 {
+		t.Fatalf("expected the misread title to merge, got %d candidates", len(candidates))
+	}
+	top := candidates[0]
+	if top.TMDbID != 27205 || top.Year != 2010 || len(top.Frames) != 2 {
+		t.Errorf("unexpected candidate %+v", top)
+	}
+	if !strings.Contains(top.Explain(), "release year 2010 agrees") {
+		t.Errorf("expected the bracketed year to be used:\n%s", top.Explain())
+	}
+
+	// A misread title whose search returns the film resolves exactly.
+	tmdb.movies["incepti0n"] = tmdb.movies["inception"]
+	candidates, err = NewScorer(tmdb, nil, DefaultWeights()).Score(context.Background(), frames[1:])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(candidates[0].Explain(), "exact TMDb title match") {
+		t.Errorf("expected a fuzzy title to count as exact:\n%s", candidates[0].Explain())
+	}
+}
+
+func TestScorer_MatchesAlternativeTitles(t *testing.T) {
+	tmdb := &mockAlternativeTitler{
+		mockTMDbClient: mockTMDbClient{movies: map[string][]mdb.Movie{
+			"el origen": {
+				{ID: 1, Title: "Origen secreto", ReleaseDate: "2011-01-01"},
+				{ID: 27205, Title: "Inception", ReleaseDate: "2010-07-15"},
+			},
+		}},
+		alternatives: map[int][]mdb.AlternativeTitle{
+			27205: {{Country: "ES", Title: "El origen"}},
+		},
+	}
+	frames := []*ai.FrameAnalysis{
+		{FilmGuesses: []ai.FilmGuess{{Title: "El Origen", Year: 2010, Confidence: 0.9}}},
+	}
+
+	session := NewScorer(tmdb, nil, DefaultWeights()).NewSession()
+	candidates, err := session.Score(context.Background(), frames)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	top := candidates[0]
+	if top.TMDbID != 27205 || top.Title != "Inception" {
+		t.Fatalf("expected the alternative title to resolve Inception, got %+v", top)
+	}
+	if !strings.Contains(top.Explain(), `TMDb alternative title "El origen" of "Inception"`) {
+		t.Errorf("expected alternative title evidence:\n%s", top.Explain())
+	}
+
+	if _, err := session.Score(context.Background(), frames); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tmdb.lookups != 2 {
+		t.Errorf("expected alternative titles to be memoized per film, got %d lookups", tmdb.lookups)
+	}
+}
+
+func TestScorer_ResolvesAliases(t *testing.T) {
+	tmdb := &mockTMDbClient{movies: map[string][]mdb.Movie{
+		"inception": {{ID: 27205, Title: "Inception", ReleaseDate: "2010-07-15"}},
+	}}
+	frames := []*ai.FrameAnalysis{
+		{Caption: `This frame is from the movie "El Origen".`},
+		{TextOCR: []string{"INCEPTION"}},
+	}
+
+	scorer := NewScorer(tmdb, nil, DefaultWeights())
+	scorer.UseAliases(&mockAliasResolver{aliases: map[string]string{"el origen": "Inception"}})
+	candidates, err := scorer.Score(context.Background(), frames)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(candidates) != 1 {
+		t.Fatalf("expected the alias to merge with its primary title, got %d candidates", len(candidates))
+	}
+	top := candidates[0]
+	if top.TMDbID != 27205 || len(top.Frames) != 2 || !hasSource(top, SourceCaption) || !hasSource(top, SourceOCR) {
+		t.Errorf("unexpected candidate %+v", top)
+	}
+	if !strings.Contains(top.Explain(), `"Inception" is also known as "El Origen"`) {
+		t.Errorf("expected alias evidence:\n%s", top.Explain())
+	}
+	if tmdb.calls != 1 {
+		t.Errorf("expected one TMDb lookup for the primary title, got %d", tmdb.calls)
+	}
+}
@@ -0,0 +1,132 @@
+package identify
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/kdimtricp/vshazam/internal/ai"
+	"github.com/kdimtricp/vshazam/internal/models/transcript"
+)
+
+// TranscriptStore keeps the timed text tracks of a video.
+type TranscriptStore interface {
+	ReplaceCues(ctx context.Context, videoID, source string, cues []*transcript.CueDB) error
+	ListByVideoID(ctx context.Context, videoID string) ([]*transcript.CueDB, error)
+}
+
+// defaultCueDuration is how long a subtitle seen in a single sampled frame is
+// assumed to stay on screen.
+const defaultCueDuration = 2.0
+
+// SubtitleCues reassembles burned-in subtitles into a timed track. Frames are
+// ordered by time; lines in the subtitle band of one frame form one cue, and
+// the same text in consecutive frames extends that cue instead of repeating
+// it.
+func SubtitleCues(frames []*ai.FrameAnalysis, timestamps []float64) []*transcript.CueDB {
+	type sample struct {
+		at     float64
+		text   string
+		locale string
+	}
+
+	var samples []sample
+	for i, frame := range frames {
+		if frame == nil || i >= len(timestamps) {
+			continue
+		}
+		lines := ai.TextInRegion(frame.TextBlocks, ai.RegionSubtitle)
+		locale := ""
+		for _, block := range frame.TextBlocks {
+			if block.Region == ai.RegionSubtitle {
+				locale = block.Locale
+				break
+			}
+		}
+		samples = append(samples, sample{at: timestamps[i], text: strings.Join(lines, " "), locale: locale})
+	}
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].at < samples[j].at })
+
+	var cues []*transcript.CueDB
+	var current *transcript.CueDB
+	for i, s := range samples {
+		if s.text == "" {
+			current = nil
+			continue
+		}
+		if current != nil && normalizeTitle(current.Text) == normalizeTitle(s.text) {
+			current.End = s.at
+		} else {
+			current = &transcript.CueDB{
+				Source:   transcript.SourceOCR,
+				Language: s.locale,
+				Start:    s.at,
+				End:      s.at,
+				Text:     s.text,
+			}
+			cues = append(cues, current)
+		}
+
+		// A cue lasts until the next sample, at most defaultCueDuration past
+		// the last frame it was seen in.
+		end := current.End + defaultCueDuration
+		if i+1 < len(samples) && samples[i+1].at < end {
+			end = samples[i+1].at
+		}
+		if end > current.End {
+			current.End = end
+		}
+	}
+
+	return cues
+}
+
+// SpeechCues converts transcribed speech into cues, one per segment.
+func SpeechCues(speech *ai.Transcript) []*transcript.CueDB {
+	cues := make([]*transcript.CueDB, 0, len(speech.Segments))
+	for _, segment := range speech.Segments {
+		cues = append(cues, &transcript.CueDB{
+			Source:   transcript.SourceSpeech,
+			Language: speech.Language,
+			Start:    segment.Start,
+			End:      segment.End,
+			Text:     segment.Text,
+		})
+	}
+	return cues
+}
+
+const (
+	minDialogueWords = 4
+	maxDialogueWords = 15
+)
+
+// DialogueQueries picks up to max lines of dialogue worth searching for as
+// quotes. Short lines like "Come on." match every film and long ones rarely
+// appear verbatim, so lines in between are kept, longest first.
+func DialogueQueries(texts []string, max int) []string {
+	var lines []string
+	seen := make(map[string]bool)
+	for _, text := range texts {
+		line := strings.Trim(strings.Join(strings.Fields(text), " "), `"“”-–— `)
+		line = strings.NewReplacer(`"`, "", "“", "", "”", "").Replace(line)
+		words := len(strings.Fields(line))
+		if words < minDialogueWords || words > maxDialogueWords {
+			continue
+		}
+		key := normalizeTitle(line)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		lines = append(lines, line)
+	}
+
+	sort.SliceStable(lines, func(i, j int) bool {
+		return len(strings.Fields(lines[i])) > len(strings.Fields(lines[j]))
+	})
+	if len(lines) > max {
+		lines = lines[:max]
+	}
+	return lines
+}
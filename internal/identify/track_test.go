@@ -0,0 +1,74 @@
+package identify
+
+import (
+	"testing"
+
+	"github.com/kdimtricp/vshazam/internal/ai"
+)
+
+func subtitleFrame(text string) *ai.FrameAnalysis {
+	if text == "" {
+		return &ai.FrameAnalysis{}
+	}
+	return &ai.FrameAnalysis{TextBlocks: []ai.TextBlock{
+		{Text: "CNN", Region: ai.RegionOther},
+		{Text: text, Locale: "en", Region: ai.RegionSubtitle},
+	}}
+}
+
+func TestSubtitleCues(t *testing.T) {
+	// Frames arrive out of order when extra frames are sampled between the
+	// initial ones.
+	frames := []*ai.FrameAnalysis{
+		subtitleFrame("You mustn't be afraid"),
+		subtitleFrame(""),
+		subtitleFrame("to dream a little bigger."),
+		subtitleFrame("You mustn't be afraid"),
+	}
+	timestamps := []float64{10, 30, 20, 11}
+
+	cues := SubtitleCues(frames, timestamps)
+	if len(cues) != 2 {
+		t.Fatalf("expected 2 cues, got %d", len(cues))
+	}
+
+	if cues[0].Text != "You mustn't be afraid" || cues[0].Start != 10 || cues[0].End != 13 {
+		t.Errorf("expected repeated line to be merged into 10-13s, got %+v", cues[0])
+	}
+	if cues[1].Text != "to dream a little bigger." || cues[1].Start != 20 || cues[1].End != 22 {
+		t.Errorf("unexpected second cue %+v", cues[1])
+	}
+	if cues[0].Language != "en" || cues[0].Source != "ocr" {
+		t.Errorf("unexpected language or source %+v", cues[0])
+	}
+}
+
+func TestDialogueQueries(t *testing.T) {
+	texts := []string{
+		"Come on.",
+		`"An idea is like a virus."`,
+		"You mustn't be afraid to dream a little bigger, darling.",
+		"an idea is like a VIRUS",
+		"Okay, so what I want you to do is to take this and walk down the corridor to the left until you see the door",
+	}
+
+	lines := DialogueQueries(texts, 2)
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", lines)
+	}
+	if lines[0] != "You mustn't be afraid to dream a little bigger, darling." || lines[1] != "An idea is like a virus." {
+		t.Errorf("expected the longest distinctive lines without quotes, got %q", lines)
+	}
+}
+
+func TestSpeechCues(t *testing.T) {
+	speech := &ai.Transcript{
+		Language: "english",
+		Segments: []ai.SpeechSegment{{Start: 1.5, End: 3, Text: "I need you to trust me."}},
+	}
+
+	cues := SpeechCues(speech)
+	if len(cues) != 1 || cues[0].Source != "speech" || cues[0].Language != "english" || cues[0].End != 3 {
+		t.Errorf("unexpected cues %+v", cues)
+	}
+}
@@ -0,0 +1,182 @@
+package imdb
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/kdimtricp/vshazam/internal/mdb"
+	"github.com/kdimtricp/vshazam/internal/models/imdb_dataset"
+	"github.com/kdimtricp/vshazam/internal/titles"
+)
+
+const (
+	// scanLimit bounds the rows fetched per table before ranking.
+	scanLimit = 200
+	// resultLimit bounds the films SearchMovies returns.
+	resultLimit = 10
+	// maxQueryWords bounds the words of a query that are scanned for.
+	maxQueryWords = 3
+	// DefaultMinSimilarity drops matches whose title is less similar to the
+	// query than this.
+	DefaultMinSimilarity = 0.75
+)
+
+// Searcher is implemented by database.IMDbRepo. Searches return rows whose
+// search title contains any of fragments, closest to length first.
+type Searcher interface {
+	SearchTitles(ctx context.Context, fragments []string, length, limit int) ([]*imdb_dataset.TitleDB, error)
+	SearchAkas(ctx context.Context, fragments []string, length, limit int) ([]*imdb_dataset.AkaDB, error)
+	GetTitles(ctx context.Context, tconsts []string) ([]*imdb_dataset.TitleDB, error)
+}
+
+// Dataset is an mdb.Provider over the imported IMDb datasets. Titles are
+// matched by edit distance against primary and alternative titles, so OCR
+// typos and localized titles still resolve without a network.
+type Dataset struct {
+	store         Searcher
+	minSimilarity float64
+}
+
+func NewDataset(store Searcher) *Dataset {
+	return &Dataset{store: store, minSimilarity: DefaultMinSimilarity}
+}
+
+// UseMinSimilarity changes how close a title must be to match, from 0 to 1.
+func (d *Dataset) UseMinSimilarity(min float64) {
+	d.minSimilarity = min
+}
+
+// Name identifies the dataset as a Provider.
+func (d *Dataset) Name() string {
+	return "IMDb dataset"
+}
+
+// SearchMovies returns the imported films closest to query, best first.
+// Results carry IMDbID and the release year as ReleaseDate; ID is always
+// zero.
+func (d *Dataset) SearchMovies(ctx context.Context, query string) ([]mdb.Movie, error) {
+	key := titles.Normalize(query)
+	fragments := queryFragments(key, maxQueryWords)
+	if len(fragments) == 0 {
+		return nil, nil
+	}
+	length := utf8.RuneCountInString(key)
+
+	found, err := d.store.SearchTitles(ctx, fragments, length, scanLimit)
+	if err != nil {
+		return nil, err
+	}
+	akas, err := d.store.SearchAkas(ctx, fragments, length, scanLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make(map[string]float64)
+	byTConst := make(map[string]*imdb_dataset.TitleDB)
+	for _, t := range found {
+		byTConst[t.TConst] = t
+		d.keep(scores, t.TConst, titles.Similarity(key, t.SearchTitle))
+	}
+	var missing []string
+	for _, a := range akas {
+		before := len(scores)
+		d.keep(scores, a.TConst, titles.Similarity(key, a.SearchTitle))
+		if len(scores) > before && byTConst[a.TConst] == nil {
+			missing = append(missing, a.TConst)
+		}
+	}
+	if len(missing) > 0 {
+		extra, err := d.store.GetTitles(ctx, missing)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range extra {
+			byTConst[t.TConst] = t
+		}
+	}
+
+	matches := make([]*imdb_dataset.TitleDB, 0, len(scores))
+	for tconst := range scores {
+		if t := byTConst[tconst]; t != nil {
+			matches = append(matches, t)
+		}
+	}
+	// Ties go to the lower tconst, which is the older and usually the better
+	// known title.
+	sort.Slice(matches, func(i, j int) bool {
+		si, sj := scores[matches[i].TConst], scores[matches[j].TConst]
+		if si != sj {
+			return si > sj
+		}
+		return matches[i].TConst < matches[j].TConst
+	})
+	if len(matches) > resultLimit {
+		matches = matches[:resultLimit]
+	}
+
+	movies := make([]mdb.Movie, 0, len(matches))
+	for _, t := range matches {
+		movie := mdb.Movie{IMDbID: t.TConst, Title: t.PrimaryTitle}
+		if t.StartYear > 0 {
+			movie.ReleaseDate = strconv.Itoa(t.StartYear)
+		}
+		movies = append(movies, movie)
+	}
+	return movies, nil
+}
+
+// keep records score for tconst when it passes the threshold and beats the
+// title's best score so far.
+func (d *Dataset) keep(scores map[string]float64, tconst string, score float64) {
+	if score < d.minSimilarity {
+		return
+	}
+	if best, ok := scores[tconst]; !ok || score > best {
+		scores[tconst] = score
+	}
+}
+
+// ResolveAlias returns the primary title of the film that IMDb lists title
+// as an alternative, e.g. localized, title of. It returns "" when title is
+// itself a primary title or matches no alternative title exactly.
+func (d *Dataset) ResolveAlias(ctx context.Context, title string) (string, error) {
+	key := titles.Normalize(title)
+	fragments := queryFragments(key, maxQueryWords)
+	if len(fragments) == 0 {
+		return "", nil
+	}
+	length := utf8.RuneCountInString(key)
+
+	found, err := d.store.SearchTitles(ctx, fragments, length, scanLimit)
+	if err != nil {
+		return "", err
+	}
+	for _, t := range found {
+		if t.SearchTitle == key {
+			return "", nil
+		}
+	}
+
+	akas, err := d.store.SearchAkas(ctx, fragments, length, scanLimit)
+	if err != nil {
+		return "", err
+	}
+	// Ties go to the lower tconst, as in SearchMovies.
+	var tconst string
+	for _, a := range akas {
+		if a.SearchTitle == key && (tconst == "" || a.TConst < tconst) {
+			tconst = a.TConst
+		}
+	}
+	if tconst == "" {
+		return "", nil
+	}
+
+	primary, err := d.store.GetTitles(ctx, []string{tconst})
+	if err != nil || len(primary) == 0 {
+		return "", err
+	}
+	return primary[0].PrimaryTitle, nil
+}
@@ -0,0 +1,137 @@
+package imdb
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kdimtricp/vshazam/internal/models/imdb_dataset"
+)
+
+// fakeSearcher answers like database.IMDbRepo over an imported memoryStore.
+type fakeSearcher struct {
+	*memoryStore
+	lookups int
+}
+
+func (f *fakeSearcher) SearchTitles(ctx context.Context, fragments []string, length, limit int) ([]*imdb_dataset.TitleDB, error) {
+	var found []*imdb_dataset.TitleDB
+	for _, t := range f.titles {
+		if containsAnyWord(t.SearchTitle, fragments) {
+			found = append(found, t)
+		}
+	}
+	return found, nil
+}
+
+func (f *fakeSearcher) SearchAkas(ctx context.Context, fragments []string, length, limit int) ([]*imdb_dataset.AkaDB, error) {
+	var found []*imdb_dataset.AkaDB
+	for _, a := range f.akas {
+		if containsAnyWord(a.SearchTitle, fragments) {
+			found = append(found, a)
+		}
+	}
+	return found, nil
+}
+
+func (f *fakeSearcher) GetTitles(ctx context.Context, tconsts []string) ([]*imdb_dataset.TitleDB, error) {
+	f.lookups++
+	var found []*imdb_dataset.TitleDB
+	for _, t := range f.titles {
+		for _, tconst := range tconsts {
+			if t.TConst == tconst {
+				found = append(found, t)
+			}
+		}
+	}
+	return found, nil
+}
+
+func containsAnyWord(s string, words []string) bool {
+	for _, w := range words {
+		if strings.Contains(s, w) {
+			return true
+		}
+	}
+	return false
+}
+
+func newTestDataset(t *testing.T) (*Dataset, *fakeSearcher) {
+	t.Helper()
+	store := &memoryStore{}
+	im := NewImporter(store)
+	ctx := context.Background()
+	extra := "tt5295894\tmovie\tInception: The Cobol Job\tInception: The Cobol Job\t0\t2010\t\\N\t14\tAction\n"
+	if _, err := im.ImportTitles(ctx, strings.NewReader(titleBasics+extra)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := im.ImportAkas(ctx, strings.NewReader(titleAkas)); err != nil {
+		t.Fatal(err)
+	}
+	searcher := &fakeSearcher{memoryStore: store}
+	return NewDataset(searcher), searcher
+}
+
+func TestDataset_SearchMovies(t *testing.T) {
+	dataset, searcher := newTestDataset(t)
+	ctx := context.Background()
+
+	if _, err := dataset.SearchMovies(ctx, "Inception"); err != nil || searcher.lookups != 0 {
+		t.Errorf("expected titles matched directly not to be looked up again, got %d lookups, %v", searcher.lookups, err)
+	}
+
+	tests := []struct {
+		query  string
+		imdbID string
+	}{
+		{query: "Inception", imdbID: "tt1375666"},
+		{query: "INCEPTI0N", imdbID: "tt1375666"},
+		{query: "El Origen", imdbID: "tt1375666"},
+		{query: "heat", imdbID: "tt0113277"},
+	}
+	for _, tt := range tests {
+		movies, err := dataset.SearchMovies(ctx, tt.query)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", tt.query, err)
+		}
+		if len(movies) != 1 || movies[0].IMDbID != tt.imdbID {
+			t.Errorf("%q: expected only %s, got %+v", tt.query, tt.imdbID, movies)
+			continue
+		}
+		if movies[0].ID != 0 || movies[0].ReleaseDate == "" {
+			t.Errorf("%q: unexpected movie %+v", tt.query, movies[0])
+		}
+	}
+	movies, err := dataset.SearchMovies(ctx, "Interstellar")
+	if err != nil || len(movies) != 0 {
+		t.Errorf("expected no match, got %+v, %v", movies, err)
+	}
+
+	dataset.UseMinSimilarity(0.3)
+	movies, err = dataset.SearchMovies(ctx, "Inception")
+	if err != nil || len(movies) != 2 || movies[0].Title != "Inception" {
+		t.Errorf("expected the exact title ranked first, got %+v, %v", movies, err)
+	}
+}
+
+func TestDataset_ResolveAlias(t *testing.T) {
+	dataset, _ := newTestDataset(t)
+	ctx := context.Background()
+
+	tests := map[string]string{
+		"El Origen":  "Inception",
+		"EL ORIGEN":  "Inception",
+		"Inception":  "",
+		"Heat":       "",
+		"El Secreto": "",
+	}
+	for alias, want := range tests {
+		got, err := dataset.ResolveAlias(ctx, alias)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", alias, err)
+		}
+		if got != want {
+			t.Errorf("ResolveAlias(%q) = %q, want %q", alias, got, want)
+		}
+	}
+}
@@ -0,0 +1,329 @@
+// Package imdb loads IMDb's public dataset dumps (https://datasets.imdbws.com/)
+// into local tables and searches them by fuzzy title, so identification can
+// resolve candidates without calling any metadata API.
+package imdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/kdimtricp/vshazam/internal/models/imdb_dataset"
+	"github.com/kdimtricp/vshazam/internal/titles"
+)
+
+// Dataset files as published by IMDb. Uncompressed copies without the ".gz"
+// suffix are read as well.
+const (
+	FileTitleBasics     = "title.basics.tsv.gz"
+	FileTitleAkas       = "title.akas.tsv.gz"
+	FileTitlePrincipals = "title.principals.tsv.gz"
+	FileNameBasics      = "name.basics.tsv.gz"
+)
+
+// DefaultTitleTypes are the title types imported unless UseTitleTypes says
+// otherwise. Series, episodes and shorts make up most of the dump and are
+// skipped.
+var DefaultTitleTypes = []string{"movie", "tvMovie"}
+
+// defaultBatchSize is how many rows are handed to the store at once.
+const defaultBatchSize = 1000
+
+// principalCategories are the credits kept from title.principals.
+var principalCategories = map[string]bool{
+	imdb_dataset.CategoryActor:    true,
+	imdb_dataset.CategoryActress:  true,
+	imdb_dataset.CategoryDirector: true,
+	imdb_dataset.CategoryWriter:   true,
+}
+
+// Store is implemented by database.IMDbRepo.
+type Store interface {
+	SaveTitles(ctx context.Context, titles []*imdb_dataset.TitleDB) error
+	SaveAkas(ctx context.Context, akas []*imdb_dataset.AkaDB) error
+	SavePrincipals(ctx context.Context, principals []*imdb_dataset.PrincipalDB) error
+	SaveNames(ctx context.Context, names []*imdb_dataset.NameDB) error
+}
+
+// Stats counts the rows an import stored.
+type Stats struct {
+	Titles     int
+	Akas       int
+	Principals int
+	Names      int
+}
+
+// Importer streams dataset files into a Store. Akas and principals are only
+// kept for titles imported by the same Importer, and names only for people
+// among those principals, so the files must be imported in the order
+// ImportDir uses.
+type Importer struct {
+	store     Store
+	types     map[string]bool
+	batchSize int
+	titles    map[string]bool
+	people    map[string]bool
+}
+
+func NewImporter(store Store) *Importer {
+	im := &Importer{
+		store:     store,
+		batchSize: defaultBatchSize,
+		titles:    make(map[string]bool),
+		people:    make(map[string]bool),
+	}
+	im.UseTitleTypes(DefaultTitleTypes...)
+	return im
+}
+
+// UseTitleTypes imports only titles of the given types, e.g. "movie" or
+// "tvSeries".
+func (im *Importer) UseTitleTypes(types ...string) {
+	im.types = make(map[string]bool, len(types))
+	for _, t := range types {
+		im.types[t] = true
+	}
+}
+
+// ImportDir imports the dataset files in dir. title.basics is required; the
+// other files are imported when present.
+func (im *Importer) ImportDir(ctx context.Context, dir string) (*Stats, error) {
+	stats := &Stats{}
+	steps := []struct {
+		file     string
+		required bool
+		count    *int
+		run      func(context.Context, io.Reader) (int, error)
+	}{
+		{FileTitleBasics, true, &stats.Titles, im.ImportTitles},
+		{FileTitleAkas, false, &stats.Akas, im.ImportAkas},
+		{FileTitlePrincipals, false, &stats.Principals, im.ImportPrincipals},
+		{FileNameBasics, false, &stats.Names, im.ImportNames},
+	}
+
+	for _, step := range steps {
+		path, ok := findDataset(dir, step.file)
+		if !ok {
+			if step.required {
+				return stats, fmt.Errorf("%s not found in %s", step.file, dir)
+			}
+			log.Printf("IMDb import: %s not found, skipping", step.file)
+			continue
+		}
+		n, err := im.importPath(ctx, path, step.run)
+		*step.count = n
+		if err != nil {
+			return stats, fmt.Errorf("importing %s: %w", path, err)
+		}
+		log.Printf("IMDb import: %d rows from %s", n, path)
+	}
+	return stats, nil
+}
+
+func (im *Importer) importPath(ctx context.Context, path string, run func(context.Context, io.Reader) (int, error)) (int, error) {
+	f, err := openDataset(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return run(ctx, f)
+}
+
+// findDataset returns the path of name in dir, falling back to the
+// uncompressed file.
+func findDataset(dir, name string) (string, bool) {
+	for _, candidate := range []string{name, name[:len(name)-len(".gz")]} {
+		path := filepath.Join(dir, candidate)
+		if _, err := os.Stat(path); err == nil {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// ImportTitles imports title.basics, skipping adult titles and types not
+// selected by UseTitleTypes.
+func (im *Importer) ImportTitles(ctx context.Context, r io.Reader) (int, error) {
+	var batch []*imdb_dataset.TitleDB
+	count := 0
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := im.store.SaveTitles(ctx, batch); err != nil {
+			return err
+		}
+		count += len(batch)
+		batch = nil
+		return ctx.Err()
+	}
+
+	err := readTSV(r, func(row row) error {
+		if row.str("isAdult") == "1" || !im.types[row.str("titleType")] {
+			return nil
+		}
+		title := &imdb_dataset.TitleDB{
+			TConst:         row.str("tconst"),
+			TitleType:      row.str("titleType"),
+			PrimaryTitle:   row.str("primaryTitle"),
+			OriginalTitle:  row.str("originalTitle"),
+			SearchTitle:    titles.Normalize(row.str("primaryTitle")),
+			StartYear:      row.int("startYear"),
+			EndYear:        row.int("endYear"),
+			RuntimeMinutes: row.int("runtimeMinutes"),
+			Genres:         row.list("genres"),
+		}
+		if title.TConst == "" || title.PrimaryTitle == "" {
+			return nil
+		}
+		if title.Genres == nil {
+			title.Genres = []string{}
+		}
+		im.titles[title.TConst] = true
+		batch = append(batch, title)
+		if len(batch) >= im.batchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+	return count, flush()
+}
+
+// ImportAkas imports title.akas for titles imported before.
+func (im *Importer) ImportAkas(ctx context.Context, r io.Reader) (int, error) {
+	var batch []*imdb_dataset.AkaDB
+	count := 0
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := im.store.SaveAkas(ctx, batch); err != nil {
+			return err
+		}
+		count += len(batch)
+		batch = nil
+		return ctx.Err()
+	}
+
+	err := readTSV(r, func(row row) error {
+		tconst := row.str("titleId")
+		if !im.titles[tconst] || row.str("title") == "" {
+			return nil
+		}
+		batch = append(batch, &imdb_dataset.AkaDB{
+			TConst:          tconst,
+			Ordering:        row.int("ordering"),
+			Title:           row.str("title"),
+			SearchTitle:     titles.Normalize(row.str("title")),
+			Region:          row.str("region"),
+			Language:        row.str("language"),
+			IsOriginalTitle: row.str("isOriginalTitle") == "1",
+		})
+		if len(batch) >= im.batchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+	return count, flush()
+}
+
+// ImportPrincipals imports the actors, directors and writers of titles
+// imported before.
+func (im *Importer) ImportPrincipals(ctx context.Context, r io.Reader) (int, error) {
+	var batch []*imdb_dataset.PrincipalDB
+	count := 0
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := im.store.SavePrincipals(ctx, batch); err != nil {
+			return err
+		}
+		count += len(batch)
+		batch = nil
+		return ctx.Err()
+	}
+
+	err := readTSV(r, func(row row) error {
+		tconst := row.str("tconst")
+		if !im.titles[tconst] || !principalCategories[row.str("category")] {
+			return nil
+		}
+		principal := &imdb_dataset.PrincipalDB{
+			TConst:     tconst,
+			Ordering:   row.int("ordering"),
+			NConst:     row.str("nconst"),
+			Category:   row.str("category"),
+			Job:        row.str("job"),
+			Characters: parseCharacters(row.str("characters")),
+		}
+		im.people[principal.NConst] = true
+		batch = append(batch, principal)
+		if len(batch) >= im.batchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+	return count, flush()
+}
+
+// ImportNames imports name.basics for people among the imported principals.
+func (im *Importer) ImportNames(ctx context.Context, r io.Reader) (int, error) {
+	var batch []*imdb_dataset.NameDB
+	count := 0
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := im.store.SaveNames(ctx, batch); err != nil {
+			return err
+		}
+		count += len(batch)
+		batch = nil
+		return ctx.Err()
+	}
+
+	err := readTSV(r, func(row row) error {
+		nconst := row.str("nconst")
+		if !im.people[nconst] || row.str("primaryName") == "" {
+			return nil
+		}
+		batch = append(batch, &imdb_dataset.NameDB{
+			NConst:      nconst,
+			PrimaryName: row.str("primaryName"),
+			BirthYear:   row.int("birthYear"),
+			DeathYear:   row.int("deathYear"),
+		})
+		if len(batch) >= im.batchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+	return count, flush()
+}
+
+// parseCharacters decodes the characters column, a JSON array such as
+// ["Dom Cobb"].
+func parseCharacters(value string) []string {
+	characters := []string{}
+	if value != "" {
+		json.Unmarshal([]byte(value), &characters)
+	}
+	return characters
+}
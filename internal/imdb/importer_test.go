@@ -0,0 +1,140 @@
+package imdb
+
+import (
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kdimtricp/vshazam/internal/models/imdb_dataset"
+)
+
+const titleBasics = "tconst\ttitleType\tprimaryTitle\toriginalTitle\tisAdult\tstartYear\tendYear\truntimeMinutes\tgenres\n" +
+	"tt1375666\tmovie\tInception\tInception\t0\t2010\t\\N\t148\tAction,Adventure,Sci-Fi\n" +
+	"tt0903747\ttvSeries\tBreaking Bad\tBreaking Bad\t0\t2008\t2013\t49\tCrime,Drama,Thriller\n" +
+	"tt0000001\tmovie\tAdult Film\tAdult Film\t1\t1999\t\\N\t90\t\\N\n" +
+	"tt0113277\tmovie\tHeat\tHeat\t0\t1995\t\\N\t170\t\\N\n"
+
+const titleAkas = "titleId\tordering\ttitle\tregion\tlanguage\ttypes\tattributes\tisOriginalTitle\n" +
+	"tt1375666\t1\tInception\t\\N\t\\N\toriginal\t\\N\t1\n" +
+	"tt1375666\t2\tEl origen\tAR\tes\timdbDisplay\t\\N\t0\n" +
+	"tt0903747\t1\tBreaking Bad\t\\N\t\\N\toriginal\t\\N\t1\n"
+
+const titlePrincipals = "tconst\tordering\tnconst\tcategory\tjob\tcharacters\n" +
+	"tt1375666\t1\tnm0000138\tactor\t\\N\t[\"Dom Cobb\"]\n" +
+	"tt1375666\t5\tnm0634240\tdirector\t\\N\t\\N\n" +
+	"tt1375666\t6\tnm0002892\tcomposer\t\\N\t\\N\n"
+
+const nameBasics = "nconst\tprimaryName\tbirthYear\tdeathYear\tprimaryProfession\tknownForTitles\n" +
+	"nm0000138\tLeonardo DiCaprio\t1974\t\\N\tactor,producer\ttt1375666\n" +
+	"nm0634240\tChristopher Nolan\t1970\t\\N\twriter,director\ttt1375666\n" +
+	"nm0000001\tFred Astaire\t1899\t1987\tactor\ttt0050419\n"
+
+type memoryStore struct {
+	titles     []*imdb_dataset.TitleDB
+	akas       []*imdb_dataset.AkaDB
+	principals []*imdb_dataset.PrincipalDB
+	names      []*imdb_dataset.NameDB
+}
+
+func (m *memoryStore) SaveTitles(ctx context.Context, titles []*imdb_dataset.TitleDB) error {
+	m.titles = append(m.titles, titles...)
+	return nil
+}
+
+func (m *memoryStore) SaveAkas(ctx context.Context, akas []*imdb_dataset.AkaDB) error {
+	m.akas = append(m.akas, akas...)
+	return nil
+}
+
+func (m *memoryStore) SavePrincipals(ctx context.Context, principals []*imdb_dataset.PrincipalDB) error {
+	m.principals = append(m.principals, principals...)
+	return nil
+}
+
+func (m *memoryStore) SaveNames(ctx context.Context, names []*imdb_dataset.NameDB) error {
+	m.names = append(m.names, names...)
+	return nil
+}
+
+func TestImporter_FiltersAndParsesRows(t *testing.T) {
+	store := &memoryStore{}
+	im := NewImporter(store)
+	im.batchSize = 1
+	ctx := context.Background()
+
+	n, err := im.ImportTitles(ctx, strings.NewReader(titleBasics))
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2 titles, got %d, %v", n, err)
+	}
+	inception := store.titles[0]
+	if inception.TConst != "tt1375666" || inception.StartYear != 2010 || inception.EndYear != 0 || inception.RuntimeMinutes != 148 {
+		t.Errorf("unexpected title %+v", inception)
+	}
+	if len(inception.Genres) != 3 || inception.SearchTitle != "inception" {
+		t.Errorf("unexpected genres or search title %+v", inception)
+	}
+	if store.titles[1].Genres == nil {
+		t.Error("expected missing genres to be an empty list")
+	}
+
+	if n, err := im.ImportAkas(ctx, strings.NewReader(titleAkas)); err != nil || n != 2 {
+		t.Errorf("expected the akas of imported titles only, got %d, %v", n, err)
+	}
+	if aka := store.akas[1]; aka.Title != "El origen" || aka.SearchTitle != "origen" || aka.Region != "AR" || aka.IsOriginalTitle {
+		t.Errorf("unexpected aka %+v", aka)
+	}
+
+	if n, err := im.ImportPrincipals(ctx, strings.NewReader(titlePrincipals)); err != nil || n != 2 {
+		t.Errorf("expected the actor and director only, got %d, %v", n, err)
+	}
+	if chars := store.principals[0].Characters; len(chars) != 1 || chars[0] != "Dom Cobb" {
+		t.Errorf("unexpected characters %v", chars)
+	}
+
+	if n, err := im.ImportNames(ctx, strings.NewReader(nameBasics)); err != nil || n != 2 {
+		t.Errorf("expected the names of imported principals only, got %d, %v", n, err)
+	}
+}
+
+func TestImporter_ImportDir(t *testing.T) {
+	dir := t.TempDir()
+	writeGzip(t, filepath.Join(dir, FileTitleBasics), titleBasics)
+	if err := os.WriteFile(filepath.Join(dir, "title.akas.tsv"), []byte(titleAkas), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	store := &memoryStore{}
+	im := NewImporter(store)
+	im.UseTitleTypes("movie", "tvSeries")
+
+	stats, err := im.ImportDir(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.Titles != 3 || stats.Akas != 3 || stats.Principals != 0 || stats.Names != 0 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	if _, err := NewImporter(store).ImportDir(context.Background(), t.TempDir()); err == nil {
+		t.Error("expected an error without title.basics")
+	}
+}
+
+func writeGzip(t *testing.T, path, content string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	if _, err := gz.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
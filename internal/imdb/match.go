@@ -0,0 +1,47 @@
+package imdb
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// queryFragments picks substrings of a normalized query for a LIKE scan: the
+// longest few words, skipping short and common ones. Words of six letters or
+// more are split in half, so a single OCR typo still leaves one half to
+// match. A query made only of short words is searched as a whole.
+func queryFragments(key string, max int) []string {
+	var words []string
+	for _, word := range strings.Fields(key) {
+		if utf8.RuneCountInString(word) >= 3 && !commonWords[word] {
+			words = append(words, word)
+		}
+	}
+	if len(words) == 0 {
+		if key == "" {
+			return nil
+		}
+		return []string{key}
+	}
+	// Longer words are rarer, so they narrow the scan most.
+	sort.SliceStable(words, func(i, j int) bool { return len(words[i]) > len(words[j]) })
+	if len(words) > max {
+		words = words[:max]
+	}
+
+	var fragments []string
+	for _, word := range words {
+		runes := []rune(word)
+		if len(runes) < 6 {
+			fragments = append(fragments, word)
+			continue
+		}
+		half := len(runes) / 2
+		fragments = append(fragments, string(runes[:half]), string(runes[half:]))
+	}
+	return fragments
+}
+
+var commonWords = map[string]bool{
+	"the": true, "and": true, "of": true, "les": true, "der": true, "die": true, "das": true,
+}
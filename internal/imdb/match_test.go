@@ -0,0 +1,23 @@
+package imdb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQueryFragments(t *testing.T) {
+	tests := []struct {
+		key  string
+		want []string
+	}{
+		{key: "the lord of the rings the two towers", want: []string{"tow", "ers", "rings", "lord"}},
+		{key: "incepti0n", want: []string{"ince", "pti0n"}},
+		{key: "up", want: []string{"up"}},
+		{key: "", want: nil},
+	}
+	for _, tt := range tests {
+		if got := queryFragments(tt.key, 3); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("queryFragments(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
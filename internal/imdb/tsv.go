@@ -0,0 +1,104 @@
+package imdb
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// null is how the datasets spell a missing value.
+const null = `\N`
+
+// maxLine bounds one TSV line; akas and principals rows stay far below it.
+const maxLine = 1 << 20
+
+// row is one line of a dataset file, addressed by the header's column names.
+type row struct {
+	columns map[string]int
+	fields  []string
+}
+
+// str returns the column's value, or "" when it is missing.
+func (r row) str(column string) string {
+	i, ok := r.columns[column]
+	if !ok || i >= len(r.fields) || r.fields[i] == null {
+		return ""
+	}
+	return r.fields[i]
+}
+
+func (r row) int(column string) int {
+	n, err := strconv.Atoi(r.str(column))
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// list splits a comma-separated column such as genres.
+func (r row) list(column string) []string {
+	value := r.str(column)
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// readTSV calls fn for every row after the header. The datasets do not
+// quote fields, so lines are split on tabs rather than parsed as CSV.
+func readTSV(r io.Reader, fn func(row) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLine)
+
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("reading header: %w", err)
+		}
+		return nil
+	}
+	columns := make(map[string]int)
+	for i, name := range strings.Split(scanner.Text(), "\t") {
+		columns[name] = i
+	}
+
+	for scanner.Scan() {
+		if err := fn(row{columns: columns, fields: strings.Split(scanner.Text(), "\t")}); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading rows: %w", err)
+	}
+	return nil
+}
+
+// openDataset opens path, decompressing it when it ends in ".gz".
+func openDataset(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		return f, nil
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	return &gzipFile{Reader: gz, file: f}, nil
+}
+
+type gzipFile struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (g *gzipFile) Close() error {
+	g.Reader.Close()
+	return g.file.Close()
+}
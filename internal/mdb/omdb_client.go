@@ -0,0 +1,216 @@
+package mdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/kdimtricp/vshazam/internal/metering"
+	"github.com/kdimtricp/vshazam/internal/outbound"
+)
+
+const defaultOMDbBaseURL = "https://www.omdbapi.com/"
+
+// OMDbClient looks films up on OMDb, which serves IMDb data keyed by IMDb ID.
+type OMDbClient struct {
+	apiKey     string
+	baseURL    string
+	httpClient *outbound.Client
+	meter      metering.Meter
+}
+
+// OMDbTitle is a title as OMDb describes it. OMDb formats every field as
+// text and uses "N/A" for missing values.
+type OMDbTitle struct {
+	IMDbID     string `json:"imdbID"`
+	Title      string `json:"Title"`
+	Year       string `json:"Year"`
+	Type       string `json:"Type"`
+	Released   string `json:"Released"`
+	Runtime    string `json:"Runtime"`
+	Genre      string `json:"Genre"`
+	Director   string `json:"Director"`
+	Writer     string `json:"Writer"`
+	Actors     string `json:"Actors"`
+	Plot       string `json:"Plot"`
+	Poster     string `json:"Poster"`
+	IMDbRating string `json:"imdbRating"`
+}
+
+// StartYear returns the first year of Year, which is a range such as
+// "2008–2013" for series, or 0 when it is unknown.
+func (t *OMDbTitle) StartYear() int {
+	if len(t.Year) < 4 {
+		return 0
+	}
+	year, err := strconv.Atoi(t.Year[:4])
+	if err != nil {
+		return 0
+	}
+	return year
+}
+
+// RuntimeMinutes parses Runtime, e.g. "148 min".
+func (t *OMDbTitle) RuntimeMinutes() int {
+	minutes, err := strconv.Atoi(strings.TrimSuffix(t.Runtime, " min"))
+	if err != nil {
+		return 0
+	}
+	return minutes
+}
+
+// Genres splits Genre, e.g. "Action, Sci-Fi".
+func (t *OMDbTitle) Genres() []string {
+	return omdbList(t.Genre)
+}
+
+// Directors splits Director.
+func (t *OMDbTitle) Directors() []string {
+	return omdbList(t.Director)
+}
+
+// ActorNames splits Actors, which OMDb limits to the top-billed few.
+func (t *OMDbTitle) ActorNames() []string {
+	return omdbList(t.Actors)
+}
+
+func omdbList(field string) []string {
+	if field == "" || field == "N/A" {
+		return nil
+	}
+	var items []string
+	for _, item := range strings.Split(field, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// omdbResponse carries the error OMDb reports with a 200 status when nothing
+// matched, e.g. {"Response":"False","Error":"Movie not found!"}.
+type omdbResponse struct {
+	Response string `json:"Response"`
+	Error    string `json:"Error"`
+}
+
+func (r omdbResponse) ok() bool {
+	return r.Response != "False"
+}
+
+type omdbSearchResult struct {
+	omdbResponse
+	Search       []OMDbTitle `json:"Search"`
+	TotalResults string      `json:"totalResults"`
+}
+
+func NewOMDbClient(apiKey string) *OMDbClient {
+	return &OMDbClient{
+		apiKey:     apiKey,
+		baseURL:    defaultOMDbBaseURL,
+		httpClient: outbound.New("omdb", omdbPolicy()),
+		meter:      metering.Nop{},
+	}
+}
+
+// Name identifies the client as a Provider.
+func (c *OMDbClient) Name() string {
+	return "OMDb"
+}
+
+// UseBaseURL points the client at another OMDb API root.
+func (c *OMDbClient) UseBaseURL(baseURL string) {
+	c.baseURL = baseURL
+}
+
+// UseTransport sends requests through rt instead of the default transport.
+func (c *OMDbClient) UseTransport(rt http.RoundTripper) {
+	c.httpClient.UseTransport(rt)
+}
+
+// UseMeter records every request with m.
+func (c *OMDbClient) UseMeter(m metering.Meter) {
+	c.meter = m
+}
+
+// omdbPolicy stays well within OMDb's free tier of 1,000 requests a day.
+func omdbPolicy() outbound.Policy {
+	policy := outbound.DefaultPolicy()
+	policy.RequestsPerSecond = 5
+	policy.Burst = 5
+	return policy
+}
+
+// SearchMovies returns the films whose title matches query. Results carry
+// IMDbID and the release year as ReleaseDate; ID is always zero.
+func (c *OMDbClient) SearchMovies(ctx context.Context, query string) ([]Movie, error) {
+	params := url.Values{}
+	params.Set("s", query)
+	params.Set("type", "movie")
+
+	var result omdbSearchResult
+	if err := c.get(ctx, params, "search", &result); err != nil {
+		return nil, err
+	}
+	if !result.ok() {
+		return nil, nil
+	}
+
+	movies := make([]Movie, 0, len(result.Search))
+	for _, t := range result.Search {
+		movie := Movie{IMDbID: t.IMDbID, Title: t.Title}
+		if year := t.StartYear(); year > 0 {
+			movie.ReleaseDate = strconv.Itoa(year)
+		}
+		movies = append(movies, movie)
+	}
+	return movies, nil
+}
+
+// GetTitle returns the title with imdbID, or nil when OMDb does not know it.
+func (c *OMDbClient) GetTitle(ctx context.Context, imdbID string) (*OMDbTitle, error) {
+	params := url.Values{}
+	params.Set("i", imdbID)
+	params.Set("plot", "short")
+
+	var result struct {
+		omdbResponse
+		OMDbTitle
+	}
+	if err := c.get(ctx, params, "title", &result); err != nil {
+		return nil, err
+	}
+	if !result.ok() {
+		return nil, nil
+	}
+	return &result.OMDbTitle, nil
+}
+
+// get requests the API root with params and decodes the JSON response into
+// v. Every request is metered as operation.
+func (c *OMDbClient) get(ctx context.Context, params url.Values, operation string, v interface{}) error {
+	params.Set("apikey", c.apiKey)
+	fullURL := fmt.Sprintf("%s?%s", c.baseURL, params.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, "GET", fullURL, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("executing request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	c.meter.Record(ctx, metering.Event{Provider: metering.ProviderOMDb, Operation: operation, Units: 1, Unit: "requests"})
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
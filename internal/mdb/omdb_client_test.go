@@ -0,0 +1,81 @@
+package mdb
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/kdimtricp/vshazam/internal/outbound"
+	"github.com/kdimtricp/vshazam/internal/outbound/cassette"
+)
+
+func newOMDbCassetteClient(t *testing.T, name string) *OMDbClient {
+	t.Helper()
+	client := NewOMDbClient("test-key")
+	client.UseTransport(cassette.ForTest(t, filepath.Join("testdata", "cassettes", name)))
+	return client
+}
+
+func TestOMDbClientSearchMovies(t *testing.T) {
+	client := newOMDbCassetteClient(t, "omdb_search.json")
+	ctx := context.Background()
+
+	movies, err := client.SearchMovies(ctx, "Inception")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(movies) != 2 {
+		t.Fatalf("expected 2 movies, got %d", len(movies))
+	}
+	if movies[0].IMDbID != "tt1375666" || movies[0].ID != 0 || movies[0].ReleaseDate != "2010" {
+		t.Errorf("unexpected first movie %+v", movies[0])
+	}
+
+	movies, err = client.SearchMovies(ctx, "Nonexistent Film")
+	if err != nil || len(movies) != 0 {
+		t.Errorf("expected no movies and no error, got %+v, %v", movies, err)
+	}
+}
+
+func TestOMDbClientGetTitle(t *testing.T) {
+	client := newOMDbCassetteClient(t, "omdb_title.json")
+	ctx := context.Background()
+
+	title, err := client.GetTitle(ctx, "tt1375666")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if title.Title != "Inception" || title.StartYear() != 2010 || title.RuntimeMinutes() != 148 {
+		t.Errorf("unexpected title %+v", title)
+	}
+	if genres := title.Genres(); len(genres) != 3 || genres[2] != "Sci-Fi" {
+		t.Errorf("unexpected genres %v", genres)
+	}
+	if directors := title.Directors(); len(directors) != 1 || directors[0] != "Christopher Nolan" {
+		t.Errorf("unexpected directors %v", directors)
+	}
+
+	missing, err := client.GetTitle(ctx, "tt0000000")
+	if err != nil || missing != nil {
+		t.Errorf("expected nil for an unknown ID, got %+v, %v", missing, err)
+	}
+}
+
+func TestOMDbClientInvalidKey(t *testing.T) {
+	client := newOMDbCassetteClient(t, "omdb_auth_error.json")
+
+	_, err := client.SearchMovies(context.Background(), "Inception")
+	if !outbound.IsAuth(err) {
+		t.Errorf("expected auth error, got %v", err)
+	}
+}
+
+func TestOMDbTitleParsing(t *testing.T) {
+	title := OMDbTitle{Year: "2008–2013", Runtime: "N/A", Genre: "N/A"}
+	if title.StartYear() != 2008 {
+		t.Errorf("expected the first year of the range, got %d", title.StartYear())
+	}
+	if title.RuntimeMinutes() != 0 || title.Genres() != nil {
+		t.Errorf("expected N/A fields to be empty, got %d and %v", title.RuntimeMinutes(), title.Genres())
+	}
+}
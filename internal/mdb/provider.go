@@ -0,0 +1,12 @@
+package mdb
+
+import "context"
+
+// Provider is a source of film metadata that identification can search.
+// TMDbClient and OMDbClient implement it over HTTP; imdb.Dataset implements
+// it over a local copy of the IMDb datasets, so it works without a network.
+type Provider interface {
+	// Name is shown in evidence, e.g. "TMDb".
+	Name() string
+	SearchMovies(ctx context.Context, query string) ([]Movie, error)
+}
@@ -6,16 +6,28 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
-	"time"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kdimtricp/vshazam/internal/metering"
+	"github.com/kdimtricp/vshazam/internal/outbound"
 )
 
+const defaultTMDbBaseURL = "https://api.themoviedb.org/3"
+
 type TMDbClient struct {
 	apiKey     string
-	httpClient *http.Client
+	baseURL    string
+	language   string
+	region     string
+	httpClient *outbound.Client
+	meter      metering.Meter
 }
 
 type FilmDetails struct {
 	ID           int     `json:"id"`
+	IMDbID       string  `json:"imdb_id"`
 	Title        string  `json:"title"`
 	ReleaseDate  string  `json:"release_date"`
 	Overview     string  `json:"overview"`
@@ -52,11 +64,15 @@ type CrewMember struct {
 type SearchMovieResult struct {
 	Page         int     `json:"page"`
 	Results      []Movie `json:"results"`
+	TotalPages   int     `json:"total_pages"`
 	TotalResults int     `json:"total_results"`
 }
 
+// Movie is a search result. ID is the TMDb ID; results from providers that
+// only know IMDb, such as OMDb, leave it zero and set IMDbID instead.
 type Movie struct {
 	ID          int     `json:"id"`
+	IMDbID      string  `json:"imdb_id,omitempty"`
 	Title       string  `json:"title"`
 	ReleaseDate string  `json:"release_date"`
 	Overview    string  `json:"overview"`
@@ -66,76 +82,155 @@ type Movie struct {
 
 func NewTMDbClient(apiKey string) *TMDbClient {
 	return &TMDbClient{
-		apiKey: apiKey,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		apiKey:     apiKey,
+		baseURL:    defaultTMDbBaseURL,
+		httpClient: outbound.New("tmdb", tmdbPolicy()),
+		meter:      metering.Nop{},
 	}
 }
 
+// Name identifies the client as a Provider.
+func (c *TMDbClient) Name() string {
+	return "TMDb"
+}
+
+// UseBaseURL points the client at another TMDb API root.
+func (c *TMDbClient) UseBaseURL(baseURL string) {
+	c.baseURL = strings.TrimRight(baseURL, "/")
+}
+
+// UseTransport sends requests through rt instead of the default transport.
+func (c *TMDbClient) UseTransport(rt http.RoundTripper) {
+	c.httpClient.UseTransport(rt)
+}
+
+// UseMeter records every request with m.
+func (c *TMDbClient) UseMeter(m metering.Meter) {
+	c.meter = m
+}
+
+// UseLocale asks for titles and overviews in language, an ISO-639-1 code
+// optionally followed by a country such as "pt-BR", and for release dates in
+// region, an ISO-3166-1 code. Empty values keep TMDb's defaults.
+func (c *TMDbClient) UseLocale(language, region string) {
+	c.language = language
+	c.region = region
+}
+
+// tmdbPolicy keeps below TMDb's limit of roughly 50 requests per second.
+func tmdbPolicy() outbound.Policy {
+	policy := outbound.DefaultPolicy()
+	policy.RequestsPerSecond = 20
+	policy.Burst = 20
+	return policy
+}
+
 func (c *TMDbClient) GetFilm(ctx context.Context, tmdbID string) (*FilmDetails, error) {
-	url := fmt.Sprintf("https://api.themoviedb.org/3/movie/%s?api_key=%s&append_to_response=credits",
-		tmdbID, c.apiKey)
+	params := url.Values{}
+	params.Set("append_to_response", "credits")
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
+	var details FilmDetails
+	if err := c.get(ctx, "/movie/"+url.PathEscape(tmdbID), params, "movie_details", &details); err != nil {
+		return nil, err
 	}
+	return &details, nil
+}
 
-	resp, err := c.httpClient.Do(req)
+func (c *TMDbClient) SearchMovies(ctx context.Context, query string) ([]Movie, error) {
+	result, err := c.SearchMoviesPage(ctx, query, 1)
 	if err != nil {
-		return nil, fmt.Errorf("executing request: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
+	return result.Results, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("TMDb API returned status %d", resp.StatusCode)
+// SearchMoviesPage returns one page of movie search results. Pages start at 1.
+func (c *TMDbClient) SearchMoviesPage(ctx context.Context, query string, page int) (*SearchMovieResult, error) {
+	params := searchParams(query, page)
+	if c.region != "" {
+		params.Set("region", c.region)
 	}
 
-	var details FilmDetails
-	if err := json.NewDecoder(resp.Body).Decode(&details); err != nil {
-		return nil, fmt.Errorf("decoding response: %w", err)
+	var result SearchMovieResult
+	if err := c.get(ctx, "/search/movie", params, "search_movie", &result); err != nil {
+		return nil, err
 	}
-
-	return &details, nil
+	return &result, nil
 }
 
-func (c *TMDbClient) SearchMovies(ctx context.Context, query string) ([]Movie, error) {
-	apiURL := "https://api.themoviedb.org/3/search/movie"
-
-	params := url.Values{}
+// get requests path with params and the client's language and decodes the
+// JSON response into v. Every request is metered as operation.
+func (c *TMDbClient) get(ctx context.Context, path string, params url.Values, operation string, v interface{}) error {
+	if params == nil {
+		params = url.Values{}
+	}
 	params.Set("api_key", c.apiKey)
-	params.Set("query", query)
-	params.Set("page", "1")
+	if c.language != "" && params.Get("language") == "" {
+		params.Set("language", c.language)
+	}
 
-	fullURL := fmt.Sprintf("%s?%s", apiURL, params.Encode())
+	fullURL := fmt.Sprintf("%s%s?%s", c.baseURL, path, params.Encode())
 
 	req, err := http.NewRequestWithContext(ctx, "GET", fullURL, nil)
 	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
+		return fmt.Errorf("creating request: %w", err)
 	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("executing request: %w", err)
+		return fmt.Errorf("executing request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("TMDb API returned status %d", resp.StatusCode)
-	}
+	c.meter.Record(ctx, metering.Event{Provider: metering.ProviderTMDb, Operation: operation, Units: 1, Unit: "requests"})
 
-	var searchResult SearchMovieResult
-	if err := json.NewDecoder(resp.Body).Decode(&searchResult); err != nil {
-		return nil, fmt.Errorf("decoding response: %w", err)
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
 	}
+	return nil
+}
 
-	return searchResult.Results, nil
+func searchParams(query string, page int) url.Values {
+	if page < 1 {
+		page = 1
+	}
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("page", strconv.Itoa(page))
+	return params
 }
 
 func (c *TMDbClient) GetImageURL(path string, size string) string {
+	return ImageURL(path, size)
+}
+
+// ImageURL returns the address of a TMDb image at size, e.g. "w342" or
+// "original", or "" when path is empty.
+func ImageURL(path string, size string) string {
 	if path == "" {
 		return ""
 	}
 	return fmt.Sprintf("https://image.tmdb.org/t/p/%s%s", size, path)
 }
+
+// TopCast returns the first n cast members in billing order.
+func (c Credits) TopCast(n int) []CastMember {
+	cast := make([]CastMember, len(c.Cast))
+	copy(cast, c.Cast)
+	sort.SliceStable(cast, func(i, j int) bool { return cast[i].Order < cast[j].Order })
+	if len(cast) > n {
+		cast = cast[:n]
+	}
+	return cast
+}
+
+// Directors returns the names of the crew credited as director.
+func (c Credits) Directors() []string {
+	var names []string
+	for _, member := range c.Crew {
+		if member.Job == "Director" {
+			names = append(names, member.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,263 @@
+package mdb
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/kdimtricp/vshazam/internal/outbound"
+	"github.com/kdimtricp/vshazam/internal/outbound/cassette"
+)
+
+func newCassetteClient(t *testing.T, name string) *TMDbClient {
+	t.Helper()
+	client := NewTMDbClient("test-key")
+	client.UseTransport(cassette.ForTest(t, filepath.Join("testdata", "cassettes", name)))
+	return client
+}
+
+func TestTMDbClientSearchMovies(t *testing.T) {
+	client := newCassetteClient(t, "tmdb_search_success.json")
+
+	movies, err := client.SearchMovies(context.Background(), "Inception")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(movies) != 2 {
+		t.Fatalf("expected 2 movies, got %d", len(movies))
+	}
+	if movies[0].ID != 27205 || movies[0].ReleaseDate != "2010-07-15" {
+		t.Errorf("unexpected first movie %+v", movies[0])
+	}
+}
+
+func TestTMDbClientGetFilm(t *testing.T) {
+	client := newCassetteClient(t, "tmdb_movie_details.json")
+
+	film, err := client.GetFilm(context.Background(), "27205")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if film.Title != "Inception" || film.Runtime != 148 {
+		t.Errorf("unexpected film %+v", film)
+	}
+	if len(film.Genres) != 2 || len(film.Credits.Cast) != 2 {
+		t.Errorf("expected genres and credits, got %+v", film)
+	}
+	if len(film.Credits.Crew) != 1 || film.Credits.Crew[0].Job != "Director" {
+		t.Errorf("unexpected crew %+v", film.Credits.Crew)
+	}
+}
+
+func TestTMDbClientErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		cassette string
+		kind     outbound.Kind
+	}{
+		{name: "invalid key", cassette: "tmdb_auth_error.json", kind: outbound.KindAuth},
+		{name: "rate limited", cassette: "tmdb_rate_limited.json", kind: outbound.KindQuota},
+		{name: "malformed response", cassette: "tmdb_malformed.json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newCassetteClient(t, tt.cassette)
+
+			_, err := client.SearchMovies(context.Background(), "Inception")
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if tt.kind != "" && outbound.KindOf(err) != tt.kind {
+				t.Errorf("expected %s error, got %s: %v", tt.kind, outbound.KindOf(err), err)
+			}
+		})
+	}
+}
+
+func TestTMDbClientSearchMoviesPageWithLocale(t *testing.T) {
+	client := newCassetteClient(t, "tmdb_search_region.json")
+	client.UseLocale("de-DE", "DE")
+
+	result, err := client.SearchMoviesPage(context.Background(), "Inception", 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.Page != 3 || result.TotalPages != 3 || len(result.Results) != 2 {
+		t.Errorf("unexpected page %+v", result)
+	}
+}
+
+func TestTMDbClientSearchMulti(t *testing.T) {
+	client := newCassetteClient(t, "tmdb_search_multi.json")
+	client.UseLocale("de-DE", "")
+
+	result, err := client.SearchMultiPage(context.Background(), "Heat", 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(result.Results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(result.Results))
+	}
+	movie, show := result.Results[0], result.Results[1]
+	if movie.MediaType != MediaMovie || movie.DisplayName() != "Heat" || movie.Date() != "1995-12-15" {
+		t.Errorf("unexpected movie %+v", movie)
+	}
+	if show.MediaType != MediaTV || show.DisplayName() != "The Flash" || show.Date() != "2014-10-07" {
+		t.Errorf("unexpected show %+v", show)
+	}
+}
+
+func TestTMDbClientSearchTV(t *testing.T) {
+	client := newCassetteClient(t, "tmdb_search_tv.json")
+
+	shows, err := client.SearchTV(context.Background(), "Breaking Bad")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(shows) != 1 || shows[0].ID != 1396 || shows[0].FirstAirDate != "2008-01-20" {
+		t.Errorf("unexpected shows %+v", shows)
+	}
+}
+
+func TestTMDbClientTVDetails(t *testing.T) {
+	client := newCassetteClient(t, "tmdb_tv.json")
+	ctx := context.Background()
+
+	show, err := client.GetTVShow(ctx, 1396)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if show.NumberOfSeasons != 5 || len(show.Seasons) != 2 || show.ExternalIDs.IMDbID != "tt0903747" {
+		t.Errorf("unexpected show %+v", show)
+	}
+
+	season, err := client.GetSeason(ctx, 1396, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(season.Episodes) != 2 || season.Episodes[1].Name != "Breakage" {
+		t.Errorf("unexpected season %+v", season)
+	}
+
+	episode, err := client.GetEpisode(ctx, 1396, 2, 5)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if episode.Code() != "S02E05" || episode.ShowID != 1396 || episode.Runtime != 47 {
+		t.Errorf("unexpected episode %+v", episode)
+	}
+
+	if _, err := client.GetEpisode(ctx, 1396, 9, 1); !outbound.IsPermanent(err) {
+		t.Errorf("expected permanent error for missing episode, got %v", err)
+	}
+}
+
+func TestTMDbClientFindByIMDbID(t *testing.T) {
+	client := newCassetteClient(t, "tmdb_find.json")
+
+	result, err := client.FindByIMDbID(context.Background(), "tt1232249")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(result.MovieResults) != 0 || len(result.TVEpisodeResults) != 1 {
+		t.Fatalf("expected one episode, got %+v", result)
+	}
+	if episode := result.TVEpisodeResults[0]; episode.ShowID != 1396 || episode.Code() != "S02E05" {
+		t.Errorf("unexpected episode %+v", episode)
+	}
+}
+
+func TestTMDbClientMovieMedia(t *testing.T) {
+	client := newCassetteClient(t, "tmdb_movie_media.json")
+	client.UseLocale("de-DE", "DE")
+	ctx := context.Background()
+
+	images, err := client.GetMovieImages(ctx, 27205)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(images.Backdrops) != 1 || len(images.Posters) != 1 || images.Posters[0].Language != "de" {
+		t.Errorf("unexpected images %+v", images)
+	}
+
+	videos, err := client.GetMovieVideos(ctx, 27205)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	trailer := Trailer(videos)
+	if trailer == nil || trailer.URL() != "https://www.youtube.com/watch?v=YoHD9XEInc0" {
+		t.Errorf("expected the official trailer, got %+v", trailer)
+	}
+
+	ids, err := client.GetMovieExternalIDs(ctx, 27205)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ids.IMDbID != "tt1375666" {
+		t.Errorf("unexpected external IDs %+v", ids)
+	}
+
+	titles, err := client.GetAlternativeTitles(ctx, 27205)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(titles) != 3 || titles[0].Country != "ES" || titles[0].Title != "El origen" {
+		t.Errorf("unexpected alternative titles %+v", titles)
+	}
+}
+
+func TestTrailer(t *testing.T) {
+	if Trailer([]Video{{Site: "YouTube", Type: "Clip"}}) != nil {
+		t.Error("expected no trailer among clips")
+	}
+
+	videos := []Video{
+		{Key: "vimeo", Site: "Vimeo", Type: "Trailer", Official: true},
+		{Key: "teaser", Site: "YouTube", Type: "Teaser", Official: true},
+		{Key: "fan", Site: "YouTube", Type: "Trailer"},
+	}
+	if trailer := Trailer(videos); trailer == nil || trailer.Key != "fan" {
+		t.Errorf("expected the YouTube trailer, got %+v", trailer)
+	}
+}
+
+func TestCredits_TopCastAndDirectors(t *testing.T) {
+	credits := Credits{
+		Cast: []CastMember{
+			{Name: "Third", Order: 2},
+			{Name: "First", Order: 0},
+			{Name: "Second", Order: 1},
+		},
+		Crew: []CrewMember{
+			{Name: "Writer", Job: "Screenplay"},
+			{Name: "Director", Job: "Director"},
+		},
+	}
+
+	top := credits.TopCast(2)
+	if len(top) != 2 || top[0].Name != "First" || top[1].Name != "Second" {
+		t.Errorf("unexpected top cast %+v", top)
+	}
+	if credits.Cast[0].Name != "Third" {
+		t.Error("TopCast must not reorder the original credits")
+	}
+	if directors := credits.Directors(); len(directors) != 1 || directors[0] != "Director" {
+		t.Errorf("unexpected directors %v", directors)
+	}
+}
+
+func TestImageURL(t *testing.T) {
+	if got := ImageURL("", "w342"); got != "" {
+		t.Errorf("expected no URL without a path, got %q", got)
+	}
+	if got := ImageURL("/poster.jpg", "w342"); got != "https://image.tmdb.org/t/p/w342/poster.jpg" {
+		t.Errorf("unexpected URL %q", got)
+	}
+}
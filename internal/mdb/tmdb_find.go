@@ -0,0 +1,110 @@
+package mdb
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// Media types returned by multi search.
+const (
+	MediaMovie  = "movie"
+	MediaTV     = "tv"
+	MediaPerson = "person"
+)
+
+// MultiResult is a movie, TV show or person from /search/multi. Movies carry
+// Title and ReleaseDate, shows carry Name and FirstAirDate.
+type MultiResult struct {
+	ID           int     `json:"id"`
+	MediaType    string  `json:"media_type"`
+	Title        string  `json:"title"`
+	Name         string  `json:"name"`
+	ReleaseDate  string  `json:"release_date"`
+	FirstAirDate string  `json:"first_air_date"`
+	Overview     string  `json:"overview"`
+	PosterPath   string  `json:"poster_path"`
+	VoteAverage  float64 `json:"vote_average"`
+}
+
+// DisplayName returns the movie title or the show or person name.
+func (r *MultiResult) DisplayName() string {
+	if r.Title != "" {
+		return r.Title
+	}
+	return r.Name
+}
+
+// Date returns the release or first air date.
+func (r *MultiResult) Date() string {
+	if r.ReleaseDate != "" {
+		return r.ReleaseDate
+	}
+	return r.FirstAirDate
+}
+
+type SearchMultiResult struct {
+	Page         int           `json:"page"`
+	Results      []MultiResult `json:"results"`
+	TotalPages   int           `json:"total_pages"`
+	TotalResults int           `json:"total_results"`
+}
+
+// ExternalIDs are a movie's or show's IDs on other sites. TVDbID is only set
+// for shows.
+type ExternalIDs struct {
+	IMDbID     string `json:"imdb_id"`
+	WikidataID string `json:"wikidata_id"`
+	TVDbID     int    `json:"tvdb_id"`
+}
+
+// FindResult holds everything TMDb knows under one external ID. Usually only
+// one of the lists is non-empty.
+type FindResult struct {
+	MovieResults     []Movie   `json:"movie_results"`
+	TVResults        []TVShow  `json:"tv_results"`
+	TVEpisodeResults []Episode `json:"tv_episode_results"`
+}
+
+// SearchMultiPage searches movies, TV shows and people at once. Pages start
+// at 1.
+func (c *TMDbClient) SearchMultiPage(ctx context.Context, query string, page int) (*SearchMultiResult, error) {
+	var result SearchMultiResult
+	if err := c.get(ctx, "/search/multi", searchParams(query, page), "search_multi", &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// FindByIMDbID looks up a movie, show or episode by its IMDb ID, e.g.
+// "tt1375666".
+func (c *TMDbClient) FindByIMDbID(ctx context.Context, imdbID string) (*FindResult, error) {
+	if imdbID == "" {
+		return nil, fmt.Errorf("empty IMDb ID")
+	}
+	params := url.Values{}
+	params.Set("external_source", "imdb_id")
+
+	var result FindResult
+	if err := c.get(ctx, "/find/"+url.PathEscape(imdbID), params, "find", &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (c *TMDbClient) GetMovieExternalIDs(ctx context.Context, movieID int) (*ExternalIDs, error) {
+	var ids ExternalIDs
+	if err := c.get(ctx, "/movie/"+strconv.Itoa(movieID)+"/external_ids", nil, "movie_external_ids", &ids); err != nil {
+		return nil, err
+	}
+	return &ids, nil
+}
+
+func (c *TMDbClient) GetTVExternalIDs(ctx context.Context, tvID int) (*ExternalIDs, error) {
+	var ids ExternalIDs
+	if err := c.get(ctx, "/tv/"+strconv.Itoa(tvID)+"/external_ids", nil, "tv_external_ids", &ids); err != nil {
+		return nil, err
+	}
+	return &ids, nil
+}
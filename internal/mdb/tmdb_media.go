@@ -0,0 +1,128 @@
+package mdb
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+type Image struct {
+	FilePath    string  `json:"file_path"`
+	Width       int     `json:"width"`
+	Height      int     `json:"height"`
+	AspectRatio float64 `json:"aspect_ratio"`
+	Language    string  `json:"iso_639_1"`
+	VoteAverage float64 `json:"vote_average"`
+}
+
+type Images struct {
+	ID        int     `json:"id"`
+	Backdrops []Image `json:"backdrops"`
+	Posters   []Image `json:"posters"`
+	Logos     []Image `json:"logos"`
+}
+
+// Video is a trailer, teaser or clip hosted on YouTube or Vimeo.
+type Video struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Key         string `json:"key"`
+	Site        string `json:"site"`
+	Type        string `json:"type"`
+	Size        int    `json:"size"`
+	Official    bool   `json:"official"`
+	Language    string `json:"iso_639_1"`
+	PublishedAt string `json:"published_at"`
+}
+
+// URL returns the video's page on its host, or "" for unknown hosts.
+func (v *Video) URL() string {
+	switch v.Site {
+	case "YouTube":
+		return "https://www.youtube.com/watch?v=" + url.QueryEscape(v.Key)
+	case "Vimeo":
+		return "https://vimeo.com/" + url.PathEscape(v.Key)
+	default:
+		return ""
+	}
+}
+
+type videosResult struct {
+	Results []Video `json:"results"`
+}
+
+// GetMovieImages returns a movie's backdrops, posters and logos. With a
+// language set, images in that language and images without text are
+// returned.
+func (c *TMDbClient) GetMovieImages(ctx context.Context, movieID int) (*Images, error) {
+	params := url.Values{}
+	if c.language != "" {
+		lang, _, _ := strings.Cut(c.language, "-")
+		params.Set("include_image_language", lang+",null")
+	}
+
+	var images Images
+	if err := c.get(ctx, "/movie/"+strconv.Itoa(movieID)+"/images", params, "movie_images", &images); err != nil {
+		return nil, err
+	}
+	return &images, nil
+}
+
+func (c *TMDbClient) GetMovieVideos(ctx context.Context, movieID int) ([]Video, error) {
+	var result videosResult
+	if err := c.get(ctx, "/movie/"+strconv.Itoa(movieID)+"/videos", nil, "movie_videos", &result); err != nil {
+		return nil, err
+	}
+	return result.Results, nil
+}
+
+// AlternativeTitle is a title a film is released or known under in one
+// country, e.g. a translation or a working title.
+type AlternativeTitle struct {
+	Country string `json:"iso_3166_1"`
+	Title   string `json:"title"`
+	Type    string `json:"type"`
+}
+
+type alternativeTitlesResult struct {
+	ID     int                `json:"id"`
+	Titles []AlternativeTitle `json:"titles"`
+}
+
+// GetAlternativeTitles returns the titles a movie is known under in every
+// country TMDb has one for.
+func (c *TMDbClient) GetAlternativeTitles(ctx context.Context, movieID int) ([]AlternativeTitle, error) {
+	var result alternativeTitlesResult
+	if err := c.get(ctx, "/movie/"+strconv.Itoa(movieID)+"/alternative_titles", nil, "movie_alternative_titles", &result); err != nil {
+		return nil, err
+	}
+	return result.Titles, nil
+}
+
+// Trailer picks the trailer to show for a title: an official YouTube
+// trailer if there is one, then any YouTube trailer, then a teaser. It
+// returns nil when there is none.
+func Trailer(videos []Video) *Video {
+	var best *Video
+	rank := func(v *Video) int {
+		if v.Site != "YouTube" {
+			return 0
+		}
+		switch {
+		case v.Type == "Trailer" && v.Official:
+			return 3
+		case v.Type == "Trailer":
+			return 2
+		case v.Type == "Teaser":
+			return 1
+		}
+		return 0
+	}
+	for i := range videos {
+		if rank(&videos[i]) > 0 && (best == nil || rank(&videos[i]) > rank(best)) {
+			best = &videos[i]
+		}
+	}
+	return best
+}
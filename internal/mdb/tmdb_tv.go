@@ -0,0 +1,136 @@
+package mdb
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+type TVShow struct {
+	ID           int     `json:"id"`
+	Name         string  `json:"name"`
+	OriginalName string  `json:"original_name"`
+	FirstAirDate string  `json:"first_air_date"`
+	Overview     string  `json:"overview"`
+	PosterPath   string  `json:"poster_path"`
+	VoteAverage  float64 `json:"vote_average"`
+}
+
+type SearchTVResult struct {
+	Page         int      `json:"page"`
+	Results      []TVShow `json:"results"`
+	TotalPages   int      `json:"total_pages"`
+	TotalResults int      `json:"total_results"`
+}
+
+type TVShowDetails struct {
+	ID               int             `json:"id"`
+	Name             string          `json:"name"`
+	FirstAirDate     string          `json:"first_air_date"`
+	LastAirDate      string          `json:"last_air_date"`
+	Overview         string          `json:"overview"`
+	PosterPath       string          `json:"poster_path"`
+	BackdropPath     string          `json:"backdrop_path"`
+	VoteAverage      float64         `json:"vote_average"`
+	Genres           []Genre         `json:"genres"`
+	NumberOfSeasons  int             `json:"number_of_seasons"`
+	NumberOfEpisodes int             `json:"number_of_episodes"`
+	Seasons          []SeasonSummary `json:"seasons"`
+	ExternalIDs      ExternalIDs     `json:"external_ids"`
+}
+
+type SeasonSummary struct {
+	ID           int    `json:"id"`
+	SeasonNumber int    `json:"season_number"`
+	Name         string `json:"name"`
+	AirDate      string `json:"air_date"`
+	EpisodeCount int    `json:"episode_count"`
+	PosterPath   string `json:"poster_path"`
+}
+
+type Season struct {
+	ID           int       `json:"id"`
+	SeasonNumber int       `json:"season_number"`
+	Name         string    `json:"name"`
+	AirDate      string    `json:"air_date"`
+	Overview     string    `json:"overview"`
+	PosterPath   string    `json:"poster_path"`
+	Episodes     []Episode `json:"episodes"`
+}
+
+type Episode struct {
+	ID            int     `json:"id"`
+	ShowID        int     `json:"show_id"`
+	SeasonNumber  int     `json:"season_number"`
+	EpisodeNumber int     `json:"episode_number"`
+	Name          string  `json:"name"`
+	AirDate       string  `json:"air_date"`
+	Overview      string  `json:"overview"`
+	StillPath     string  `json:"still_path"`
+	Runtime       int     `json:"runtime"`
+	VoteAverage   float64 `json:"vote_average"`
+}
+
+// Code returns the episode's position in its show, e.g. "S02E05".
+func (e *Episode) Code() string {
+	return EpisodeCode(e.SeasonNumber, e.EpisodeNumber)
+}
+
+// EpisodeCode formats a season and episode number as "S02E05".
+func EpisodeCode(season, episode int) string {
+	return fmt.Sprintf("S%02dE%02d", season, episode)
+}
+
+func (c *TMDbClient) SearchTV(ctx context.Context, query string) ([]TVShow, error) {
+	result, err := c.SearchTVPage(ctx, query, 1)
+	if err != nil {
+		return nil, err
+	}
+	return result.Results, nil
+}
+
+// SearchTVPage returns one page of TV search results. Pages start at 1.
+func (c *TMDbClient) SearchTVPage(ctx context.Context, query string, page int) (*SearchTVResult, error) {
+	var result SearchTVResult
+	if err := c.get(ctx, "/search/tv", searchParams(query, page), "search_tv", &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetTVShow returns a show with its seasons and external IDs.
+func (c *TMDbClient) GetTVShow(ctx context.Context, tvID int) (*TVShowDetails, error) {
+	params := url.Values{}
+	params.Set("append_to_response", "external_ids")
+
+	var details TVShowDetails
+	if err := c.get(ctx, "/tv/"+strconv.Itoa(tvID), params, "tv_details", &details); err != nil {
+		return nil, err
+	}
+	return &details, nil
+}
+
+// GetSeason returns a season with all of its episodes.
+func (c *TMDbClient) GetSeason(ctx context.Context, tvID, season int) (*Season, error) {
+	path := fmt.Sprintf("/tv/%d/season/%d", tvID, season)
+
+	var result Season
+	if err := c.get(ctx, path, nil, "tv_season", &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (c *TMDbClient) GetEpisode(ctx context.Context, tvID, season, episode int) (*Episode, error) {
+	path := fmt.Sprintf("/tv/%d/season/%d/episode/%d", tvID, season, episode)
+
+	var result Episode
+	if err := c.get(ctx, path, nil, "tv_episode", &result); err != nil {
+		return nil, err
+	}
+	if result.ShowID == 0 {
+		result.ShowID = tvID
+	}
+	return &result, nil
+}
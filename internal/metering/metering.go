@@ -0,0 +1,62 @@
+// Package metering records what every call to a paid external API costs and
+// enforces daily and monthly budgets.
+package metering
+
+import (
+	"context"
+	"errors"
+)
+
+// Provider names match the outbound client names.
+const (
+	ProviderOpenAI       = "openai"
+	ProviderGoogleVision = "google_vision"
+	ProviderGoogleSearch = "google_search"
+	ProviderTMDb         = "tmdb"
+	ProviderOMDb         = "omdb"
+	ProviderSpeech       = "speech"
+)
+
+// ErrBudgetExceeded is returned by Meter.Allow when a call would break the
+// configured budget.
+var ErrBudgetExceeded = errors.New("API budget exceeded")
+
+// Event describes one call. Units is what the provider bills for: tokens for
+// OpenAI, feature units for Google Vision, seconds of audio for speech and
+// queries or requests otherwise.
+// OpenAI also reports input and output tokens separately since they are
+// priced differently.
+type Event struct {
+	Provider     string
+	Operation    string
+	Units        int
+	Unit         string
+	InputTokens  int
+	OutputTokens int
+}
+
+// Meter is implemented by Tracker. Clients call Allow before a request and
+// Record after a successful one.
+type Meter interface {
+	Allow(ctx context.Context, provider string) error
+	Record(ctx context.Context, event Event)
+}
+
+// Nop is a Meter that allows everything and records nothing.
+type Nop struct{}
+
+func (Nop) Allow(ctx context.Context, provider string) error { return nil }
+func (Nop) Record(ctx context.Context, event Event)          {}
+
+type videoIDKey struct{}
+
+// WithVideoID attributes calls made with the returned context to a video.
+func WithVideoID(ctx context.Context, videoID string) context.Context {
+	return context.WithValue(ctx, videoIDKey{}, videoID)
+}
+
+// VideoID returns the video set by WithVideoID, or "".
+func VideoID(ctx context.Context) string {
+	videoID, _ := ctx.Value(videoIDKey{}).(string)
+	return videoID
+}
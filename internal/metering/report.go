@@ -0,0 +1,137 @@
+package metering
+
+import (
+	"sort"
+	"time"
+
+	"github.com/kdimtricp/vshazam/internal/models/api_usage"
+)
+
+type ProviderTotal struct {
+	Provider string
+	Unit     string
+	Calls    int
+	Units    int
+	Cost     float64
+}
+
+type DayTotal struct {
+	Day   time.Time
+	Calls int
+	Cost  float64
+}
+
+type VideoTotal struct {
+	VideoID string
+	Calls   int
+	Cost    float64
+}
+
+// Report summarises API usage for the usage page.
+type Report struct {
+	Budget       Budget
+	DailySpend   float64
+	MonthlySpend float64
+	Today        []*ProviderTotal
+	Month        []*ProviderTotal
+	Days         []*DayTotal
+	TopVideos    []*VideoTotal
+}
+
+// Used is the largest fraction of a budget limit spent so far.
+func (r *Report) Used() float64 {
+	return r.Budget.Used(r.DailySpend, r.MonthlySpend)
+}
+
+// ReportWindow returns the earliest time BuildReport needs records from: the
+// start of the month or 30 days ago, whichever is earlier.
+func ReportWindow(now time.Time) time.Time {
+	day, month := periodStarts(now)
+	if start := day.AddDate(0, 0, -29); start.Before(month) {
+		return start
+	}
+	return month
+}
+
+// BuildReport aggregates usage records per provider for today and this
+// month, per day for the last 30 days and per video for this month.
+func BuildReport(records []*api_usage.UsageDB, budget Budget, now time.Time) *Report {
+	day, month := periodStarts(now)
+	daysStart := day.AddDate(0, 0, -29)
+
+	report := &Report{Budget: budget}
+	today := make(map[string]*ProviderTotal)
+	thisMonth := make(map[string]*ProviderTotal)
+	days := make(map[time.Time]*DayTotal)
+	videos := make(map[string]*VideoTotal)
+
+	for i := 0; i < 30; i++ {
+		d := &DayTotal{Day: daysStart.AddDate(0, 0, i)}
+		days[d.Day] = d
+		report.Days = append(report.Days, d)
+	}
+
+	for _, record := range records {
+		created := record.CreatedAt.UTC()
+
+		if !created.Before(day) {
+			report.DailySpend += record.CostUSD
+			addProvider(today, record)
+		}
+		if !created.Before(month) {
+			report.MonthlySpend += record.CostUSD
+			addProvider(thisMonth, record)
+
+			if record.VideoID != "" {
+				v, ok := videos[record.VideoID]
+				if !ok {
+					v = &VideoTotal{VideoID: record.VideoID}
+					videos[record.VideoID] = v
+				}
+				v.Calls++
+				v.Cost += record.CostUSD
+			}
+		}
+		if d, ok := days[time.Date(created.Year(), created.Month(), created.Day(), 0, 0, 0, 0, time.UTC)]; ok {
+			d.Calls++
+			d.Cost += record.CostUSD
+		}
+	}
+
+	report.Today = sortedProviders(today)
+	report.Month = sortedProviders(thisMonth)
+
+	for _, v := range videos {
+		report.TopVideos = append(report.TopVideos, v)
+	}
+	sort.Slice(report.TopVideos, func(i, j int) bool {
+		return report.TopVideos[i].Cost > report.TopVideos[j].Cost
+	})
+	if len(report.TopVideos) > 10 {
+		report.TopVideos = report.TopVideos[:10]
+	}
+
+	return report
+}
+
+func addProvider(totals map[string]*ProviderTotal, record *api_usage.UsageDB) {
+	total, ok := totals[record.Provider]
+	if !ok {
+		total = &ProviderTotal{Provider: record.Provider, Unit: record.Unit}
+		totals[record.Provider] = total
+	}
+	total.Calls++
+	total.Units += record.Units
+	total.Cost += record.CostUSD
+}
+
+func sortedProviders(totals map[string]*ProviderTotal) []*ProviderTotal {
+	result := make([]*ProviderTotal, 0, len(totals))
+	for _, total := range totals {
+		result = append(result, total)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Cost > result[j].Cost || (result[i].Cost == result[j].Cost && result[i].Provider < result[j].Provider)
+	})
+	return result
+}
@@ -0,0 +1,205 @@
+package metering
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/kdimtricp/vshazam/internal/models/api_usage"
+)
+
+// Pricing holds list prices in US dollars.
+type Pricing struct {
+	OpenAIInputPerMillion   float64
+	OpenAIOutputPerMillion  float64
+	GoogleVisionPerThousand float64
+	GoogleSearchPerThousand float64
+	TMDbPerRequest          float64
+	OMDbPerRequest          float64
+	SpeechPerMinute         float64
+}
+
+// DefaultPricing uses public list prices for gpt-4o, Cloud Vision, Custom
+// Search and whisper-1. TMDb and OMDb's free tier cost nothing.
+func DefaultPricing() Pricing {
+	return Pricing{
+		OpenAIInputPerMillion:   2.50,
+		OpenAIOutputPerMillion:  10.00,
+		GoogleVisionPerThousand: 1.50,
+		GoogleSearchPerThousand: 5.00,
+		SpeechPerMinute:         0.006,
+	}
+}
+
+// Cost estimates the price of one event.
+func (p Pricing) Cost(event Event) float64 {
+	switch event.Provider {
+	case ProviderOpenAI:
+		return float64(event.InputTokens)*p.OpenAIInputPerMillion/1e6 +
+			float64(event.OutputTokens)*p.OpenAIOutputPerMillion/1e6
+	case ProviderGoogleVision:
+		return float64(event.Units) * p.GoogleVisionPerThousand / 1000
+	case ProviderGoogleSearch:
+		return float64(event.Units) * p.GoogleSearchPerThousand / 1000
+	case ProviderTMDb:
+		return float64(event.Units) * p.TMDbPerRequest
+	case ProviderOMDb:
+		return float64(event.Units) * p.OMDbPerRequest
+	case ProviderSpeech:
+		return float64(event.Units) * p.SpeechPerMinute / 60
+	}
+	return 0
+}
+
+// Budget limits spend in US dollars. Zero disables a limit. Once spend reaches
+// DegradeAt of either limit the most expensive provider, OpenAI, is refused so
+// analysis degrades to Google Vision; at the limit every paid call is refused.
+type Budget struct {
+	Daily     float64
+	Monthly   float64
+	DegradeAt float64
+}
+
+func DefaultBudget() Budget {
+	return Budget{DegradeAt: 0.8}
+}
+
+type Store interface {
+	Create(ctx context.Context, record *api_usage.UsageDB) error
+	SpendSince(ctx context.Context, since time.Time) (float64, error)
+}
+
+// Tracker stores usage events and enforces the budget.
+// Spend totals are cached for spendTTL so budget checks do not query the
+// database on every call.
+type Tracker struct {
+	store   Store
+	pricing Pricing
+	budget  Budget
+	now     func() time.Time
+
+	mu       sync.Mutex
+	cachedAt time.Time
+	daily    float64
+	monthly  float64
+}
+
+const spendTTL = 10 * time.Second
+
+func NewTracker(store Store, pricing Pricing, budget Budget) *Tracker {
+	return &Tracker{
+		store:   store,
+		pricing: pricing,
+		budget:  budget,
+		now:     time.Now,
+	}
+}
+
+func (t *Tracker) Budget() Budget {
+	return t.budget
+}
+
+// Spend returns the estimated spend so far today and this month, in UTC.
+func (t *Tracker) Spend(ctx context.Context) (daily, monthly float64, err error) {
+	now := t.now()
+
+	t.mu.Lock()
+	if !t.cachedAt.IsZero() && now.Sub(t.cachedAt) < spendTTL && sameDay(t.cachedAt, now) {
+		daily, monthly = t.daily, t.monthly
+		t.mu.Unlock()
+		return daily, monthly, nil
+	}
+	t.mu.Unlock()
+
+	day, month := periodStarts(now)
+	daily, err = t.store.SpendSince(ctx, day)
+	if err != nil {
+		return 0, 0, err
+	}
+	monthly, err = t.store.SpendSince(ctx, month)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	t.mu.Lock()
+	t.cachedAt, t.daily, t.monthly = now, daily, monthly
+	t.mu.Unlock()
+	return daily, monthly, nil
+}
+
+// Allow reports whether provider may be called under the current budget.
+// Free providers are always allowed. If spend cannot be read the call is
+// allowed rather than blocking identification on a database error.
+func (t *Tracker) Allow(ctx context.Context, provider string) error {
+	if t.budget.Daily <= 0 && t.budget.Monthly <= 0 {
+		return nil
+	}
+	if t.pricing.Cost(Event{Provider: provider, Units: 1, InputTokens: 1, OutputTokens: 1}) == 0 {
+		return nil
+	}
+
+	daily, monthly, err := t.Spend(ctx)
+	if err != nil {
+		log.Printf("Failed to check API budget: %v", err)
+		return nil
+	}
+
+	used := t.budget.Used(daily, monthly)
+	switch {
+	case used >= 1:
+		return fmt.Errorf("%s refused: %w", provider, ErrBudgetExceeded)
+	case provider == ProviderOpenAI && t.budget.DegradeAt > 0 && used >= t.budget.DegradeAt:
+		return fmt.Errorf("%s refused, %.0f%% of budget used: %w", provider, used*100, ErrBudgetExceeded)
+	}
+	return nil
+}
+
+// Used returns the largest fraction of the daily or monthly budget spent.
+func (b Budget) Used(daily, monthly float64) float64 {
+	used := 0.0
+	if b.Daily > 0 && daily/b.Daily > used {
+		used = daily / b.Daily
+	}
+	if b.Monthly > 0 && monthly/b.Monthly > used {
+		used = monthly / b.Monthly
+	}
+	return used
+}
+
+// Record stores the event with its estimated cost. Failures are logged; a
+// lost usage row should never fail the call that was already paid for.
+func (t *Tracker) Record(ctx context.Context, event Event) {
+	record := &api_usage.UsageDB{
+		Provider:  event.Provider,
+		Operation: event.Operation,
+		VideoID:   VideoID(ctx),
+		Units:     event.Units,
+		Unit:      event.Unit,
+		CostUSD:   t.pricing.Cost(event),
+		CreatedAt: t.now(),
+	}
+	if err := t.store.Create(context.WithoutCancel(ctx), record); err != nil {
+		log.Printf("Failed to record %s usage: %v", event.Provider, err)
+		return
+	}
+
+	t.mu.Lock()
+	t.daily += record.CostUSD
+	t.monthly += record.CostUSD
+	t.mu.Unlock()
+}
+
+func sameDay(a, b time.Time) bool {
+	a, b = a.UTC(), b.UTC()
+	return a.Year() == b.Year() && a.YearDay() == b.YearDay()
+}
+
+// periodStarts returns midnight UTC today and on the first of the month.
+func periodStarts(now time.Time) (day, month time.Time) {
+	now = now.UTC()
+	day = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	month = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return day, month
+}
@@ -0,0 +1,151 @@
+package metering
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/kdimtricp/vshazam/internal/models/api_usage"
+)
+
+type mockStore struct {
+	records []*api_usage.UsageDB
+	queries int
+}
+
+func (m *mockStore) Create(ctx context.Context, record *api_usage.UsageDB) error {
+	m.records = append(m.records, record)
+	return nil
+}
+
+func (m *mockStore) SpendSince(ctx context.Context, since time.Time) (float64, error) {
+	m.queries++
+	total := 0.0
+	for _, record := range m.records {
+		if !record.CreatedAt.Before(since) {
+			total += record.CostUSD
+		}
+	}
+	return total, nil
+}
+
+func TestPricingCost(t *testing.T) {
+	pricing := DefaultPricing()
+
+	tests := []struct {
+		event    Event
+		expected float64
+	}{
+		{Event{Provider: ProviderOpenAI, InputTokens: 1000, OutputTokens: 200}, 0.0045},
+		{Event{Provider: ProviderGoogleVision, Units: 4}, 0.006},
+		{Event{Provider: ProviderGoogleSearch, Units: 1}, 0.005},
+		{Event{Provider: ProviderTMDb, Units: 1}, 0},
+		{Event{Provider: ProviderSpeech, Units: 90}, 0.009},
+	}
+
+	for _, tt := range tests {
+		if got := pricing.Cost(tt.event); math.Abs(got-tt.expected) > 1e-9 {
+			t.Errorf("%s: expected cost %f, got %f", tt.event.Provider, tt.expected, got)
+		}
+	}
+}
+
+func TestTrackerRecordsWithVideoID(t *testing.T) {
+	store := &mockStore{}
+	tracker := NewTracker(store, DefaultPricing(), DefaultBudget())
+
+	ctx := WithVideoID(context.Background(), "video-1")
+	tracker.Record(ctx, Event{Provider: ProviderGoogleSearch, Operation: "search", Units: 1, Unit: "queries"})
+
+	if len(store.records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(store.records))
+	}
+	record := store.records[0]
+	if record.VideoID != "video-1" || record.Provider != ProviderGoogleSearch || record.CostUSD != 0.005 {
+		t.Errorf("unexpected record: %+v", record)
+	}
+}
+
+func TestTrackerAllowDegradesThenRefuses(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	store := &mockStore{}
+	tracker := NewTracker(store, DefaultPricing(), Budget{Daily: 1, DegradeAt: 0.8})
+	tracker.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if err := tracker.Allow(ctx, ProviderOpenAI); err != nil {
+		t.Fatalf("expected OpenAI to be allowed under budget, got %v", err)
+	}
+
+	store.records = append(store.records, &api_usage.UsageDB{Provider: ProviderOpenAI, CostUSD: 0.85, CreatedAt: now.Add(-time.Hour)})
+	now = now.Add(time.Minute)
+
+	if err := tracker.Allow(ctx, ProviderOpenAI); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("expected OpenAI to be refused past the degrade threshold, got %v", err)
+	}
+	if err := tracker.Allow(ctx, ProviderGoogleVision); err != nil {
+		t.Errorf("expected Google Vision to stay allowed, got %v", err)
+	}
+
+	tracker.Record(ctx, Event{Provider: ProviderGoogleSearch, Units: 40})
+	if err := tracker.Allow(ctx, ProviderGoogleVision); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("expected every paid provider to be refused over budget, got %v", err)
+	}
+	if err := tracker.Allow(ctx, ProviderTMDb); err != nil {
+		t.Errorf("expected free provider to stay allowed, got %v", err)
+	}
+}
+
+func TestTrackerCachesSpend(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	store := &mockStore{}
+	tracker := NewTracker(store, DefaultPricing(), Budget{Monthly: 10})
+	tracker.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		tracker.Allow(ctx, ProviderOpenAI)
+	}
+	if store.queries != 2 {
+		t.Errorf("expected one daily and one monthly query, got %d", store.queries)
+	}
+
+	now = now.Add(spendTTL)
+	tracker.Allow(ctx, ProviderOpenAI)
+	if store.queries != 4 {
+		t.Errorf("expected spend to be reloaded after the TTL, got %d queries", store.queries)
+	}
+}
+
+func TestBuildReport(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	records := []*api_usage.UsageDB{
+		{Provider: ProviderOpenAI, VideoID: "a", Units: 1200, Unit: "tokens", CostUSD: 0.01, CreatedAt: now.AddDate(0, 0, -20)},
+		{Provider: ProviderOpenAI, VideoID: "b", Units: 800, Unit: "tokens", CostUSD: 0.02, CreatedAt: now.AddDate(0, 0, -3)},
+		{Provider: ProviderGoogleVision, VideoID: "b", Units: 4, Unit: "units", CostUSD: 0.006, CreatedAt: now.Add(-time.Hour)},
+		{Provider: ProviderTMDb, Units: 1, Unit: "requests", CreatedAt: now.Add(-time.Hour)},
+	}
+
+	report := BuildReport(records, Budget{Daily: 0.01}, now)
+
+	if math.Abs(report.DailySpend-0.006) > 1e-9 || math.Abs(report.MonthlySpend-0.026) > 1e-9 {
+		t.Errorf("unexpected spend: daily %f, monthly %f", report.DailySpend, report.MonthlySpend)
+	}
+	if len(report.Today) != 2 || report.Today[0].Provider != ProviderGoogleVision {
+		t.Errorf("expected Google Vision to lead today's providers, got %+v", report.Today)
+	}
+	if len(report.Month) != 3 || report.Month[0].Units != 800 {
+		t.Errorf("expected this month's OpenAI usage to exclude last month, got %+v", report.Month)
+	}
+	if len(report.Days) != 30 || report.Days[29].Calls != 2 || report.Days[9].Calls != 1 {
+		t.Errorf("unexpected daily totals: last day %d calls, day 10 %d calls", report.Days[29].Calls, report.Days[9].Calls)
+	}
+	if len(report.TopVideos) != 1 || report.TopVideos[0].VideoID != "b" {
+		t.Errorf("expected only video b this month, got %+v", report.TopVideos)
+	}
+	if math.Abs(report.Used()-0.6) > 1e-9 {
+		t.Errorf("expected 60%% of the daily budget used, got %f", report.Used())
+	}
+}
@@ -0,0 +1,21 @@
+package api_usage
+
+import "time"
+
+// UsageDB is one billable call to an external API. VideoID is empty for calls
+// made outside an identification and deliberately has no foreign key so spend
+// history survives video deletion.
+type UsageDB struct {
+	ID        string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	Provider  string    `gorm:"type:varchar(32);not null;index" json:"provider"`
+	Operation string    `gorm:"type:varchar(64)" json:"operation"`
+	VideoID   string    `gorm:"type:varchar(36);index" json:"video_id"`
+	Units     int       `gorm:"default:0" json:"units"`
+	Unit      string    `gorm:"type:varchar(16)" json:"unit"`
+	CostUSD   float64   `gorm:"column:cost_usd;default:0" json:"cost_usd"`
+	CreatedAt time.Time `gorm:"not null;index" json:"created_at"`
+}
+
+func (UsageDB) TableName() string {
+	return "api_usage"
+}
@@ -0,0 +1,70 @@
+package face
+
+import (
+	"math"
+	"time"
+)
+
+// FaceDB is one face detected in an analyzed frame. The box is in pixels of
+// the extracted frame. ClusterID is nil when no embedding could be computed.
+type FaceDB struct {
+	ID            string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	VideoID       string    `gorm:"type:uuid;not null;index" json:"video_id"`
+	FrameNumber   int       `gorm:"not null" json:"frame_number"`
+	X             int       `gorm:"not null" json:"x"`
+	Y             int       `gorm:"not null" json:"y"`
+	Width         int       `gorm:"not null" json:"width"`
+	Height        int       `gorm:"not null" json:"height"`
+	Confidence    float64   `gorm:"default:0" json:"confidence"`
+	ThumbnailPath string    `gorm:"type:text" json:"thumbnail_path"`
+	Embedding     []float64 `gorm:"type:jsonb;serializer:json" json:"embedding"`
+	ClusterID     *string   `gorm:"type:uuid;index" json:"cluster_id"`
+	CreatedAt     time.Time `gorm:"not null" json:"created_at"`
+}
+
+func (FaceDB) TableName() string {
+	return "faces"
+}
+
+// FaceClusterDB groups faces of what is probably the same person, across
+// frames and across videos. Centroid is the mean embedding of its faces.
+type FaceClusterDB struct {
+	ID        string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	Centroid  []float64 `gorm:"type:jsonb;serializer:json" json:"centroid"`
+	FaceCount int       `gorm:"default:0" json:"face_count"`
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+	UpdatedAt time.Time `gorm:"not null" json:"updated_at"`
+}
+
+func (FaceClusterDB) TableName() string {
+	return "face_clusters"
+}
+
+// Recompute sets the centroid to the normalized mean of embeddings, the
+// embeddings of the faces left in the cluster, and the face count to their
+// number.
+func (c *FaceClusterDB) Recompute(embeddings [][]float64) {
+	c.FaceCount = len(embeddings)
+	c.Centroid = nil
+	for _, embedding := range embeddings {
+		if c.Centroid == nil {
+			c.Centroid = make([]float64, len(embedding))
+		}
+		if len(embedding) != len(c.Centroid) {
+			continue
+		}
+		for i, v := range embedding {
+			c.Centroid[i] += v
+		}
+	}
+
+	norm := 0.0
+	for _, v := range c.Centroid {
+		norm += v * v
+	}
+	if norm = math.Sqrt(norm); norm > 0 {
+		for i := range c.Centroid {
+			c.Centroid[i] /= norm
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package film
+
+import "time"
+
+// Credit roles.
+const (
+	RoleCast = "cast"
+	RoleCrew = "crew"
+)
+
+// JobDirector is the crew job TMDb gives directors.
+const JobDirector = "Director"
+
+// FilmDB is a film's metadata copied from TMDb, so identified videos can be
+// browsed by film and identification keeps working when TMDb is unreachable.
+// SyncedAt is when the copy was last fetched.
+type FilmDB struct {
+	ID           string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	TMDbID       int       `gorm:"column:tmdb_id;not null;uniqueIndex" json:"tmdb_id"`
+	IMDbID       string    `gorm:"column:imdb_id;type:varchar(16);index" json:"imdb_id"`
+	Title        string    `gorm:"type:text;not null" json:"title"`
+	Year         int       `gorm:"default:0;index" json:"year"`
+	ReleaseDate  string    `gorm:"type:varchar(10)" json:"release_date"`
+	Overview     string    `gorm:"type:text" json:"overview"`
+	Runtime      int       `gorm:"default:0" json:"runtime"`
+	PosterPath   string    `gorm:"type:text" json:"poster_path"`
+	BackdropPath string    `gorm:"type:text" json:"backdrop_path"`
+	VoteAverage  float64   `gorm:"default:0" json:"vote_average"`
+	Genres       []string  `gorm:"type:jsonb;serializer:json" json:"genres"`
+	SyncedAt     time.Time `gorm:"not null;index" json:"synced_at"`
+	CreatedAt    time.Time `gorm:"not null" json:"created_at"`
+}
+
+func (FilmDB) TableName() string {
+	return "films"
+}
+
+// CreditDB is one cast member or crew job of a film. Position is the cast
+// billing order; crew credits keep TMDb's order.
+type CreditDB struct {
+	ID         string `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	FilmID     string `gorm:"type:uuid;not null;index" json:"film_id"`
+	Role       string `gorm:"type:varchar(8);not null" json:"role"`
+	Name       string `gorm:"type:text;not null;index" json:"name"`
+	Character  string `gorm:"column:character_name;type:text" json:"character"`
+	Job        string `gorm:"type:varchar(64)" json:"job"`
+	Department string `gorm:"type:varchar(64)" json:"department"`
+	Position   int    `gorm:"default:0" json:"position"`
+}
+
+func (CreditDB) TableName() string {
+	return "film_credits"
+}
@@ -15,6 +15,17 @@ type FrameAnalysisDB struct {
 	FaceCount    int             `gorm:"default:0" json:"face_count"`
 	AnalysisTime time.Time       `gorm:"not null;index" json:"analysis_time"`
 	RawResponse  json.RawMessage `gorm:"type:jsonb" json:"raw_response"`
+
+	BestGuessLabels []string        `gorm:"type:jsonb;serializer:json" json:"best_guess_labels"`
+	WebEntities     json.RawMessage `gorm:"type:jsonb" json:"web_entities"`
+	MatchingPages   json.RawMessage `gorm:"type:jsonb" json:"matching_pages"`
+	Logos           json.RawMessage `gorm:"type:jsonb" json:"logos"`
+	Landmarks       json.RawMessage `gorm:"type:jsonb" json:"landmarks"`
+	Objects         json.RawMessage `gorm:"type:jsonb" json:"objects"`
+
+	// FrameTime is the frame's position in the video in seconds.
+	FrameTime  float64         `gorm:"default:0" json:"frame_time"`
+	TextBlocks json.RawMessage `gorm:"type:jsonb" json:"text_blocks"`
 }
 
 func (FrameAnalysisDB) TableName() string {
@@ -0,0 +1,88 @@
+package identification
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// IdentificationDB is one run of the identification pipeline for a video.
+type IdentificationDB struct {
+	ID             string          `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	VideoID        string          `gorm:"type:uuid;not null;index" json:"video_id"`
+	TopTitle       string          `gorm:"type:text" json:"top_title"`
+	TopYear        int             `gorm:"default:0" json:"top_year"`
+	TopTMDbID      int             `gorm:"column:top_tmdb_id;default:0" json:"top_tmdb_id"`
+	TopScore       float64         `gorm:"default:0" json:"top_score"`
+	Candidates     json.RawMessage `gorm:"type:jsonb" json:"candidates"`
+	FramesAnalyzed int             `gorm:"default:0" json:"frames_analyzed"`
+	StopReason     string          `gorm:"type:varchar(32)" json:"stop_reason"`
+	// RequestedBy is the user who started the run, nil for runs from before
+	// accounts.
+	RequestedBy *string   `gorm:"type:uuid;index" json:"requested_by,omitempty"`
+	CreatedAt   time.Time `gorm:"not null;index" json:"created_at"`
+}
+
+func (IdentificationDB) TableName() string {
+	return "identifications"
+}
+
+// RequestDB records an identification a user started, whether or not it
+// finished, for the daily identification quota.
+type RequestDB struct {
+	ID        string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	UserID    string    `gorm:"type:uuid;not null;index:idx_identification_requests_user,priority:1" json:"user_id"`
+	VideoID   string    `gorm:"type:uuid;not null" json:"video_id"`
+	CreatedAt time.Time `gorm:"not null;index:idx_identification_requests_user,priority:2" json:"created_at"`
+}
+
+func (RequestDB) TableName() string {
+	return "identification_requests"
+}
+
+const (
+	VerdictCorrect   = "correct"
+	VerdictWrong     = "wrong"
+	VerdictCorrected = "corrected"
+)
+
+// FeedbackDB is a user's verdict on an identification. For corrected
+// verdicts the film fields hold the title the user picked instead.
+type FeedbackDB struct {
+	ID               string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	IdentificationID string    `gorm:"type:uuid;not null;index" json:"identification_id"`
+	VideoID          string    `gorm:"type:uuid;not null;index" json:"video_id"`
+	Verdict          string    `gorm:"type:varchar(16);not null" json:"verdict"`
+	TMDbID           int       `gorm:"column:tmdb_id;default:0" json:"tmdb_id"`
+	Title            string    `gorm:"type:text" json:"title"`
+	Year             int       `gorm:"default:0" json:"year"`
+	CreatedAt        time.Time `gorm:"not null;index" json:"created_at"`
+}
+
+func (FeedbackDB) TableName() string {
+	return "identification_feedback"
+}
+
+// ReferenceFingerprintDB ties the distinctive tokens seen in a confirmed
+// video to the film it turned out to be.
+type ReferenceFingerprintDB struct {
+	ID        string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	VideoID   string    `gorm:"type:uuid;not null;uniqueIndex" json:"video_id"`
+	TMDbID    int       `gorm:"column:tmdb_id;default:0;index" json:"tmdb_id"`
+	Title     string    `gorm:"type:text;not null" json:"title"`
+	Year      int       `gorm:"default:0" json:"year"`
+	Tokens    []string  `gorm:"type:jsonb;serializer:json" json:"tokens"`
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+}
+
+func (ReferenceFingerprintDB) TableName() string {
+	return "reference_fingerprints"
+}
+
+// FeedbackSample is a verdict joined with the identification it judged,
+// used to build accuracy reports.
+type FeedbackSample struct {
+	Verdict   string
+	TopScore  float64
+	TopTitle  string
+	CreatedAt time.Time
+}
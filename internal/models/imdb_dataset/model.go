@@ -0,0 +1,68 @@
+package imdb_dataset
+
+// Principal categories kept from title.principals.
+const (
+	CategoryActor    = "actor"
+	CategoryActress  = "actress"
+	CategoryDirector = "director"
+	CategoryWriter   = "writer"
+)
+
+// TitleDB is a row of IMDb's title.basics dataset. SearchTitle is the
+// primary title normalized for matching.
+type TitleDB struct {
+	TConst         string   `gorm:"column:tconst;type:varchar(16);primaryKey" json:"tconst"`
+	TitleType      string   `gorm:"type:varchar(32);index" json:"title_type"`
+	PrimaryTitle   string   `gorm:"type:text;not null" json:"primary_title"`
+	OriginalTitle  string   `gorm:"type:text" json:"original_title"`
+	SearchTitle    string   `gorm:"type:text;not null;index" json:"search_title"`
+	StartYear      int      `gorm:"default:0" json:"start_year"`
+	EndYear        int      `gorm:"default:0" json:"end_year"`
+	RuntimeMinutes int      `gorm:"default:0" json:"runtime_minutes"`
+	Genres         []string `gorm:"type:jsonb;serializer:json" json:"genres"`
+}
+
+func (TitleDB) TableName() string {
+	return "imdb_titles"
+}
+
+// AkaDB is a row of title.akas, an alternative or localized title.
+type AkaDB struct {
+	TConst          string `gorm:"column:tconst;type:varchar(16);primaryKey" json:"tconst"`
+	Ordering        int    `gorm:"primaryKey;autoIncrement:false" json:"ordering"`
+	Title           string `gorm:"type:text;not null" json:"title"`
+	SearchTitle     string `gorm:"type:text;not null;index" json:"search_title"`
+	Region          string `gorm:"type:varchar(8)" json:"region"`
+	Language        string `gorm:"type:varchar(8)" json:"language"`
+	IsOriginalTitle bool   `gorm:"default:false" json:"is_original_title"`
+}
+
+func (AkaDB) TableName() string {
+	return "imdb_akas"
+}
+
+// PrincipalDB is a row of title.principals, a person credited on a title.
+type PrincipalDB struct {
+	TConst     string   `gorm:"column:tconst;type:varchar(16);primaryKey" json:"tconst"`
+	Ordering   int      `gorm:"primaryKey;autoIncrement:false" json:"ordering"`
+	NConst     string   `gorm:"column:nconst;type:varchar(16);not null;index" json:"nconst"`
+	Category   string   `gorm:"type:varchar(32)" json:"category"`
+	Job        string   `gorm:"type:text" json:"job"`
+	Characters []string `gorm:"type:jsonb;serializer:json" json:"characters"`
+}
+
+func (PrincipalDB) TableName() string {
+	return "imdb_principals"
+}
+
+// NameDB is a row of name.basics, which principals refer to by NConst.
+type NameDB struct {
+	NConst      string `gorm:"column:nconst;type:varchar(16);primaryKey" json:"nconst"`
+	PrimaryName string `gorm:"type:text;not null;index" json:"primary_name"`
+	BirthYear   int    `gorm:"default:0" json:"birth_year"`
+	DeathYear   int    `gorm:"default:0" json:"death_year"`
+}
+
+func (NameDB) TableName() string {
+	return "imdb_names"
+}
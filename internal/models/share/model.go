@@ -0,0 +1,46 @@
+package share
+
+import "time"
+
+// What a share link opens. AccessVideo is view-only: the recipient can watch
+// the video but not see how it was identified.
+const (
+	AccessVideo   = "video"
+	AccessResults = "results"
+)
+
+// LinkDB is a signed URL that lets someone without an account watch a video
+// and, for AccessResults, see its latest identification. MaxViews of 0 means
+// unlimited.
+type LinkDB struct {
+	ID        string     `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	VideoID   string     `gorm:"type:uuid;not null;index" json:"video_id"`
+	CreatedBy string     `gorm:"type:uuid;not null;index" json:"created_by"`
+	Access    string     `gorm:"type:varchar(16);not null" json:"access"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	MaxViews  int        `gorm:"not null;default:0" json:"max_views"`
+	Views     int        `gorm:"not null;default:0" json:"views"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `gorm:"not null" json:"created_at"`
+}
+
+func (LinkDB) TableName() string {
+	return "share_links"
+}
+
+// ShowsResults reports whether the link includes identification results.
+func (l *LinkDB) ShowsResults() bool {
+	return l.Access == AccessResults
+}
+
+// Active reports whether the link is neither revoked nor expired at now.
+// Views are counted, and MaxViews enforced, when the video page is opened;
+// the stream and results behind it open with a short-lived view token.
+func (l *LinkDB) Active(now time.Time) bool {
+	return l.RevokedAt == nil && now.Before(l.ExpiresAt)
+}
+
+// ViewsLeft reports whether the link can be opened again.
+func (l *LinkDB) ViewsLeft() bool {
+	return l.MaxViews == 0 || l.Views < l.MaxViews
+}
@@ -0,0 +1,31 @@
+package subtitle
+
+import "time"
+
+// Sources of subtitle tracks.
+const (
+	// SourceEmbedded tracks were extracted from a subtitle stream in the
+	// uploaded video.
+	SourceEmbedded = "embedded"
+	// SourceSidecar tracks were uploaded as separate SRT or WebVTT files.
+	SourceSidecar = "sidecar"
+)
+
+// TrackDB is one subtitle track of a video, normalized to WebVTT and kept in
+// storage at FilePath. Its cues are also stored as transcript cues so they
+// can be searched.
+type TrackDB struct {
+	ID          string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	VideoID     string    `gorm:"type:uuid;not null;index" json:"video_id"`
+	Source      string    `gorm:"type:varchar(16);not null" json:"source"`
+	Language    string    `gorm:"type:varchar(16)" json:"language"`
+	Label       string    `gorm:"type:varchar(255)" json:"label"`
+	StreamIndex int       `gorm:"default:-1" json:"stream_index"`
+	FilePath    string    `gorm:"type:text;not null" json:"file_path"`
+	CueCount    int       `gorm:"default:0" json:"cue_count"`
+	CreatedAt   time.Time `gorm:"not null" json:"created_at"`
+}
+
+func (TrackDB) TableName() string {
+	return "subtitle_tracks"
+}
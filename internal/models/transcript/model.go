@@ -0,0 +1,28 @@
+package transcript
+
+import "time"
+
+// Sources of transcript cues.
+const (
+	SourceOCR      = "ocr"
+	SourceSpeech   = "speech"
+	SourceSubtitle = "subtitle"
+)
+
+// CueDB is one timed line of text from a video, such as a burned-in subtitle
+// read from the frames or a segment of transcribed speech. Start and End are
+// seconds into the video.
+type CueDB struct {
+	ID        string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	VideoID   string    `gorm:"type:uuid;not null;index" json:"video_id"`
+	Source    string    `gorm:"type:varchar(16);not null;index" json:"source"`
+	Language  string    `gorm:"type:varchar(16)" json:"language"`
+	Start     float64   `gorm:"column:start_time;not null" json:"start"`
+	End       float64   `gorm:"column:end_time;not null" json:"end"`
+	Text      string    `gorm:"type:text;not null" json:"text"`
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+}
+
+func (CueDB) TableName() string {
+	return "transcript_cues"
+}
@@ -0,0 +1,106 @@
+package user
+
+import "time"
+
+// Roles a user can have. Admins see and manage every video; users only their
+// own.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// UserDB is an account that signs in with an email address and password.
+type UserDB struct {
+	ID           string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	Email        string    `gorm:"not null;uniqueIndex" json:"email"`
+	PasswordHash string    `gorm:"not null" json:"-"`
+	Role         string    `gorm:"not null;default:user" json:"role"`
+	CreatedAt    time.Time `gorm:"not null" json:"created_at"`
+}
+
+func (UserDB) TableName() string {
+	return "users"
+}
+
+func (u *UserDB) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+// SessionDB is a signed-in browser. ID is the SHA-256 of the token kept in
+// the session cookie, so a leaked table does not leak usable sessions.
+// CSRFToken must accompany every state-changing request of the session.
+type SessionDB struct {
+	ID        string    `gorm:"primaryKey" json:"-"`
+	UserID    string    `gorm:"type:uuid;not null;index" json:"user_id"`
+	CSRFToken string    `gorm:"not null" json:"-"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+}
+
+func (SessionDB) TableName() string {
+	return "sessions"
+}
+
+// Scopes an API key can be granted. ScopeAdmin implies every other scope but
+// only works for admins.
+const (
+	ScopeUpload   = "upload"
+	ScopeRead     = "read"
+	ScopeIdentify = "identify"
+	ScopeAdmin    = "admin"
+)
+
+// Scopes lists every scope in the order the settings page offers them.
+var Scopes = []string{ScopeRead, ScopeUpload, ScopeIdentify, ScopeAdmin}
+
+// APIKeyDB lets a script act as its user with an "Authorization: Bearer"
+// header. Only the SHA-256 of the key is stored; Prefix is kept in clear so
+// the user can tell keys apart.
+type APIKeyDB struct {
+	ID         string     `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	UserID     string     `gorm:"type:uuid;not null;index" json:"user_id"`
+	Name       string     `gorm:"not null" json:"name"`
+	Prefix     string     `gorm:"not null" json:"prefix"`
+	KeyHash    string     `gorm:"not null;uniqueIndex" json:"-"`
+	Scopes     []string   `gorm:"type:jsonb;serializer:json" json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `gorm:"not null" json:"created_at"`
+}
+
+func (APIKeyDB) TableName() string {
+	return "api_keys"
+}
+
+// HasScope reports whether the key was granted scope, directly or through
+// ScopeAdmin.
+func (k *APIKeyDB) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// Active reports whether the key can still be used at now.
+func (k *APIKeyDB) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// APIKeyUseDB is one request made with an API key, kept as an audit trail.
+type APIKeyUseDB struct {
+	ID        string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	KeyID     string    `gorm:"type:uuid;not null;index" json:"key_id"`
+	UserID    string    `gorm:"type:uuid;not null;index" json:"user_id"`
+	Method    string    `gorm:"not null" json:"method"`
+	Path      string    `gorm:"type:text;not null" json:"path"`
+	Status    int       `gorm:"not null" json:"status"`
+	RemoteIP  string    `json:"remote_ip"`
+	CreatedAt time.Time `gorm:"not null;index" json:"created_at"`
+}
+
+func (APIKeyUseDB) TableName() string {
+	return "api_key_uses"
+}
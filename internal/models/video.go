@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type Video struct {
@@ -14,6 +15,12 @@ type Video struct {
 	ContentType string    `gorm:"not null"`
 	Size        int64     `gorm:"not null"`
 	UploadTime  time.Time `gorm:"not null;index"`
+	// OwnerID is the user who uploaded the video, or nil for videos uploaded
+	// before accounts existed.
+	OwnerID *string `gorm:"type:uuid;index"`
+	// DeletedAt is set while the video is in the trash. Gorm leaves trashed
+	// videos out of every query unless it is made Unscoped.
+	DeletedAt gorm.DeletedAt `gorm:"index"`
 }
 
 func (Video) TableName() string {
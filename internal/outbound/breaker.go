@@ -0,0 +1,98 @@
+package outbound
+
+import (
+	"sync"
+	"time"
+)
+
+type State string
+
+const (
+	StateClosed   State = "closed"
+	StateOpen     State = "open"
+	StateHalfOpen State = "half_open"
+)
+
+// breaker opens after threshold consecutive failures and rejects calls for
+// cooldown. After the cooldown it lets a single probe call through and
+// rejects the others until the probe finishes; a successful probe closes it
+// and a failed one reopens it straight away.
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openedAt  time.Time
+	probing   bool
+	now       func() time.Time
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	return &breaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+func (b *breaker) state() State {
+	if b.threshold <= 0 || b.failures < b.threshold {
+		return StateClosed
+	}
+	if b.now().Sub(b.openedAt) < b.cooldown {
+		return StateOpen
+	}
+	return StateHalfOpen
+}
+
+// State reports the current breaker state.
+func (b *breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state()
+}
+
+// Allow reports whether a call may be made now, and whether it is the
+// half-open probe. The caller of a probe must call Release once the call is
+// over, whatever its outcome.
+func (b *breaker) Allow() (allowed, probe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state() {
+	case StateOpen:
+		return false, false
+	case StateHalfOpen:
+		if b.probing {
+			return false, false
+		}
+		b.probing = true
+		return true, true
+	}
+	return true, false
+}
+
+// Release ends the half-open probe, letting another call probe if the probe
+// recorded neither a success nor a failure.
+func (b *breaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func (b *breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.probing = false
+}
+
+// Failure records a failed call and reports whether it opened, or reopened,
+// the breaker.
+func (b *breaker) Failure() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	before := b.state()
+	b.failures++
+	b.probing = false
+	if b.threshold > 0 && b.failures >= b.threshold {
+		b.openedAt = b.now()
+		return before != StateOpen
+	}
+	return false
+}
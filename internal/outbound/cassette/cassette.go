@@ -0,0 +1,240 @@
+// Package cassette records HTTP interactions to a JSON file and replays them,
+// so API clients can be tested offline against real response bodies.
+//
+// Requests are matched on method and URL. Query parameters that carry
+// credentials are dropped before matching and before anything is written, and
+// request bodies and headers are never stored.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// RecordEnv switches ForTest from replaying to recording when set.
+const RecordEnv = "RECORD_CASSETTES"
+
+var secretParams = map[string]bool{"key": true, "api_key": true, "api-key": true, "apikey": true, "access_token": true}
+
+type Cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+type Request struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+}
+
+type Response struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body"`
+}
+
+// Transport is an http.RoundTripper that replays a cassette, or records one
+// when it wraps a real transport.
+type Transport struct {
+	mu       sync.Mutex
+	path     string
+	cassette *Cassette
+	used     []bool
+	real     http.RoundTripper
+}
+
+// Load returns a transport that replays the cassette at path. Each recorded
+// interaction is served at most once, in recorded order.
+func Load(path string) (*Transport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+
+	return &Transport{path: path, cassette: &c, used: make([]bool, len(c.Interactions))}, nil
+}
+
+// Record returns a transport that sends requests through real and keeps every
+// interaction until Save.
+func Record(path string, real http.RoundTripper) *Transport {
+	if real == nil {
+		real = http.DefaultTransport
+	}
+	return &Transport{path: path, cassette: &Cassette{}, real: real}
+}
+
+// ForTest replays the cassette at path, or records it when RECORD_CASSETTES is
+// set, saving it when the test finishes.
+func ForTest(t testing.TB, path string) *Transport {
+	t.Helper()
+
+	if os.Getenv(RecordEnv) != "" {
+		transport := Record(path, nil)
+		t.Cleanup(func() {
+			if err := transport.Save(); err != nil {
+				t.Errorf("failed to save cassette: %v", err)
+			}
+		})
+		return transport
+	}
+
+	transport, err := Load(path)
+	if err != nil {
+		t.Fatalf("failed to load cassette: %v", err)
+	}
+	return transport
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.real != nil {
+		return t.record(req)
+	}
+	return t.replay(req)
+}
+
+func (t *Transport) replay(req *http.Request) (*http.Response, error) {
+	method, target := req.Method, scrubURL(req.URL)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i, interaction := range t.cassette.Interactions {
+		if t.used[i] || interaction.Request.Method != method || interaction.Request.URL != target {
+			continue
+		}
+		t.used[i] = true
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return interaction.Response.toHTTP(req), nil
+	}
+
+	return nil, fmt.Errorf("cassette %s has no unused interaction for %s %s", filepath.Base(t.path), method, target)
+}
+
+func (t *Transport) record(req *http.Request) (*http.Response, error) {
+	resp, err := t.real.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	interaction := &Interaction{
+		Request: Request{Method: req.Method, URL: scrubURL(req.URL)},
+		Response: Response{
+			Status: resp.StatusCode,
+			Body:   string(body),
+		},
+	}
+	for _, name := range []string{"Content-Type", "Retry-After"} {
+		if value := resp.Header.Get(name); value != "" {
+			if interaction.Response.Headers == nil {
+				interaction.Response.Headers = make(map[string]string)
+			}
+			interaction.Response.Headers[name] = value
+		}
+	}
+
+	t.mu.Lock()
+	t.cassette.Interactions = append(t.cassette.Interactions, interaction)
+	t.mu.Unlock()
+
+	return interaction.Response.toHTTP(req), nil
+}
+
+// Save writes the recorded interactions to the cassette path.
+func (t *Transport) Save() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	data, err := json.MarshalIndent(t.cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(t.path, append(data, '\n'), 0644)
+}
+
+// Unused returns the interactions that were never replayed, which usually
+// means the code under test made fewer calls than when it was recorded.
+func (t *Transport) Unused() []*Interaction {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var unused []*Interaction
+	for i, interaction := range t.cassette.Interactions {
+		if i < len(t.used) && !t.used[i] {
+			unused = append(unused, interaction)
+		}
+	}
+	return unused
+}
+
+func (r Response) toHTTP(req *http.Request) *http.Response {
+	header := make(http.Header)
+	for name, value := range r.Headers {
+		header.Set(name, value)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.Status, http.StatusText(r.Status)),
+		StatusCode:    r.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader([]byte(r.Body))),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+// scrubURL drops credential parameters and sorts the rest so URLs match
+// regardless of parameter order.
+func scrubURL(u *url.URL) string {
+	query := u.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		if !secretParams[strings.ToLower(key)] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var params []string
+	for _, key := range keys {
+		for _, value := range query[key] {
+			params = append(params, url.QueryEscape(key)+"="+url.QueryEscape(value))
+		}
+	}
+
+	scrubbed := u.Scheme + "://" + u.Host + u.Path
+	if len(params) > 0 {
+		scrubbed += "?" + strings.Join(params, "&")
+	}
+	return scrubbed
+}
@@ -0,0 +1,69 @@
+package cassette
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", "5")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error":"slow down"}`))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "cassettes", "record.json")
+	recorder := Record(path, nil)
+	client := &http.Client{Transport: recorder}
+
+	resp, err := client.Get(server.URL + "/search?q=heat&key=secret&cx=engine")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if err := recorder.Save(); err != nil {
+		t.Fatalf("failed to save cassette: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read cassette: %v", err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Errorf("cassette contains the API key:\n%s", data)
+	}
+
+	player, err := Load(path)
+	if err != nil {
+		t.Fatalf("failed to load cassette: %v", err)
+	}
+	client = &http.Client{Transport: player}
+
+	resp, err = client.Get(server.URL + "/search?cx=engine&key=other&q=heat")
+	if err != nil {
+		t.Fatalf("unexpected replay error: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") != "5" {
+		t.Errorf("unexpected replayed response %d %v", resp.StatusCode, resp.Header)
+	}
+	if string(body) != `{"error":"slow down"}` {
+		t.Errorf("unexpected replayed body %q", body)
+	}
+	if len(player.Unused()) != 0 {
+		t.Error("expected the interaction to be used")
+	}
+
+	if _, err := client.Get(server.URL + "/search?cx=engine&q=heat"); err == nil {
+		t.Error("expected error once every interaction was used")
+	}
+}
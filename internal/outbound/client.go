@@ -0,0 +1,254 @@
+// Package outbound is the shared HTTP layer for calls to external APIs. Every
+// provider gets retries with exponential backoff and jitter, a token-bucket
+// rate limit and a circuit breaker, and failures are reported as *Error with
+// a Kind that callers can act on.
+package outbound
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Policy configures retries, rate limiting and circuit breaking for one
+// provider.
+type Policy struct {
+	Timeout    time.Duration
+	MaxRetries int
+	BaseDelay  time.Duration
+	// MaxDelay caps the backoff. A Retry-After longer than MaxDelay is not
+	// waited for; the call fails with the requested delay in Error.RetryAfter.
+	MaxDelay time.Duration
+	// RequestsPerSecond of zero disables rate limiting.
+	RequestsPerSecond float64
+	Burst             int
+	// FailureThreshold consecutive failed calls open the circuit for
+	// Cooldown. Zero disables the breaker.
+	FailureThreshold int
+	Cooldown         time.Duration
+}
+
+func DefaultPolicy() Policy {
+	return Policy{
+		Timeout:          30 * time.Second,
+		MaxRetries:       3,
+		BaseDelay:        500 * time.Millisecond,
+		MaxDelay:         10 * time.Second,
+		Burst:            1,
+		FailureThreshold: 5,
+		Cooldown:         30 * time.Second,
+	}
+}
+
+// Client sends requests to one provider.
+type Client struct {
+	name    string
+	policy  Policy
+	http    *http.Client
+	limiter *tokenBucket
+	breaker *breaker
+	sleep   func(ctx context.Context, d time.Duration) error
+}
+
+// New creates a client for the named provider and registers it for Providers.
+func New(name string, policy Policy) *Client {
+	c := &Client{
+		name:    name,
+		policy:  policy,
+		http:    &http.Client{Timeout: policy.Timeout},
+		limiter: newTokenBucket(policy.RequestsPerSecond, policy.Burst),
+		breaker: newBreaker(policy.FailureThreshold, policy.Cooldown),
+		sleep:   sleepContext,
+	}
+	register(c)
+	return c
+}
+
+func (c *Client) Name() string {
+	return c.name
+}
+
+// UseTransport sends requests through rt instead of the default transport,
+// e.g. a cassette in tests.
+func (c *Client) UseTransport(rt http.RoundTripper) {
+	c.http.Transport = rt
+}
+
+// Healthy reports whether the provider's circuit is not open.
+func (c *Client) Healthy() bool {
+	return c.breaker.State() != StateOpen
+}
+
+// Do sends req, retrying 429, 5xx and network errors. It returns the response
+// only for status codes below 400; every other outcome is an *Error and the
+// response body has already been consumed.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	if req.Body != nil && req.GetBody == nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, &Error{Provider: c.name, Kind: KindPermanent, Err: err}
+		}
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+		req.Body, _ = req.GetBody()
+	}
+
+	// A half-open probe keeps its slot across its retries.
+	probe := false
+	for attempt := 0; ; attempt++ {
+		if !probe {
+			allowed, isProbe := c.breaker.Allow()
+			if !allowed {
+				return nil, &Error{Provider: c.name, Kind: KindTransient, Err: ErrCircuitOpen}
+			}
+			if isProbe {
+				probe = true
+				defer c.breaker.Release()
+			}
+		}
+		if err := c.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		attemptReq := req
+		if attempt > 0 {
+			attemptReq = req.Clone(ctx)
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, &Error{Provider: c.name, Kind: KindPermanent, Err: err}
+				}
+				attemptReq.Body = body
+			}
+		}
+
+		resp, err := c.http.Do(attemptReq)
+		if err == nil && resp.StatusCode < 400 {
+			c.breaker.Success()
+			return resp, nil
+		}
+		if err != nil && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		callErr := c.failure(resp, err)
+		retryable := callErr.Kind == KindTransient || callErr.Kind == KindQuota
+		if !retryable {
+			return nil, callErr
+		}
+
+		delay := c.backoff(attempt, callErr.RetryAfter)
+		if attempt >= c.policy.MaxRetries || (callErr.RetryAfter > 0 && delay > c.policy.MaxDelay) {
+			if c.breaker.Failure() {
+				log.Printf("%s marked unhealthy after %d consecutive failures: %v", c.name, c.policy.FailureThreshold, callErr)
+			}
+			return nil, callErr
+		}
+
+		if err := c.sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// failure converts a transport error or error response into an *Error.
+func (c *Client) failure(resp *http.Response, err error) *Error {
+	if err != nil {
+		return &Error{Provider: c.name, Kind: KindTransient, Err: err}
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	return &Error{
+		Provider:   c.name,
+		Kind:       classify(resp.StatusCode, body),
+		StatusCode: resp.StatusCode,
+		Message:    errorMessage(body),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+// backoff returns the delay before the next attempt: exponential with jitter,
+// or the provider's Retry-After when that is longer.
+func (c *Client) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	delay := c.policy.BaseDelay << uint(attempt)
+	if delay <= 0 || (c.policy.MaxDelay > 0 && delay > c.policy.MaxDelay) {
+		delay = c.policy.MaxDelay
+	}
+	if delay > 0 {
+		delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+	}
+	if retryAfter > delay {
+		delay = retryAfter
+	}
+	return delay
+}
+
+// parseRetryAfter accepts both forms of the header: delay in seconds or an
+// HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// ProviderStatus is a snapshot of one provider's health.
+type ProviderStatus struct {
+	Name    string `json:"name"`
+	State   State  `json:"state"`
+	Healthy bool   `json:"healthy"`
+}
+
+var registry = struct {
+	sync.Mutex
+	clients map[string]*Client
+}{clients: make(map[string]*Client)}
+
+func register(c *Client) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.clients[c.name] = c
+}
+
+// Providers returns the health of every client created with New, by name.
+func Providers() []ProviderStatus {
+	registry.Lock()
+	defer registry.Unlock()
+
+	statuses := make([]ProviderStatus, 0, len(registry.clients))
+	for _, c := range registry.clients {
+		state := c.breaker.State()
+		statuses = append(statuses, ProviderStatus{Name: c.name, State: state, Healthy: state != StateOpen})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
@@ -0,0 +1,304 @@
+package outbound
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestClient(name string, policy Policy) (*Client, *[]time.Duration) {
+	var delays []time.Duration
+	client := New(name, policy)
+	client.sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+	return client, &delays
+}
+
+func TestClientRetriesTransientErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "payload" {
+			t.Errorf("expected body to be resent, got %q", body)
+		}
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client, delays := newTestClient("test_retry", DefaultPolicy())
+	req, _ := http.NewRequest("POST", server.URL, strings.NewReader("payload"))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	if calls != 3 {
+		t.Errorf("expected 3 attempts, got %d", calls)
+	}
+	if len(*delays) != 2 || (*delays)[1] < (*delays)[0]/2 {
+		t.Errorf("expected 2 growing backoff delays, got %v", *delays)
+	}
+}
+
+func TestClientHonorsRetryAfter(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "3")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client, delays := newTestClient("test_retry_after", DefaultPolicy())
+	req, _ := http.NewRequest("GET", server.URL, nil)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	if len(*delays) != 1 || (*delays)[0] != 3*time.Second {
+		t.Errorf("expected to wait 3s as requested, got %v", *delays)
+	}
+}
+
+func TestClientGivesUpOnLongRetryAfter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error": {"message": "Rate limit reached"}}`))
+	}))
+	defer server.Close()
+
+	client, delays := newTestClient("test_quota", DefaultPolicy())
+	req, _ := http.NewRequest("GET", server.URL, nil)
+
+	_, err := client.Do(req)
+	if !IsQuota(err) {
+		t.Fatalf("expected quota error, got %v", err)
+	}
+	var outErr *Error
+	if !errors.As(err, &outErr) || outErr.RetryAfter != time.Hour || outErr.Message != "Rate limit reached" {
+		t.Errorf("unexpected error details: %+v", outErr)
+	}
+	if len(*delays) != 0 {
+		t.Errorf("expected no waiting, got %v", *delays)
+	}
+}
+
+func TestClientClassifiesErrors(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		kind   Kind
+	}{
+		{http.StatusUnauthorized, `{"status_message": "Invalid API key"}`, KindAuth},
+		{http.StatusForbidden, `{"error": {"message": "Daily Limit Exceeded", "status": "RESOURCE_EXHAUSTED", "reason": "dailyLimitExceeded quota"}}`, KindQuota},
+		{http.StatusNotFound, `not found`, KindPermanent},
+		{http.StatusServiceUnavailable, ``, KindTransient},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.kind), func(t *testing.T) {
+			var calls int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&calls, 1)
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			policy := DefaultPolicy()
+			policy.MaxRetries = 1
+			client, _ := newTestClient("test_classify", policy)
+			req, _ := http.NewRequest("GET", server.URL, nil)
+
+			_, err := client.Do(req)
+			if KindOf(err) != tt.kind {
+				t.Errorf("expected %s error, got %v", tt.kind, err)
+			}
+
+			expectedCalls := int32(1)
+			if tt.kind == KindTransient || tt.kind == KindQuota {
+				expectedCalls = 2
+			}
+			if calls != expectedCalls {
+				t.Errorf("expected %d attempts, got %d", expectedCalls, calls)
+			}
+		})
+	}
+}
+
+func TestClientCircuitBreaker(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	policy := DefaultPolicy()
+	policy.MaxRetries = 0
+	policy.FailureThreshold = 2
+	policy.Cooldown = time.Minute
+	client, _ := newTestClient("test_breaker", policy)
+
+	now := time.Now()
+	client.breaker.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		client.Do(req)
+	}
+
+	if calls != 2 {
+		t.Errorf("expected the open circuit to block the third call, got %d calls", calls)
+	}
+	if client.Healthy() {
+		t.Error("expected provider to be unhealthy")
+	}
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	if _, err := client.Do(req); !errors.Is(err, ErrCircuitOpen) || !IsTransient(err) {
+		t.Errorf("expected transient circuit open error, got %v", err)
+	}
+
+	found := false
+	for _, status := range Providers() {
+		if status.Name == "test_breaker" {
+			found = true
+			if status.State != StateOpen {
+				t.Errorf("expected open state in provider status, got %s", status.State)
+			}
+		}
+	}
+	if !found {
+		t.Error("expected client to be listed in Providers")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if !client.Healthy() {
+		t.Error("expected circuit to half-open after the cooldown")
+	}
+}
+
+func TestClientCircuitBreakerSingleProbe(t *testing.T) {
+	var calls int32
+	arrived := make(chan struct{}, 1)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		arrived <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	policy := DefaultPolicy()
+	policy.MaxRetries = 0
+	policy.FailureThreshold = 1
+	policy.Cooldown = time.Minute
+	client, _ := newTestClient("test_breaker_probe", policy)
+
+	now := time.Now()
+	var mu sync.Mutex
+	client.breaker.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	client.Do(req)
+
+	mu.Lock()
+	now = now.Add(2 * time.Minute)
+	mu.Unlock()
+
+	probeErr := make(chan error, 1)
+	go func() {
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		resp, err := client.Do(req)
+		if resp != nil {
+			resp.Body.Close()
+		}
+		probeErr <- err
+	}()
+	<-arrived
+
+	req, _ = http.NewRequest("GET", server.URL, nil)
+	if _, err := client.Do(req); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected calls during the probe to be rejected, got %v", err)
+	}
+
+	close(release)
+	if err := <-probeErr; err != nil {
+		t.Fatalf("unexpected probe error: %v", err)
+	}
+	if client.breaker.State() != StateClosed {
+		t.Errorf("expected a successful probe to close the circuit, got %s", client.breaker.State())
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls to reach the provider, got %d", calls)
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	bucket := newTokenBucket(10, 2)
+	now := time.Now()
+	bucket.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if err := bucket.Wait(ctx); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := bucket.Wait(cancelled); err == nil {
+		t.Error("expected empty bucket to block until the context is done")
+	}
+
+	now = now.Add(100 * time.Millisecond)
+	if err := bucket.Wait(cancelled); err != nil {
+		t.Errorf("expected a refilled token, got %v", err)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	if got := parseRetryAfter("120", now); got != 2*time.Minute {
+		t.Errorf("expected 2m, got %s", got)
+	}
+	if got := parseRetryAfter(now.Add(30*time.Second).Format(http.TimeFormat), now); got != 30*time.Second {
+		t.Errorf("expected 30s, got %s", got)
+	}
+	if got := parseRetryAfter("soon", now); got != 0 {
+		t.Errorf("expected 0 for invalid header, got %s", got)
+	}
+}
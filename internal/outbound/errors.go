@@ -0,0 +1,131 @@
+package outbound
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Kind classifies why a call to an external provider failed.
+type Kind string
+
+const (
+	// KindQuota means the provider rejected the call because a rate limit or
+	// usage quota was exceeded.
+	KindQuota Kind = "quota"
+	// KindAuth means the credentials were missing, invalid or not allowed to
+	// use the API.
+	KindAuth Kind = "auth"
+	// KindTransient covers network errors, timeouts, 5xx responses and an open
+	// circuit breaker. The same call may succeed later.
+	KindTransient Kind = "transient"
+	// KindPermanent means the request itself was rejected and retrying it
+	// will not help.
+	KindPermanent Kind = "permanent"
+)
+
+// ErrCircuitOpen is wrapped by errors returned while a provider is marked
+// unhealthy.
+var ErrCircuitOpen = errors.New("circuit open")
+
+// Error is returned by Client.Do for every failed call.
+type Error struct {
+	Provider   string
+	Kind       Kind
+	StatusCode int
+	Message    string
+	// RetryAfter is the delay the provider asked for, if any.
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s error", e.Provider, e.Kind)
+	if e.StatusCode != 0 {
+		fmt.Fprintf(&b, " (status %d)", e.StatusCode)
+	}
+	if e.Message != "" {
+		fmt.Fprintf(&b, ": %s", e.Message)
+	} else if e.Err != nil {
+		fmt.Fprintf(&b, ": %v", e.Err)
+	}
+	return b.String()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// KindOf returns the kind of an outbound error, or "" for other errors.
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return ""
+}
+
+func IsQuota(err error) bool     { return KindOf(err) == KindQuota }
+func IsAuth(err error) bool      { return KindOf(err) == KindAuth }
+func IsTransient(err error) bool { return KindOf(err) == KindTransient }
+func IsPermanent(err error) bool { return KindOf(err) == KindPermanent }
+
+// classify maps an HTTP status and response body onto an error kind.
+func classify(status int, body []byte) Kind {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return KindQuota
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		// Google reports exhausted quotas as 403 with a reason in the body.
+		lower := strings.ToLower(string(body))
+		if strings.Contains(lower, "quota") || strings.Contains(lower, "ratelimit") {
+			return KindQuota
+		}
+		return KindAuth
+	case status == http.StatusRequestTimeout || status >= 500:
+		return KindTransient
+	default:
+		return KindPermanent
+	}
+}
+
+// errorMessage extracts a human readable message from the error bodies used by
+// OpenAI, Google and TMDb, falling back to the start of the raw body.
+func errorMessage(body []byte) string {
+	var parsed struct {
+		Error json.RawMessage `json:"error"`
+		// TMDb
+		StatusMessage string `json:"status_message"`
+		Message       string `json:"message"`
+	}
+	if json.Unmarshal(body, &parsed) == nil {
+		if len(parsed.Error) > 0 {
+			var nested struct {
+				Message string `json:"message"`
+			}
+			if json.Unmarshal(parsed.Error, &nested) == nil && nested.Message != "" {
+				return nested.Message
+			}
+			var text string
+			if json.Unmarshal(parsed.Error, &text) == nil && text != "" {
+				return text
+			}
+		}
+		if parsed.StatusMessage != "" {
+			return parsed.StatusMessage
+		}
+		if parsed.Message != "" {
+			return parsed.Message
+		}
+	}
+
+	message := strings.TrimSpace(string(body))
+	if len(message) > 200 {
+		message = message[:200] + "…"
+	}
+	return message
+}
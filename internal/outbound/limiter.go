@@ -0,0 +1,66 @@
+package outbound
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// tokenBucket allows rate requests per second on average with bursts of up
+// to burst requests.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		now:    time.Now,
+	}
+}
+
+// Wait blocks until a token is available or ctx is done. A bucket with a
+// non-positive rate never blocks.
+func (b *tokenBucket) Wait(ctx context.Context) error {
+	if b == nil || b.rate <= 0 {
+		return nil
+	}
+
+	for {
+		b.mu.Lock()
+		now := b.now()
+		if !b.last.IsZero() {
+			b.tokens += now.Sub(b.last).Seconds() * b.rate
+			if b.tokens > b.burst {
+				b.tokens = b.burst
+			}
+		}
+		b.last = now
+
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
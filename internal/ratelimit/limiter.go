@@ -0,0 +1,118 @@
+// Package ratelimit limits how often each client may call the server. Every
+// client gets its own token bucket per Limiter, so one route group can be
+// limited more tightly than the rest.
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often buckets of idle clients are dropped.
+const sweepInterval = time.Minute
+
+// Policy allows RequestsPerSecond on average with bursts of up to Burst
+// requests. The zero Policy allows everything.
+type Policy struct {
+	RequestsPerSecond float64
+	Burst             int
+}
+
+// ParsePolicy reads a policy such as "10/m": ten requests a minute, all of
+// which may be made at once. The unit is s, m or h; "0" or "off" disables
+// limiting.
+func ParsePolicy(s string) (Policy, error) {
+	s = strings.TrimSpace(s)
+	if s == "0" || s == "off" {
+		return Policy{}, nil
+	}
+	count, unit, ok := strings.Cut(s, "/")
+	n, err := strconv.Atoi(count)
+	if !ok || err != nil || n <= 0 {
+		return Policy{}, fmt.Errorf("invalid rate limit %q: want e.g. 10/m", s)
+	}
+	periods := map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour}
+	period, ok := periods[unit]
+	if !ok {
+		return Policy{}, fmt.Errorf("invalid rate limit %q: unit must be s, m or h", s)
+	}
+	return Policy{RequestsPerSecond: float64(n) / period.Seconds(), Burst: n}, nil
+}
+
+func (p Policy) Enabled() bool {
+	return p.RequestsPerSecond > 0
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter keeps a token bucket per client key.
+type Limiter struct {
+	mu        sync.Mutex
+	policy    Policy
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewLimiter(policy Policy) *Limiter {
+	if policy.Burst < 1 {
+		policy.Burst = 1
+	}
+	return &Limiter{policy: policy, buckets: make(map[string]*bucket), now: time.Now}
+}
+
+// Allow takes a token from key's bucket. When the bucket is empty it returns
+// false and how long until a token is available. A nil or disabled Limiter
+// allows everything.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	if l == nil || !l.policy.Enabled() {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.policy.Burst), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = l.refill(b, now)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration(math.Ceil((1 - b.tokens) / l.policy.RequestsPerSecond * float64(time.Second)))
+	return false, wait
+}
+
+func (l *Limiter) refill(b *bucket, now time.Time) float64 {
+	tokens := b.tokens + now.Sub(b.last).Seconds()*l.policy.RequestsPerSecond
+	return math.Min(tokens, float64(l.policy.Burst))
+}
+
+// sweep drops buckets that have refilled completely: a new bucket for the
+// same client would be identical.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if l.refill(b, now) >= float64(l.policy.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	l := NewLimiter(Policy{RequestsPerSecond: 1, Burst: 2})
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.Allow("ip:10.0.0.1"); !ok {
+			t.Fatalf("request %d: expected the burst to be allowed", i+1)
+		}
+	}
+	ok, wait := l.Allow("ip:10.0.0.1")
+	if ok || wait != time.Second {
+		t.Errorf("expected to wait 1s, got %v %v", ok, wait)
+	}
+	if ok, _ := l.Allow("ip:10.0.0.2"); !ok {
+		t.Error("expected other clients to have their own bucket")
+	}
+
+	now = now.Add(1500 * time.Millisecond)
+	if ok, _ := l.Allow("ip:10.0.0.1"); !ok {
+		t.Error("expected a token after refilling")
+	}
+
+	now = now.Add(time.Hour)
+	l.Allow("ip:10.0.0.3")
+	if len(l.buckets) != 1 {
+		t.Errorf("expected idle buckets to be swept, got %d", len(l.buckets))
+	}
+}
+
+func TestLimiter_Disabled(t *testing.T) {
+	var nilLimiter *Limiter
+	for _, l := range []*Limiter{nilLimiter, NewLimiter(Policy{})} {
+		for i := 0; i < 100; i++ {
+			if ok, _ := l.Allow("user:1"); !ok {
+				t.Fatal("expected a disabled limiter to allow everything")
+			}
+		}
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	tests := map[string]Policy{
+		"10/s": {RequestsPerSecond: 10, Burst: 10},
+		"60/m": {RequestsPerSecond: 1, Burst: 60},
+		"off":  {},
+		"0":    {},
+	}
+	for s, want := range tests {
+		got, err := ParsePolicy(s)
+		if err != nil || got != want {
+			t.Errorf("ParsePolicy(%q) = %+v, %v, want %+v", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"", "10", "10/d", "-1/m", "x/m"} {
+		if _, err := ParsePolicy(s); err == nil {
+			t.Errorf("ParsePolicy(%q): expected an error", s)
+		}
+	}
+}
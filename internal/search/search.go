@@ -23,7 +23,7 @@ func (s *SearchService) Search(query string) ([]models.Video, error) {
 	sql := `
 		SELECT id, title, description, filename, content_type, size, upload_time
 		FROM videos
-		WHERE search_vector @@ plainto_tsquery('english', $1)
+		WHERE search_vector @@ plainto_tsquery('english', $1) AND deleted_at IS NULL
 		ORDER BY ts_rank(search_vector, plainto_tsquery('english', $1)) DESC
 		LIMIT 20
 	`
@@ -0,0 +1,257 @@
+// Package sharing creates and verifies share links: HMAC-signed URLs that
+// open one video, and optionally its identification, for someone without an
+// account. The signature covers the link, its video and its expiry, so
+// forged or altered URLs are refused before the database is consulted; the
+// stored link carries revocation and the view count. Opening the video page
+// counts a view and issues a short-lived view token, signed the same way,
+// that the stream and results behind that page require.
+package sharing
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/kdimtricp/vshazam/internal/models/share"
+)
+
+// Query parameters of a share URL.
+const (
+	ParamLink      = "share"
+	ParamExpires   = "exp"
+	ParamSignature = "sig"
+	// ParamView and ParamViewSignature carry the view token that opens the
+	// stream and results of one counted view.
+	ParamView          = "view"
+	ParamViewSignature = "vsig"
+)
+
+// ViewTTL is how long the stream and results opened from one counted view
+// keep working.
+const ViewTTL = 4 * time.Hour
+
+const (
+	DefaultTTL = 7 * 24 * time.Hour
+	MaxTTL     = 90 * 24 * time.Hour
+)
+
+var (
+	ErrInvalidLink  = errors.New("invalid share link")
+	ErrLinkExpired  = errors.New("share link has expired")
+	ErrLinkRevoked  = errors.New("share link was revoked")
+	ErrViewsUsedUp  = errors.New("share link has no views left")
+	ErrViewExpired  = errors.New("share link view has expired")
+	ErrInvalidShare = errors.New("invalid share options")
+)
+
+// Store is implemented by database.ShareRepo.
+type Store interface {
+	CreateLink(ctx context.Context, link *share.LinkDB) error
+	GetLink(ctx context.Context, id string) (*share.LinkDB, error)
+	ListLinks(ctx context.Context, videoID string) ([]*share.LinkDB, error)
+	RevokeLink(ctx context.Context, id string, at time.Time) (bool, error)
+	CountView(ctx context.Context, id string) (bool, error)
+}
+
+// Options configure a new link. A zero TTL means DefaultTTL and a zero
+// MaxViews unlimited views.
+type Options struct {
+	Access   string
+	TTL      time.Duration
+	MaxViews int
+}
+
+type Service struct {
+	store  Store
+	secret []byte
+	now    func() time.Time
+}
+
+// NewService signs links with secret. Without a secret a random one is used,
+// and links stop working when the server restarts.
+func NewService(store Store, secret string) *Service {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			panic("sharing: no randomness for the signing key: " + err.Error())
+		}
+		log.Println("SHARE_SECRET not set: share links will stop working when the server restarts")
+	}
+	return &Service{store: store, secret: key, now: time.Now}
+}
+
+// Create stores a link to the video with videoID on behalf of createdBy.
+func (s *Service) Create(ctx context.Context, videoID, createdBy string, opts Options) (*share.LinkDB, error) {
+	if opts.Access != share.AccessVideo && opts.Access != share.AccessResults {
+		return nil, ErrInvalidShare
+	}
+	if opts.TTL == 0 {
+		opts.TTL = DefaultTTL
+	}
+	if opts.TTL < 0 || opts.TTL > MaxTTL || opts.MaxViews < 0 {
+		return nil, ErrInvalidShare
+	}
+
+	now := s.now()
+	link := &share.LinkDB{
+		VideoID:   videoID,
+		CreatedBy: createdBy,
+		Access:    opts.Access,
+		// Whole seconds, as carried in the URL.
+		ExpiresAt: now.Add(opts.TTL).Truncate(time.Second),
+		MaxViews:  opts.MaxViews,
+		CreatedAt: now,
+	}
+	if err := s.store.CreateLink(ctx, link); err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// Query returns the signed query parameters that open link.
+func (s *Service) Query(link *share.LinkDB) url.Values {
+	expires := link.ExpiresAt.Unix()
+	q := url.Values{}
+	q.Set(ParamLink, link.ID)
+	q.Set(ParamExpires, strconv.FormatInt(expires, 10))
+	q.Set(ParamSignature, s.sign(link.ID, link.VideoID, expires))
+	return q
+}
+
+// ViewQuery returns the query of link with a view token added. The token
+// opens the stream and results behind one counted view for ViewTTL, or until
+// the link expires if that is sooner.
+func (s *Service) ViewQuery(link *share.LinkDB) url.Values {
+	until := s.now().Add(ViewTTL).Unix()
+	if expires := link.ExpiresAt.Unix(); expires < until {
+		until = expires
+	}
+	q := s.Query(link)
+	q.Set(ParamView, strconv.FormatInt(until, 10))
+	q.Set(ParamViewSignature, s.sign(viewPrefix+link.ID, link.VideoID, until))
+	return q
+}
+
+// HasLink reports whether q carries share link parameters.
+func HasLink(q url.Values) bool {
+	return q.Get(ParamLink) != ""
+}
+
+// Verify checks the share parameters in q for the video with videoID and
+// returns the link. It does not count a view.
+func (s *Service) Verify(ctx context.Context, q url.Values, videoID string) (*share.LinkDB, error) {
+	id := q.Get(ParamLink)
+	expires, err := strconv.ParseInt(q.Get(ParamExpires), 10, 64)
+	if id == "" || err != nil {
+		return nil, ErrInvalidLink
+	}
+	signature := s.sign(id, videoID, expires)
+	if !hmac.Equal([]byte(signature), []byte(q.Get(ParamSignature))) {
+		return nil, ErrInvalidLink
+	}
+	now := s.now()
+	if !now.Before(time.Unix(expires, 0)) {
+		return nil, ErrLinkExpired
+	}
+
+	link, err := s.store.GetLink(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if link == nil || link.VideoID != videoID || link.ExpiresAt.Unix() != expires {
+		return nil, ErrInvalidLink
+	}
+	if link.RevokedAt != nil {
+		return nil, ErrLinkRevoked
+	}
+	if !link.Active(now) {
+		return nil, ErrLinkExpired
+	}
+	return link, nil
+}
+
+// VerifyView checks q like Verify and also requires a view token from
+// ViewQuery. The stream and results of a shared video open only this way, so
+// requesting them directly does not get around the view limit.
+func (s *Service) VerifyView(ctx context.Context, q url.Values, videoID string) (*share.LinkDB, error) {
+	until, err := strconv.ParseInt(q.Get(ParamView), 10, 64)
+	if err != nil {
+		return nil, ErrInvalidLink
+	}
+	signature := s.sign(viewPrefix+q.Get(ParamLink), videoID, until)
+	if !hmac.Equal([]byte(signature), []byte(q.Get(ParamViewSignature))) {
+		return nil, ErrInvalidLink
+	}
+
+	link, err := s.Verify(ctx, q, videoID)
+	if err != nil {
+		return nil, err
+	}
+	if !s.now().Before(time.Unix(until, 0)) {
+		return nil, ErrViewExpired
+	}
+	return link, nil
+}
+
+// CountView records that link was opened, or returns ErrViewsUsedUp.
+func (s *Service) CountView(ctx context.Context, link *share.LinkDB) error {
+	counted, err := s.store.CountView(ctx, link.ID)
+	if err != nil {
+		return err
+	}
+	if !counted {
+		return ErrViewsUsedUp
+	}
+	link.Views++
+	return nil
+}
+
+func (s *Service) Get(ctx context.Context, id string) (*share.LinkDB, error) {
+	return s.store.GetLink(ctx, id)
+}
+
+// List returns the links to a video, newest first.
+func (s *Service) List(ctx context.Context, videoID string) ([]*share.LinkDB, error) {
+	return s.store.ListLinks(ctx, videoID)
+}
+
+// Revoke stops link from opening. It reports false when the link was
+// revoked before.
+func (s *Service) Revoke(ctx context.Context, id string) (bool, error) {
+	return s.store.RevokeLink(ctx, id, s.now())
+}
+
+// Active reports whether link can still be opened now.
+func (s *Service) Active(link *share.LinkDB) bool {
+	return link.Active(s.now()) && link.ViewsLeft()
+}
+
+// viewPrefix sets view token signatures apart from link signatures.
+const viewPrefix = "view:"
+
+func (s *Service) sign(id, videoID string, expires int64) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(id + "\n" + videoID + "\n" + strconv.FormatInt(expires, 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+type contextKey struct{}
+
+// WithLink returns a context for a request opened through link.
+func WithLink(ctx context.Context, link *share.LinkDB) context.Context {
+	return context.WithValue(ctx, contextKey{}, link)
+}
+
+// LinkFrom returns the share link a request was opened through, or nil.
+func LinkFrom(ctx context.Context) *share.LinkDB {
+	link, _ := ctx.Value(contextKey{}).(*share.LinkDB)
+	return link
+}
@@ -0,0 +1,189 @@
+package sharing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kdimtricp/vshazam/internal/models/share"
+)
+
+type memoryStore struct {
+	links map[string]*share.LinkDB
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{links: make(map[string]*share.LinkDB)}
+}
+
+func (m *memoryStore) CreateLink(ctx context.Context, link *share.LinkDB) error {
+	link.ID = "link-" + string(rune('a'+len(m.links)))
+	m.links[link.ID] = link
+	return nil
+}
+
+func (m *memoryStore) GetLink(ctx context.Context, id string) (*share.LinkDB, error) {
+	return m.links[id], nil
+}
+
+func (m *memoryStore) ListLinks(ctx context.Context, videoID string) ([]*share.LinkDB, error) {
+	var links []*share.LinkDB
+	for _, link := range m.links {
+		if link.VideoID == videoID {
+			links = append(links, link)
+		}
+	}
+	return links, nil
+}
+
+func (m *memoryStore) RevokeLink(ctx context.Context, id string, at time.Time) (bool, error) {
+	link := m.links[id]
+	if link == nil || link.RevokedAt != nil {
+		return false, nil
+	}
+	link.RevokedAt = &at
+	return true, nil
+}
+
+func (m *memoryStore) CountView(ctx context.Context, id string) (bool, error) {
+	link := m.links[id]
+	if link == nil || link.RevokedAt != nil || !link.ViewsLeft() {
+		return false, nil
+	}
+	link.Views++
+	return true, nil
+}
+
+func newTestService() (*Service, *time.Time) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	service := NewService(newMemoryStore(), "secret")
+	service.now = func() time.Time { return now }
+	return service, &now
+}
+
+func TestService_Verify(t *testing.T) {
+	service, now := newTestService()
+	ctx := context.Background()
+
+	link, err := service.Create(ctx, "video-1", "user-1", Options{Access: share.AccessVideo, TTL: time.Hour})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	q := service.Query(link)
+
+	got, err := service.Verify(ctx, q, "video-1")
+	if err != nil || got.ID != link.ID {
+		t.Fatalf("expected the link to verify, got %+v, %v", got, err)
+	}
+
+	if _, err := service.Verify(ctx, q, "video-2"); !errors.Is(err, ErrInvalidLink) {
+		t.Errorf("expected the link not to open another video, got %v", err)
+	}
+
+	tampered := service.Query(link)
+	tampered.Set(ParamExpires, "4102444800")
+	if _, err := service.Verify(ctx, tampered, "video-1"); !errors.Is(err, ErrInvalidLink) {
+		t.Errorf("expected an extended expiry to be refused, got %v", err)
+	}
+
+	other := NewService(newMemoryStore(), "other secret")
+	if _, err := other.Verify(ctx, q, "video-1"); !errors.Is(err, ErrInvalidLink) {
+		t.Errorf("expected a signature made with another secret to be refused, got %v", err)
+	}
+
+	*now = now.Add(2 * time.Hour)
+	if _, err := service.Verify(ctx, q, "video-1"); !errors.Is(err, ErrLinkExpired) {
+		t.Errorf("expected an expired link to be refused, got %v", err)
+	}
+}
+
+func TestService_ViewsAndRevocation(t *testing.T) {
+	service, _ := newTestService()
+	ctx := context.Background()
+
+	link, err := service.Create(ctx, "video-1", "user-1", Options{Access: share.AccessResults, MaxViews: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !link.ShowsResults() || link.ExpiresAt.Sub(service.now()) != DefaultTTL {
+		t.Errorf("unexpected link %+v", link)
+	}
+
+	if err := service.CountView(ctx, link); err != nil {
+		t.Errorf("expected the first view to count, got %v", err)
+	}
+	if err := service.CountView(ctx, link); !errors.Is(err, ErrViewsUsedUp) {
+		t.Errorf("expected the second view to be refused, got %v", err)
+	}
+	if service.Active(link) {
+		t.Error("expected a link without views left to be inactive")
+	}
+	if _, err := service.VerifyView(ctx, service.ViewQuery(link), "video-1"); err != nil {
+		t.Errorf("expected the stream of the counted view to keep working, got %v", err)
+	}
+	if _, err := service.VerifyView(ctx, service.Query(link), "video-1"); !errors.Is(err, ErrInvalidLink) {
+		t.Errorf("expected the stream to need a view token, got %v", err)
+	}
+
+	if revoked, _ := service.Revoke(ctx, link.ID); !revoked {
+		t.Error("expected the link to be revoked")
+	}
+	if _, err := service.Verify(ctx, service.Query(link), "video-1"); !errors.Is(err, ErrLinkRevoked) {
+		t.Errorf("expected a revoked link to be refused, got %v", err)
+	}
+}
+
+func TestService_VerifyView(t *testing.T) {
+	service, now := newTestService()
+	ctx := context.Background()
+
+	link, err := service.Create(ctx, "video-1", "user-1", Options{Access: share.AccessVideo, TTL: 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	q := service.ViewQuery(link)
+
+	if got, err := service.VerifyView(ctx, q, "video-1"); err != nil || got.ID != link.ID {
+		t.Fatalf("expected the view token to verify, got %+v, %v", got, err)
+	}
+	if _, err := service.VerifyView(ctx, q, "video-2"); !errors.Is(err, ErrInvalidLink) {
+		t.Errorf("expected the view token not to open another video, got %v", err)
+	}
+
+	tampered := service.ViewQuery(link)
+	tampered.Set(ParamView, "4102444800")
+	if _, err := service.VerifyView(ctx, tampered, "video-1"); !errors.Is(err, ErrInvalidLink) {
+		t.Errorf("expected an extended view token to be refused, got %v", err)
+	}
+	forged := service.Query(link)
+	forged.Set(ParamView, q.Get(ParamView))
+	forged.Set(ParamViewSignature, q.Get(ParamSignature))
+	if _, err := service.VerifyView(ctx, forged, "video-1"); !errors.Is(err, ErrInvalidLink) {
+		t.Errorf("expected the link signature not to pass as a view token, got %v", err)
+	}
+
+	*now = now.Add(ViewTTL)
+	if _, err := service.VerifyView(ctx, q, "video-1"); !errors.Is(err, ErrViewExpired) {
+		t.Errorf("expected an old view token to be refused, got %v", err)
+	}
+
+	*now = link.ExpiresAt.Add(-time.Hour)
+	late := service.ViewQuery(link)
+	if late.Get(ParamView) != late.Get(ParamExpires) {
+		t.Errorf("expected the view token to end with the link, got %s and %s", late.Get(ParamView), late.Get(ParamExpires))
+	}
+}
+
+func TestService_CreateValidatesOptions(t *testing.T) {
+	service, _ := newTestService()
+	for name, opts := range map[string]Options{
+		"unknown access": {Access: "edit"},
+		"too long":       {Access: share.AccessVideo, TTL: MaxTTL + time.Hour},
+		"negative views": {Access: share.AccessVideo, MaxViews: -1},
+	} {
+		if _, err := service.Create(context.Background(), "video-1", "user-1", opts); !errors.Is(err, ErrInvalidShare) {
+			t.Errorf("%s: got %v, want ErrInvalidShare", name, err)
+		}
+	}
+}
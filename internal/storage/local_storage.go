@@ -73,3 +73,12 @@ func (ls *LocalStorage) DeleteFile(path string) error {
 
 	return nil
 }
+
+func (ls *LocalStorage) LocalPath(path string) (string, error) {
+	cleanPath := filepath.Clean(path)
+	if strings.Contains(cleanPath, "..") {
+		return "", fmt.Errorf("invalid path")
+	}
+
+	return filepath.Join(ls.basePath, cleanPath), nil
+}
@@ -16,6 +16,9 @@ type Storage interface {
 	SaveFile(file multipart.File, info FileInfo) (string, error)
 	OpenFile(path string) (io.ReadSeekCloser, error)
 	DeleteFile(path string) error
+	// LocalPath returns a filesystem path for tools such as ffmpeg that
+	// cannot read from an io.Reader.
+	LocalPath(path string) (string, error)
 }
 
 func FormatFileSize(size int64) string {
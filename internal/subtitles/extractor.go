@@ -0,0 +1,123 @@
+package subtitles
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os/exec"
+	"strconv"
+)
+
+// textCodecs are the subtitle codecs ffmpeg can convert to WebVTT. Image
+// based subtitles such as PGS or DVB need OCR and are skipped.
+var textCodecs = map[string]bool{
+	"subrip":   true,
+	"srt":      true,
+	"ass":      true,
+	"ssa":      true,
+	"webvtt":   true,
+	"mov_text": true,
+	"text":     true,
+}
+
+// Stream is a text subtitle stream inside a video file.
+type Stream struct {
+	Index    int
+	Codec    string
+	Language string
+	Title    string
+}
+
+// Extractor reads subtitle streams with ffprobe and ffmpeg.
+type Extractor struct {
+	ffmpegPath  string
+	ffprobePath string
+}
+
+func NewExtractor() (*Extractor, error) {
+	ffmpegPath, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg not found in PATH: %w", err)
+	}
+	ffprobePath, err := exec.LookPath("ffprobe")
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe not found in PATH: %w", err)
+	}
+
+	return &Extractor{
+		ffmpegPath:  ffmpegPath,
+		ffprobePath: ffprobePath,
+	}, nil
+}
+
+type probeOutput struct {
+	Streams []struct {
+		Index     int    `json:"index"`
+		CodecName string `json:"codec_name"`
+		Tags      struct {
+			Language string `json:"language"`
+			Title    string `json:"title"`
+		} `json:"tags"`
+	} `json:"streams"`
+}
+
+// Streams lists the text subtitle streams of a video.
+func (e *Extractor) Streams(videoPath string) ([]Stream, error) {
+	cmd := exec.Command(e.ffprobePath,
+		"-v", "error",
+		"-select_streams", "s",
+		"-show_entries", "stream=index,codec_name:stream_tags=language,title",
+		"-of", "json",
+		videoPath)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		log.Printf("FFprobe stderr output: %s", stderr.String())
+		return nil, fmt.Errorf("failed to probe subtitle streams: %w", err)
+	}
+
+	var probe probeOutput
+	if err := json.Unmarshal(stdout.Bytes(), &probe); err != nil {
+		return nil, fmt.Errorf("failed to parse ffprobe output: %w", err)
+	}
+
+	var streams []Stream
+	for _, s := range probe.Streams {
+		if !textCodecs[s.CodecName] {
+			log.Printf("Skipping subtitle stream %d with unsupported codec %s", s.Index, s.CodecName)
+			continue
+		}
+		streams = append(streams, Stream{
+			Index:    s.Index,
+			Codec:    s.CodecName,
+			Language: s.Tags.Language,
+			Title:    s.Tags.Title,
+		})
+	}
+	return streams, nil
+}
+
+// Extract converts one subtitle stream to WebVTT and parses it.
+func (e *Extractor) Extract(videoPath string, stream Stream) ([]Cue, error) {
+	cmd := exec.Command(e.ffmpegPath,
+		"-v", "error",
+		"-i", videoPath,
+		"-map", "0:"+strconv.Itoa(stream.Index),
+		"-f", "webvtt",
+		"pipe:1")
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		log.Printf("FFmpeg stderr output: %s", stderr.String())
+		return nil, fmt.Errorf("failed to extract subtitle stream %d: %w", stream.Index, err)
+	}
+
+	return Parse(stdout.Bytes())
+}
@@ -0,0 +1,194 @@
+package subtitles
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/kdimtricp/vshazam/internal/models/subtitle"
+	"github.com/kdimtricp/vshazam/internal/models/transcript"
+	"github.com/kdimtricp/vshazam/internal/storage"
+)
+
+type TrackStore interface {
+	CreateTrack(ctx context.Context, track *subtitle.TrackDB) error
+}
+
+// CueStore indexes the text of every track for search and identification.
+type CueStore interface {
+	ReplaceCues(ctx context.Context, videoID, source string, cues []*transcript.CueDB) error
+}
+
+// StreamExtractor is implemented by Extractor.
+type StreamExtractor interface {
+	Streams(videoPath string) ([]Stream, error)
+	Extract(videoPath string, stream Stream) ([]Cue, error)
+}
+
+// Sidecar is a parsed subtitle file uploaded next to a video.
+type Sidecar struct {
+	Filename string
+	Language string
+	Cues     []Cue
+}
+
+// sidecarLanguage matches a language code before the extension, as in
+// "film.en.srt" or "film.pt-BR.vtt".
+var sidecarLanguage = regexp.MustCompile(`\.([a-zA-Z]{2,3}(?:[-_][a-zA-Z]{2})?)$`)
+
+var videoExtensions = map[string]bool{"mp4": true, "mkv": true, "mov": true, "avi": true}
+
+// ParseSidecar reads an uploaded .srt or .vtt file.
+func ParseSidecar(filename string, data []byte) (*Sidecar, error) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext != ".srt" && ext != ".vtt" {
+		return nil, fmt.Errorf("unsupported subtitle format %q", ext)
+	}
+
+	cues, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+
+	sidecar := &Sidecar{Filename: filename, Cues: cues}
+	base := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	if match := sidecarLanguage.FindStringSubmatch(base); match != nil && !videoExtensions[strings.ToLower(match[1])] {
+		sidecar.Language = strings.ReplaceAll(match[1], "_", "-")
+	}
+	return sidecar, nil
+}
+
+// Service stores subtitle tracks as WebVTT files and indexes their cues.
+type Service struct {
+	storage   storage.Storage
+	tracks    TrackStore
+	cues      CueStore
+	extractor StreamExtractor
+}
+
+func NewService(st storage.Storage, tracks TrackStore, cues CueStore) *Service {
+	return &Service{
+		storage: st,
+		tracks:  tracks,
+		cues:    cues,
+	}
+}
+
+// UseExtractor imports the text subtitle streams embedded in uploaded videos.
+func (s *Service) UseExtractor(extractor StreamExtractor) {
+	s.extractor = extractor
+}
+
+// Import stores the embedded subtitle streams of the video at videoPath and
+// the uploaded sidecars. A stream that cannot be extracted is logged and
+// skipped. The cues of all tracks replace the video's subtitle cues.
+func (s *Service) Import(ctx context.Context, videoID, videoPath string, sidecars []*Sidecar) ([]*subtitle.TrackDB, error) {
+	var tracks []*subtitle.TrackDB
+	var indexed []*transcript.CueDB
+
+	add := func(track *subtitle.TrackDB, cues []Cue) error {
+		if err := s.save(ctx, track, cues); err != nil {
+			return err
+		}
+		tracks = append(tracks, track)
+		for _, cue := range cues {
+			indexed = append(indexed, &transcript.CueDB{
+				Source:   transcript.SourceSubtitle,
+				Language: track.Language,
+				Start:    cue.Start,
+				End:      cue.End,
+				Text:     strings.ReplaceAll(cue.Text, "\n", " "),
+			})
+		}
+		return nil
+	}
+
+	if s.extractor != nil && videoPath != "" {
+		streams, err := s.extractor.Streams(videoPath)
+		if err != nil {
+			log.Printf("Failed to list subtitle streams of video %s: %v", videoID, err)
+		}
+		for _, stream := range streams {
+			cues, err := s.extractor.Extract(videoPath, stream)
+			if err != nil {
+				log.Printf("Failed to extract subtitle stream %d of video %s: %v", stream.Index, videoID, err)
+				continue
+			}
+			track := &subtitle.TrackDB{
+				VideoID:     videoID,
+				Source:      subtitle.SourceEmbedded,
+				Language:    stream.Language,
+				Label:       streamLabel(stream),
+				StreamIndex: stream.Index,
+			}
+			if err := add(track, cues); err != nil {
+				return tracks, err
+			}
+		}
+	}
+
+	for _, sidecar := range sidecars {
+		track := &subtitle.TrackDB{
+			VideoID:     videoID,
+			Source:      subtitle.SourceSidecar,
+			Language:    sidecar.Language,
+			Label:       sidecar.Filename,
+			StreamIndex: -1,
+		}
+		if err := add(track, sidecar.Cues); err != nil {
+			return tracks, err
+		}
+	}
+
+	if len(tracks) == 0 {
+		return nil, nil
+	}
+	if err := s.cues.ReplaceCues(ctx, videoID, transcript.SourceSubtitle, indexed); err != nil {
+		return tracks, fmt.Errorf("failed to index subtitles: %w", err)
+	}
+	log.Printf("Imported %d subtitle tracks with %d cues for video %s", len(tracks), len(indexed), videoID)
+	return tracks, nil
+}
+
+// save writes the track as WebVTT and records it.
+func (s *Service) save(ctx context.Context, track *subtitle.TrackDB, cues []Cue) error {
+	vtt := FormatVTT(cues)
+	path, err := s.storage.SaveFile(vttFile{bytes.NewReader(vtt)}, storage.FileInfo{
+		Filename:    "subtitles.vtt",
+		ContentType: "text/vtt",
+		Size:        int64(len(vtt)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save subtitle track: %w", err)
+	}
+
+	track.FilePath = path
+	track.CueCount = len(cues)
+	if err := s.tracks.CreateTrack(ctx, track); err != nil {
+		s.storage.DeleteFile(path)
+		return err
+	}
+	return nil
+}
+
+func streamLabel(stream Stream) string {
+	switch {
+	case stream.Title != "":
+		return stream.Title
+	case stream.Language != "":
+		return stream.Language
+	default:
+		return fmt.Sprintf("Track %d", stream.Index)
+	}
+}
+
+// vttFile adapts an in-memory WebVTT file to multipart.File.
+type vttFile struct {
+	*bytes.Reader
+}
+
+func (vttFile) Close() error { return nil }
@@ -0,0 +1,118 @@
+package subtitles
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"strings"
+	"testing"
+
+	"github.com/kdimtricp/vshazam/internal/models/subtitle"
+	"github.com/kdimtricp/vshazam/internal/models/transcript"
+	"github.com/kdimtricp/vshazam/internal/storage"
+)
+
+type memoryStorage struct {
+	files map[string][]byte
+}
+
+func (m *memoryStorage) SaveFile(file multipart.File, info storage.FileInfo) (string, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return "", err
+	}
+	path := fmt.Sprintf("%d-%s", len(m.files), info.Filename)
+	m.files[path] = data
+	return path, nil
+}
+
+func (m *memoryStorage) OpenFile(path string) (io.ReadSeekCloser, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *memoryStorage) DeleteFile(path string) error {
+	delete(m.files, path)
+	return nil
+}
+
+func (m *memoryStorage) LocalPath(path string) (string, error) {
+	return path, nil
+}
+
+type memoryStore struct {
+	tracks []*subtitle.TrackDB
+	cues   map[string][]*transcript.CueDB
+}
+
+func (m *memoryStore) CreateTrack(ctx context.Context, track *subtitle.TrackDB) error {
+	m.tracks = append(m.tracks, track)
+	return nil
+}
+
+func (m *memoryStore) ReplaceCues(ctx context.Context, videoID, source string, cues []*transcript.CueDB) error {
+	m.cues[source] = cues
+	return nil
+}
+
+// fakeExtractor has an English subtitle stream and a broken second stream.
+type fakeExtractor struct{}
+
+func (fakeExtractor) Streams(videoPath string) ([]Stream, error) {
+	return []Stream{
+		{Index: 2, Codec: "mov_text", Language: "eng"},
+		{Index: 3, Codec: "subrip", Language: "fre", Title: "Français"},
+	}, nil
+}
+
+func (fakeExtractor) Extract(videoPath string, stream Stream) ([]Cue, error) {
+	if stream.Index == 3 {
+		return nil, fmt.Errorf("corrupt stream")
+	}
+	return []Cue{{Start: 1, End: 2, Text: "An idea is like\na virus."}}, nil
+}
+
+func TestServiceImport(t *testing.T) {
+	files := &memoryStorage{files: make(map[string][]byte)}
+	store := &memoryStore{cues: make(map[string][]*transcript.CueDB)}
+	service := NewService(files, store, store)
+	service.UseExtractor(fakeExtractor{})
+
+	sidecar := &Sidecar{Filename: "clip.de.srt", Language: "de", Cues: []Cue{{Start: 5, End: 6, Text: "Träum größer."}}}
+	tracks, err := service.Import(context.Background(), "video-1", "/videos/clip.mp4", []*Sidecar{sidecar})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(tracks) != 2 || len(store.tracks) != 2 {
+		t.Fatalf("expected the broken stream to be skipped, got %+v", tracks)
+	}
+	embedded, uploaded := tracks[0], tracks[1]
+	if embedded.Source != subtitle.SourceEmbedded || embedded.StreamIndex != 2 || embedded.Label != "eng" || embedded.CueCount != 1 {
+		t.Errorf("unexpected embedded track %+v", embedded)
+	}
+	if uploaded.Source != subtitle.SourceSidecar || uploaded.Language != "de" || uploaded.Label != "clip.de.srt" {
+		t.Errorf("unexpected sidecar track %+v", uploaded)
+	}
+	if vtt := string(files.files[embedded.FilePath]); !strings.HasPrefix(vtt, "WEBVTT\n") || !strings.Contains(vtt, "a virus.") {
+		t.Errorf("expected the track to be stored as WebVTT, got %q", vtt)
+	}
+
+	cues := store.cues[transcript.SourceSubtitle]
+	if len(cues) != 2 || cues[0].Text != "An idea is like a virus." || cues[1].Language != "de" {
+		t.Errorf("expected both tracks to be indexed, got %+v", cues)
+	}
+}
+
+func TestServiceImportWithoutTracks(t *testing.T) {
+	store := &memoryStore{cues: make(map[string][]*transcript.CueDB)}
+	service := NewService(&memoryStorage{files: make(map[string][]byte)}, store, store)
+
+	tracks, err := service.Import(context.Background(), "video-1", "/videos/clip.mp4", nil)
+	if err != nil || tracks != nil {
+		t.Fatalf("expected nothing to import, got %+v, %v", tracks, err)
+	}
+	if _, ok := store.cues[transcript.SourceSubtitle]; ok {
+		t.Error("expected existing cues to be left alone")
+	}
+}
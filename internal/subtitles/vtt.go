@@ -0,0 +1,144 @@
+// Package subtitles reads subtitle tracks embedded in videos or uploaded as
+// SRT and WebVTT files, normalizes them to WebVTT and indexes their text.
+package subtitles
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrNoCues is returned when a file contains no timed text.
+var ErrNoCues = errors.New("no subtitle cues found")
+
+// Cue is one timed subtitle. Start and End are seconds into the video.
+type Cue struct {
+	Start float64
+	End   float64
+	Text  string
+}
+
+var (
+	blankLines = regexp.MustCompile(`\n[ \t]*\n`)
+	// HTML-style tags such as <i> or <c.yellow> and ASS overrides such as
+	// {\an8}.
+	markup = regexp.MustCompile(`<[^>]*>|\{\\[^}]*\}`)
+)
+
+// Parse reads SRT or WebVTT. Both consist of blocks separated by blank lines
+// with a "start --> end" timing line followed by text; SRT uses a comma
+// before the milliseconds and numbers its blocks, WebVTT adds a header and
+// may carry NOTE and STYLE blocks, which have no timing line and are skipped.
+// Formatting tags are removed and cues are returned in start order.
+func Parse(data []byte) ([]Cue, error) {
+	text := strings.TrimPrefix(string(data), "\ufeff")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	var cues []Cue
+	for _, block := range blankLines.Split(text, -1) {
+		lines := strings.Split(strings.TrimSpace(block), "\n")
+		timing := -1
+		for i, line := range lines {
+			if strings.Contains(line, "-->") {
+				timing = i
+				break
+			}
+		}
+		if timing < 0 {
+			continue
+		}
+
+		start, end, err := parseTiming(lines[timing])
+		if err != nil {
+			return nil, err
+		}
+
+		var textLines []string
+		for _, line := range lines[timing+1:] {
+			line = strings.TrimSpace(markup.ReplaceAllString(line, ""))
+			if line != "" {
+				textLines = append(textLines, line)
+			}
+		}
+		if len(textLines) == 0 {
+			continue
+		}
+
+		cues = append(cues, Cue{Start: start, End: end, Text: strings.Join(textLines, "\n")})
+	}
+
+	if len(cues) == 0 {
+		return nil, ErrNoCues
+	}
+	sort.SliceStable(cues, func(i, j int) bool { return cues[i].Start < cues[j].Start })
+	return cues, nil
+}
+
+// parseTiming reads "00:01:02,500 --> 00:01:04,000", ignoring any WebVTT
+// cue settings after the end time.
+func parseTiming(line string) (float64, float64, error) {
+	parts := strings.SplitN(line, "-->", 2)
+	startField := strings.TrimSpace(parts[0])
+	endFields := strings.Fields(parts[1])
+	if startField == "" || len(endFields) == 0 {
+		return 0, 0, fmt.Errorf("invalid timing line %q", line)
+	}
+
+	start, err := parseTimestamp(startField)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := parseTimestamp(endFields[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	if end < start {
+		end = start
+	}
+	return start, end, nil
+}
+
+// parseTimestamp reads HH:MM:SS.mmm or MM:SS.mmm, with a comma or a dot
+// before the milliseconds.
+func parseTimestamp(s string) (float64, error) {
+	parts := strings.Split(strings.Replace(s, ",", ".", 1), ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid timestamp %q", s)
+	}
+
+	seconds, err := strconv.ParseFloat(parts[len(parts)-1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timestamp %q", s)
+	}
+	multiplier := 60.0
+	for i := len(parts) - 2; i >= 0; i-- {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid timestamp %q", s)
+		}
+		seconds += float64(n) * multiplier
+		multiplier *= 60
+	}
+	return seconds, nil
+}
+
+// FormatVTT writes cues as a WebVTT file.
+func FormatVTT(cues []Cue) []byte {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n")
+	for _, cue := range cues {
+		// An arrow in the text would be read as a timing line.
+		text := strings.ReplaceAll(cue.Text, "-->", "->")
+		fmt.Fprintf(&b, "\n%s --> %s\n%s\n", formatTimestamp(cue.Start), formatTimestamp(cue.End), text)
+	}
+	return []byte(b.String())
+}
+
+func formatTimestamp(seconds float64) string {
+	millis := int64(seconds*1000 + 0.5)
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", millis/3600000, millis/60000%60, millis/1000%60, millis%1000)
+}
@@ -0,0 +1,106 @@
+package subtitles
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		cues  []Cue
+	}{
+		{
+			name: "srt with windows line endings",
+			input: "\ufeff1\r\n00:00:01,500 --> 00:00:03,000\r\n<i>I need you</i>\r\nto trust me.\r\n\r\n" +
+				"2\r\n00:01:02,250 --> 00:01:04,000\r\n{\\an8}Wake up.\r\n",
+			cues: []Cue{
+				{Start: 1.5, End: 3, Text: "I need you\nto trust me."},
+				{Start: 62.25, End: 64, Text: "Wake up."},
+			},
+		},
+		{
+			name: "webvtt with notes, settings and short timestamps",
+			input: "WEBVTT - Inception\n\nNOTE generated by ffmpeg\n\n" +
+				"intro\n00:05.000 --> 00:07.000 align:center line:90%\n<c.yellow>Paradox.</c>\n\n" +
+				"01:00:00.000 --> 01:00:02.000\n  \n\n" +
+				"00:02.000 --> 00:04.000\nFirst.\n",
+			cues: []Cue{
+				{Start: 2, End: 4, Text: "First."},
+				{Start: 5, End: 7, Text: "Paradox."},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cues, err := Parse([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(cues) != len(tt.cues) {
+				t.Fatalf("expected %d cues, got %+v", len(tt.cues), cues)
+			}
+			for i, cue := range cues {
+				if cue != tt.cues[i] {
+					t.Errorf("cue %d: expected %+v, got %+v", i, tt.cues[i], cue)
+				}
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	if _, err := Parse([]byte("WEBVTT\n\nNOTE nothing here\n")); !errors.Is(err, ErrNoCues) {
+		t.Errorf("expected ErrNoCues, got %v", err)
+	}
+	if _, err := Parse([]byte("1\n00:00:aa,000 --> 00:00:02,000\nHello\n")); err == nil {
+		t.Error("expected an error for a malformed timestamp")
+	}
+}
+
+func TestFormatVTT(t *testing.T) {
+	cues := []Cue{
+		{Start: 3723.5, End: 3725, Text: "Left --> right"},
+		{Start: 0.25, End: 1, Text: "Two\nlines"},
+	}
+
+	vtt := string(FormatVTT(cues))
+	expected := "WEBVTT\n\n01:02:03.500 --> 01:02:05.000\nLeft -> right\n\n00:00:00.250 --> 00:00:01.000\nTwo\nlines\n"
+	if vtt != expected {
+		t.Errorf("unexpected WebVTT:\n%s", vtt)
+	}
+
+	parsed, err := Parse([]byte(vtt))
+	if err != nil || len(parsed) != 2 || parsed[1].Start != 3723.5 {
+		t.Errorf("expected the output to parse back, got %+v, %v", parsed, err)
+	}
+}
+
+func TestParseSidecar(t *testing.T) {
+	srt := []byte("1\n00:00:01,000 --> 00:00:02,000\nHello\n")
+
+	tests := []struct {
+		filename string
+		language string
+	}{
+		{"Inception.en.srt", "en"},
+		{"Inception.pt_BR.srt", "pt-BR"},
+		{"Inception.mkv.srt", ""},
+		{"Inception.srt", ""},
+	}
+	for _, tt := range tests {
+		sidecar, err := ParseSidecar(tt.filename, srt)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.filename, err)
+		}
+		if sidecar.Language != tt.language || len(sidecar.Cues) != 1 {
+			t.Errorf("%s: expected language %q, got %+v", tt.filename, tt.language, sidecar)
+		}
+	}
+
+	if _, err := ParseSidecar("Inception.ass", srt); err == nil {
+		t.Error("expected an error for an unsupported extension")
+	}
+}
@@ -0,0 +1,202 @@
+// Package titles normalizes and compares film titles as they arrive from OCR,
+// GPT captions and metadata providers. Keys ignore case, punctuation,
+// diacritics, script, leading articles, roman numerals and a trailing year,
+// and Match tolerates the few wrong characters OCR typically produces.
+package titles
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// DefaultThreshold is the Similarity at which Match treats two keys as the
+// same title.
+const DefaultThreshold = 0.85
+
+// minFuzzyLength is the shortest key Match compares by edit distance; shorter
+// keys must be equal, since one wrong letter in "heat" makes another film.
+const minFuzzyLength = 6
+
+// articles are dropped from the start of a key. German articles are left
+// alone: "Die Hard" is not a title about hardness.
+var articles = map[string]bool{
+	"the": true, "a": true, "an": true,
+	"le": true, "la": true, "les": true, "l": true,
+	"el": true, "los": true, "las": true,
+	"il": true,
+}
+
+var (
+	// yearSuffix matches a release year in brackets at the end of a title,
+	// e.g. "Inception (2010)". A bare trailing number is part of the title,
+	// as in "Blade Runner 2049".
+	yearSuffix = regexp.MustCompile(`\s*[(\[]\s*((?:18|19|20)\d{2})\s*[)\]]\s*$`)
+	// trailingArticle matches a library-style title such as "Dark Knight
+	// Rises, The".
+	trailingArticle = regexp.MustCompile(`(?i)^(.+?),\s*(the|a|an)\s*$`)
+	romanNumeral    = regexp.MustCompile(`^[ivx]{2,}$`)
+)
+
+// transliterations map letters that do not decompose into a Latin base
+// letter and a diacritic.
+var transliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d", 'ł': "l", 'þ': "th", 'ı': "i",
+	// Cyrillic
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ж': "zh", 'з': "z",
+	'и': "i", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r",
+	'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh",
+	'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	'і': "i", 'є': "ye", 'ґ': "g",
+	// Greek
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i", 'θ': "th",
+	'ι': "i", 'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p",
+	'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps",
+	'ω': "o",
+}
+
+// Fold lower-cases s, strips diacritics and transliterates Cyrillic and Greek
+// letters, so "Amélie" and "Амели" both fold to Latin letters.
+func Fold(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(strings.ToLower(s)) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		if t, ok := transliterations[r]; ok {
+			b.WriteString(t)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// SplitYear removes a bracketed release year from the end of title and
+// returns it, or 0 when there is none.
+func SplitYear(title string) (string, int) {
+	m := yearSuffix.FindStringSubmatchIndex(title)
+	if m == nil || m[0] == 0 {
+		return title, 0
+	}
+	year, _ := strconv.Atoi(title[m[2]:m[3]])
+	return title[:m[0]], year
+}
+
+// Normalize produces a comparison key: folded, punctuation removed, "&"
+// spelled out, roman numerals turned into digits, a trailing year and a
+// leading article dropped. "The Godfather: Part II (1974)" becomes
+// "godfather part 2".
+func Normalize(title string) string {
+	title, _ = SplitYear(title)
+	if m := trailingArticle.FindStringSubmatch(title); m != nil {
+		title = m[2] + " " + m[1]
+	}
+
+	var b strings.Builder
+	for _, r := range Fold(title) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		case r == '&':
+			b.WriteString(" and ")
+		default:
+			b.WriteRune(' ')
+		}
+	}
+
+	words := strings.Fields(b.String())
+	for i, word := range words {
+		if n := romanValue(word); n > 0 {
+			words[i] = strconv.Itoa(n)
+		}
+	}
+	if len(words) > 1 && articles[words[0]] {
+		words = words[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+// romanValue parses a numeral of two or more of i, v and x, up to 39, or
+// returns 0. A lone "i", "v" or "x" is too often a word or a letter.
+func romanValue(word string) int {
+	if !romanNumeral.MatchString(word) {
+		return 0
+	}
+	values := map[byte]int{'i': 1, 'v': 5, 'x': 10}
+	total := 0
+	for i := 0; i < len(word); i++ {
+		v := values[word[i]]
+		if i+1 < len(word) && v < values[word[i+1]] {
+			total -= v
+		} else {
+			total += v
+		}
+	}
+	if toRoman(total) != word {
+		return 0
+	}
+	return total
+}
+
+// toRoman formats n below 40 as a canonical numeral, so malformed numerals
+// such as "iiv" are rejected.
+func toRoman(n int) string {
+	var b strings.Builder
+	for _, step := range []struct {
+		value   int
+		numeral string
+	}{{10, "x"}, {9, "ix"}, {5, "v"}, {4, "iv"}, {1, "i"}} {
+		for n >= step.value {
+			b.WriteString(step.numeral)
+			n -= step.value
+		}
+	}
+	return b.String()
+}
+
+// Similarity is 1 minus the edit distance between two keys divided by the
+// longer key's length: 1 for equal keys, 0 for nothing in common.
+func Similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+// Match reports whether two keys name the same title: equal, or both long
+// enough to compare fuzzily and at least DefaultThreshold similar.
+func Match(a, b string) bool {
+	if a == b {
+		return true
+	}
+	if len([]rune(a)) < minFuzzyLength || len([]rune(b)) < minFuzzyLength {
+		return false
+	}
+	return Similarity(a, b) >= DefaultThreshold
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
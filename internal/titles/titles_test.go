@@ -0,0 +1,91 @@
+package titles
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"The Dark Knight Rises":         "dark knight rises",
+		"Dark Knight Rises, The":        "dark knight rises",
+		"THE DARK KNIGHT RISES":         "dark knight rises",
+		"Fast & Furious":                "fast and furious",
+		"Mission: Impossible":           "mission impossible",
+		"The Godfather: Part II (1974)": "godfather part 2",
+		"Rocky IV":                      "rocky 4",
+		"Star Wars: Episode VI":         "star wars episode 6",
+		"Blade Runner 2049":             "blade runner 2049",
+		"1917":                          "1917",
+		"(2010)":                        "2010",
+		"Amélie":                        "amelie",
+		"Léon: The Professional":        "leon the professional",
+		"Le Fabuleux Destin d'Amélie":   "fabuleux destin d amelie",
+		"Die Hard":                      "die hard",
+		"Брат":                          "brat",
+		"Malcolm X":                     "malcolm x",
+		"A":                             "a",
+		"":                              "",
+	}
+	for input, want := range tests {
+		if got := Normalize(input); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestSplitYear(t *testing.T) {
+	tests := []struct {
+		input string
+		title string
+		year  int
+	}{
+		{"Inception (2010)", "Inception", 2010},
+		{"Heat [1995]", "Heat", 1995},
+		{"Blade Runner 2049", "Blade Runner 2049", 0},
+		{"(1999)", "(1999)", 0},
+		{"Apollo 13", "Apollo 13", 0},
+	}
+	for _, tt := range tests {
+		title, year := SplitYear(tt.input)
+		if title != tt.title || year != tt.year {
+			t.Errorf("SplitYear(%q) = %q, %d, want %q, %d", tt.input, title, year, tt.title, tt.year)
+		}
+	}
+}
+
+func TestRomanValue(t *testing.T) {
+	tests := map[string]int{"ii": 2, "iv": 4, "ix": 9, "xiv": 14, "xxxix": 39, "iiv": 0, "vv": 0, "i": 0, "mix": 0}
+	for word, want := range tests {
+		if got := romanValue(word); got != want {
+			t.Errorf("romanValue(%q) = %d, want %d", word, got, want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"THE DARK KNIGHT RISFS", "The Dark Knight Rises", true},
+		{"INCEPTI0N", "Inception", true},
+		{"Rocky II", "Rocky 2", true},
+		{"Heat", "Heal", false},
+		{"Inception", "Interstellar", false},
+	}
+	for _, tt := range tests {
+		if got := Match(Normalize(tt.a), Normalize(tt.b)); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	if s := Similarity("inception", "inception"); s != 1 {
+		t.Errorf("expected equal keys to score 1, got %v", s)
+	}
+	if s := Similarity("", ""); s != 1 {
+		t.Errorf("expected empty keys to score 1, got %v", s)
+	}
+	if s := Similarity("heat", "inception"); s > 0.3 {
+		t.Errorf("expected unrelated keys to score low, got %v", s)
+	}
+}
@@ -0,0 +1,137 @@
+// Package trash keeps deleted videos restorable for a retention period and
+// then purges them: their database rows in one transaction, then their files
+// in storage.
+package trash
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/kdimtricp/vshazam/internal/models"
+	"github.com/kdimtricp/vshazam/internal/storage"
+)
+
+// DefaultRetention is how long a trashed video can be restored.
+const DefaultRetention = 30 * 24 * time.Hour
+
+// purgeBatch bounds how many expired videos one run purges.
+const purgeBatch = 50
+
+// Store is implemented by database.VideoRepository.
+type Store interface {
+	TrashVideo(id string) error
+	RestoreVideo(id string) (bool, error)
+	ListTrashedBefore(cutoff time.Time, limit int) ([]models.Video, error)
+	PurgeVideo(ctx context.Context, id string) ([]string, error)
+}
+
+// FaceCache is implemented by faces.Service, which keeps face clusters in
+// memory that purging a video changes.
+type FaceCache interface {
+	Invalidate(videoID string)
+}
+
+type Service struct {
+	store     Store
+	files     storage.Storage
+	faces     FaceCache
+	retention time.Duration
+	now       func() time.Time
+}
+
+func New(store Store, files storage.Storage) *Service {
+	return &Service{store: store, files: files, retention: DefaultRetention, now: time.Now}
+}
+
+// UseRetention keeps trashed videos restorable for d.
+func (s *Service) UseRetention(d time.Duration) {
+	s.retention = d
+}
+
+// UseFaces invalidates faces once a purge deleted a video's faces.
+func (s *Service) UseFaces(faces FaceCache) {
+	s.faces = faces
+}
+
+// Retention returns how long trashed videos can be restored.
+func (s *Service) Retention() time.Duration {
+	return s.retention
+}
+
+// PurgeAt returns when a video trashed at deletedAt will be purged.
+func (s *Service) PurgeAt(deletedAt time.Time) time.Time {
+	return deletedAt.Add(s.retention)
+}
+
+// Trash moves the video with id to the trash.
+func (s *Service) Trash(id string) error {
+	return s.store.TrashVideo(id)
+}
+
+// Restore takes the video with id out of the trash. It reports false when
+// the video was not in the trash.
+func (s *Service) Restore(id string) (bool, error) {
+	return s.store.RestoreVideo(id)
+}
+
+// Purge deletes the video with id for good. Files are deleted only after
+// their rows are gone; a file that cannot be deleted is logged and left
+// behind rather than leaving rows that point to nothing.
+func (s *Service) Purge(ctx context.Context, id string) error {
+	paths, err := s.store.PurgeVideo(ctx, id)
+	if err != nil {
+		return err
+	}
+	if s.faces != nil {
+		s.faces.Invalidate(id)
+	}
+	for _, path := range paths {
+		if err := s.files.DeleteFile(path); err != nil {
+			log.Printf("Failed to delete %s of purged video %s: %v", path, id, err)
+		}
+	}
+	return nil
+}
+
+// PurgeExpired purges videos trashed longer than the retention period and
+// returns how many it purged.
+func (s *Service) PurgeExpired(ctx context.Context) (int, error) {
+	expired, err := s.store.ListTrashedBefore(s.now().Add(-s.retention), purgeBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, video := range expired {
+		if err := ctx.Err(); err != nil {
+			return purged, err
+		}
+		if err := s.Purge(ctx, video.ID); err != nil {
+			log.Printf("Failed to purge video %s (%s): %v", video.ID, video.Title, err)
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// Run purges expired videos every interval until ctx is done.
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := s.PurgeExpired(ctx)
+			if err != nil {
+				log.Printf("Failed to empty the trash: %v", err)
+			} else if purged > 0 {
+				log.Printf("Purged %d videos from the trash", purged)
+			}
+		}
+	}
+}
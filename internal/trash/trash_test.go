@@ -0,0 +1,132 @@
+package trash
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime/multipart"
+	"testing"
+	"time"
+
+	"github.com/kdimtricp/vshazam/internal/models"
+	"github.com/kdimtricp/vshazam/internal/storage"
+	"gorm.io/gorm"
+)
+
+type memoryStore struct {
+	videos map[string]*models.Video
+	files  map[string][]string
+}
+
+func (m *memoryStore) TrashVideo(id string) error {
+	m.videos[id].DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	return nil
+}
+
+func (m *memoryStore) RestoreVideo(id string) (bool, error) {
+	video := m.videos[id]
+	if video == nil || !video.DeletedAt.Valid {
+		return false, nil
+	}
+	video.DeletedAt = gorm.DeletedAt{}
+	return true, nil
+}
+
+func (m *memoryStore) ListTrashedBefore(cutoff time.Time, limit int) ([]models.Video, error) {
+	var videos []models.Video
+	for _, video := range m.videos {
+		if video.DeletedAt.Valid && video.DeletedAt.Time.Before(cutoff) {
+			videos = append(videos, *video)
+		}
+	}
+	return videos, nil
+}
+
+func (m *memoryStore) PurgeVideo(ctx context.Context, id string) ([]string, error) {
+	if m.videos[id] == nil {
+		return nil, errors.New("video not found")
+	}
+	delete(m.videos, id)
+	return m.files[id], nil
+}
+
+type recordingFaces struct {
+	invalidated []string
+}
+
+func (f *recordingFaces) Invalidate(videoID string) {
+	f.invalidated = append(f.invalidated, videoID)
+}
+
+type recordingStorage struct {
+	deleted []string
+}
+
+func (s *recordingStorage) SaveFile(file multipart.File, info storage.FileInfo) (string, error) {
+	return "", nil
+}
+
+func (s *recordingStorage) OpenFile(path string) (io.ReadSeekCloser, error) {
+	return nil, errors.New("not found")
+}
+
+func (s *recordingStorage) DeleteFile(path string) error {
+	s.deleted = append(s.deleted, path)
+	return nil
+}
+
+func (s *recordingStorage) LocalPath(path string) (string, error) {
+	return path, nil
+}
+
+func TestService_PurgeExpired(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	store := &memoryStore{
+		videos: map[string]*models.Video{
+			"old":    {ID: "old", DeletedAt: gorm.DeletedAt{Time: now.Add(-8 * 24 * time.Hour), Valid: true}},
+			"recent": {ID: "recent", DeletedAt: gorm.DeletedAt{Time: now.Add(-time.Hour), Valid: true}},
+			"live":   {ID: "live"},
+		},
+		files: map[string][]string{"old": {"old.mp4", "face.jpg", "track.vtt"}},
+	}
+	files := &recordingStorage{}
+	faces := &recordingFaces{}
+	service := New(store, files)
+	service.UseFaces(faces)
+	service.UseRetention(7 * 24 * time.Hour)
+	service.now = func() time.Time { return now }
+
+	purged, err := service.PurgeExpired(context.Background())
+	if err != nil || purged != 1 {
+		t.Fatalf("expected one video purged, got %d, %v", purged, err)
+	}
+	if store.videos["old"] != nil || store.videos["recent"] == nil || store.videos["live"] == nil {
+		t.Errorf("unexpected videos left: %v", store.videos)
+	}
+	if len(files.deleted) != 3 {
+		t.Errorf("expected the video's files to be deleted, got %v", files.deleted)
+	}
+	if len(faces.invalidated) != 1 || faces.invalidated[0] != "old" {
+		t.Errorf("expected the face clusters to be invalidated, got %v", faces.invalidated)
+	}
+}
+
+func TestService_TrashAndRestore(t *testing.T) {
+	store := &memoryStore{videos: map[string]*models.Video{"v": {ID: "v"}}}
+	service := New(store, &recordingStorage{})
+
+	if restored, _ := service.Restore("v"); restored {
+		t.Error("expected a video outside the trash not to be restored")
+	}
+	if err := service.Trash("v"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if restored, _ := service.Restore("v"); !restored || store.videos["v"].DeletedAt.Valid {
+		t.Error("expected the video to be restored")
+	}
+
+	deletedAt := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	if got := service.PurgeAt(deletedAt); !got.Equal(deletedAt.Add(DefaultRetention)) {
+		t.Errorf("unexpected purge time %v", got)
+	}
+}
@@ -0,0 +1,45 @@
+-- Create identifications table for storing identification results
+CREATE TABLE IF NOT EXISTS identifications (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    video_id UUID NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+    top_title TEXT,
+    top_year INT DEFAULT 0,
+    top_tmdb_id INT DEFAULT 0,
+    top_score DOUBLE PRECISION DEFAULT 0,
+    candidates JSONB,
+    frames_analyzed INT DEFAULT 0,
+    stop_reason VARCHAR(32),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_identifications_video_id ON identifications(video_id);
+CREATE INDEX IF NOT EXISTS idx_identifications_created_at ON identifications(created_at);
+
+-- Create identification_feedback table for user verdicts on results
+CREATE TABLE IF NOT EXISTS identification_feedback (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    identification_id UUID NOT NULL REFERENCES identifications(id) ON DELETE CASCADE,
+    video_id UUID NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+    verdict VARCHAR(16) NOT NULL,
+    tmdb_id INT DEFAULT 0,
+    title TEXT,
+    year INT DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_identification_feedback_identification_id ON identification_feedback(identification_id);
+CREATE INDEX IF NOT EXISTS idx_identification_feedback_video_id ON identification_feedback(video_id);
+CREATE INDEX IF NOT EXISTS idx_identification_feedback_created_at ON identification_feedback(created_at);
+
+-- Create reference_fingerprints table for confirmed film fingerprints
+CREATE TABLE IF NOT EXISTS reference_fingerprints (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    video_id UUID NOT NULL UNIQUE REFERENCES videos(id) ON DELETE CASCADE,
+    tmdb_id INT DEFAULT 0,
+    title TEXT NOT NULL,
+    year INT DEFAULT 0,
+    tokens JSONB,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_reference_fingerprints_tmdb_id ON reference_fingerprints(tmdb_id);
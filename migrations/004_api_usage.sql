@@ -0,0 +1,15 @@
+-- Create api_usage table for cost and quota accounting of external API calls
+CREATE TABLE IF NOT EXISTS api_usage (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    provider VARCHAR(32) NOT NULL,
+    operation VARCHAR(64),
+    video_id VARCHAR(36),
+    units INT DEFAULT 0,
+    unit VARCHAR(16),
+    cost_usd DOUBLE PRECISION DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_usage_provider ON api_usage(provider);
+CREATE INDEX IF NOT EXISTS idx_api_usage_video_id ON api_usage(video_id);
+CREATE INDEX IF NOT EXISTS idx_api_usage_created_at ON api_usage(created_at);
@@ -0,0 +1,9 @@
+-- Store Google Vision web, logo, landmark and object detection results
+ALTER TABLE frame_analyses ADD COLUMN IF NOT EXISTS best_guess_labels JSONB;
+ALTER TABLE frame_analyses ADD COLUMN IF NOT EXISTS web_entities JSONB;
+ALTER TABLE frame_analyses ADD COLUMN IF NOT EXISTS matching_pages JSONB;
+ALTER TABLE frame_analyses ADD COLUMN IF NOT EXISTS logos JSONB;
+ALTER TABLE frame_analyses ADD COLUMN IF NOT EXISTS landmarks JSONB;
+ALTER TABLE frame_analyses ADD COLUMN IF NOT EXISTS objects JSONB;
+
+CREATE INDEX IF NOT EXISTS idx_frame_analyses_web_entities ON frame_analyses USING GIN (web_entities);
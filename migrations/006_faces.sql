@@ -0,0 +1,27 @@
+-- Create face_clusters table for faces grouped by person across the library
+CREATE TABLE IF NOT EXISTS face_clusters (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    centroid JSONB,
+    face_count INT DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create faces table for every face detected in an analyzed frame
+CREATE TABLE IF NOT EXISTS faces (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    video_id UUID NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+    frame_number INT NOT NULL,
+    x INT NOT NULL,
+    y INT NOT NULL,
+    width INT NOT NULL,
+    height INT NOT NULL,
+    confidence DOUBLE PRECISION DEFAULT 0,
+    thumbnail_path TEXT,
+    embedding JSONB,
+    cluster_id UUID REFERENCES face_clusters(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_faces_video_id ON faces(video_id);
+CREATE INDEX IF NOT EXISTS idx_faces_cluster_id ON faces(cluster_id);
@@ -0,0 +1,18 @@
+-- Store OCR lines with their position and the frame's time in the video
+ALTER TABLE frame_analyses ADD COLUMN IF NOT EXISTS frame_time DOUBLE PRECISION DEFAULT 0;
+ALTER TABLE frame_analyses ADD COLUMN IF NOT EXISTS text_blocks JSONB;
+
+-- Create transcript_cues table for timed text reassembled from a video
+CREATE TABLE IF NOT EXISTS transcript_cues (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    video_id UUID NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+    source VARCHAR(16) NOT NULL,
+    language VARCHAR(16),
+    start_time DOUBLE PRECISION NOT NULL,
+    end_time DOUBLE PRECISION NOT NULL,
+    text TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_transcript_cues_video_id ON transcript_cues(video_id);
+CREATE INDEX IF NOT EXISTS idx_transcript_cues_source ON transcript_cues(source);
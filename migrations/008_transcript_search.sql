@@ -0,0 +1,3 @@
+-- Full-text index over transcript cues for video search. The simple
+-- configuration is used because tracks come in many languages.
+CREATE INDEX IF NOT EXISTS idx_transcript_cues_text_search ON transcript_cues USING GIN (to_tsvector('simple', text));
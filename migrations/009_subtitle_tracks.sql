@@ -0,0 +1,14 @@
+-- Create subtitle_tracks table for embedded and uploaded subtitles, stored as WebVTT
+CREATE TABLE IF NOT EXISTS subtitle_tracks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    video_id UUID NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+    source VARCHAR(16) NOT NULL,
+    language VARCHAR(16),
+    label VARCHAR(255),
+    stream_index INT DEFAULT -1,
+    file_path TEXT NOT NULL,
+    cue_count INT DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_subtitle_tracks_video_id ON subtitle_tracks(video_id);
@@ -0,0 +1,37 @@
+-- Create films table, a local copy of TMDb metadata for identified films
+CREATE TABLE IF NOT EXISTS films (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tmdb_id INT NOT NULL UNIQUE,
+    imdb_id VARCHAR(16),
+    title TEXT NOT NULL,
+    year INT DEFAULT 0,
+    release_date VARCHAR(10),
+    overview TEXT,
+    runtime INT DEFAULT 0,
+    poster_path TEXT,
+    backdrop_path TEXT,
+    vote_average DOUBLE PRECISION DEFAULT 0,
+    genres JSONB,
+    synced_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_films_imdb_id ON films(imdb_id);
+CREATE INDEX IF NOT EXISTS idx_films_year ON films(year);
+CREATE INDEX IF NOT EXISTS idx_films_synced_at ON films(synced_at);
+CREATE INDEX IF NOT EXISTS idx_films_title_lower ON films(LOWER(title));
+
+-- Create film_credits table for the cast and crew of cataloged films
+CREATE TABLE IF NOT EXISTS film_credits (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    film_id UUID NOT NULL REFERENCES films(id) ON DELETE CASCADE,
+    role VARCHAR(8) NOT NULL,
+    name TEXT NOT NULL,
+    character_name TEXT,
+    job VARCHAR(64),
+    department VARCHAR(64),
+    position INT DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_film_credits_film_id ON film_credits(film_id);
+CREATE INDEX IF NOT EXISTS idx_film_credits_name ON film_credits(name);
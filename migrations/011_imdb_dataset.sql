@@ -0,0 +1,50 @@
+-- Create tables for a local copy of the IMDb datasets
+-- (https://datasets.imdbws.com/), loaded by cmd/imdb-import
+CREATE TABLE IF NOT EXISTS imdb_titles (
+    tconst VARCHAR(16) PRIMARY KEY,
+    title_type VARCHAR(32),
+    primary_title TEXT NOT NULL,
+    original_title TEXT,
+    search_title TEXT NOT NULL,
+    start_year INT DEFAULT 0,
+    end_year INT DEFAULT 0,
+    runtime_minutes INT DEFAULT 0,
+    genres JSONB
+);
+
+CREATE INDEX IF NOT EXISTS idx_imdb_titles_title_type ON imdb_titles(title_type);
+CREATE INDEX IF NOT EXISTS idx_imdb_titles_search_title ON imdb_titles(search_title);
+
+CREATE TABLE IF NOT EXISTS imdb_akas (
+    tconst VARCHAR(16) NOT NULL,
+    ordering INT NOT NULL,
+    title TEXT NOT NULL,
+    search_title TEXT NOT NULL,
+    region VARCHAR(8),
+    language VARCHAR(8),
+    is_original_title BOOLEAN DEFAULT FALSE,
+    PRIMARY KEY (tconst, ordering)
+);
+
+CREATE INDEX IF NOT EXISTS idx_imdb_akas_search_title ON imdb_akas(search_title);
+
+CREATE TABLE IF NOT EXISTS imdb_principals (
+    tconst VARCHAR(16) NOT NULL,
+    ordering INT NOT NULL,
+    nconst VARCHAR(16) NOT NULL,
+    category VARCHAR(32),
+    job TEXT,
+    characters JSONB,
+    PRIMARY KEY (tconst, ordering)
+);
+
+CREATE INDEX IF NOT EXISTS idx_imdb_principals_nconst ON imdb_principals(nconst);
+
+CREATE TABLE IF NOT EXISTS imdb_names (
+    nconst VARCHAR(16) PRIMARY KEY,
+    primary_name TEXT NOT NULL,
+    birth_year INT DEFAULT 0,
+    death_year INT DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_imdb_names_primary_name ON imdb_names(primary_name);
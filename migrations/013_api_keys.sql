@@ -0,0 +1,31 @@
+-- Create api_keys table for bearer tokens used by scripts
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    key_hash VARCHAR(64) NOT NULL UNIQUE,
+    scopes JSONB,
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);
+
+-- Create api_key_uses table, the audit trail of requests made with a key
+CREATE TABLE IF NOT EXISTS api_key_uses (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    key_id UUID NOT NULL REFERENCES api_keys(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    method VARCHAR(8) NOT NULL,
+    path TEXT NOT NULL,
+    status INT NOT NULL,
+    remote_ip VARCHAR(64),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_key_uses_key_id ON api_key_uses(key_id);
+CREATE INDEX IF NOT EXISTS idx_api_key_uses_user_id ON api_key_uses(user_id);
+CREATE INDEX IF NOT EXISTS idx_api_key_uses_created_at ON api_key_uses(created_at);
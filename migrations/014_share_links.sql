@@ -0,0 +1,15 @@
+-- Create share_links table for signed URLs that open a video without an account
+CREATE TABLE IF NOT EXISTS share_links (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    video_id UUID NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+    created_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    access VARCHAR(16) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    max_views INT NOT NULL DEFAULT 0,
+    views INT NOT NULL DEFAULT 0,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_share_links_video_id ON share_links(video_id);
+CREATE INDEX IF NOT EXISTS idx_share_links_created_by ON share_links(created_by);
@@ -0,0 +1,4 @@
+-- Record who started an identification, for the daily identification quota
+ALTER TABLE identifications ADD COLUMN IF NOT EXISTS requested_by UUID REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_identifications_requested_by ON identifications(requested_by, created_at);
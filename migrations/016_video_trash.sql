@@ -0,0 +1,4 @@
+-- Keep deleted videos in a trash until they are restored or purged
+ALTER TABLE videos ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_videos_deleted_at ON videos(deleted_at);
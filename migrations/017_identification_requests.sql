@@ -0,0 +1,10 @@
+-- Count every identification a user starts, finished or not, for the daily
+-- identification quota
+CREATE TABLE IF NOT EXISTS identification_requests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    video_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_identification_requests_user ON identification_requests(user_id, created_at);
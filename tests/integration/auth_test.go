@@ -0,0 +1,61 @@
+package integration
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAnonymousRequestsRejected(t *testing.T) {
+	ts := setupTestServer(t)
+	defer ts.Cleanup()
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	// Pages redirect to the sign-in form
+	resp, err := client.Get(ts.Server.URL + "/videos")
+	if err != nil {
+		t.Fatalf("Failed to get videos: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusSeeOther {
+		t.Errorf("Expected status 303, got %d", resp.StatusCode)
+	}
+	if location := resp.Header.Get("Location"); !strings.HasPrefix(location, "/login") {
+		t.Errorf("Expected a redirect to /login, got %q", location)
+	}
+
+	// Uploads are refused without storing anything
+	body, contentType, err := createMultipartUpload("Anonymous Video", "Should not be stored", "test.mp4", []byte("fake mp4 content"))
+	if err != nil {
+		t.Fatalf("Failed to create upload: %v", err)
+	}
+	req, err := http.NewRequest("POST", ts.Server.URL+"/upload", body)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to perform request: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", resp.StatusCode)
+	}
+
+	count, err := countVideosInDB(ts.DB.Conn())
+	if err != nil {
+		t.Fatalf("Failed to count videos: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected no videos, got %d", count)
+	}
+}
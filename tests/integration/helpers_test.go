@@ -2,6 +2,7 @@ package integration
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"fmt"
 	"io"
@@ -13,7 +14,9 @@ import (
 	"testing"
 
 	"github.com/kdimtricp/vshazam/internal/api"
+	"github.com/kdimtricp/vshazam/internal/auth"
 	"github.com/kdimtricp/vshazam/internal/database"
+	"github.com/kdimtricp/vshazam/internal/models/user"
 	"github.com/kdimtricp/vshazam/internal/storage"
 )
 
@@ -23,6 +26,8 @@ type TestServer struct {
 	DB          *database.DB
 	VideoRepo   *database.VideoRepository
 	Storage     storage.Storage
+	Auth        *auth.Service
+	Client      *http.Client
 	TempDir     string
 	OriginalDir string
 }
@@ -69,11 +74,25 @@ func setupTestServer(t *testing.T) *TestServer {
 
 	videoRepo := database.NewVideoRepository(db)
 
+	authService := auth.NewService(database.NewUserRepo(db))
+	authService.UseAPIKeys(database.NewAPIKeyRepo(db))
+
+	// Register a user whose API key signs in the test client
+	u, err := authService.Register(context.Background(), "test@example.com", "password123")
+	if err != nil {
+		t.Fatalf("Failed to register user: %v", err)
+	}
+	token, _, err := authService.CreateAPIKey(context.Background(), u, "integration tests", []string{user.ScopeRead, user.ScopeUpload, user.ScopeIdentify}, 0)
+	if err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+
 	// Create app
 	app := &api.App{
 		Storage:       localStorage,
 		DB:            db,
 		VideoRepo:     videoRepo,
+		Auth:          authService,
 		MaxUploadSize: 10 * 1024 * 1024, // 10MB
 	}
 
@@ -87,6 +106,8 @@ func setupTestServer(t *testing.T) *TestServer {
 		DB:          db,
 		VideoRepo:   videoRepo,
 		Storage:     localStorage,
+		Auth:        authService,
+		Client:      &http.Client{Transport: bearerTransport{token: token}},
 		TempDir:     tempDir,
 		OriginalDir: originalDir,
 	}
@@ -100,6 +121,17 @@ func (ts *TestServer) Cleanup() {
 	os.Chdir(ts.OriginalDir)
 }
 
+// bearerTransport sends every request with an API key.
+type bearerTransport struct {
+	token string
+}
+
+func (t bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
 func createMultipartUpload(title, description, filename string, content []byte) (*bytes.Buffer, string, error) {
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
@@ -136,7 +168,7 @@ func countVideosInDB(db *sql.DB) (int, error) {
 	return count, err
 }
 
-func uploadTestVideo(t *testing.T, server string, title, description string) *http.Response {
+func uploadTestVideo(t *testing.T, ts *TestServer, title, description string) *http.Response {
 	// Create a simple test video content
 	content := []byte("fake mp4 content for testing")
 	body, contentType, err := createMultipartUpload(title, description, "test.mp4", content)
@@ -144,14 +176,13 @@ func uploadTestVideo(t *testing.T, server string, title, description string) *ht
 		t.Fatalf("Failed to create multipart upload: %v", err)
 	}
 
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/upload", server), body)
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/upload", ts.Server.URL), body)
 	if err != nil {
 		t.Fatalf("Failed to create request: %v", err)
 	}
 	req.Header.Set("Content-Type", contentType)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := ts.Client.Do(req)
 	if err != nil {
 		t.Fatalf("Failed to upload video: %v", err)
 	}
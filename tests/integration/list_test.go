@@ -5,6 +5,8 @@ import (
 	"net/http"
 	"strings"
 	"testing"
+
+	"github.com/kdimtricp/vshazam/internal/database"
 )
 
 func TestVideoListing(t *testing.T) {
@@ -22,7 +24,7 @@ func TestVideoListing(t *testing.T) {
 	}
 
 	for _, v := range testVideos {
-		resp := uploadTestVideo(t, ts.Server.URL, v.title, v.description)
+		resp := uploadTestVideo(t, ts, v.title, v.description)
 		if resp.StatusCode != http.StatusOK {
 			t.Fatalf("Failed to upload video: %s", v.title)
 		}
@@ -30,7 +32,7 @@ func TestVideoListing(t *testing.T) {
 	}
 
 	// Test listing endpoint
-	resp, err := http.Get(ts.Server.URL + "/videos")
+	resp, err := ts.Client.Get(ts.Server.URL + "/videos")
 	if err != nil {
 		t.Fatalf("Failed to get videos: %v", err)
 	}
@@ -63,7 +65,7 @@ func TestEmptyVideoList(t *testing.T) {
 	defer ts.Cleanup()
 
 	// Test listing with no videos
-	resp, err := http.Get(ts.Server.URL + "/videos")
+	resp, err := ts.Client.Get(ts.Server.URL + "/videos")
 	if err != nil {
 		t.Fatalf("Failed to get videos: %v", err)
 	}
@@ -89,14 +91,14 @@ func TestVideoPartialEndpoint(t *testing.T) {
 	defer ts.Cleanup()
 
 	// Upload a test video
-	resp := uploadTestVideo(t, ts.Server.URL, "Test Video", "Test Description")
+	resp := uploadTestVideo(t, ts, "Test Video", "Test Description")
 	if resp.StatusCode != http.StatusOK {
 		t.Fatal("Failed to upload test video")
 	}
 	resp.Body.Close()
 
 	// Test partial endpoint (used by HTMX)
-	resp, err := http.Get(ts.Server.URL + "/videos/partial")
+	resp, err := ts.Client.Get(ts.Server.URL + "/videos/partial")
 	if err != nil {
 		t.Fatalf("Failed to get partial videos: %v", err)
 	}
@@ -122,14 +124,14 @@ func TestVideoDetailPage(t *testing.T) {
 	defer ts.Cleanup()
 
 	// Upload a test video
-	resp := uploadTestVideo(t, ts.Server.URL, "Detail Test Video", "Detailed description")
+	resp := uploadTestVideo(t, ts, "Detail Test Video", "Detailed description")
 	if resp.StatusCode != http.StatusOK {
 		t.Fatal("Failed to upload test video")
 	}
 	resp.Body.Close()
 
 	// Get the video ID
-	videos, err := ts.VideoRepo.ListVideos()
+	videos, err := ts.VideoRepo.ListVideos(database.AllVideos())
 	if err != nil || len(videos) == 0 {
 		t.Fatal("Failed to get uploaded video")
 	}
@@ -137,7 +139,7 @@ func TestVideoDetailPage(t *testing.T) {
 	videoID := videos[0].ID
 
 	// Test video detail page
-	resp, err = http.Get(ts.Server.URL + "/videos/" + videoID)
+	resp, err = ts.Client.Get(ts.Server.URL + "/videos/" + videoID)
 	if err != nil {
 		t.Fatalf("Failed to get video detail: %v", err)
 	}
@@ -167,7 +169,7 @@ func TestNonExistentVideo(t *testing.T) {
 	defer ts.Cleanup()
 
 	// Try to access non-existent video
-	resp, err := http.Get(ts.Server.URL + "/videos/non-existent-id")
+	resp, err := ts.Client.Get(ts.Server.URL + "/videos/non-existent-id")
 	if err != nil {
 		t.Fatalf("Failed to get video: %v", err)
 	}
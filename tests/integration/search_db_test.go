@@ -2,6 +2,8 @@ package integration
 
 import (
 	"testing"
+
+	"github.com/kdimtricp/vshazam/internal/database"
 )
 
 func TestSearchDatabase(t *testing.T) {
@@ -21,7 +23,7 @@ func TestSearchDatabase(t *testing.T) {
 	}
 
 	for _, v := range testVideos {
-		resp := uploadTestVideo(t, ts.Server.URL, v.title, v.description)
+		resp := uploadTestVideo(t, ts, v.title, v.description)
 		if resp.StatusCode != 200 {
 			t.Fatalf("Failed to upload video: %s", v.title)
 		}
@@ -75,7 +77,7 @@ func TestSearchDatabase(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			videos, err := ts.VideoRepo.SearchVideos(tt.query)
+			videos, err := ts.VideoRepo.SearchVideos(tt.query, database.AllVideos())
 			if err != nil {
 				t.Fatalf("Search failed: %v", err)
 			}
@@ -106,7 +108,7 @@ func TestSearchPartialWords(t *testing.T) {
 	defer ts.Cleanup()
 
 	// Upload a video
-	resp := uploadTestVideo(t, ts.Server.URL, "Programming Tutorial", "Learn programming basics")
+	resp := uploadTestVideo(t, ts, "Programming Tutorial", "Learn programming basics")
 	if resp.StatusCode != 200 {
 		t.Fatal("Failed to upload video")
 	}
@@ -117,7 +119,7 @@ func TestSearchPartialWords(t *testing.T) {
 	
 	for _, query := range searches {
 		t.Run("Search for "+query, func(t *testing.T) {
-			videos, err := ts.VideoRepo.SearchVideos(query)
+			videos, err := ts.VideoRepo.SearchVideos(query, database.AllVideos())
 			if err != nil {
 				t.Fatalf("Search failed: %v", err)
 			}
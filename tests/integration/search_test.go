@@ -27,7 +27,7 @@ func TestSearchFunctionality(t *testing.T) {
 	}
 
 	for _, v := range testVideos {
-		resp := uploadTestVideo(t, ts.Server.URL, v.title, v.description)
+		resp := uploadTestVideo(t, ts, v.title, v.description)
 		if resp.StatusCode != http.StatusOK {
 			t.Fatalf("Failed to upload video: %s", v.title)
 		}
@@ -105,7 +105,7 @@ func TestSearchFunctionality(t *testing.T) {
 			}
 			req.Header.Set("HX-Request", "true")
 			
-			client := &http.Client{}
+			client := ts.Client
 			resp, err := client.Do(req)
 			if err != nil {
 				t.Fatalf("Failed to perform search: %v", err)
@@ -158,7 +158,7 @@ func TestSearchWithHTMXHeaders(t *testing.T) {
 	defer ts.Cleanup()
 
 	// Upload a test video
-	resp := uploadTestVideo(t, ts.Server.URL, "HTMX Test Video", "Testing HTMX search functionality")
+	resp := uploadTestVideo(t, ts, "HTMX Test Video", "Testing HTMX search functionality")
 	if resp.StatusCode != http.StatusOK {
 		t.Fatal("Failed to upload test video")
 	}
@@ -172,7 +172,7 @@ func TestSearchWithHTMXHeaders(t *testing.T) {
 	}
 	req.Header.Set("HX-Request", "true")
 
-	client := &http.Client{}
+	client := ts.Client
 	resp, err = client.Do(req)
 	if err != nil {
 		t.Fatalf("Failed to perform HTMX search: %v", err)
@@ -204,7 +204,7 @@ func TestEmptySearchQuery(t *testing.T) {
 	defer ts.Cleanup()
 
 	// Upload a test video
-	resp := uploadTestVideo(t, ts.Server.URL, "Empty Query Test", "Should not appear in empty search")
+	resp := uploadTestVideo(t, ts, "Empty Query Test", "Should not appear in empty search")
 	if resp.StatusCode != http.StatusOK {
 		t.Fatal("Failed to upload test video")
 	}
@@ -218,7 +218,7 @@ func TestEmptySearchQuery(t *testing.T) {
 	}
 	req.Header.Set("HX-Request", "true")
 	
-	client := &http.Client{}
+	client := ts.Client
 	resp, err = client.Do(req)
 	if err != nil {
 		t.Fatalf("Failed to perform search: %v", err)
@@ -257,7 +257,7 @@ func TestSearchSpecialCharacters(t *testing.T) {
 	}
 
 	for _, v := range testVideos {
-		resp := uploadTestVideo(t, ts.Server.URL, v.title, v.description)
+		resp := uploadTestVideo(t, ts, v.title, v.description)
 		if resp.StatusCode != http.StatusOK {
 			t.Fatalf("Failed to upload video: %s", v.title)
 		}
@@ -274,7 +274,7 @@ func TestSearchSpecialCharacters(t *testing.T) {
 			}
 			req.Header.Set("HX-Request", "true")
 			
-			client := &http.Client{}
+			client := ts.Client
 			resp, err := client.Do(req)
 			if err != nil {
 				t.Fatalf("Failed to search: %v", err)
@@ -67,7 +67,7 @@ func TestVideoUpload(t *testing.T) {
 			req.Header.Set("Content-Type", contentType)
 
 			// Perform upload
-			client := &http.Client{}
+			client := ts.Client
 			resp, err := client.Do(req)
 			if err != nil {
 				t.Fatalf("Failed to perform request: %v", err)
@@ -114,7 +114,7 @@ func TestMultipleUploads(t *testing.T) {
 	}
 
 	for _, v := range videos {
-		resp := uploadTestVideo(t, ts.Server.URL, v.title, v.description)
+		resp := uploadTestVideo(t, ts, v.title, v.description)
 		if resp.StatusCode != http.StatusOK {
 			t.Errorf("Failed to upload video '%s': status %d", v.title, resp.StatusCode)
 		}
@@ -0,0 +1,9 @@
+// Sends the session's CSRF token with every HTMX request. The server sets
+// the vshazam_csrf cookie at sign-in and rejects state-changing requests
+// without a matching X-CSRF-Token header.
+document.addEventListener("htmx:configRequest", function (event) {
+    var match = document.cookie.match(/(?:^|; )vshazam_csrf=([^;]*)/);
+    if (match) {
+        event.detail.headers["X-CSRF-Token"] = decodeURIComponent(match[1]);
+    }
+});
@@ -0,0 +1,9 @@
+// HTMX leaves error responses unswapped. Quota and rate limit refusals come
+// with an alert fragment meant for the request's target, so show those.
+document.addEventListener("htmx:beforeSwap", function (event) {
+    var status = event.detail.xhr.status;
+    if (status === 413 || status === 429) {
+        event.detail.shouldSwap = true;
+        event.detail.isError = false;
+    }
+});